@@ -10,7 +10,6 @@ import (
 	"net/http"
 	"net/url"
 	"strings"
-	"sync"
 	"time"
 
 	"github.com/gorilla/websocket"
@@ -27,17 +26,19 @@ import (
 type Client struct {
 	*chshare.Logger
 
-	config         *Config
-	sshConfig      *ssh.ClientConfig
-	sshConn        ssh.Conn
-	running        bool
-	runningc       chan error
-	connStats      chshare.ConnStats
-	cmdExec        CmdExecutor
-	curCmdPID      *int
-	curCmdPIDMutex sync.Mutex
-	systemInfo     SystemInfo
-	runCmdMutex    sync.Mutex
+	config     *Config
+	sshConfig  *ssh.ClientConfig
+	sshConn    ssh.Conn
+	running    bool
+	runningc   chan error
+	connStats  chshare.ConnStats
+	cmdExec    CmdExecutor
+	cmdPool    *cmdPool
+	systemInfo SystemInfo
+
+	reverseTunnels *reverseTunnels
+	hostKeys       *hostKeys
+	bandwidth      *chshare.TokenBucket // limits the total bandwidth of all tunnels, nil if not limited
 }
 
 //NewClient creates a new client instance
@@ -48,12 +49,17 @@ func NewClient(config *Config) *Client {
 		running:    true,
 		runningc:   make(chan error, 1),
 		cmdExec:    NewCmdExecutor(),
+		cmdPool:    newCmdPool(config.RemoteCommands.Concurrency),
 		systemInfo: NewSystemInfo(),
+
+		reverseTunnels: newReverseTunnels(),
+		hostKeys:       newHostKeys(config.Client.Fingerprints),
+		bandwidth:      chshare.NewTokenBucket(config.Connection.MaxBytesPerSec),
 	}
 
 	client.sshConfig = &ssh.ClientConfig{
 		User:            config.Client.authUser,
-		Auth:            []ssh.AuthMethod{ssh.Password(config.Client.authPass)},
+		Auth:            config.Client.authMethods(),
 		ClientVersion:   "SSH-" + chshare.ProtocolVersion + "-client",
 		HostKeyCallback: client.verifyServer,
 		Timeout:         30 * time.Second,
@@ -73,12 +79,11 @@ func (c *Client) Run() error {
 }
 
 func (c *Client) verifyServer(hostname string, remote net.Addr, key ssh.PublicKey) error {
-	got := chshare.FingerprintKey(key)
-	if c.config.Client.Fingerprint != "" && !strings.HasPrefix(got, c.config.Client.Fingerprint) {
-		return fmt.Errorf("Invalid fingerprint (%s)", got)
+	if err := c.hostKeys.verify(c.Logger, key); err != nil {
+		return err
 	}
 	//overwrite with complete fingerprint
-	c.Infof("Fingerprint %s", got)
+	c.Infof("Fingerprint %s", chshare.FingerprintKey(key))
 	return nil
 }
 
@@ -224,9 +229,11 @@ func (c *Client) connectionLoop(ctx context.Context) {
 		c.sshConn = sshConn
 		go c.handleSSHRequests(ctx, reqs)
 		go c.connectStreams(chans)
+		c.startReverseTunnels(sshConn, remotes)
 		err = sshConn.Wait()
 		//disconnected
 		c.sshConn = nil
+		c.reverseTunnels.closeAll()
 		if err != nil && err != io.EOF {
 			connerr = err
 			continue
@@ -245,6 +252,17 @@ func (c *Client) handleSSHRequests(ctx context.Context, reqs <-chan *ssh.Request
 			resp, err = checkPort(r.Payload)
 		case comm.RequestTypeRunCmd:
 			resp, err = c.HandleRunCmdRequest(ctx, r.Payload)
+		case comm.RequestTypeCancelCmd:
+			resp, err = c.HandleCancelCmdRequest(r.Payload)
+		case comm.RequestTypeStartReverseTunnel:
+			err = c.HandleStartReverseTunnelRequest(r.Payload)
+		case comm.RequestTypeStopReverseTunnel:
+			err = c.HandleStopReverseTunnelRequest(r.Payload)
+		case comm.RequestTypeHostKeys:
+			if err := c.hostKeys.announce(c.Logger, r.Payload); err != nil {
+				c.Errorf("Failed to handle %q request: %v", r.Type, err)
+			}
+			continue
 		default:
 			c.Debugf("Unknown request: %q", r.Type)
 			continue
@@ -308,6 +326,14 @@ func (c *Client) Close() error {
 
 func (c *Client) connectStreams(chans <-chan ssh.NewChannel) {
 	for ch := range chans {
+		if ch.ChannelType() == comm.ChannelTypeFile {
+			go c.handleFileChannel(ch)
+			continue
+		}
+		if ch.ChannelType() == comm.ChannelTypeSocks {
+			go c.handleSocksChannel(ch)
+			continue
+		}
 		remote := string(ch.ExtraData())
 		stream, reqs, err := ch.Accept()
 		if err != nil {
@@ -316,7 +342,11 @@ func (c *Client) connectStreams(chans <-chan ssh.NewChannel) {
 		}
 		go ssh.DiscardRequests(reqs)
 		l := c.Logger.Fork("conn#%d", c.connStats.New())
-		go chshare.HandleTCPStream(l, &c.connStats, stream, remote)
+		if ch.ChannelType() == comm.ChannelTypeUDP {
+			go chshare.HandleUDPStream(l, &c.connStats, stream, remote, c.bandwidth)
+			continue
+		}
+		go chshare.HandleTCPStream(l, &c.connStats, stream, remote, c.bandwidth)
 	}
 }
 
@@ -349,18 +379,6 @@ func (c *Client) localIPAddresses() ([]string, []string, error) {
 	return ipv4, ipv6, nil
 }
 
-func (c *Client) getCurCmdPID() *int {
-	c.curCmdPIDMutex.Lock()
-	defer c.curCmdPIDMutex.Unlock()
-	return c.curCmdPID
-}
-
-func (c *Client) setCurCmdPID(pid *int) {
-	c.curCmdPIDMutex.Lock()
-	defer c.curCmdPIDMutex.Unlock()
-	c.curCmdPID = pid
-}
-
 func (c *Client) connectionRequest(ctx context.Context) *chshare.ConnectionRequest {
 	ctx, cancel := context.WithTimeout(ctx, time.Second*5)
 	defer cancel()
@@ -27,10 +27,10 @@ func TestCustomHeaders(t *testing.T) {
 
 	config := Config{
 		Client: ClientConfig{
-			Fingerprint: "",
-			Auth:        "",
-			Server:      server.URL,
-			Remotes:     []string{"192.168.0.5:3000:google.com:80"},
+			Fingerprints: nil,
+			Auth:         "",
+			Server:       server.URL,
+			Remotes:      []string{"192.168.0.5:3000:google.com:80"},
 		},
 		Connection: ConnectionConfig{
 			KeepAlive:        time.Second,
@@ -39,6 +39,10 @@ func TestCustomHeaders(t *testing.T) {
 			HeadersRaw:       []string{"Foo: Bar"},
 		},
 		RemoteCommands: CommandsConfig{
+			Order:       allowDenyOrder,
+			Concurrency: 1,
+		},
+		FileTransfer: FileTransferConfig{
 			Order: allowDenyOrder,
 		},
 	}
@@ -3,12 +3,16 @@ package chclient
 import (
 	"errors"
 	"fmt"
+	"io/ioutil"
 	"net/http"
 	"net/url"
+	"os"
 	"regexp"
 	"strings"
 	"time"
 
+	"golang.org/x/crypto/ssh"
+
 	chshare "github.com/cloudradar-monitoring/rport/share"
 )
 
@@ -18,6 +22,7 @@ type ConnectionConfig struct {
 	MaxRetryInterval time.Duration `mapstructure:"max_retry_interval"`
 	HeadersRaw       []string      `mapstructure:"headers"`
 	Hostname         string        `mapstructure:"hostname"`
+	MaxBytesPerSec   int64         `mapstructure:"max_bytes_per_sec"`
 
 	headers http.Header
 }
@@ -28,20 +33,36 @@ type LogConfig struct {
 }
 
 type ClientConfig struct {
-	Server      string   `mapstructure:"server"`
-	Fingerprint string   `mapstructure:"fingerprint"`
-	Auth        string   `mapstructure:"auth"`
-	Proxy       string   `mapstructure:"proxy"`
-	ID          string   `mapstructure:"id"`
-	Name        string   `mapstructure:"name"`
-	Tags        []string `mapstructure:"tags"`
-	Remotes     []string `mapstructure:"remotes"`
-	AllowRoot   bool     `mapstructure:"allow_root"`
+	Server       string   `mapstructure:"server"`
+	Fingerprints []string `mapstructure:"fingerprint"`
+	Auth         string   `mapstructure:"auth"`
+	AuthKey      string   `mapstructure:"auth_key"`
+	AuthCert     string   `mapstructure:"auth_cert"`
+	Proxy        string   `mapstructure:"proxy"`
+	ID           string   `mapstructure:"id"`
+	Name         string   `mapstructure:"name"`
+	Tags         []string `mapstructure:"tags"`
+	Remotes      []string `mapstructure:"remotes"`
+	AllowRoot    bool     `mapstructure:"allow_root"`
 
 	proxyURL *url.URL
 	remotes  []*chshare.Remote
 	authUser string
 	authPass string
+	// authSigner is nil if the client authenticates only with a password
+	authSigner ssh.Signer
+}
+
+// authMethods returns ssh auth methods of the client. A key is tried first, a password is used as a fallback if it's set.
+func (c *ClientConfig) authMethods() []ssh.AuthMethod {
+	if c.authSigner == nil {
+		return []ssh.AuthMethod{ssh.Password(c.authPass)}
+	}
+	methods := []ssh.AuthMethod{ssh.PublicKeys(c.authSigner)}
+	if c.authPass != "" {
+		methods = append(methods, ssh.Password(c.authPass))
+	}
+	return methods
 }
 
 func (c *ConnectionConfig) Headers() http.Header {
@@ -56,19 +77,41 @@ var (
 type CommandsConfig struct {
 	Enabled       bool      `mapstructure:"enabled"`
 	SendBackLimit int       `mapstructure:"send_back_limit"`
+	Concurrency   int       `mapstructure:"concurrency"`
+	ScriptDir     string    `mapstructure:"script_dir"`
+	ScriptSHA256  []string  `mapstructure:"script_allow_sha256"`
 	Allow         []string  `mapstructure:"allow"`
 	Deny          []string  `mapstructure:"deny"`
 	Order         [2]string `mapstructure:"order"`
 
+	allowRegexp  []*regexp.Regexp
+	denyRegexp   []*regexp.Regexp
+	scriptHashes map[string]bool
+}
+
+type FileTransferConfig struct {
+	Enabled bool      `mapstructure:"enabled"`
+	Allow   []string  `mapstructure:"allow"`
+	Deny    []string  `mapstructure:"deny"`
+	Order   [2]string `mapstructure:"order"`
+
 	allowRegexp []*regexp.Regexp
 	denyRegexp  []*regexp.Regexp
 }
 
+type SocksConfig struct {
+	Allow []string `mapstructure:"allow"`
+
+	destinations *chshare.TunnelDestinations
+}
+
 type Config struct {
-	Client         ClientConfig     `mapstructure:"client"`
-	Connection     ConnectionConfig `mapstructure:"connection"`
-	Logging        LogConfig        `mapstructure:"logging"`
-	RemoteCommands CommandsConfig   `mapstructure:"remote-commands"`
+	Client         ClientConfig       `mapstructure:"client"`
+	Connection     ConnectionConfig   `mapstructure:"connection"`
+	Logging        LogConfig          `mapstructure:"logging"`
+	RemoteCommands CommandsConfig     `mapstructure:"remote-commands"`
+	FileTransfer   FileTransferConfig `mapstructure:"file-transfer"`
+	Socks          SocksConfig        `mapstructure:"socks"`
 }
 
 func (c *Config) ParseAndValidate() error {
@@ -87,13 +130,105 @@ func (c *Config) ParseAndValidate() error {
 	if c.Connection.MaxRetryInterval < time.Second {
 		c.Connection.MaxRetryInterval = 5 * time.Minute
 	}
+	if c.Connection.MaxBytesPerSec < 0 {
+		return errors.New("max_bytes_per_sec can't be negative")
+	}
 	if err := c.parseRemoteCommands(); err != nil {
 		return fmt.Errorf("remote commands: %v", err)
 	}
+	if err := c.parseFileTransfer(); err != nil {
+		return fmt.Errorf("file transfer: %v", err)
+	}
+	if err := c.parseSocks(); err != nil {
+		return fmt.Errorf("socks: %v", err)
+	}
+	if err := c.parseAuth(); err != nil {
+		return fmt.Errorf("auth: %v", err)
+	}
+	if err := c.parseFingerprints(); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (c *Config) parseFingerprints() error {
+	fingerprints := make([]string, 0, len(c.Client.Fingerprints))
+	for _, f := range c.Client.Fingerprints {
+		f = strings.TrimSpace(f)
+		if f == "" {
+			continue
+		}
+		if err := chshare.ValidateFingerprint(f); err != nil {
+			return err
+		}
+		fingerprints = append(fingerprints, f)
+	}
+	c.Client.Fingerprints = fingerprints
+	return nil
+}
+
+func (c *Config) parseAuth() error {
 	c.Client.authUser, c.Client.authPass = chshare.ParseAuth(c.Client.Auth)
+	if c.Client.AuthKey == "" {
+		if c.Client.AuthCert != "" {
+			return errors.New("'auth_cert' requires 'auth_key' to be set")
+		}
+		return nil
+	}
+
+	// a client auth id without a password is allowed when a key is used
+	if c.Client.authUser == "" {
+		c.Client.authUser = c.Client.Auth
+	}
+
+	key, err := ioutil.ReadFile(c.Client.AuthKey)
+	if err != nil {
+		return fmt.Errorf("failed to read 'auth_key': %v", err)
+	}
+	signer, err := ssh.ParsePrivateKey(key)
+	if err != nil {
+		return fmt.Errorf("failed to parse 'auth_key': %v", err)
+	}
+
+	if c.Client.AuthCert != "" {
+		signer, err = c.certSigner(signer)
+		if err != nil {
+			return err
+		}
+	}
+
+	if c.Client.authUser == "" {
+		return errors.New("'auth' must contain a client auth id when 'auth_key' is used without 'auth_cert'")
+	}
+	c.Client.authSigner = signer
 	return nil
 }
 
+// certSigner returns a signer that authenticates with the certificate of a given key. The client auth id is taken from
+// the certificate unless it is set.
+func (c *Config) certSigner(signer ssh.Signer) (ssh.Signer, error) {
+	b, err := ioutil.ReadFile(c.Client.AuthCert)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read 'auth_cert': %v", err)
+	}
+	key, _, _, _, err := ssh.ParseAuthorizedKey(b)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse 'auth_cert': %v", err)
+	}
+	cert, ok := key.(*ssh.Certificate)
+	if !ok {
+		return nil, fmt.Errorf("'auth_cert' is not a certificate: %s", key.Type())
+	}
+	certSigner, err := ssh.NewCertSigner(cert, signer)
+	if err != nil {
+		return nil, fmt.Errorf("'auth_cert' doesn't match 'auth_key': %v", err)
+	}
+	if c.Client.authUser == "" && len(cert.ValidPrincipals) > 0 {
+		c.Client.authUser = cert.ValidPrincipals[0]
+	}
+	return certSigner, nil
+}
+
 func (c *Config) parseHeaders() error {
 	c.Connection.headers = http.Header{}
 	for _, h := range c.Connection.HeadersRaw {
@@ -175,6 +310,10 @@ func (c *Config) parseRemoteCommands() error {
 		return fmt.Errorf("send back limit can not be negative: %d", c.RemoteCommands.SendBackLimit)
 	}
 
+	if c.RemoteCommands.Concurrency < 1 {
+		return fmt.Errorf("concurrency should be a positive number: %d", c.RemoteCommands.Concurrency)
+	}
+
 	allow, err := parseRegexpList(c.RemoteCommands.Allow)
 	if err != nil {
 		return fmt.Errorf("allow regexp: %v", err)
@@ -191,9 +330,59 @@ func (c *Config) parseRemoteCommands() error {
 		return fmt.Errorf("invalid order: %v", c.RemoteCommands.Order)
 	}
 
+	if c.RemoteCommands.ScriptDir == "" {
+		c.RemoteCommands.ScriptDir = os.TempDir()
+	}
+	info, err := os.Stat(c.RemoteCommands.ScriptDir)
+	if err != nil {
+		return fmt.Errorf("script dir: %v", err)
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("script dir: %q is not a directory", c.RemoteCommands.ScriptDir)
+	}
+
+	c.RemoteCommands.scriptHashes = make(map[string]bool, len(c.RemoteCommands.ScriptSHA256))
+	for _, hash := range c.RemoteCommands.ScriptSHA256 {
+		if !sha256Regexp.MatchString(hash) {
+			return fmt.Errorf("invalid script SHA256 hash %q: expected 64 hex characters", hash)
+		}
+		c.RemoteCommands.scriptHashes[strings.ToLower(hash)] = true
+	}
+
 	return nil
 }
 
+func (c *Config) parseFileTransfer() error {
+	allow, err := parseRegexpList(c.FileTransfer.Allow)
+	if err != nil {
+		return fmt.Errorf("allow regexp: %v", err)
+	}
+	c.FileTransfer.allowRegexp = allow
+
+	deny, err := parseRegexpList(c.FileTransfer.Deny)
+	if err != nil {
+		return fmt.Errorf("deny regexp: %v", err)
+	}
+	c.FileTransfer.denyRegexp = deny
+
+	if c.FileTransfer.Order != allowDenyOrder && c.FileTransfer.Order != denyAllowOrder {
+		return fmt.Errorf("invalid order: %v", c.FileTransfer.Order)
+	}
+
+	return nil
+}
+
+func (c *Config) parseSocks() error {
+	destinations, err := chshare.ParseTunnelDestinations(c.Socks.Allow)
+	if err != nil {
+		return err
+	}
+	c.Socks.destinations = destinations
+	return nil
+}
+
+var sha256Regexp = regexp.MustCompile("^[0-9a-fA-F]{64}$")
+
 func parseRegexpList(regexpList []string) ([]*regexp.Regexp, error) {
 	res := make([]*regexp.Regexp, 0, len(regexpList))
 	for _, cur := range regexpList {
@@ -1,14 +1,20 @@
 package chclient
 
 import (
+	"crypto/rand"
+	"io/ioutil"
 	"net/http"
 	"net/url"
+	"os"
+	"path/filepath"
 	"regexp"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh"
 
 	chshare "github.com/cloudradar-monitoring/rport/share"
 )
@@ -20,9 +26,13 @@ var defaultValidMinConfig = Config{
 	RemoteCommands: CommandsConfig{
 		Enabled:       true,
 		SendBackLimit: 2048,
+		Concurrency:   4,
 		Order:         allowDenyOrder,
 		allowRegexp:   []*regexp.Regexp{regexp.MustCompile(".*")},
 	},
+	FileTransfer: FileTransferConfig{
+		Order: allowDenyOrder,
+	},
 }
 
 func TestConfigParseAndValidateHeaders(t *testing.T) {
@@ -170,6 +180,40 @@ func TestConfigParseAndValidateMaxRetryInterval(t *testing.T) {
 	}
 }
 
+func TestConfigParseAndValidateMaxBytesPerSec(t *testing.T) {
+	testCases := []struct {
+		Name           string
+		MaxBytesPerSec int64
+		ExpectedError  string
+	}{
+		{
+			Name:           "unlimited",
+			MaxBytesPerSec: 0,
+		}, {
+			Name:           "limited",
+			MaxBytesPerSec: 1024 * 1024,
+		}, {
+			Name:           "negative",
+			MaxBytesPerSec: -1,
+			ExpectedError:  "max_bytes_per_sec can't be negative",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			config := defaultValidMinConfig
+			config.Connection.MaxBytesPerSec = tc.MaxBytesPerSec
+			err := config.ParseAndValidate()
+
+			if tc.ExpectedError == "" {
+				require.NoError(t, err)
+			} else {
+				require.EqualError(t, err, tc.ExpectedError)
+			}
+		})
+	}
+}
+
 func TestConfigParseAndValidateProxyURL(t *testing.T) {
 	expectedProxyURL, err := url.Parse("http://proxy.com")
 	require.NoError(t, err)
@@ -230,6 +274,7 @@ func TestConfigParseAndValidateRemotes(t *testing.T) {
 				&chshare.Remote{
 					RemoteHost: "0.0.0.0",
 					RemotePort: "8000",
+					Protocol:   chshare.ProtocolTCP,
 				},
 			},
 		}, {
@@ -239,12 +284,41 @@ func TestConfigParseAndValidateRemotes(t *testing.T) {
 				&chshare.Remote{
 					RemoteHost: "0.0.0.0",
 					RemotePort: "8000",
+					Protocol:   chshare.ProtocolTCP,
 				},
 				&chshare.Remote{
 					RemoteHost: "0.0.0.0",
 					RemotePort: "3000",
+					Protocol:   chshare.ProtocolTCP,
 				},
 			},
+		}, {
+			Name:    "udp",
+			Remotes: []string{"5353:8.8.8.8:53/udp"},
+			ExpectedRemotes: []*chshare.Remote{
+				&chshare.Remote{
+					LocalHost:  "0.0.0.0",
+					LocalPort:  "5353",
+					RemoteHost: "8.8.8.8",
+					RemotePort: "53",
+					Protocol:   chshare.ProtocolUDP,
+				},
+			},
+		}, {
+			Name:    "socks",
+			Remotes: []string{"1080:socks"},
+			ExpectedRemotes: []*chshare.Remote{
+				&chshare.Remote{
+					LocalHost: "0.0.0.0",
+					LocalPort: "1080",
+					Protocol:  chshare.ProtocolTCP,
+					Socks:     true,
+				},
+			},
+		}, {
+			Name:          "invalid protocol",
+			Remotes:       []string{"8000/sctp"},
+			ExpectedError: `failed to decode remote "8000/sctp": Invalid protocol`,
 		}, {
 			Name:          "invalid",
 			Remotes:       []string{"abc"},
@@ -299,6 +373,125 @@ func TestConfigParseAndValidateAuth(t *testing.T) {
 	}
 }
 
+func TestConfigParseAndValidateFingerprints(t *testing.T) {
+	config := defaultValidMinConfig
+	config.Client.Fingerprints = []string{"", " SHA256:mVPwvezndPv/ARoIadVY98vAC0g+P/5633yTC4d/wXE ", "36:98:56:12"}
+
+	err := config.ParseAndValidate()
+
+	require.NoError(t, err)
+	assert.Equal(t, []string{"SHA256:mVPwvezndPv/ARoIadVY98vAC0g+P/5633yTC4d/wXE", "36:98:56:12"}, config.Client.Fingerprints)
+
+	config = defaultValidMinConfig
+	config.Client.Fingerprints = []string{"mVPwvezndPv"}
+
+	err = config.ParseAndValidate()
+
+	assert.EqualError(t, err, `invalid fingerprint "mVPwvezndPv": expected 'SHA256:<base64>' or md5 'xx:xx:...'`)
+}
+
+func TestConfigParseAndValidateAuthKey(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rport-auth")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	writeKey := func(name string) (string, ssh.Signer) {
+		key, err := chshare.GenerateKey("")
+		require.NoError(t, err)
+		signer, err := ssh.ParsePrivateKey(key)
+		require.NoError(t, err)
+		path := filepath.Join(dir, name)
+		require.NoError(t, ioutil.WriteFile(path, key, 0600))
+		return path, signer
+	}
+	keyFile, signer := writeKey("client.key")
+	_, caSigner := writeKey("ca.key")
+	cert := &ssh.Certificate{
+		Key:             signer.PublicKey(),
+		CertType:        ssh.UserCert,
+		ValidPrincipals: []string{"cert-client"},
+		ValidBefore:     ssh.CertTimeInfinity,
+	}
+	require.NoError(t, cert.SignCert(rand.Reader, caSigner))
+	certFile := filepath.Join(dir, "client-cert.pub")
+	require.NoError(t, ioutil.WriteFile(certFile, ssh.MarshalAuthorizedKey(cert), 0600))
+	otherKeyFile, _ := writeKey("other.key")
+
+	testCases := []struct {
+		Name         string
+		Auth         string
+		AuthKey      string
+		AuthCert     string
+		ExpectedUser string
+		ExpectedCert bool
+		ExpectedErr  string
+	}{
+		{
+			Name:         "key with client auth id",
+			Auth:         "test",
+			AuthKey:      keyFile,
+			ExpectedUser: "test",
+		}, {
+			Name:        "key without client auth id",
+			AuthKey:     keyFile,
+			ExpectedErr: "auth: 'auth' must contain a client auth id when 'auth_key' is used without 'auth_cert'",
+		}, {
+			Name:         "certificate",
+			AuthKey:      keyFile,
+			AuthCert:     certFile,
+			ExpectedUser: "cert-client",
+			ExpectedCert: true,
+		}, {
+			Name:         "certificate with client auth id and password",
+			Auth:         "test:pass123",
+			AuthKey:      keyFile,
+			AuthCert:     certFile,
+			ExpectedUser: "test",
+			ExpectedCert: true,
+		}, {
+			Name:        "certificate of another key",
+			AuthKey:     otherKeyFile,
+			AuthCert:    certFile,
+			ExpectedErr: "auth: 'auth_cert' doesn't match 'auth_key': ssh: signer and cert have different public key",
+		}, {
+			Name:        "certificate without key",
+			AuthCert:    certFile,
+			ExpectedErr: "auth: 'auth_cert' requires 'auth_key' to be set",
+		}, {
+			Name:        "key is not a certificate",
+			AuthKey:     keyFile,
+			AuthCert:    keyFile,
+			ExpectedErr: "auth: failed to parse 'auth_cert': ssh: no key found",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			config := defaultValidMinConfig
+			config.Client.Auth = tc.Auth
+			config.Client.AuthKey = tc.AuthKey
+			config.Client.AuthCert = tc.AuthCert
+
+			err := config.ParseAndValidate()
+
+			if tc.ExpectedErr != "" {
+				assert.EqualError(t, err, tc.ExpectedErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.ExpectedUser, config.Client.authUser)
+			require.NotNil(t, config.Client.authSigner)
+			_, isCert := config.Client.authSigner.PublicKey().(*ssh.Certificate)
+			assert.Equal(t, tc.ExpectedCert, isCert)
+			wantMethods := 1
+			if config.Client.authPass != "" {
+				wantMethods = 2
+			}
+			assert.Len(t, config.Client.authMethods(), wantMethods)
+		})
+	}
+}
+
 func TestConfigParseAndValidateSendBackLimit(t *testing.T) {
 	testCases := []struct {
 		name            string
@@ -342,6 +535,105 @@ func TestConfigParseAndValidateSendBackLimit(t *testing.T) {
 	}
 }
 
+func TestConfigParseAndValidateConcurrency(t *testing.T) {
+	testCases := []struct {
+		name            string
+		concurrency     int
+		wantErrContains string
+	}{
+		{
+			name:        "valid concurrency",
+			concurrency: 1,
+		},
+		{
+			name:            "zero concurrency",
+			concurrency:     0,
+			wantErrContains: "concurrency should be a positive number: 0",
+		},
+		{
+			name:            "negative concurrency",
+			concurrency:     -1,
+			wantErrContains: "concurrency should be a positive number: -1",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// given
+			config := defaultValidMinConfig
+			config.RemoteCommands.Concurrency = tc.concurrency
+
+			// when
+			gotErr := config.ParseAndValidate()
+
+			// then
+			if tc.wantErrContains != "" {
+				require.Error(t, gotErr)
+				assert.Contains(t, gotErr.Error(), tc.wantErrContains)
+			} else {
+				require.NoError(t, gotErr)
+			}
+		})
+	}
+}
+
+func TestConfigParseAndValidateScripts(t *testing.T) {
+	hash := "0E5751C026E543B2E8AB2EB06099DAA1D1E5DF47778F7787FAAB45CDF12FE3A8"
+	testCases := []struct {
+		name            string
+		scriptDir       string
+		scriptSHA256    []string
+		wantScriptDir   string
+		wantHashes      map[string]bool
+		wantErrContains string
+	}{
+		{
+			name:          "default script dir, no scripts allowed",
+			wantScriptDir: os.TempDir(),
+			wantHashes:    map[string]bool{},
+		},
+		{
+			name:          "valid hash",
+			scriptDir:     os.TempDir(),
+			scriptSHA256:  []string{hash},
+			wantScriptDir: os.TempDir(),
+			wantHashes:    map[string]bool{strings.ToLower(hash): true},
+		},
+		{
+			name:            "invalid hash",
+			scriptSHA256:    []string{"abc"},
+			wantErrContains: `invalid script SHA256 hash "abc": expected 64 hex characters`,
+		},
+		{
+			name:            "non-existing script dir",
+			scriptDir:       "/non-existing-dir",
+			wantErrContains: "script dir: stat /non-existing-dir",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// given
+			config := defaultValidMinConfig
+			config.RemoteCommands.ScriptDir = tc.scriptDir
+			config.RemoteCommands.ScriptSHA256 = tc.scriptSHA256
+
+			// when
+			gotErr := config.ParseAndValidate()
+
+			// then
+			if tc.wantErrContains != "" {
+				require.Error(t, gotErr)
+				assert.Contains(t, gotErr.Error(), tc.wantErrContains)
+			} else {
+				require.NoError(t, gotErr)
+				assert.Equal(t, tc.wantScriptDir, config.RemoteCommands.ScriptDir)
+				assert.Equal(t, tc.wantHashes, config.RemoteCommands.scriptHashes)
+			}
+		})
+	}
+}
+
 func TestConfigParseAndValidateAllowRegexp(t *testing.T) {
 	testCases := []struct {
 		name            string
@@ -487,3 +779,86 @@ func TestConfigParseAndValidateAllowDenyOrder(t *testing.T) {
 		})
 	}
 }
+
+func TestConfigParseAndValidateFileTransfer(t *testing.T) {
+	testCases := []struct {
+		name            string
+		allow           []string
+		deny            []string
+		order           [2]string
+		wantErrContains string
+	}{
+		{
+			name:  "valid",
+			allow: []string{"^/etc/rport/.*"},
+			deny:  []string{`\.key$`},
+			order: denyAllowOrder,
+		},
+		{
+			name:            "invalid allow regexp",
+			allow:           []string{"[a-z"},
+			order:           allowDenyOrder,
+			wantErrContains: "file transfer: allow regexp: invalid regular expression",
+		},
+		{
+			name:            "invalid deny regexp",
+			deny:            []string{"[a-z"},
+			order:           allowDenyOrder,
+			wantErrContains: "file transfer: deny regexp: invalid regular expression",
+		},
+		{
+			name:            "invalid order",
+			order:           [2]string{"deny", "unknown"},
+			wantErrContains: "file transfer: invalid order:",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// given
+			config := defaultValidMinConfig
+			config.FileTransfer = FileTransferConfig{
+				Enabled: true,
+				Allow:   tc.allow,
+				Deny:    tc.deny,
+				Order:   tc.order,
+			}
+
+			// when
+			gotErr := config.ParseAndValidate()
+
+			// then
+			if tc.wantErrContains != "" {
+				require.Error(t, gotErr)
+				assert.Contains(t, gotErr.Error(), tc.wantErrContains)
+			} else {
+				require.NoError(t, gotErr)
+				assert.Len(t, config.FileTransfer.allowRegexp, len(tc.allow))
+				assert.Len(t, config.FileTransfer.denyRegexp, len(tc.deny))
+			}
+		})
+	}
+}
+
+func TestConfigParseAndValidateSocks(t *testing.T) {
+	// given
+	config := defaultValidMinConfig
+	config.Socks = SocksConfig{Allow: []string{"192.168.178.0/24:*", "nas.local:443"}}
+
+	// when
+	gotErr := config.ParseAndValidate()
+
+	// then
+	require.NoError(t, gotErr)
+	assert.True(t, config.Socks.destinations.Allows("192.168.178.10", "22"))
+	assert.False(t, config.Socks.destinations.Allows("nas.local", "80"))
+
+	// given
+	config.Socks = SocksConfig{Allow: []string{"nas.local"}}
+
+	// when
+	gotErr = config.ParseAndValidate()
+
+	// then
+	assert.EqualError(t, gotErr, `socks: invalid destination "nas.local": address nas.local: missing port in address`)
+}
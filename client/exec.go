@@ -3,9 +3,13 @@ package chclient
 import (
 	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io/ioutil"
+	"os"
 	"os/exec"
 	"regexp"
 	"runtime"
@@ -17,8 +21,12 @@ import (
 
 type CmdExecutor interface {
 	New(ctx context.Context, shell, cmd string) *exec.Cmd
+	// NewScript returns a command that executes a given script file by a given interpreter
+	NewScript(ctx context.Context, interpreter, scriptPath string) *exec.Cmd
 	Start(cmd *exec.Cmd) error
 	Wait(cmd *exec.Cmd) error
+	// Kill kills a process with a given PID and all its child processes
+	Kill(pid int) error
 }
 
 type CmdExecutorImpl struct {
@@ -44,6 +52,13 @@ func (e *CmdExecutorImpl) newCmd(ctx context.Context, shell, command string) *ex
 	return cmd
 }
 
+func (e *CmdExecutorImpl) newScriptCmd(ctx context.Context, interpreter, scriptPath string) *exec.Cmd {
+	var args []string
+	args = append(args, scriptInterpreters[interpreter].options...)
+	args = append(args, scriptPath)
+	return exec.CommandContext(ctx, scriptInterpreters[interpreter].cmd, args...)
+}
+
 const (
 	unixShell  = "/bin/sh"
 	cmdShell   = "cmd"
@@ -60,6 +75,38 @@ var shellOptions = map[string][]string{
 	},
 }
 
+const (
+	shInterpreter     = "sh"
+	bashInterpreter   = "bash"
+	cmdInterpreter    = "cmd"
+	psInterpreter     = "powershell"
+	pythonInterpreter = "python"
+)
+
+type scriptInterpreter struct {
+	cmd     string
+	options []string
+	// ext is an extension of a script file, some interpreters refuse to run files without a proper one
+	ext string
+}
+
+var scriptInterpreters = map[string]scriptInterpreter{
+	shInterpreter:   {cmd: unixShell, ext: ".sh"},
+	bashInterpreter: {cmd: "bash", ext: ".sh"},
+	cmdInterpreter:  {cmd: cmdShell, options: []string{"/c"}, ext: ".bat"},
+	psInterpreter: {
+		cmd: powerShell,
+		options: []string{
+			"-NoProfile",
+			"-Noninteractive",
+			"-ExecutionPolicy", "Bypass", // allow to run unsigned scripts
+			"-File",
+		},
+		ext: ".ps1",
+	},
+	pythonInterpreter: {cmd: pythonCmd, ext: ".py"},
+}
+
 // now is used to stub time.Now in tests
 var now = time.Now
 
@@ -74,45 +121,63 @@ func (c *Client) HandleRunCmdRequest(ctx context.Context, reqPayload []byte) (*c
 		return nil, fmt.Errorf("failed to decode requested job: %s", err)
 	}
 
-	// do not accept a new request when the previous is not finished yet, except multi-client job. In this case wait
-	// NOTE: HandleRunCmdRequest is run sequentially, that's why no need to lock a block with read/write curPID
-	curPID := c.getCurCmdPID()
-	if curPID != nil {
-		if job.MultiJobID == nil {
-			return nil, fmt.Errorf("a previous command execution with PID %d is still running", *curPID)
+	if job.Script != "" {
+		job.Interpreter, err = getInterpreter(job.Interpreter, runtime.GOOS)
+		if err != nil {
+			return nil, err
+		}
+
+		if hash := scriptHash(job.Script); !c.config.RemoteCommands.scriptHashes[hash] {
+			return nil, fmt.Errorf("script is not allowed, its SHA256 hash %s is not in the list of allowed scripts", hash)
+		}
+	} else {
+		job.Shell, err = getShell(job.Shell, runtime.GOOS)
+		if err != nil {
+			return nil, err
+		}
+
+		if !c.isAllowed(job.Command) {
+			return nil, fmt.Errorf("command is not allowed: %v", job.Command)
 		}
-		c.Debugf("Waiting for a previous command with PID %d to finish", *curPID)
 	}
 
-	// TODO: temporary solution, refactor with using worker pool
-	c.runCmdMutex.Lock()
+	// if all workers are busy the job is started later, as soon as one of the running commands is finished
+	if !c.cmdPool.Acquire(&job) {
+		c.Debugf("all %d workers are busy, cmd [jid=%q] is queued", c.config.RemoteCommands.Concurrency, job.JID)
+		return &comm.RunCmdResponse{Queued: true}, nil
+	}
 
-	job.Shell, err = getShell(job.Shell, runtime.GOOS)
+	res, err := c.startCmd(ctx, &job, false)
 	if err != nil {
-		c.runCmdMutex.Unlock()
+		c.releaseCmdWorker(ctx, job.JID)
 		return nil, err
 	}
+	return res, nil
+}
 
-	if !c.isAllowed(job.Command) {
-		c.runCmdMutex.Unlock()
-		return nil, fmt.Errorf("command is not allowed: %v", job.Command)
+// startCmd starts a command of a given job and observes its execution in background. The worker occupied by the job
+// is released when the command is finished, even if observing is stopped earlier by the timeout. If notifyStart is true
+// the server is informed that the job is running.
+func (c *Client) startCmd(ctx context.Context, job *models.Job, notifyStart bool) (*comm.RunCmdResponse, error) {
+	cmd, scriptPath, err := c.newCmd(ctx, job)
+	if err != nil {
+		return nil, err
 	}
-
-	cmd := c.cmdExec.New(ctx, job.Shell, job.Command)
 	stdOut := CapacityBuffer{capacity: c.config.RemoteCommands.SendBackLimit}
 	stdErr := CapacityBuffer{capacity: c.config.RemoteCommands.SendBackLimit}
-	cmd.Stdout = &stdOut
-	cmd.Stderr = &stdErr
+	streamer := newOutputStreamer(c, job)
+	cmd.Stdout = streamer.StdOut(&stdOut)
+	cmd.Stderr = streamer.StdErr(&stdErr)
 
 	startedAt := now()
 	err = c.cmdExec.Start(cmd)
 	if err != nil {
-		c.runCmdMutex.Unlock()
+		c.removeScript(scriptPath)
 		return nil, fmt.Errorf("failed to start a command: %s", err)
 	}
 
 	// set running PID
-	c.setCurCmdPID(&cmd.Process.Pid)
+	c.cmdPool.SetPID(job.JID, cmd.Process.Pid)
 
 	res := &comm.RunCmdResponse{
 		Pid:       cmd.Process.Pid,
@@ -120,17 +185,33 @@ func (c *Client) HandleRunCmdRequest(ctx context.Context, reqPayload []byte) (*c
 	}
 
 	// observe the cmd execution in background
+	streamer.Start()
 	go func() {
 		c.Debugf("started to observe cmd [jid=%q,pid=%d]", job.JID, res.Pid)
 
+		if notifyStart {
+			job.Status = models.JobStatusRunning
+			job.PID = &res.Pid
+			job.StartedAt = startedAt
+			c.sendJob(job)
+		}
+
 		// after timeout stop observing but leave the cmd running
-		done := make(chan error)
-		go func() { done <- c.cmdExec.Wait(cmd) }()
+		done := make(chan error, 1)
+		go func() {
+			err := c.cmdExec.Wait(cmd)
+			// the script file is not needed anymore when the cmd is finished
+			c.removeScript(scriptPath)
+			done <- err
+		}()
 
 		var status string
 		select {
 		case err := <-done:
-			if err != nil {
+			if c.cmdPool.IsCancelled(job.JID) {
+				status = models.JobStatusCancelled
+				c.Debugf("command[jid=%q,pid=%d] is cancelled", job.JID, res.Pid)
+			} else if err != nil {
 				status = models.JobStatusFailed
 				c.Errorf("failed to run command[jid=%q,pid=%d]:\ncmd:\n%s\nerr: %s", job.JID, res.Pid, job.Command, err)
 			} else {
@@ -141,39 +222,147 @@ func (c *Client) HandleRunCmdRequest(ctx context.Context, reqPayload []byte) (*c
 			c.Debugf("timeout (%d seconds) reached, stop observing command[jid=%q,pid=%d]:\n%s", job.TimeoutSec, job.JID, res.Pid, job.Command)
 		}
 
-		// observing stopped - unset PID
-		c.setCurCmdPID(nil)
-		c.runCmdMutex.Unlock()
+		// observing stopped - stop streaming the output
+		streamer.Stop()
 
 		// fill all unset fields
-		now := now()
-		job.FinishedAt = &now
+		finishedAt := now()
+		job.FinishedAt = &finishedAt
 		job.Status = status
 		job.PID = &res.Pid
 		job.StartedAt = startedAt
-		job.Result = &models.JobResult{
-			StdOut: stdOut.String(),
-			StdErr: stdErr.String(),
-		}
+		job.Result = streamer.Result(&stdOut, &stdErr)
 
 		// send the filled job to the server
-		jobBytes, err := json.Marshal(job)
-		if err != nil {
-			c.Errorf("failed to send command result for [jid=%q,pid=%d]: failed to encode job result: %s", job.JID, res.Pid, err)
-			return
-		}
-		c.Debugf("sending job to server: %v", job)
-		_, _, err = c.sshConn.SendRequest(comm.RequestTypeCmdResult, false, jobBytes)
-		if err != nil {
-			c.Errorf("failed to send command result to server[jid=%q,pid=%d]: %s", job.JID, res.Pid, err)
-		}
+		c.sendJob(job)
 
 		c.Debugf("finished to observe cmd [jid=%q,pid=%d]", job.JID, res.Pid)
+
+		// a cmd that outlived the timeout keeps occupying the worker and can be cancelled until it's finished
+		if status == models.JobStatusUnknown {
+			<-done
+			c.Debugf("command[jid=%q,pid=%d] is finished after the timeout", job.JID, res.Pid)
+
+			if c.cmdPool.IsCancelled(job.JID) {
+				c.Debugf("command[jid=%q,pid=%d] is cancelled", job.JID, res.Pid)
+				finishedAt := now()
+				job.FinishedAt = &finishedAt
+				job.Status = models.JobStatusCancelled
+				c.sendJob(job)
+			}
+		}
+
+		c.releaseCmdWorker(ctx, job.JID)
 	}()
 
 	return res, nil
 }
 
+// newCmd returns a command to execute a given job. If the job executes a script then the script is written to
+// a file in the configured script dir and its path is returned as well. The file should be removed by the caller.
+func (c *Client) newCmd(ctx context.Context, job *models.Job) (*exec.Cmd, string, error) {
+	if job.Script == "" {
+		return c.cmdExec.New(ctx, job.Shell, job.Command), "", nil
+	}
+
+	scriptPath, err := writeScript(c.config.RemoteCommands.ScriptDir, job.Script, scriptInterpreters[job.Interpreter].ext)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to write a script: %s", err)
+	}
+	return c.cmdExec.NewScript(ctx, job.Interpreter, scriptPath), scriptPath, nil
+}
+
+func writeScript(dir, script, ext string) (string, error) {
+	f, err := ioutil.TempFile(dir, "rport-script-*"+ext)
+	if err != nil {
+		return "", err
+	}
+	_, err = f.WriteString(script)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return "", err
+	}
+	return f.Name(), nil
+}
+
+func (c *Client) removeScript(scriptPath string) {
+	if scriptPath == "" {
+		return
+	}
+	if err := os.Remove(scriptPath); err != nil {
+		c.Errorf("failed to remove script file %q: %s", scriptPath, err)
+	}
+}
+
+// releaseCmdWorker frees a worker occupied by a given job and starts the next queued job if any.
+func (c *Client) releaseCmdWorker(ctx context.Context, jid string) {
+	next := c.cmdPool.Release(jid)
+	for next != nil {
+		_, err := c.startCmd(ctx, next, true)
+		if err == nil {
+			return
+		}
+
+		c.Errorf("failed to start queued cmd [jid=%q]: %s", next.JID, err)
+		now := now()
+		next.Status = models.JobStatusFailed
+		next.FinishedAt = &now
+		next.Error = err.Error()
+		c.sendJob(next)
+
+		next = c.cmdPool.Release(next.JID)
+	}
+}
+
+// sendJob sends a given job with its current status to the server.
+func (c *Client) sendJob(job *models.Job) {
+	jobBytes, err := json.Marshal(job)
+	if err != nil {
+		c.Errorf("failed to send job[jid=%q] to server: failed to encode job: %s", job.JID, err)
+		return
+	}
+	c.Debugf("sending job to server: %v", job)
+	_, _, err = c.sshConn.SendRequest(comm.RequestTypeCmdResult, false, jobBytes)
+	if err != nil {
+		c.Errorf("failed to send job[jid=%q] to server: %s", job.JID, err)
+	}
+}
+
+// HandleCancelCmdRequest kills a running command of a given job together with all its child processes.
+// A queued job is removed from the queue.
+func (c *Client) HandleCancelCmdRequest(reqPayload []byte) (*comm.CancelCmdResponse, error) {
+	req := comm.CancelCmdRequest{}
+	err := json.Unmarshal(reqPayload, &req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode cancel cmd request: %s", err)
+	}
+
+	pid, queuedJob, err := c.cmdPool.Cancel(req.JID)
+	if err != nil {
+		return nil, err
+	}
+
+	if queuedJob != nil {
+		c.Debugf("cancelling queued command[jid=%q]", req.JID)
+		now := now()
+		queuedJob.Status = models.JobStatusCancelled
+		queuedJob.FinishedAt = &now
+		c.sendJob(queuedJob)
+		return &comm.CancelCmdResponse{}, nil
+	}
+
+	c.Debugf("cancelling command[jid=%q,pid=%d]", req.JID, pid)
+	if err := c.cmdExec.Kill(pid); err != nil {
+		c.cmdPool.SetCancelled(req.JID, false)
+		return nil, fmt.Errorf("failed to kill command[jid=%q,pid=%d]: %s", req.JID, pid, err)
+	}
+
+	return &comm.CancelCmdResponse{Pid: pid}, nil
+}
+
 // var is used to override in tests
 var getShell = func(inputShell, os string) (string, error) {
 	if os == "windows" {
@@ -192,11 +381,42 @@ var getShell = func(inputShell, os string) (string, error) {
 	return unixShell, nil
 }
 
+func getInterpreter(inputInterpreter, os string) (string, error) {
+	if os == "windows" {
+		switch inputInterpreter {
+		case "":
+			return cmdInterpreter, nil
+		case cmdInterpreter, psInterpreter, pythonInterpreter:
+			return inputInterpreter, nil
+		}
+		return "", fmt.Errorf("invalid windows script interpreter: %q", inputInterpreter)
+	}
+
+	switch inputInterpreter {
+	case "":
+		return shInterpreter, nil
+	case shInterpreter, bashInterpreter, pythonInterpreter:
+		return inputInterpreter, nil
+	}
+	return "", fmt.Errorf("invalid unix script interpreter: %q", inputInterpreter)
+}
+
+// scriptHash returns a hex encoded SHA256 hash of a given script.
+func scriptHash(script string) string {
+	sum := sha256.Sum256([]byte(script))
+	return hex.EncodeToString(sum[:])
+}
+
 // isAllowed returns true if a given command passes configured restrictions.
 func (c *Client) isAllowed(cmd string) bool {
-	allowMatch := matchRegexp(cmd, c.config.RemoteCommands.allowRegexp)
-	denyMatch := matchRegexp(cmd, c.config.RemoteCommands.denyRegexp)
-	switch c.config.RemoteCommands.Order {
+	return matchAllowDeny(cmd, c.config.RemoteCommands.allowRegexp, c.config.RemoteCommands.denyRegexp, c.config.RemoteCommands.Order)
+}
+
+// matchAllowDeny returns true if a given value passes given allow and deny regular expressions applied in a given order.
+func matchAllowDeny(value string, allow, deny []*regexp.Regexp, order [2]string) bool {
+	allowMatch := matchRegexp(value, allow)
+	denyMatch := matchRegexp(value, deny)
+	switch order {
 	case allowDenyOrder:
 		if !allowMatch {
 			return false
@@ -5,8 +5,25 @@ package chclient
 import (
 	"context"
 	"os/exec"
+	"syscall"
 )
 
+const pythonCmd = "python3"
+
 func (e *CmdExecutorImpl) New(ctx context.Context, shell, command string) *exec.Cmd {
-	return e.newCmd(ctx, shell, command)
+	cmd := e.newCmd(ctx, shell, command)
+	// run in a separate process group to be able to kill the entire process tree
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	return cmd
+}
+
+func (e *CmdExecutorImpl) NewScript(ctx context.Context, interpreter, scriptPath string) *exec.Cmd {
+	cmd := e.newScriptCmd(ctx, interpreter, scriptPath)
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	return cmd
+}
+
+func (e *CmdExecutorImpl) Kill(pid int) error {
+	// negative PID kills all processes in the process group
+	return syscall.Kill(-pid, syscall.SIGKILL)
 }
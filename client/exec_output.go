@@ -0,0 +1,126 @@
+package chclient
+
+import (
+	"bytes"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/cloudradar-monitoring/rport/share/comm"
+	"github.com/cloudradar-monitoring/rport/share/models"
+)
+
+// cmdOutputInterval defines how often output of a running command is sent to the server. Var is used to override in tests.
+var cmdOutputInterval = time.Second
+
+// outputStreamer collects output of a running command and periodically sends new chunks of it to the server.
+// Only the output that fits into the send back limit is streamed, the same output is sent in the final job result.
+type outputStreamer struct {
+	c   *Client
+	job *models.Job
+
+	mu      sync.Mutex
+	stdOut  bytes.Buffer
+	stdErr  bytes.Buffer
+	stop    chan struct{}
+	stopped chan struct{}
+}
+
+func newOutputStreamer(c *Client, job *models.Job) *outputStreamer {
+	return &outputStreamer{
+		c:       c,
+		job:     job,
+		stop:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+}
+
+// StdOut returns a writer that writes to a given buffer and streams everything the buffer accepted.
+func (s *outputStreamer) StdOut(buf *CapacityBuffer) *streamWriter {
+	return &streamWriter{s: s, buf: buf, pending: &s.stdOut}
+}
+
+// StdErr is the same as StdOut but for stderr.
+func (s *outputStreamer) StdErr(buf *CapacityBuffer) *streamWriter {
+	return &streamWriter{s: s, buf: buf, pending: &s.stdErr}
+}
+
+// Start starts sending output chunks in background until Stop is called.
+func (s *outputStreamer) Start() {
+	go func() {
+		defer close(s.stopped)
+		ticker := time.NewTicker(cmdOutputInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-s.stop:
+				return
+			case <-ticker.C:
+				s.send()
+			}
+		}
+	}()
+}
+
+// Stop stops sending output chunks and waits until a chunk that is being sent is sent. Output that is not sent yet is
+// discarded because the final job result contains the entire output.
+func (s *outputStreamer) Stop() {
+	close(s.stop)
+	<-s.stopped
+}
+
+func (s *outputStreamer) send() {
+	s.mu.Lock()
+	if s.stdOut.Len() == 0 && s.stdErr.Len() == 0 {
+		s.mu.Unlock()
+		return
+	}
+	output := models.JobOutput{
+		JID:        s.job.JID,
+		MultiJobID: s.job.MultiJobID,
+		ClientID:   s.job.ClientID,
+		Output: &models.JobResult{
+			StdOut: s.stdOut.String(),
+			StdErr: s.stdErr.String(),
+		},
+	}
+	s.stdOut.Reset()
+	s.stdErr.Reset()
+	s.mu.Unlock()
+
+	outputBytes, err := json.Marshal(output)
+	if err != nil {
+		s.c.Errorf("failed to encode command output[jid=%q]: %s", s.job.JID, err)
+		return
+	}
+	_, _, err = s.c.sshConn.SendRequest(comm.RequestTypeCmdOutput, false, outputBytes)
+	if err != nil {
+		s.c.Errorf("failed to send command output to server[jid=%q]: %s", s.job.JID, err)
+	}
+}
+
+// Result returns the entire output that was accepted by given buffers.
+func (s *outputStreamer) Result(stdOut, stdErr *CapacityBuffer) *models.JobResult {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return &models.JobResult{
+		StdOut: stdOut.String(),
+		StdErr: stdErr.String(),
+	}
+}
+
+type streamWriter struct {
+	s       *outputStreamer
+	buf     *CapacityBuffer
+	pending *bytes.Buffer
+}
+
+func (w *streamWriter) Write(p []byte) (int, error) {
+	w.s.mu.Lock()
+	defer w.s.mu.Unlock()
+
+	before := w.buf.Len()
+	n, err := w.buf.Write(p)
+	w.pending.Write(w.buf.Bytes()[before:])
+	return n, err
+}
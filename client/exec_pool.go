@@ -0,0 +1,108 @@
+package chclient
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/cloudradar-monitoring/rport/share/models"
+)
+
+// cmdPool limits the number of commands that are executed at the same time. Jobs above the limit wait in a queue
+// and get a worker in the order they came.
+type cmdPool struct {
+	mu      sync.Mutex
+	size    int
+	running map[string]*runningCmd // by JID
+	queue   []*models.Job
+}
+
+// runningCmd is a command that occupies a worker of the pool.
+type runningCmd struct {
+	pid       *int // nil until the command is started
+	cancelled bool
+}
+
+func newCmdPool(size int) *cmdPool {
+	return &cmdPool{
+		size:    size,
+		running: make(map[string]*runningCmd),
+	}
+}
+
+// Acquire occupies a worker for a given job and returns true. If all workers are busy the job is queued and false is returned.
+func (p *cmdPool) Acquire(job *models.Job) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if len(p.running) < p.size {
+		p.running[job.JID] = &runningCmd{}
+		return true
+	}
+	p.queue = append(p.queue, job)
+	return false
+}
+
+// Release frees a worker of a given job. If there are queued jobs the worker is handed over to the first of them
+// that is returned. Otherwise nil is returned.
+func (p *cmdPool) Release(jid string) *models.Job {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.running, jid)
+	if len(p.queue) == 0 {
+		return nil
+	}
+	next := p.queue[0]
+	p.queue = p.queue[1:]
+	p.running[next.JID] = &runningCmd{}
+	return next
+}
+
+func (p *cmdPool) SetPID(jid string, pid int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if cmd := p.running[jid]; cmd != nil {
+		cmd.pid = &pid
+	}
+}
+
+// PID returns PID of a running command of a given job or nil if the command is not running.
+func (p *cmdPool) PID(jid string) *int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if cmd := p.running[jid]; cmd != nil {
+		return cmd.pid
+	}
+	return nil
+}
+
+// Cancel marks a running command of a given job as cancelled and returns its PID. A queued job is removed from
+// the queue and returned.
+func (p *cmdPool) Cancel(jid string) (int, *models.Job, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if cmd := p.running[jid]; cmd != nil && cmd.pid != nil {
+		cmd.cancelled = true
+		return *cmd.pid, nil, nil
+	}
+	for i, job := range p.queue {
+		if job.JID == jid {
+			p.queue = append(p.queue[:i], p.queue[i+1:]...)
+			return 0, job, nil
+		}
+	}
+	return 0, nil, fmt.Errorf("command with jid %q is not running", jid)
+}
+
+func (p *cmdPool) SetCancelled(jid string, cancelled bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if cmd := p.running[jid]; cmd != nil {
+		cmd.cancelled = cancelled
+	}
+}
+
+func (p *cmdPool) IsCancelled(jid string) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	cmd := p.running[jid]
+	return cmd != nil && cmd.cancelled
+}
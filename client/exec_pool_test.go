@@ -0,0 +1,51 @@
+package chclient
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cloudradar-monitoring/rport/share/models"
+)
+
+func TestCmdPool(t *testing.T) {
+	job1 := &models.Job{JobSummary: models.JobSummary{JID: "job-1"}}
+	job2 := &models.Job{JobSummary: models.JobSummary{JID: "job-2"}}
+	job3 := &models.Job{JobSummary: models.JobSummary{JID: "job-3"}}
+	job4 := &models.Job{JobSummary: models.JobSummary{JID: "job-4"}}
+
+	p := newCmdPool(2)
+	assert.True(t, p.Acquire(job1))
+	assert.True(t, p.Acquire(job2))
+	assert.False(t, p.Acquire(job3))
+	assert.False(t, p.Acquire(job4))
+
+	p.SetPID(job1.JID, 1)
+	assert.Equal(t, 1, *p.PID(job1.JID))
+	assert.Nil(t, p.PID(job2.JID))
+	assert.Nil(t, p.PID(job3.JID))
+
+	// running cmd
+	pid, queuedJob, err := p.Cancel(job1.JID)
+	require.NoError(t, err)
+	assert.Equal(t, 1, pid)
+	assert.Nil(t, queuedJob)
+	assert.True(t, p.IsCancelled(job1.JID))
+
+	// not started cmd
+	_, _, err = p.Cancel(job2.JID)
+	assert.EqualError(t, err, `command with jid "job-2" is not running`)
+
+	// queued cmd
+	_, queuedJob, err = p.Cancel(job3.JID)
+	require.NoError(t, err)
+	assert.Equal(t, job3, queuedJob)
+
+	// the worker is handed over to the next queued job
+	assert.Equal(t, job4, p.Release(job1.JID))
+	assert.False(t, p.IsCancelled(job1.JID))
+	assert.Nil(t, p.Release(job2.JID))
+	assert.Nil(t, p.Release(job4.JID))
+	assert.True(t, p.Acquire(job1))
+}
@@ -2,7 +2,8 @@ package chclient
 
 import (
 	"context"
-	"fmt"
+	"errors"
+	"io/ioutil"
 	"log"
 	"os"
 	"os/exec"
@@ -27,12 +28,21 @@ type CmdExecutorMock struct {
 	ReturnWaitErr  error
 	ReturnStdOut   []string
 	ReturnStdErr   []string
+	ReturnKillErr  error
+	WaitForKill    bool // if set Wait returns only after Kill is called
 
-	wg sync.WaitGroup
+	InputKillPID     int
+	InputInterpreter string
+	InputScript      string // content of a script file at the moment the cmd is created
+
+	wg     sync.WaitGroup
+	killed chan struct{}
 }
 
 func NewCmdExecutorMock() *CmdExecutorMock {
-	return &CmdExecutorMock{}
+	return &CmdExecutorMock{
+		killed: make(chan struct{}),
+	}
 }
 
 func (e *CmdExecutorMock) New(ctx context.Context, shell, command string) *exec.Cmd {
@@ -43,6 +53,16 @@ func (e *CmdExecutorMock) New(ctx context.Context, shell, command string) *exec.
 	return cmd
 }
 
+func (e *CmdExecutorMock) NewScript(ctx context.Context, interpreter, scriptPath string) *exec.Cmd {
+	e.InputInterpreter = interpreter
+	script, err := ioutil.ReadFile(scriptPath)
+	if err != nil {
+		log.Fatalf("Failed to read script file: %s", err)
+	}
+	e.InputScript = string(script)
+	return exec.CommandContext(ctx, scriptInterpreters[interpreter].cmd, scriptPath)
+}
+
 func (e *CmdExecutorMock) Start(cmd *exec.Cmd) error {
 	if e.ReturnStartErr != nil {
 		return e.ReturnStartErr
@@ -88,10 +108,24 @@ func (e *CmdExecutorMock) Wait(cmd *exec.Cmd) error {
 		return e.ReturnWaitErr
 	}
 	e.wg.Wait()
+	var err error
+	if e.WaitForKill {
+		<-e.killed
+		err = errors.New("signal: killed")
+	}
 	// wait if needed
 	if e.DoneChannel != nil {
 		e.DoneChannel <- true
 	}
+	return err
+}
+
+func (e *CmdExecutorMock) Kill(pid int) error {
+	e.InputKillPID = pid
+	if e.ReturnKillErr != nil {
+		return e.ReturnKillErr
+	}
+	close(e.killed)
 	return nil
 }
 
@@ -216,6 +250,7 @@ func TestHandleRunCmdRequestPositiveCase(t *testing.T) {
 		sshConn: connMock,
 		Logger:  testLog,
 		config:  &configCopy,
+		cmdPool: newCmdPool(configCopy.RemoteCommands.Concurrency),
 	}
 
 	wantJSONPart1 := `
@@ -227,6 +262,8 @@ func TestHandleRunCmdRequestPositiveCase(t *testing.T) {
 	"client_name": "",
 	"command": "/bin/date;foo;whoami",
 	"shell": "test-shell",
+	"interpreter": "",
+	"script": "",
 	"pid": 123,
 	"started_at": "2020-08-19T12:00:00+03:00",
 	"created_by": "admin",
@@ -329,7 +366,7 @@ func TestHandleRunCmdRequestPositiveCase(t *testing.T) {
 	}
 }
 
-func TestHandleRunCmdRequestHasRunningCmd(t *testing.T) {
+func TestHandleRunCmdRequestQueuesCmd(t *testing.T) {
 	now = nowMockF
 	assert := assert.New(t)
 
@@ -337,49 +374,198 @@ func TestHandleRunCmdRequestHasRunningCmd(t *testing.T) {
 	wantPID := 123
 	execMock := NewCmdExecutorMock()
 	execMock.ReturnPID = wantPID
-	execMock.ReturnStdOut = []string{"1", "2", "3"}
-	execMock.ReturnStdErr = []string{"error1", "error2"}
 
 	connMock := test.NewConnMock()
 
 	// mimic real behavior to have the 1st command still running when the 2nd request comes
-	doneSendResp := make(chan bool)
-	connMock.DoneChannel = doneSendResp
+	doneSendReq := make(chan bool)
+	connMock.DoneChannel = doneSendReq
 	doneCmd := make(chan bool)
 	execMock.DoneChannel = doneCmd
 
 	configCopy := defaultValidMinConfig
+	configCopy.RemoteCommands.Concurrency = 1
 	c := Client{
 		cmdExec: execMock,
 		sshConn: connMock,
 		Logger:  testLog,
 		config:  &configCopy,
+		cmdPool: newCmdPool(configCopy.RemoteCommands.Concurrency),
 	}
+	job2JSON := strings.Replace(jobToRunJSON, "5f02b216-3f8a-42be-b66c-f4c1d0ea3809", "job-2", 1)
 
 	// when
-	// run two cmds to get an error for the 2nd
 	res1, err1 := c.HandleRunCmdRequest(context.Background(), []byte(jobToRunJSON))
-	res2, err2 := c.HandleRunCmdRequest(context.Background(), []byte(jobToRunJSON))
+	res2, err2 := c.HandleRunCmdRequest(context.Background(), []byte(job2JSON))
 
 	// then
-	// check that running new commands is blocked
-	curPID := c.getCurCmdPID()
-	require.NotNil(t, curPID)
-	assert.Equal(wantPID, *curPID)
-	// finish the cmd execution
+	require.NoError(t, err1)
+	assert.Equal(&comm.RunCmdResponse{Pid: wantPID, StartedAt: nowMock}, res1)
+	require.NoError(t, err2)
+	assert.Equal(&comm.RunCmdResponse{Queued: true}, res2)
+	gotPID := c.cmdPool.PID("5f02b216-3f8a-42be-b66c-f4c1d0ea3809")
+	require.NotNil(t, gotPID)
+	assert.Equal(wantPID, *gotPID)
+	assert.Nil(c.cmdPool.PID("job-2"))
+
+	// finish the 1st cmd and send its result
+	<-doneCmd
+	<-doneSendReq
+
+	// the queued cmd is started
+	<-doneSendReq
+	inputRequestName, _, inputPayload := connMock.InputSendRequest()
+	assert.Equal(comm.RequestTypeCmdResult, inputRequestName)
+	assert.Contains(string(inputPayload), `"jid":"job-2","status":"running"`)
+	assert.Contains(string(inputPayload), `"pid":123`)
+
+	// finish the 2nd cmd
 	<-doneCmd
-	// finish to send the response to server
-	<-doneSendResp
-	// check that running new commands is not blocked anymore
-	curPID = c.getCurCmdPID()
-	assert.Nil(curPID)
+	<-doneSendReq
+	inputRequestName, _, inputPayload = connMock.InputSendRequest()
+	assert.Equal(comm.RequestTypeCmdResult, inputRequestName)
+	assert.Contains(string(inputPayload), `"jid":"job-2","status":"successful"`)
+}
 
-	// check the result
+func TestHandleRunCmdRequestKeepsWorkerAfterTimeout(t *testing.T) {
+	now = nowMockF
+	assert := assert.New(t)
+
+	// given
+	execMock := NewCmdExecutorMock()
+	execMock.ReturnPID = 123
+	doneCmd := make(chan bool)
+	execMock.DoneChannel = doneCmd
+	connMock := test.NewConnMock()
+	doneSendReq := make(chan bool)
+	connMock.DoneChannel = doneSendReq
+
+	configCopy := defaultValidMinConfig
+	configCopy.RemoteCommands.Concurrency = 1
+	c := Client{
+		cmdExec: execMock,
+		sshConn: connMock,
+		Logger:  testLog,
+		config:  &configCopy,
+		cmdPool: newCmdPool(configCopy.RemoteCommands.Concurrency),
+	}
+	job1JSON := strings.Replace(jobToRunJSON, `"timeout_sec": 60`, `"timeout_sec": 1`, 1)
+	job2JSON := strings.Replace(jobToRunJSON, "5f02b216-3f8a-42be-b66c-f4c1d0ea3809", "job-2", 1)
+
+	// when
+	_, err1 := c.HandleRunCmdRequest(context.Background(), []byte(job1JSON))
+	res2, err2 := c.HandleRunCmdRequest(context.Background(), []byte(job2JSON))
+
+	// then
 	require.NoError(t, err1)
-	assert.Equal(&comm.RunCmdResponse{Pid: wantPID, StartedAt: nowMock}, res1)
-	assert.Error(err2)
-	assert.Equal(fmt.Errorf("a previous command execution with PID %d is still running", wantPID), err2)
-	assert.Nil(res2)
+	require.NoError(t, err2)
+	assert.Equal(&comm.RunCmdResponse{Queued: true}, res2)
+
+	// the timeout is reached while the 1st cmd is still running
+	<-doneSendReq
+	inputRequestName, _, inputPayload := connMock.InputSendRequest()
+	assert.Equal(comm.RequestTypeCmdResult, inputRequestName)
+	assert.Contains(string(inputPayload), `"status":"unknown"`)
+	assert.NotNil(c.cmdPool.PID("5f02b216-3f8a-42be-b66c-f4c1d0ea3809"))
+	assert.Nil(c.cmdPool.PID("job-2"))
+
+	// the queued cmd is started only when the 1st cmd is finished
+	<-doneCmd
+	<-doneSendReq
+	inputRequestName, _, inputPayload = connMock.InputSendRequest()
+	assert.Equal(comm.RequestTypeCmdResult, inputRequestName)
+	assert.Contains(string(inputPayload), `"jid":"job-2","status":"running"`)
+	assert.Nil(c.cmdPool.PID("5f02b216-3f8a-42be-b66c-f4c1d0ea3809"))
+
+	<-doneCmd
+	<-doneSendReq
+}
+
+func TestHandleRunCmdRequestRunsScript(t *testing.T) {
+	now = nowMockF
+	assert := assert.New(t)
+
+	// given
+	script := "#!/bin/sh\necho test\n"
+	scriptDir, err := ioutil.TempDir("", "rport-test-scripts")
+	require.NoError(t, err)
+	defer os.RemoveAll(scriptDir)
+
+	execMock := NewCmdExecutorMock()
+	execMock.ReturnPID = 123
+	connMock := test.NewConnMock()
+	done := make(chan bool)
+	connMock.DoneChannel = done
+	configCopy := defaultValidMinConfig
+	configCopy.RemoteCommands.ScriptDir = scriptDir
+	configCopy.RemoteCommands.scriptHashes = map[string]bool{scriptHash(script): true}
+	c := Client{
+		cmdExec: execMock,
+		sshConn: connMock,
+		Logger:  testLog,
+		config:  &configCopy,
+		cmdPool: newCmdPool(configCopy.RemoteCommands.Concurrency),
+	}
+	scriptJobJSON := `{"jid": "job-1", "script": "#!/bin/sh\necho test\n", "interpreter": "bash", "timeout_sec": 60}`
+
+	// when
+	res, err := c.HandleRunCmdRequest(context.Background(), []byte(scriptJobJSON))
+
+	// then
+	require.NoError(t, err)
+	assert.Equal(&comm.RunCmdResponse{Pid: 123, StartedAt: nowMock}, res)
+	<-done
+	assert.Equal(bashInterpreter, execMock.InputInterpreter)
+	assert.Equal(script, execMock.InputScript)
+	inputRequestName, _, inputPayload := connMock.InputSendRequest()
+	assert.Equal(comm.RequestTypeCmdResult, inputRequestName)
+	assert.Contains(string(inputPayload), `"jid":"job-1","status":"successful"`)
+
+	// the script file is removed when the cmd is finished
+	files, err := ioutil.ReadDir(scriptDir)
+	require.NoError(t, err)
+	assert.Empty(files)
+
+	// when
+	_, err = c.HandleRunCmdRequest(context.Background(), []byte(strings.Replace(scriptJobJSON, "echo test", "rm -rf /", 1)))
+
+	// then
+	require.Error(t, err)
+	assert.Contains(err.Error(), "script is not allowed")
+}
+
+func TestGetInterpreter(t *testing.T) {
+	testCases := []struct {
+		interpreter     string
+		os              string
+		wantInterpreter string
+		wantErrContains string
+	}{
+		{interpreter: "", os: "windows", wantInterpreter: cmdInterpreter},
+		{interpreter: psInterpreter, os: "windows", wantInterpreter: psInterpreter},
+		{interpreter: pythonInterpreter, os: "windows", wantInterpreter: pythonInterpreter},
+		{interpreter: bashInterpreter, os: "windows", wantErrContains: "invalid windows script interpreter"},
+		{interpreter: "", os: "linux", wantInterpreter: shInterpreter},
+		{interpreter: bashInterpreter, os: "linux", wantInterpreter: bashInterpreter},
+		{interpreter: pythonInterpreter, os: "linux", wantInterpreter: pythonInterpreter},
+		{interpreter: psInterpreter, os: "linux", wantErrContains: "invalid unix script interpreter"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.os+", "+tc.interpreter, func(t *testing.T) {
+			// when
+			gotInterpreter, gotErr := getInterpreter(tc.interpreter, tc.os)
+
+			// then
+			if tc.wantErrContains != "" {
+				require.Error(t, gotErr)
+				assert.Contains(t, gotErr.Error(), tc.wantErrContains)
+			} else {
+				require.NoError(t, gotErr)
+				assert.Equal(t, tc.wantInterpreter, gotInterpreter)
+			}
+		})
+	}
 }
 
 func TestRemoteCommandsDisabled(t *testing.T) {
@@ -540,3 +726,142 @@ func getRegexpList(list []string) []*regexp.Regexp {
 	}
 	return res
 }
+
+func TestHandleRunCmdRequestStreamsOutput(t *testing.T) {
+	now = nowMockF
+	cmdOutputInterval = 10 * time.Millisecond
+	defer func() { cmdOutputInterval = time.Second }()
+	getShell = func(inputShell, os string) (string, error) {
+		return "test-shell", nil
+	}
+
+	// given
+	execMock := NewCmdExecutorMock()
+	execMock.ReturnPID = 123
+	execMock.ReturnStdOut = []string{"output1", "output2"}
+	execMock.ReturnStdErr = []string{"error1"}
+	doneCmd := make(chan bool)
+	execMock.DoneChannel = doneCmd
+	connMock := test.NewConnMock()
+	doneSendReq := make(chan bool)
+	connMock.DoneChannel = doneSendReq
+	configCopy := defaultValidMinConfig
+	configCopy.RemoteCommands.SendBackLimit = 10
+	c := Client{
+		cmdExec: execMock,
+		sshConn: connMock,
+		Logger:  testLog,
+		config:  &configCopy,
+		cmdPool: newCmdPool(configCopy.RemoteCommands.Concurrency),
+	}
+
+	// when
+	_, err := c.HandleRunCmdRequest(context.Background(), []byte(jobToRunJSON))
+	require.NoError(t, err)
+
+	// then
+	// output is sent while the command is running
+	<-doneSendReq
+	inputRequestName, inputWantReply, inputPayload := connMock.InputSendRequest()
+	assert.Equal(t, comm.RequestTypeCmdOutput, inputRequestName)
+	assert.Equal(t, false, inputWantReply)
+	assert.JSONEq(t, `{
+		"jid": "5f02b216-3f8a-42be-b66c-f4c1d0ea3809",
+		"multi_job_id": null,
+		"client_id": "d81e6b93e75aef59a7701b90555f43808458b34e30370c3b808c1816a32252b3",
+		"output": {
+			"stdout": "output1out",
+			"stderr": "error1"
+		}
+	}`, string(inputPayload))
+
+	// the final result is sent when the command is finished
+	<-doneCmd
+	<-doneSendReq
+	inputRequestName, _, inputPayload = connMock.InputSendRequest()
+	assert.Equal(t, comm.RequestTypeCmdResult, inputRequestName)
+	assert.Contains(t, string(inputPayload), `"result":{"stdout":"output1out","stderr":"error1"}`)
+}
+
+func TestHandleCancelCmdRequest(t *testing.T) {
+	now = nowMockF
+	getShell = func(inputShell, os string) (string, error) {
+		return "test-shell", nil
+	}
+
+	// given
+	execMock := NewCmdExecutorMock()
+	execMock.ReturnPID = 123
+	execMock.WaitForKill = true
+	connMock := test.NewConnMock()
+	doneSendReq := make(chan bool)
+	connMock.DoneChannel = doneSendReq
+	configCopy := defaultValidMinConfig
+	c := Client{
+		cmdExec: execMock,
+		sshConn: connMock,
+		Logger:  testLog,
+		config:  &configCopy,
+		cmdPool: newCmdPool(configCopy.RemoteCommands.Concurrency),
+	}
+
+	_, err := c.HandleRunCmdRequest(context.Background(), []byte(jobToRunJSON))
+	require.NoError(t, err)
+
+	// when
+	_, err = c.HandleCancelCmdRequest([]byte(`{"JID":"unknown-jid"}`))
+	assert.EqualError(t, err, `command with jid "unknown-jid" is not running`)
+
+	res, err := c.HandleCancelCmdRequest([]byte(`{"JID":"5f02b216-3f8a-42be-b66c-f4c1d0ea3809"}`))
+
+	// then
+	require.NoError(t, err)
+	assert.Equal(t, &comm.CancelCmdResponse{Pid: 123}, res)
+	assert.Equal(t, 123, execMock.InputKillPID)
+
+	<-doneSendReq
+	inputRequestName, _, inputPayload := connMock.InputSendRequest()
+	assert.Equal(t, comm.RequestTypeCmdResult, inputRequestName)
+	assert.Contains(t, string(inputPayload), `"status":"cancelled"`)
+}
+
+func TestHandleCancelCmdRequestAfterTimeout(t *testing.T) {
+	now = nowMockF
+
+	// given
+	execMock := NewCmdExecutorMock()
+	execMock.ReturnPID = 123
+	execMock.WaitForKill = true
+	connMock := test.NewConnMock()
+	doneSendReq := make(chan bool)
+	connMock.DoneChannel = doneSendReq
+	configCopy := defaultValidMinConfig
+	c := Client{
+		cmdExec: execMock,
+		sshConn: connMock,
+		Logger:  testLog,
+		config:  &configCopy,
+		cmdPool: newCmdPool(configCopy.RemoteCommands.Concurrency),
+	}
+	jobJSON := strings.Replace(jobToRunJSON, `"timeout_sec": 60`, `"timeout_sec": 1`, 1)
+
+	_, err := c.HandleRunCmdRequest(context.Background(), []byte(jobJSON))
+	require.NoError(t, err)
+
+	<-doneSendReq
+	_, _, inputPayload := connMock.InputSendRequest()
+	assert.Contains(t, string(inputPayload), `"status":"unknown"`)
+
+	// when
+	res, err := c.HandleCancelCmdRequest([]byte(`{"JID":"5f02b216-3f8a-42be-b66c-f4c1d0ea3809"}`))
+
+	// then
+	require.NoError(t, err)
+	assert.Equal(t, &comm.CancelCmdResponse{Pid: 123}, res)
+	assert.Equal(t, 123, execMock.InputKillPID)
+
+	<-doneSendReq
+	inputRequestName, _, inputPayload := connMock.InputSendRequest()
+	assert.Equal(t, comm.RequestTypeCmdResult, inputRequestName)
+	assert.Contains(t, string(inputPayload), `"status":"cancelled"`)
+}
@@ -4,11 +4,15 @@ package chclient
 
 import (
 	"context"
+	"fmt"
 	"os/exec"
+	"strconv"
 	"strings"
 	"syscall"
 )
 
+const pythonCmd = "python"
+
 func (e *CmdExecutorImpl) New(ctx context.Context, shell, command string) *exec.Cmd {
 	// workaround for the issue with escaping args on windows for cmd shell https://github.com/golang/go/issues/1849
 	if shell == cmdShell {
@@ -20,3 +24,16 @@ func (e *CmdExecutorImpl) New(ctx context.Context, shell, command string) *exec.
 
 	return e.newCmd(ctx, shell, command)
 }
+
+func (e *CmdExecutorImpl) NewScript(ctx context.Context, interpreter, scriptPath string) *exec.Cmd {
+	return e.newScriptCmd(ctx, interpreter, scriptPath)
+}
+
+func (e *CmdExecutorImpl) Kill(pid int) error {
+	// "/T" terminates the process and all child processes started by it
+	out, err := exec.Command("taskkill", "/T", "/F", "/PID", strconv.Itoa(pid)).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("%v: %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
@@ -0,0 +1,203 @@
+package chclient
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http/httputil"
+	"os"
+	"path/filepath"
+
+	"golang.org/x/crypto/ssh"
+
+	"github.com/cloudradar-monitoring/rport/share/comm"
+)
+
+// handleFileChannel handles a channel opened by server to upload a file to the client or to download a file from it.
+// The channel is rejected if the request is invalid, the path is not allowed or the file can't be opened.
+func (c *Client) handleFileChannel(newCh ssh.NewChannel) {
+	req := comm.FileRequest{}
+	if err := json.Unmarshal(newCh.ExtraData(), &req); err != nil {
+		c.rejectFileChannel(newCh, ssh.ConnectionFailed, fmt.Errorf("failed to decode file request: %s", err))
+		return
+	}
+
+	path, err := c.checkFilePath(req.Path)
+	if err != nil {
+		c.rejectFileChannel(newCh, ssh.Prohibited, err)
+		return
+	}
+
+	switch req.Op {
+	case comm.FileOpUpload:
+		c.receiveFile(newCh, path)
+	case comm.FileOpDownload:
+		c.sendFile(newCh, path)
+	default:
+		c.rejectFileChannel(newCh, ssh.ConnectionFailed, fmt.Errorf("unknown file operation: %q", req.Op))
+	}
+}
+
+// checkFilePath returns a given path with resolved symlinks if it passes configured restrictions.
+func (c *Client) checkFilePath(path string) (string, error) {
+	if !c.config.FileTransfer.Enabled {
+		return "", errors.New("file transfer is disabled")
+	}
+	if !filepath.IsAbs(path) {
+		return "", fmt.Errorf("path should be absolute: %q", path)
+	}
+	// clean the path to not allow to escape allowed dirs using ".."
+	path = filepath.Clean(path)
+	// resolve symlinks to not allow to escape allowed dirs using links
+	resolved, err := resolvePath(path)
+	if err != nil {
+		return "", err
+	}
+	if !c.isPathAllowed(resolved) {
+		if resolved != path {
+			return "", fmt.Errorf("path is not allowed: %q resolved to %q", path, resolved)
+		}
+		return "", fmt.Errorf("path is not allowed: %q", path)
+	}
+	return resolved, nil
+}
+
+// resolvePath returns a given clean path with all symlinks resolved. If the file doesn't exist, symlinks of its
+// parent dir are resolved, so a file can be uploaded to a new path.
+func resolvePath(path string) (string, error) {
+	resolved, err := filepath.EvalSymlinks(path)
+	if err == nil {
+		return resolved, nil
+	}
+	if !os.IsNotExist(err) {
+		return "", err
+	}
+	if _, lerr := os.Lstat(path); lerr == nil {
+		// a dangling symlink, its target can't be checked
+		return "", err
+	}
+	dir, err := filepath.EvalSymlinks(filepath.Dir(path))
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, filepath.Base(path)), nil
+}
+
+// isPathAllowed returns true if a given path passes configured restrictions. All paths are rejected if no allowed
+// paths are configured, regardless of the order.
+func (c *Client) isPathAllowed(path string) bool {
+	if len(c.config.FileTransfer.allowRegexp) == 0 {
+		return false
+	}
+	return matchAllowDeny(path, c.config.FileTransfer.allowRegexp, c.config.FileTransfer.denyRegexp, c.config.FileTransfer.Order)
+}
+
+func (c *Client) rejectFileChannel(newCh ssh.NewChannel, reason ssh.RejectionReason, err error) {
+	c.Debugf("Rejecting file channel: %s", err)
+	if rejectErr := newCh.Reject(reason, err.Error()); rejectErr != nil {
+		c.Errorf("Failed to reject file channel: %s", rejectErr)
+	}
+}
+
+func (c *Client) sendFile(newCh ssh.NewChannel, path string) {
+	f, err := os.Open(path)
+	if err != nil {
+		c.rejectFileChannel(newCh, ssh.ConnectionFailed, err)
+		return
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		c.rejectFileChannel(newCh, ssh.ConnectionFailed, err)
+		return
+	}
+	if info.IsDir() {
+		c.rejectFileChannel(newCh, ssh.ConnectionFailed, fmt.Errorf("%q is a directory", path))
+		return
+	}
+
+	ch, reqs, err := newCh.Accept()
+	if err != nil {
+		c.Errorf("Failed to accept file channel: %s", err)
+		return
+	}
+	go ssh.DiscardRequests(reqs)
+	defer ch.Close()
+
+	w := httputil.NewChunkedWriter(ch)
+	n, err := io.Copy(w, f)
+	if err != nil {
+		// the last chunk is not sent, so server knows the file is incomplete
+		c.Errorf("Failed to send file %q: %s", path, err)
+		return
+	}
+	if err := w.Close(); err != nil {
+		c.Errorf("Failed to send file %q: %s", path, err)
+		return
+	}
+	c.Debugf("File %q (%d bytes) is sent to server.", path, n)
+}
+
+func (c *Client) receiveFile(newCh ssh.NewChannel, path string) {
+	// write to a temp file first to not leave a partially written file if the transfer fails
+	tmp, err := ioutil.TempFile(filepath.Dir(path), "."+filepath.Base(path)+".rport-*")
+	if err != nil {
+		c.rejectFileChannel(newCh, ssh.ConnectionFailed, err)
+		return
+	}
+
+	ch, reqs, err := newCh.Accept()
+	if err != nil {
+		c.Errorf("Failed to accept file channel: %s", err)
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return
+	}
+	go ssh.DiscardRequests(reqs)
+	defer ch.Close()
+
+	res := comm.FileUploadResult{}
+	res.Size, err = storeFile(tmp, httputil.NewChunkedReader(ch), path)
+	if err != nil {
+		c.Errorf("Failed to receive file %q: %s", path, err)
+		res.ErrMsg = err.Error()
+	} else {
+		c.Debugf("File %q (%d bytes) is received from server.", path, res.Size)
+	}
+
+	if err := json.NewEncoder(ch).Encode(res); err != nil {
+		c.Errorf("Failed to send upload result of file %q: %s", path, err)
+	}
+}
+
+// storeFile writes a content from a given reader to a given temp file and replaces a file with a given path by it.
+// The temp file is removed on failure.
+func storeFile(tmp *os.File, r io.Reader, path string) (int64, error) {
+	n, err := io.Copy(tmp, r)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(tmp.Name(), fileMode(path))
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return 0, err
+	}
+	return n, nil
+}
+
+// fileMode returns a mode of a given file to keep it when the file is replaced. 0644 is returned for a new file.
+func fileMode(path string) os.FileMode {
+	info, err := os.Stat(path)
+	if err != nil {
+		return 0644
+	}
+	return info.Mode().Perm()
+}
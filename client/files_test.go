@@ -0,0 +1,249 @@
+package chclient
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http/httputil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh"
+
+	"github.com/cloudradar-monitoring/rport/share/comm"
+	"github.com/cloudradar-monitoring/rport/share/test"
+)
+
+func newFileTransferTestClient(t *testing.T, dir string) *Client {
+	config := defaultValidMinConfig
+	config.FileTransfer = FileTransferConfig{
+		Enabled:     true,
+		Order:       allowDenyOrder,
+		allowRegexp: []*regexp.Regexp{regexp.MustCompile("^" + regexp.QuoteMeta(dir) + "/.*")},
+		denyRegexp:  []*regexp.Regexp{regexp.MustCompile(`\.key$`)},
+	}
+	return &Client{
+		Logger: testLog,
+		config: &config,
+	}
+}
+
+func fileRequest(t *testing.T, op, path string) []byte {
+	b, err := json.Marshal(comm.FileRequest{Op: op, Path: path})
+	require.NoError(t, err)
+	return b
+}
+
+func TestHandleFileChannelDownload(t *testing.T) {
+	// given
+	dir, err := ioutil.TempDir("", "rport-test-files")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "test.conf")
+	require.NoError(t, ioutil.WriteFile(path, []byte("test content"), 0600))
+
+	c := newFileTransferTestClient(t, dir)
+	clientCh, serverCh := test.NewChannelPair()
+	newCh := &test.NewChannelMock{
+		Type:    comm.ChannelTypeFile,
+		Data:    fileRequest(t, comm.FileOpDownload, path),
+		Channel: clientCh,
+	}
+
+	// when
+	go c.handleFileChannel(newCh)
+	got, err := ioutil.ReadAll(httputil.NewChunkedReader(serverCh))
+
+	// then
+	require.NoError(t, err)
+	assert.Equal(t, "test content", string(got))
+	assert.False(t, newCh.Rejected)
+}
+
+func TestHandleFileChannelUpload(t *testing.T) {
+	// given
+	dir, err := ioutil.TempDir("", "rport-test-files")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "test.conf")
+	require.NoError(t, ioutil.WriteFile(path, []byte("old content"), 0600))
+
+	c := newFileTransferTestClient(t, dir)
+	clientCh, serverCh := test.NewChannelPair()
+	newCh := &test.NewChannelMock{
+		Type:    comm.ChannelTypeFile,
+		Data:    fileRequest(t, comm.FileOpUpload, path),
+		Channel: clientCh,
+	}
+
+	// when
+	go c.handleFileChannel(newCh)
+	w := httputil.NewChunkedWriter(serverCh)
+	_, err = w.Write([]byte("new content"))
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+	res := comm.FileUploadResult{}
+	err = json.NewDecoder(serverCh).Decode(&res)
+
+	// then
+	require.NoError(t, err)
+	assert.Equal(t, comm.FileUploadResult{Size: 11}, res)
+	got, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "new content", string(got))
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+	files, err := ioutil.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Len(t, files, 1, "temp file should be renamed")
+}
+
+func TestHandleFileChannelIncompleteUpload(t *testing.T) {
+	// given
+	dir, err := ioutil.TempDir("", "rport-test-files")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "test.conf")
+
+	c := newFileTransferTestClient(t, dir)
+	clientCh, serverCh := test.NewChannelPair()
+	newCh := &test.NewChannelMock{
+		Type:    comm.ChannelTypeFile,
+		Data:    fileRequest(t, comm.FileOpUpload, path),
+		Channel: clientCh,
+	}
+	done := make(chan struct{})
+
+	// when
+	go func() {
+		c.handleFileChannel(newCh)
+		close(done)
+	}()
+	_, err = httputil.NewChunkedWriter(serverCh).Write([]byte("partial content"))
+	require.NoError(t, err)
+	// close without sending the last chunk
+	require.NoError(t, serverCh.Close())
+	<-done
+
+	// then
+	files, err := ioutil.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Empty(t, files)
+}
+
+func TestHandleFileChannelRejected(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rport-test-files")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	dir, err = filepath.EvalSymlinks(dir)
+	require.NoError(t, err)
+	outsideDir, err := ioutil.TempDir("", "rport-test-outside")
+	require.NoError(t, err)
+	defer os.RemoveAll(outsideDir)
+	outsideDir, err = filepath.EvalSymlinks(outsideDir)
+	require.NoError(t, err)
+	outsideFile := filepath.Join(outsideDir, "secret.conf")
+	require.NoError(t, ioutil.WriteFile(outsideFile, []byte("secret"), 0600))
+	require.NoError(t, os.Symlink(outsideFile, filepath.Join(dir, "link.conf")))
+	require.NoError(t, os.Symlink(outsideDir, filepath.Join(dir, "linkdir")))
+
+	testCases := []struct {
+		name        string
+		disabled    bool
+		data        []byte
+		wantReason  ssh.RejectionReason
+		wantMessage string
+	}{
+		{
+			name:        "disabled",
+			disabled:    true,
+			data:        fileRequest(t, comm.FileOpDownload, dir+"/test.conf"),
+			wantReason:  ssh.Prohibited,
+			wantMessage: "file transfer is disabled",
+		},
+		{
+			name:        "relative path",
+			data:        fileRequest(t, comm.FileOpDownload, "test.conf"),
+			wantReason:  ssh.Prohibited,
+			wantMessage: `path should be absolute: "test.conf"`,
+		},
+		{
+			name:        "path is not allowed",
+			data:        fileRequest(t, comm.FileOpDownload, "/etc/passwd"),
+			wantReason:  ssh.Prohibited,
+			wantMessage: `path is not allowed: "/etc/passwd"`,
+		},
+		{
+			name:        "path escapes allowed dir",
+			data:        fileRequest(t, comm.FileOpDownload, dir+"/../passwd"),
+			wantReason:  ssh.Prohibited,
+			wantMessage: "path is not allowed: " + `"` + filepath.Join(filepath.Dir(dir), "passwd") + `"`,
+		},
+		{
+			name:        "symlink escapes allowed dir",
+			data:        fileRequest(t, comm.FileOpDownload, dir+"/link.conf"),
+			wantReason:  ssh.Prohibited,
+			wantMessage: fmt.Sprintf("path is not allowed: %q resolved to %q", dir+"/link.conf", outsideFile),
+		},
+		{
+			name:        "symlinked parent dir escapes allowed dir",
+			data:        fileRequest(t, comm.FileOpUpload, dir+"/linkdir/new.conf"),
+			wantReason:  ssh.Prohibited,
+			wantMessage: fmt.Sprintf("path is not allowed: %q resolved to %q", dir+"/linkdir/new.conf", outsideDir+"/new.conf"),
+		},
+		{
+			name:        "path is denied",
+			data:        fileRequest(t, comm.FileOpUpload, dir+"/server.key"),
+			wantReason:  ssh.Prohibited,
+			wantMessage: "path is not allowed: " + `"` + dir + `/server.key"`,
+		},
+		{
+			name:        "file not found",
+			data:        fileRequest(t, comm.FileOpDownload, dir+"/not-found.conf"),
+			wantReason:  ssh.ConnectionFailed,
+			wantMessage: "open " + dir + "/not-found.conf: no such file or directory",
+		},
+		{
+			name:        "unknown operation",
+			data:        fileRequest(t, "delete", dir+"/test.conf"),
+			wantReason:  ssh.ConnectionFailed,
+			wantMessage: `unknown file operation: "delete"`,
+		},
+		{
+			name:        "invalid request",
+			data:        []byte("invalid"),
+			wantReason:  ssh.ConnectionFailed,
+			wantMessage: "failed to decode file request: invalid character 'i' looking for beginning of value",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// given
+			c := newFileTransferTestClient(t, dir)
+			c.config.FileTransfer.Enabled = !tc.disabled
+			newCh := &test.NewChannelMock{Type: comm.ChannelTypeFile, Data: tc.data}
+
+			// when
+			c.handleFileChannel(newCh)
+
+			// then
+			assert.True(t, newCh.Rejected)
+			assert.Equal(t, tc.wantReason, newCh.RejectReason)
+			assert.Equal(t, tc.wantMessage, newCh.RejectMessage)
+		})
+	}
+}
+
+func TestIsPathAllowedEmptyAllowList(t *testing.T) {
+	for _, order := range [][2]string{allowDenyOrder, denyAllowOrder} {
+		c := &Client{config: &Config{FileTransfer: FileTransferConfig{Enabled: true, Order: order}}}
+
+		assert.False(t, c.isPathAllowed("/etc/shadow"), order)
+	}
+}
@@ -0,0 +1,73 @@
+package chclient
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/ssh"
+
+	chshare "github.com/cloudradar-monitoring/rport/share"
+	"github.com/cloudradar-monitoring/rport/share/comm"
+)
+
+// hostKeys verifies host keys of the server against configured fingerprints and fingerprints of next host keys that
+// the server announced while the client was connected. Announced fingerprints are kept only in memory.
+type hostKeys struct {
+	mu         sync.Mutex
+	configured []string
+	announced  map[string]bool
+}
+
+func newHostKeys(fingerprints []string) *hostKeys {
+	return &hostKeys{
+		configured: fingerprints,
+		announced:  make(map[string]bool),
+	}
+}
+
+// verify returns an error if a given key is not trusted. Any key is trusted if no fingerprints are configured.
+func (h *hostKeys) verify(l *chshare.Logger, key ssh.PublicKey) error {
+	got := chshare.FingerprintKey(key)
+	if len(h.configured) == 0 {
+		return nil
+	}
+	for _, f := range h.configured {
+		if chshare.MatchFingerprint(f, key) {
+			if chshare.IsMD5Fingerprint(f) {
+				l.Infof("md5 fingerprint %q is deprecated, use %q instead", f, got)
+			}
+			return nil
+		}
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.announced[got] {
+		l.Infof("Server uses announced host key %s, add it to the fingerprints of the client config", got)
+		return nil
+	}
+	return fmt.Errorf("Invalid fingerprint (%s)", got)
+}
+
+// announce adds fingerprints of next host keys received from the server.
+func (h *hostKeys) announce(l *chshare.Logger, payload []byte) error {
+	req := &comm.HostKeysRequest{}
+	if err := json.Unmarshal(payload, req); err != nil {
+		return fmt.Errorf("failed to decode %T: %v", req, err)
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, f := range req.NextFingerprints {
+		if !strings.HasPrefix(f, "SHA256:") {
+			return fmt.Errorf("invalid announced fingerprint %q: expected 'SHA256:<base64>'", f)
+		}
+		if !h.announced[f] {
+			l.Infof("Server announced next host key %s", f)
+		}
+		h.announced[f] = true
+	}
+	return nil
+}
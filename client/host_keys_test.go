@@ -0,0 +1,50 @@
+package chclient
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh"
+
+	chshare "github.com/cloudradar-monitoring/rport/share"
+	"github.com/cloudradar-monitoring/rport/share/comm"
+)
+
+func newTestHostKey(t *testing.T) ssh.PublicKey {
+	key, err := chshare.GenerateKey("")
+	require.NoError(t, err)
+	signer, err := ssh.ParsePrivateKey(key)
+	require.NoError(t, err)
+	return signer.PublicKey()
+}
+
+func TestHostKeys(t *testing.T) {
+	current := newTestHostKey(t)
+	next := newTestHostKey(t)
+	legacy := newTestHostKey(t)
+	other := newTestHostKey(t)
+	h := newHostKeys([]string{chshare.FingerprintKey(current), chshare.FingerprintKeyMD5(legacy)[:11]})
+
+	assert.NoError(t, h.verify(testLog, current))
+	assert.NoError(t, h.verify(testLog, legacy))
+	assert.EqualError(t, h.verify(testLog, next), "Invalid fingerprint ("+chshare.FingerprintKey(next)+")")
+
+	// when
+	payload, err := json.Marshal(&comm.HostKeysRequest{NextFingerprints: []string{chshare.FingerprintKey(next)}})
+	require.NoError(t, err)
+	require.NoError(t, h.announce(testLog, payload))
+
+	// then
+	assert.NoError(t, h.verify(testLog, next))
+	assert.Error(t, h.verify(testLog, other))
+
+	payload, err = json.Marshal(&comm.HostKeysRequest{NextFingerprints: []string{chshare.FingerprintKeyMD5(other)}})
+	require.NoError(t, err)
+	assert.Error(t, h.announce(testLog, payload), "md5 fingerprints can't be announced")
+	assert.Error(t, h.verify(testLog, other))
+
+	// any key is trusted without fingerprints
+	assert.NoError(t, newHostKeys(nil).verify(testLog, other))
+}
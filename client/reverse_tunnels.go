@@ -0,0 +1,132 @@
+package chclient
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"sync"
+
+	"github.com/jpillora/sizestr"
+	"golang.org/x/crypto/ssh"
+
+	chshare "github.com/cloudradar-monitoring/rport/share"
+	"github.com/cloudradar-monitoring/rport/share/comm"
+)
+
+// reverseTunnels holds listeners of reverse tunnels by local address.
+type reverseTunnels struct {
+	mu        sync.Mutex
+	listeners map[string]net.Listener
+}
+
+func newReverseTunnels() *reverseTunnels {
+	return &reverseTunnels{
+		listeners: make(map[string]net.Listener),
+	}
+}
+
+// listen returns a listener on a given local address or an error if the address is already listened.
+func (t *reverseTunnels) listen(local string) (net.Listener, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if _, ok := t.listeners[local]; ok {
+		return nil, fmt.Errorf("reverse tunnel on %s is already started", local)
+	}
+	l, err := net.Listen("tcp", local)
+	if err != nil {
+		return nil, err
+	}
+	t.listeners[local] = l
+	return l, nil
+}
+
+func (t *reverseTunnels) close(local string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	l, ok := t.listeners[local]
+	if !ok {
+		return fmt.Errorf("reverse tunnel on %s is not found", local)
+	}
+	delete(t.listeners, local)
+	return l.Close()
+}
+
+func (t *reverseTunnels) closeAll() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for local, l := range t.listeners {
+		l.Close()
+		delete(t.listeners, local)
+	}
+}
+
+// startReverseTunnel listens on a local address of a reverse tunnel. Accepted connections are forwarded
+// to the server over a given connection, the server connects them to a given remote address.
+func (c *Client) startReverseTunnel(sshConn ssh.Conn, local, remote string) error {
+	l, err := c.reverseTunnels.listen(local)
+	if err != nil {
+		return err
+	}
+
+	log := c.Fork("reverse#%s", local)
+	log.Infof("Listening, forwarding to %s", remote)
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				log.Debugf("Listener closed: %v", err)
+				return
+			}
+			go c.handleReverseConn(log, sshConn, conn, remote)
+		}
+	}()
+	return nil
+}
+
+func (c *Client) handleReverseConn(log *chshare.Logger, sshConn ssh.Conn, conn net.Conn, remote string) {
+	l := log.Fork("conn#%d", c.connStats.New())
+	ch, reqs, err := sshConn.OpenChannel(comm.ChannelTypeReverse, []byte(remote))
+	if err != nil {
+		l.Debugf("Failed to open channel: %v", err)
+		conn.Close()
+		return
+	}
+	go ssh.DiscardRequests(reqs)
+
+	c.connStats.Open()
+	l.Debugf("%s: Open", &c.connStats)
+	s, r := chshare.Pipe(conn, ch, c.bandwidth)
+	c.connStats.Close()
+	l.Debugf("%s: Close (sent %s received %s)", &c.connStats, sizestr.ToString(s), sizestr.ToString(r))
+}
+
+// startReverseTunnels starts reverse tunnels among given remotes confirmed by the server on connect.
+func (c *Client) startReverseTunnels(sshConn ssh.Conn, remotes []*chshare.Remote) {
+	for _, r := range remotes {
+		if !r.Reverse {
+			continue
+		}
+		if err := c.startReverseTunnel(sshConn, r.Local(), r.Remote()); err != nil {
+			c.Errorf("Failed to start reverse tunnel %s: %v", r, err)
+		}
+	}
+}
+
+// HandleStartReverseTunnelRequest starts a reverse tunnel requested by the server.
+func (c *Client) HandleStartReverseTunnelRequest(reqPayload []byte) error {
+	req := comm.ReverseTunnelRequest{}
+	if err := json.Unmarshal(reqPayload, &req); err != nil {
+		return fmt.Errorf("failed to decode reverse tunnel request: %s", err)
+	}
+	return c.startReverseTunnel(c.sshConn, req.Local, req.Remote)
+}
+
+// HandleStopReverseTunnelRequest stops a reverse tunnel requested by the server, active connections are kept.
+func (c *Client) HandleStopReverseTunnelRequest(reqPayload []byte) error {
+	req := comm.ReverseTunnelRequest{}
+	if err := json.Unmarshal(reqPayload, &req); err != nil {
+		return fmt.Errorf("failed to decode reverse tunnel request: %s", err)
+	}
+	c.Infof("Stopping reverse tunnel on %s", req.Local)
+	return c.reverseTunnels.close(req.Local)
+}
@@ -0,0 +1,68 @@
+package chclient
+
+import (
+	"encoding/json"
+	"io"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cloudradar-monitoring/rport/share/comm"
+	"github.com/cloudradar-monitoring/rport/share/test"
+)
+
+func TestReverseTunnel(t *testing.T) {
+	// given
+	serverCh, tunnelCh := test.NewChannelPair()
+	connMock := test.NewConnMock()
+	connMock.ReturnChannel = tunnelCh
+	c := Client{
+		Logger:         testLog,
+		sshConn:        connMock,
+		reverseTunnels: newReverseTunnels(),
+	}
+	defer c.reverseTunnels.closeAll()
+	reqBytes, err := json.Marshal(comm.ReverseTunnelRequest{Local: "127.0.0.1:0", Remote: "db.internal:5432"})
+	require.NoError(t, err)
+
+	// when
+	err = c.HandleStartReverseTunnelRequest(reqBytes)
+
+	// then
+	require.NoError(t, err)
+	l := c.reverseTunnels.listeners["127.0.0.1:0"]
+	require.NotNil(t, l)
+
+	// when
+	conn, err := net.Dial("tcp", l.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+	_, err = conn.Write([]byte("ping"))
+	require.NoError(t, err)
+
+	// then
+	buf := make([]byte, 4)
+	_, err = io.ReadFull(serverCh, buf)
+	require.NoError(t, err)
+	assert.Equal(t, "ping", string(buf))
+	gotType, gotData := connMock.InputOpenChannel()
+	assert.Equal(t, comm.ChannelTypeReverse, gotType)
+	assert.Equal(t, "db.internal:5432", string(gotData))
+
+	// when
+	err = c.HandleStartReverseTunnelRequest(reqBytes)
+
+	// then
+	assert.EqualError(t, err, "reverse tunnel on 127.0.0.1:0 is already started")
+
+	// when
+	err = c.HandleStopReverseTunnelRequest(reqBytes)
+
+	// then
+	require.NoError(t, err)
+	_, err = net.Dial("tcp", l.Addr().String())
+	assert.Error(t, err)
+	assert.EqualError(t, c.HandleStopReverseTunnelRequest(reqBytes), "reverse tunnel on 127.0.0.1:0 is not found")
+}
@@ -0,0 +1,54 @@
+package chclient
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/jpillora/sizestr"
+	"golang.org/x/crypto/ssh"
+
+	chshare "github.com/cloudradar-monitoring/rport/share"
+)
+
+// handleSocksChannel connects a channel opened by the server for a connection of a SOCKS tunnel to the requested
+// destination. The channel is rejected if the destination is not allowed or can't be connected.
+func (c *Client) handleSocksChannel(newCh ssh.NewChannel) {
+	remote := string(newCh.ExtraData())
+	l := c.Logger.Fork("socks#%d", c.connStats.New())
+
+	host, port, err := net.SplitHostPort(remote)
+	if err != nil {
+		rejectSocksChannel(l, newCh, ssh.ConnectionFailed, fmt.Errorf("invalid destination %q: %v", remote, err))
+		return
+	}
+	if !c.config.Socks.destinations.Allows(host, port) {
+		rejectSocksChannel(l, newCh, ssh.Prohibited, fmt.Errorf("destination is not allowed: %s", remote))
+		return
+	}
+
+	dst, err := net.Dial("tcp", remote)
+	if err != nil {
+		rejectSocksChannel(l, newCh, ssh.ConnectionFailed, err)
+		return
+	}
+	src, reqs, err := newCh.Accept()
+	if err != nil {
+		l.Debugf("Failed to accept stream: %v", err)
+		dst.Close()
+		return
+	}
+	go ssh.DiscardRequests(reqs)
+
+	c.connStats.Open()
+	l.Debugf("%s: Open to %s", &c.connStats, remote)
+	s, r := chshare.Pipe(src, dst, c.bandwidth)
+	c.connStats.Close()
+	l.Debugf("%s: Close (sent %s received %s)", &c.connStats, sizestr.ToString(s), sizestr.ToString(r))
+}
+
+func rejectSocksChannel(l *chshare.Logger, newCh ssh.NewChannel, reason ssh.RejectionReason, err error) {
+	l.Debugf("Rejecting SOCKS channel: %s", err)
+	if rejectErr := newCh.Reject(reason, err.Error()); rejectErr != nil {
+		l.Errorf("Failed to reject SOCKS channel: %s", rejectErr)
+	}
+}
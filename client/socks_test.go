@@ -0,0 +1,122 @@
+package chclient
+
+import (
+	"io"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh"
+
+	"github.com/cloudradar-monitoring/rport/share/comm"
+	"github.com/cloudradar-monitoring/rport/share/test"
+)
+
+func newSocksTestClient(t *testing.T, allow ...string) *Client {
+	config := defaultValidMinConfig
+	config.Socks = SocksConfig{Allow: allow}
+	require.NoError(t, config.parseSocks())
+	return &Client{
+		Logger: testLog,
+		config: &config,
+	}
+}
+
+func TestHandleSocksChannel(t *testing.T) {
+	// given
+	echo, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer echo.Close()
+	go func() {
+		conn, err := echo.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		_, _ = io.Copy(conn, conn)
+	}()
+	c := newSocksTestClient(t, "127.0.0.0/8:*")
+	serverCh, clientCh := test.NewChannelPair()
+	newCh := &test.NewChannelMock{
+		Type:    comm.ChannelTypeSocks,
+		Data:    []byte(echo.Addr().String()),
+		Channel: clientCh,
+	}
+
+	// when
+	go c.handleSocksChannel(newCh)
+	_, err = serverCh.Write([]byte("ping"))
+	require.NoError(t, err)
+
+	// then
+	buf := make([]byte, 4)
+	_, err = io.ReadFull(serverCh, buf)
+	require.NoError(t, err)
+	assert.Equal(t, "ping", string(buf))
+	assert.False(t, newCh.Rejected)
+	serverCh.Close()
+}
+
+func TestHandleSocksChannelRejected(t *testing.T) {
+	closed, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	closedAddr := closed.Addr().String()
+	closed.Close()
+
+	testCases := []struct {
+		name        string
+		allow       []string
+		remote      string
+		wantReason  ssh.RejectionReason
+		wantMessage string
+	}{
+		{
+			name:        "no allowed destinations",
+			remote:      "192.168.1.1:22",
+			wantReason:  ssh.Prohibited,
+			wantMessage: "destination is not allowed: 192.168.1.1:22",
+		},
+		{
+			name:        "destination not allowed",
+			allow:       []string{"192.168.1.0/24:80", "nas.local:*"},
+			remote:      "192.168.1.1:22",
+			wantReason:  ssh.Prohibited,
+			wantMessage: "destination is not allowed: 192.168.1.1:22",
+		},
+		{
+			name:        "invalid destination",
+			allow:       []string{"192.168.1.0/24:*"},
+			remote:      "192.168.1.1",
+			wantReason:  ssh.ConnectionFailed,
+			wantMessage: `invalid destination "192.168.1.1": address 192.168.1.1: missing port in address`,
+		},
+		{
+			name:       "connection failed",
+			allow:      []string{"127.0.0.1:*"},
+			remote:     closedAddr,
+			wantReason: ssh.ConnectionFailed,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// given
+			c := newSocksTestClient(t, tc.allow...)
+			newCh := &test.NewChannelMock{
+				Type: comm.ChannelTypeSocks,
+				Data: []byte(tc.remote),
+			}
+
+			// when
+			c.handleSocksChannel(newCh)
+
+			// then
+			assert.True(t, newCh.Rejected)
+			assert.Equal(t, tc.wantReason, newCh.RejectReason)
+			if tc.wantMessage != "" {
+				assert.Equal(t, tc.wantMessage, newCh.RejectMessage)
+			}
+		})
+	}
+}
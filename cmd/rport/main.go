@@ -29,7 +29,12 @@ var clientHelp = `
   which does reverse port forwarding, sharing <remote-host>:<remote-port>
   from the client to the server's <local-interface>:<local-port>.
   If local part is omitted, a randomly chosen server port will be assigned.
-  Only IPv4 addresses are supported.
+  Append /udp to forward UDP datagrams instead of TCP connections.
+  Prepend R: to create a reverse tunnel, the client listens on <local-interface>:<local-port>
+  and the server connects to <remote-host>:<remote-port>.
+  Use "socks" instead of the remote part to create a SOCKS5 tunnel, each connection requests
+  its own destination, which must be allowed by the [socks] section of the config file.
+  IPv6 addresses must be enclosed in square brackets, e.g. [::1]:22.
   If not set, client connects without active tunnel(s) waiting for tunnels to be initialized by the server.
 
   Examples:
@@ -48,6 +53,22 @@ var clientHelp = `
     forwards port 3000 of the server to port 80 of google.com
     originating the connection from the client
 
+    ./rport <SERVER>:<PORT> [::]:2222:[::1]:22
+    server will listen on port 2222 of all IPv4 and IPv6 interfaces
+    forwarding to IPv6 port 22 of the client
+
+    ./rport <SERVER>:<PORT> 5353:8.8.8.8:53/udp
+    forwards UDP port 5353 of the server to UDP port 53 of 8.8.8.8
+    originating the datagrams from the client
+
+    ./rport <SERVER>:<PORT> R:5432:db.internal:5432
+    forwards port 5432 of 127.0.0.1 of the client to port 5432 of db.internal
+    originating the connection from the server
+
+    ./rport <SERVER>:<PORT> 1080:socks
+    server will speak SOCKS5 on port 1080 connecting to requested destinations
+    from the client
+
     ./rport <SERVER>:<PORT> 192.168.0.5:3000:google.com:80
     server will listen on 192.168.0.5 interface forwarding all packets
     from port 3000 to port 80 of google.com
@@ -62,12 +83,21 @@ var clientHelp = `
 
   Options:
 
-    --fingerprint, A *strongly recommended* fingerprint string
-    to perform host-key validation against the server's public key.
-    You may provide just a prefix of the key or the entire string.
+    --fingerprint, A *strongly recommended* SHA256 fingerprint string
+    to perform host-key validation against the server's public key,
+    e.g. "SHA256:mVPwvezndPv/ARoIadVY98vAC0g+P/5633yTC4d/wXE".
+    Several fingerprints can be separated by comma. Legacy md5 fingerprints
+    or their prefixes are still accepted but deprecated.
     Fingerprint mismatches will close the connection.
 
-    --auth, Required client authentication credentials in the form: "<client-auth-id>:<password>".
+    --auth, Client authentication credentials in the form: "<client-auth-id>:<password>".
+    Required unless --auth-key is used. With --auth-key only "<client-auth-id>" can be given.
+
+    --auth-key, An optional path to a private key file to authenticate with a key
+    signed by the rportd client CA instead of a password.
+
+    --auth-cert, An optional path to a certificate of --auth-key signed by the rportd client CA.
+    If --auth is not set, the first principal of the certificate is used as client auth id.
 
     --keepalive, An optional keepalive interval. Since the underlying
     transport is HTTP, in many instances we'll be traversing through
@@ -81,6 +111,10 @@ var clientHelp = `
     --max-retry-interval, Maximum wait time before retrying after a
     disconnection. Defaults to 5 minutes ('5m').
 
+    --max-bytes-per-sec, An optional limit of the total bandwidth of all tunnels
+    of the client in bytes per second, both directions are counted.
+    Defaults to 0 (unlimited).
+
     --proxy, An optional HTTP CONNECT or SOCKS5 proxy which will be
     used to reach the rport server. Authentication can be specified
     inside the URL.
@@ -125,6 +159,12 @@ var clientHelp = `
     Applies to the stdout and stderr separately. If exceeded the specified number of bytes are sent.
     Defaults: 2048
 
+    --remote-commands-concurrency, Maximum number of commands that are executed at the same time.
+    Commands above the limit are queued. Defaults: 4
+
+    --file-transfer-enabled, Enable or disable uploading and downloading files by the server.
+    Only paths allowed in the config file can be accessed. Defaults: false
+
     --config, -c, An optional arg to define a path to a config file. If it is set then
     configuration will be loaded from the file. Note: command arguments and env variables will override them.
     Config file should be in TOML format. You can find an example "rport.example.conf" in the release archive.
@@ -162,9 +202,12 @@ func init() {
 
 	pFlags.String("fingerprint", "", "")
 	pFlags.String("auth", "", "")
+	pFlags.String("auth-key", "", "")
+	pFlags.String("auth-cert", "", "")
 	pFlags.Duration("keepalive", 0, "")
 	pFlags.Int("max-retry-count", 0, "")
 	pFlags.Duration("max-retry-interval", 0, "")
+	pFlags.Int64("max-bytes-per-sec", 0, "")
 	pFlags.String("proxy", "", "")
 	pFlags.StringArray("header", []string{}, "")
 	pFlags.String("id", "", "")
@@ -176,6 +219,8 @@ func init() {
 	pFlags.Bool("allow-root", false, "")
 	pFlags.Bool("remote-commands-enabled", false, "")
 	pFlags.Int("remote-commands-send-back-limit", 0, "")
+	pFlags.Int("remote-commands-concurrency", 0, "")
+	pFlags.Bool("file-transfer-enabled", false, "")
 
 	cfgPath = pFlags.StringP("config", "c", "", "")
 	svcCommand = pFlags.String("service", "", "")
@@ -198,7 +243,13 @@ func init() {
 	viperCfg.SetDefault("remote-commands.deny", []string{`(\||<|>|;|,|\n|&)`})
 	viperCfg.SetDefault("remote-commands.order", []string{"allow", "deny"})
 	viperCfg.SetDefault("remote-commands.send_back_limit", 2048)
+	viperCfg.SetDefault("remote-commands.concurrency", 4)
 	viperCfg.SetDefault("remote-commands.enabled", true)
+	viperCfg.SetDefault("file-transfer.enabled", false)
+	viperCfg.SetDefault("file-transfer.allow", []string{})
+	viperCfg.SetDefault("file-transfer.deny", []string{})
+	viperCfg.SetDefault("file-transfer.order", []string{"allow", "deny"})
+	viperCfg.SetDefault("socks.allow", []string{})
 }
 
 func bindPFlags() {
@@ -206,6 +257,8 @@ func bindPFlags() {
 	// map config fields to CLI args:
 	_ = viperCfg.BindPFlag("client.fingerprint", pFlags.Lookup("fingerprint"))
 	_ = viperCfg.BindPFlag("client.auth", pFlags.Lookup("auth"))
+	_ = viperCfg.BindPFlag("client.auth_key", pFlags.Lookup("auth-key"))
+	_ = viperCfg.BindPFlag("client.auth_cert", pFlags.Lookup("auth-cert"))
 	_ = viperCfg.BindPFlag("client.proxy", pFlags.Lookup("proxy"))
 	_ = viperCfg.BindPFlag("client.id", pFlags.Lookup("id"))
 	_ = viperCfg.BindPFlag("client.name", pFlags.Lookup("name"))
@@ -218,11 +271,15 @@ func bindPFlags() {
 	_ = viperCfg.BindPFlag("connection.keep_alive", pFlags.Lookup("keepalive"))
 	_ = viperCfg.BindPFlag("connection.max_retry_count", pFlags.Lookup("max-retry-count"))
 	_ = viperCfg.BindPFlag("connection.max_retry_interval", pFlags.Lookup("max-retry-interval"))
+	_ = viperCfg.BindPFlag("connection.max_bytes_per_sec", pFlags.Lookup("max-bytes-per-sec"))
 	_ = viperCfg.BindPFlag("connection.hostname", pFlags.Lookup("hostname"))
 	_ = viperCfg.BindPFlag("connection.headers", pFlags.Lookup("header"))
 
 	_ = viperCfg.BindPFlag("remote-commands.enabled", pFlags.Lookup("remote-commands-enabled"))
 	_ = viperCfg.BindPFlag("remote-commands.send_back_limit", pFlags.Lookup("remote-commands-send-back-limit"))
+	_ = viperCfg.BindPFlag("remote-commands.concurrency", pFlags.Lookup("remote-commands-concurrency"))
+
+	_ = viperCfg.BindPFlag("file-transfer.enabled", pFlags.Lookup("file-transfer-enabled"))
 }
 
 func main() {
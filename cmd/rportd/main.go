@@ -26,6 +26,7 @@ const (
 	DefaultServerAddress          = "0.0.0.0:8080"
 	DefaultLogLevel               = "info"
 	DefaultRunRemoteCmdTimeoutSec = 60
+	DefaultClientCertValidity     = 365 * 24 * time.Hour
 )
 
 var serverHelp = `
@@ -62,7 +63,8 @@ var serverHelp = `
     and private key pair. All communications will be secured using this
     key pair. Share the subsequent fingerprint with clients to enable detection
     of man-in-the-middle attacks. If not specified, a new key is generate each run.
-    Use "openssl rand -hex 18" to generate a secure key seed
+    Use "openssl rand -hex 18" to generate a secure key seed.
+    To rotate the host key use "key_files" in the config file instead.
 
     --authfile, An optional path to a json file with client credentials.
     This is for authentication of the rport tunnel clients.
@@ -287,6 +289,7 @@ func init() {
 	viperCfg.SetDefault("server.client_login_wait", 2)
 	viperCfg.SetDefault("server.max_failed_login", 5)
 	viperCfg.SetDefault("server.ban_time", 3600)
+	viperCfg.SetDefault("server.client_cert_validity", DefaultClientCertValidity)
 	viperCfg.SetDefault("api.user_login_wait", 2)
 	viperCfg.SetDefault("api.max_failed_login", 10)
 	viperCfg.SetDefault("api.ban_time", 600)
@@ -0,0 +1,2 @@
+DROP INDEX idx_client_certs_fingerprint;
+DROP TABLE client_certs;
@@ -0,0 +1,11 @@
+CREATE TABLE client_certs (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    client_auth_id TEXT NOT NULL,
+    fingerprint TEXT NOT NULL,
+    tenant TEXT NOT NULL DEFAULT '',
+    created_by TEXT NOT NULL,
+    created_at DATETIME NOT NULL,
+    valid_before DATETIME NOT NULL,
+    revoked_at DATETIME
+);
+CREATE INDEX idx_client_certs_fingerprint ON client_certs(client_auth_id, fingerprint);
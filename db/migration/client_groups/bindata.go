@@ -2,6 +2,8 @@
 // sources:
 // 001_init.down.sql
 // 001_init.up.sql
+// 002_tenant.down.sql
+// 002_tenant.up.sql
 package client_groups
 
 import (
@@ -78,7 +80,7 @@ func (fi bindataFileInfo) Sys() interface{} {
 	return nil
 }
 
-var __001_initDownSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x72\x09\xf2\x0f\x50\x08\x71\x74\xf2\x71\x55\x48\xce\xc9\x4c\xcd\x2b\x89\x4f\x2f\xca\x2f\x2d\x28\xb6\xe6\x02\x04\x00\x00\xff\xff\xee\xde\xdd\xb3\x1a\x00\x00\x00")
+var __001_initDownSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x00\x1a\x00\xe5\xff\x44\x52\x4f\x50\x20\x54\x41\x42\x4c\x45\x20\x63\x6c\x69\x65\x6e\x74\x5f\x67\x72\x6f\x75\x70\x73\x3b\x0a\x03\x00\xee\xde\xdd\xb3\x1a\x00\x00\x00")
 
 func _001_initDownSqlBytes() ([]byte, error) {
 	return bindataRead(
@@ -98,7 +100,7 @@ func _001_initDownSql() (*asset, error) {
 	return a, nil
 }
 
-var __001_initUpSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x72\x0e\x72\x75\x0c\x71\x55\x08\x71\x74\xf2\x71\x55\x48\xce\xc9\x4c\xcd\x2b\x89\x4f\x2f\xca\x2f\x2d\x28\x56\xd0\xe0\x52\x50\x50\x50\xc8\x4c\x51\x08\x71\x8d\x08\x51\x08\x08\xf2\xf4\x75\x0c\x8a\x54\xf0\x76\x8d\x54\xf0\xf3\x0f\x51\xf0\x0b\xf5\xf1\xd1\xe1\xe2\x4c\x49\x2d\x4e\x2e\xca\x2c\x28\xc9\xcc\xcf\x83\xa8\x43\x92\x2b\x48\x2c\x4a\xcc\x2d\x46\x15\xe6\xd2\x54\x08\xf7\x0c\xf1\xf0\x0f\x0d\x51\x08\xf2\x0f\xf7\x74\xb1\xe6\x02\x04\x00\x00\xff\xff\xa5\xc7\xf9\xc7\x82\x00\x00\x00")
+var __001_initUpSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x72\x0e\x72\x75\x0c\x71\x55\x08\x71\x74\xf2\x71\x55\x48\xce\xc9\x4c\xcd\x2b\x89\x4f\x2f\xca\x2f\x2d\x28\x56\xd0\xe0\x52\x50\x50\x50\xc8\x4c\x51\x08\x71\x8d\x08\x51\x08\x08\xf2\xf4\x75\x0c\x8a\x54\xf0\x76\x8d\x54\xf0\xf3\x0f\x51\xf0\x0b\xf5\xf1\xd1\xe1\xe2\x4c\x49\x2d\x4e\x2e\xca\x2c\x28\xc9\xcc\xcf\x83\xa8\x43\x92\x2b\x48\x2c\x4a\xcc\x2d\x46\x15\xe6\xd2\x54\x08\xf7\x0c\xf1\xf0\x0f\x0d\x51\x08\xf2\x0f\xf7\x74\xb1\xe6\x02\x0c\x00\xa5\xc7\xf9\xc7\x82\x00\x00\x00")
 
 func _001_initUpSqlBytes() ([]byte, error) {
 	return bindataRead(
@@ -118,6 +120,46 @@ func _001_initUpSql() (*asset, error) {
 	return a, nil
 }
 
+var __002_tenantDownSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x74\x8f\xcd\x4e\x85\x30\x10\x85\xd7\xf6\x29\xce\x52\x13\xde\xa0\xab\x7a\xef\x18\x1b\x4b\x4b\x86\x21\xc8\x8a\x10\x20\xa6\x89\x40\x03\xf8\xfe\x2e\x74\x01\x06\xb7\xf3\x73\xce\xf7\xdd\x98\x8c\x10\xc4\x3c\x3b\x42\xff\x19\xc7\x79\x6f\x3f\xd6\xe5\x2b\x6d\xed\x3e\x25\x3c\x2a\x00\x88\x03\x84\xde\x05\x05\xdb\xdc\x70\x83\x37\x6a\xe0\x83\xc0\x57\xce\x65\xea\x61\x18\xb7\x7e\x8d\x69\x8f\xcb\xfc\x73\x77\xd8\xa5\x6e\xed\xa6\xed\x3c\x56\x4f\xa8\xad\xbc\x86\x4a\xc0\xa1\xb6\x77\xad\xac\x2f\x89\x05\xd6\x4b\xb8\x80\x28\xc9\xd1\x4d\x10\x87\x0c\x87\xaa\x0c\xbf\xd9\x2f\x1c\xf2\xf3\x97\x56\x77\x0e\xc5\x95\x94\x56\xc6\x09\xf1\xbf\xbe\x4c\xde\xe4\x84\xbf\x18\x5a\x7d\x0f\x00\xc7\x8a\x03\xd8\x28\x01\x00\x00")
+
+func _002_tenantDownSqlBytes() ([]byte, error) {
+	return bindataRead(
+		__002_tenantDownSql,
+		"002_tenant.down.sql",
+	)
+}
+
+func _002_tenantDownSql() (*asset, error) {
+	bytes, err := _002_tenantDownSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "002_tenant.down.sql", size: 296, mode: os.FileMode(420), modTime: time.Unix(1792198332, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var __002_tenantUpSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x00\x46\x00\xb9\xff\x41\x4c\x54\x45\x52\x20\x54\x41\x42\x4c\x45\x20\x63\x6c\x69\x65\x6e\x74\x5f\x67\x72\x6f\x75\x70\x73\x20\x41\x44\x44\x20\x43\x4f\x4c\x55\x4d\x4e\x20\x74\x65\x6e\x61\x6e\x74\x20\x54\x45\x58\x54\x20\x4e\x4f\x54\x20\x4e\x55\x4c\x4c\x20\x44\x45\x46\x41\x55\x4c\x54\x20\x27\x27\x3b\x0a\x03\x00\x3b\x7b\x16\xe5\x46\x00\x00\x00")
+
+func _002_tenantUpSqlBytes() ([]byte, error) {
+	return bindataRead(
+		__002_tenantUpSql,
+		"002_tenant.up.sql",
+	)
+}
+
+func _002_tenantUpSql() (*asset, error) {
+	bytes, err := _002_tenantUpSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "002_tenant.up.sql", size: 70, mode: os.FileMode(420), modTime: time.Unix(1792198332, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
 // Asset loads and returns the asset for the given name.
 // It returns an error if the asset could not be found or
 // could not be loaded.
@@ -170,8 +212,10 @@ func AssetNames() []string {
 
 // _bindata is a table, holding each asset generator, mapped to its name.
 var _bindata = map[string]func() (*asset, error){
-	"001_init.down.sql": _001_initDownSql,
-	"001_init.up.sql":   _001_initUpSql,
+	"001_init.down.sql":   _001_initDownSql,
+	"001_init.up.sql":     _001_initUpSql,
+	"002_tenant.down.sql": _002_tenantDownSql,
+	"002_tenant.up.sql":   _002_tenantUpSql,
 }
 
 // AssetDir returns the file names below a certain
@@ -215,8 +259,10 @@ type bintree struct {
 }
 
 var _bintree = &bintree{nil, map[string]*bintree{
-	"001_init.down.sql": &bintree{_001_initDownSql, map[string]*bintree{}},
-	"001_init.up.sql":   &bintree{_001_initUpSql, map[string]*bintree{}},
+	"001_init.down.sql":   &bintree{_001_initDownSql, map[string]*bintree{}},
+	"001_init.up.sql":     &bintree{_001_initUpSql, map[string]*bintree{}},
+	"002_tenant.down.sql": &bintree{_002_tenantDownSql, map[string]*bintree{}},
+	"002_tenant.up.sql":   &bintree{_002_tenantUpSql, map[string]*bintree{}},
 }}
 
 // RestoreAsset restores an asset under the given directory
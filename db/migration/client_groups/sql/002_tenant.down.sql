@@ -0,0 +1,8 @@
+CREATE TABLE client_groups_tmp (
+    id TEXT PRIMARY KEY NOT NULL,
+	description TEXT NOT NULL,
+	params TEXT NOT NULL
+) WITHOUT ROWID;
+INSERT INTO client_groups_tmp SELECT id, description, params FROM client_groups;
+DROP TABLE client_groups;
+ALTER TABLE client_groups_tmp RENAME TO client_groups;
@@ -0,0 +1 @@
+ALTER TABLE client_groups ADD COLUMN tenant TEXT NOT NULL DEFAULT '';
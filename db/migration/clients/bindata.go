@@ -2,6 +2,8 @@
 // sources:
 // 001_init.down.sql
 // 001_init.up.sql
+// 002_port_reservations.down.sql
+// 002_port_reservations.up.sql
 package clients
 
 import (
@@ -118,6 +120,46 @@ func _001_initUpSql() (*asset, error) {
 	return a, nil
 }
 
+var __002_port_reservationsDownSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x00\x46\x00\xb9\xff\x44\x52\x4f\x50\x20\x49\x4e\x44\x45\x58\x20\x69\x64\x78\x5f\x70\x6f\x72\x74\x5f\x72\x65\x73\x65\x72\x76\x61\x74\x69\x6f\x6e\x73\x5f\x70\x6f\x72\x74\x3b\x0a\x0a\x44\x52\x4f\x50\x20\x54\x41\x42\x4c\x45\x20\x70\x6f\x72\x74\x5f\x72\x65\x73\x65\x72\x76\x61\x74\x69\x6f\x6e\x73\x3b\x0a\x03\x00\x06\x57\x81\x98\x46\x00\x00\x00")
+
+func _002_port_reservationsDownSqlBytes() ([]byte, error) {
+	return bindataRead(
+		__002_port_reservationsDownSql,
+		"002_port_reservations.down.sql",
+	)
+}
+
+func _002_port_reservationsDownSql() (*asset, error) {
+	bytes, err := _002_port_reservationsDownSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "002_port_reservations.down.sql", size: 70, mode: os.FileMode(420), modTime: time.Unix(1792203986, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var __002_port_reservationsUpSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x6c\x90\xb1\x6a\xc3\x40\x10\x44\xfb\xfb\x8a\x29\x2d\xd0\x1f\xb8\x52\xa2\x25\x39\x22\x9f\x92\x63\x85\xed\xea\x10\xd2\x15\x07\x8e\x57\xac\x97\x90\xcf\x0f\x08\xe3\xc2\x51\xbb\x6f\x61\xe6\xcd\x6b\xa4\x86\x09\xdc\xbc\x74\x84\x45\xd4\x92\xe6\x5b\xd6\x9f\xd1\x8a\x5c\x6f\xd8\x39\x00\x98\x2e\x25\x5f\x2d\x95\x19\x4c\x27\x46\xe8\x19\x61\xe8\xba\x7a\x85\x9a\xbf\xc5\xf2\x16\x59\x54\x4c\x26\xb9\x6c\x32\x51\x83\x0f\x4c\x6f\x14\x9f\xd0\xa4\x79\xb4\x3c\xa7\xd1\xd0\x36\x4c\xec\x0f\xf4\xf4\xf1\x19\xfd\xa1\x89\x67\x7c\xd0\x19\xbb\x47\xb9\xfa\x5e\xa5\x7e\x04\x57\xae\xc2\xd1\xf3\x7b\x3f\x30\x62\x7f\xf4\xed\xde\xb9\xbb\xf0\x10\xfc\xd7\x40\xf0\xa1\xa5\x13\xca\xfc\x9b\xfe\xb9\xaf\x97\x35\xae\x0f\x5b\xcb\x2c\xa2\x56\xed\xdd\xdf\x00\xdb\x4c\x35\xbe\x41\x01\x00\x00")
+
+func _002_port_reservationsUpSqlBytes() ([]byte, error) {
+	return bindataRead(
+		__002_port_reservationsUpSql,
+		"002_port_reservations.up.sql",
+	)
+}
+
+func _002_port_reservationsUpSql() (*asset, error) {
+	bytes, err := _002_port_reservationsUpSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "002_port_reservations.up.sql", size: 321, mode: os.FileMode(420), modTime: time.Unix(1792203986, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
 // Asset loads and returns the asset for the given name.
 // It returns an error if the asset could not be found or
 // could not be loaded.
@@ -170,8 +212,10 @@ func AssetNames() []string {
 
 // _bindata is a table, holding each asset generator, mapped to its name.
 var _bindata = map[string]func() (*asset, error){
-	"001_init.down.sql": _001_initDownSql,
-	"001_init.up.sql":   _001_initUpSql,
+	"001_init.down.sql":              _001_initDownSql,
+	"001_init.up.sql":                _001_initUpSql,
+	"002_port_reservations.down.sql": _002_port_reservationsDownSql,
+	"002_port_reservations.up.sql":   _002_port_reservationsUpSql,
 }
 
 // AssetDir returns the file names below a certain
@@ -215,8 +259,10 @@ type bintree struct {
 }
 
 var _bintree = &bintree{nil, map[string]*bintree{
-	"001_init.down.sql": &bintree{_001_initDownSql, map[string]*bintree{}},
-	"001_init.up.sql":   &bintree{_001_initUpSql, map[string]*bintree{}},
+	"001_init.down.sql":              &bintree{_001_initDownSql, map[string]*bintree{}},
+	"001_init.up.sql":                &bintree{_001_initUpSql, map[string]*bintree{}},
+	"002_port_reservations.down.sql": &bintree{_002_port_reservationsDownSql, map[string]*bintree{}},
+	"002_port_reservations.up.sql":   &bintree{_002_port_reservationsUpSql, map[string]*bintree{}},
 }}
 
 // RestoreAsset restores an asset under the given directory
@@ -0,0 +1,3 @@
+DROP INDEX idx_port_reservations_port;
+
+DROP TABLE port_reservations;
@@ -0,0 +1,11 @@
+CREATE TABLE port_reservations (
+    client_id TEXT NOT NULL,
+    remote TEXT NOT NULL,
+    protocol TEXT NOT NULL,
+    port INTEGER NOT NULL,
+    created_at DATETIME NOT NULL,
+    PRIMARY KEY (client_id, remote, protocol)
+) WITHOUT ROWID;
+
+CREATE UNIQUE INDEX idx_port_reservations_port
+    ON port_reservations (port);
@@ -2,6 +2,8 @@
 // sources:
 // 001_init.down.sql
 // 001_init.up.sql
+// 002_tenant.down.sql
+// 002_tenant.up.sql
 package jobs
 
 import (
@@ -78,7 +80,7 @@ func (fi bindataFileInfo) Sys() interface{} {
 	return nil
 }
 
-var __001_initDownSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x72\x09\xf2\x0f\x50\xf0\xf4\x73\x71\x8d\x50\xc8\x4c\xa9\x88\xcf\xca\x4f\x2a\x8e\x4f\xce\xc9\x4c\xcd\x2b\x89\xcf\x4c\x89\x2f\xc9\xcc\x4d\xb5\xe6\xe2\xc2\xa6\x28\xb7\x34\xa7\x24\x33\x3e\x33\x05\x26\x1d\xe2\xe8\xe4\xe3\xaa\x00\x92\x42\x15\x81\xa8\x83\x88\x03\x02\x00\x00\xff\xff\x32\x12\x92\x70\x6d\x00\x00\x00")
+var __001_initDownSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x00\x6d\x00\x92\xff\x44\x52\x4f\x50\x20\x49\x4e\x44\x45\x58\x20\x69\x64\x78\x5f\x6a\x6f\x62\x73\x5f\x63\x6c\x69\x65\x6e\x74\x5f\x69\x64\x5f\x74\x69\x6d\x65\x3b\x0a\x0a\x44\x52\x4f\x50\x20\x49\x4e\x44\x45\x58\x20\x69\x64\x78\x5f\x6a\x6f\x62\x73\x5f\x6d\x75\x6c\x74\x69\x5f\x69\x64\x3b\x0a\x0a\x44\x52\x4f\x50\x20\x54\x41\x42\x4c\x45\x20\x6a\x6f\x62\x73\x3b\x0a\x0a\x44\x52\x4f\x50\x20\x54\x41\x42\x4c\x45\x20\x6d\x75\x6c\x74\x69\x5f\x6a\x6f\x62\x73\x3b\x0a\x03\x00\x32\x12\x92\x70\x6d\x00\x00\x00")
 
 func _001_initDownSqlBytes() ([]byte, error) {
 	return bindataRead(
@@ -98,7 +100,7 @@ func _001_initDownSql() (*asset, error) {
 	return a, nil
 }
 
-var __001_initUpSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x94\x91\xcd\x6e\xab\x30\x10\x85\xf7\x3c\xc5\x2c\x41\xca\x1b\x64\xc5\x85\xe1\xd6\x2a\x31\x95\x99\x28\x64\x65\x91\xd8\x55\x07\x91\x54\x8a\x1d\xa9\x7d\xfb\xaa\xa6\x41\x71\xd5\xf4\x67\xfd\x1d\xcf\x7c\x73\x5c\x28\xcc\x09\x81\xf2\x7f\x35\x82\xa8\x40\x36\x04\xd8\x89\x96\x5a\x38\x9c\x47\xcf\x7a\x78\xde\x39\x48\x13\x00\x80\x81\x0d\x10\x76\x04\x0f\x4a\xac\x72\xb5\x85\x7b\xdc\x86\x07\x72\x5d\xd7\x8b\x10\x71\xbe\x3f\x79\x6b\x74\xef\xa1\xcc\x09\x49\xac\xf0\x53\x62\x7f\xb2\xfd\x7b\x62\xf7\x3a\xcd\x8a\xa9\xb1\xbe\xe7\xd1\xc5\x28\xc9\x60\x23\xe8\xae\x59\x13\xa8\x66\x23\xca\x65\x92\x44\xda\x7f\x56\xf4\x67\xf7\xd5\xf2\x9f\xe5\x1f\xf9\xc8\xee\x29\x8e\xfc\xe6\xac\xfd\xc8\xf6\xe8\xf5\x45\x2e\x86\x73\xcf\x17\xfe\x4d\x15\x13\xaa\x1a\x85\xe2\xbf\x0c\xc7\xa5\xd7\xcf\x33\x50\x58\xa1\x42\x59\xe0\xf5\xff\xa5\x03\x9b\xec\x76\x8b\x42\x96\xd8\x01\x9b\x97\x10\xd6\xb3\xac\xf6\x7c\xb0\x61\x61\x23\x3f\x4a\x9e\xd9\x22\xee\x02\xdb\x22\xbb\x39\x70\x12\x61\x13\x8f\x8a\xbc\x97\xc9\x5b\x00\x00\x00\xff\xff\x97\x9b\x70\x8a\x89\x02\x00\x00")
+var __001_initUpSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x94\x91\xd1\x6e\xb2\x40\x10\x85\xef\x79\x8a\x73\x09\x89\x6f\xe0\x15\x3f\x0c\x7f\x37\xc5\xa5\x59\xc6\x88\x57\x04\x5d\x9a\x0e\x41\x9b\xc8\x9a\xb4\x6f\xdf\x08\x29\x71\x4d\x6d\xec\xf5\x77\x76\xe6\xdb\x33\x89\xa1\x98\x09\x1c\xff\xcb\x09\x2a\x83\x2e\x18\x54\xa9\x92\x4b\x1c\xce\xbd\x93\xba\x7b\xdf\x0d\x08\x03\x00\xe8\xc4\x82\xa9\x62\xbc\x18\xb5\x8a\xcd\x16\xcf\xb4\x1d\x1f\xe8\x75\x9e\x2f\xc6\xc8\xe0\x9a\x93\x6b\x6d\xdd\x38\xa4\x31\x13\xab\x15\xdd\x24\xf6\xa7\xb6\xb9\x24\x76\x9f\xd3\x2c\x9f\xda\xd6\x35\xd2\x0f\x3e\x0a\x22\x6c\x14\x3f\x15\x6b\x86\x29\x36\x2a\x5d\x06\x81\xa7\xfd\x67\x45\x77\xbe\xd9\xf0\xa8\xfc\xab\x1c\x65\x78\xf3\x23\x8f\x7c\x6b\xdf\x4b\x7b\x74\xb5\xd8\x9f\xe0\xdc\xf3\x37\xff\xa5\x8a\x09\x65\x85\x21\xf5\x5f\x8f\xfd\x87\xd7\xcf\x23\x18\xca\xc8\x90\x4e\xe8\xfa\x7e\x61\x27\x36\xba\xdf\xa2\xd2\x29\x55\x10\xfb\x31\x1e\xbb\x9e\x65\x6b\x27\x87\x76\x5c\x58\x68\x5c\x10\xc2\x99\x2d\xfc\x2e\xa8\x4c\xa2\xbb\x03\x27\x11\xb1\xfe\x28\xcf\x7b\x19\x7c\x0d\x00\x97\x9b\x70\x8a\x89\x02\x00\x00")
 
 func _001_initUpSqlBytes() ([]byte, error) {
 	return bindataRead(
@@ -118,6 +120,46 @@ func _001_initUpSql() (*asset, error) {
 	return a, nil
 }
 
+var __002_tenantDownSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x6c\x90\xcd\x4a\xc4\x40\x10\x84\xef\xf3\x14\x75\x54\x98\x37\x98\xd3\xb8\x69\x71\x70\x7e\x96\xde\x5e\xd6\x3d\x85\x59\x93\x43\x42\x82\x92\x8c\x07\xdf\x5e\xa2\x20\x49\xf4\x5c\x5f\x77\x7d\xd4\x81\xc9\x0a\x41\xec\x83\x27\x8c\x1f\x43\xe9\xea\xfe\xed\x36\xd7\x65\x7c\xc7\x9d\x02\x80\xbe\x6b\x20\xf4\x22\x38\xb2\x0b\x96\xaf\x78\xa6\x2b\x62\x12\xc4\xb3\xf7\xfa\x1b\x99\x4b\x9e\x4a\xdb\xd4\xb9\xa0\xb2\x42\xe2\x02\xed\x88\xd7\xa9\xcd\x0b\x71\xfb\xfc\xf9\xb5\x4d\x9b\xb6\xe4\x6e\x98\xb7\x91\xba\xc7\xc5\xc9\x53\x3a\x0b\x38\x5d\x5c\x65\x94\x8b\x27\x62\x81\x8b\x92\xf6\xaa\x27\xf2\x74\x90\xc5\x55\xaf\x6c\xf4\xaa\x57\xff\xb6\x3c\x72\x0a\xab\x7b\xa3\x2a\x4e\xc7\x3f\x0b\x18\x65\xbd\x10\xff\xbf\x0c\x53\xb4\x81\xb0\xf1\x30\xea\x6b\x00\x6a\x18\x7a\xa1\x4c\x01\x00\x00")
+
+func _002_tenantDownSqlBytes() ([]byte, error) {
+	return bindataRead(
+		__002_tenantDownSql,
+		"002_tenant.down.sql",
+	)
+}
+
+func _002_tenantDownSql() (*asset, error) {
+	bytes, err := _002_tenantDownSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "002_tenant.down.sql", size: 332, mode: os.FileMode(420), modTime: time.Unix(1792198337, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var __002_tenantUpSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x00\x43\x00\xbc\xff\x41\x4c\x54\x45\x52\x20\x54\x41\x42\x4c\x45\x20\x6d\x75\x6c\x74\x69\x5f\x6a\x6f\x62\x73\x20\x41\x44\x44\x20\x43\x4f\x4c\x55\x4d\x4e\x20\x74\x65\x6e\x61\x6e\x74\x20\x54\x45\x58\x54\x20\x4e\x4f\x54\x20\x4e\x55\x4c\x4c\x20\x44\x45\x46\x41\x55\x4c\x54\x20\x27\x27\x3b\x0a\x03\x00\xd9\x98\x46\x39\x43\x00\x00\x00")
+
+func _002_tenantUpSqlBytes() ([]byte, error) {
+	return bindataRead(
+		__002_tenantUpSql,
+		"002_tenant.up.sql",
+	)
+}
+
+func _002_tenantUpSql() (*asset, error) {
+	bytes, err := _002_tenantUpSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "002_tenant.up.sql", size: 67, mode: os.FileMode(420), modTime: time.Unix(1792198337, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
 // Asset loads and returns the asset for the given name.
 // It returns an error if the asset could not be found or
 // could not be loaded.
@@ -170,8 +212,10 @@ func AssetNames() []string {
 
 // _bindata is a table, holding each asset generator, mapped to its name.
 var _bindata = map[string]func() (*asset, error){
-	"001_init.down.sql": _001_initDownSql,
-	"001_init.up.sql":   _001_initUpSql,
+	"001_init.down.sql":   _001_initDownSql,
+	"001_init.up.sql":     _001_initUpSql,
+	"002_tenant.down.sql": _002_tenantDownSql,
+	"002_tenant.up.sql":   _002_tenantUpSql,
 }
 
 // AssetDir returns the file names below a certain
@@ -215,8 +259,10 @@ type bintree struct {
 }
 
 var _bintree = &bintree{nil, map[string]*bintree{
-	"001_init.down.sql": &bintree{_001_initDownSql, map[string]*bintree{}},
-	"001_init.up.sql":   &bintree{_001_initUpSql, map[string]*bintree{}},
+	"001_init.down.sql":   &bintree{_001_initDownSql, map[string]*bintree{}},
+	"001_init.up.sql":     &bintree{_001_initUpSql, map[string]*bintree{}},
+	"002_tenant.down.sql": &bintree{_002_tenantDownSql, map[string]*bintree{}},
+	"002_tenant.up.sql":   &bintree{_002_tenantUpSql, map[string]*bintree{}},
 }}
 
 // RestoreAsset restores an asset under the given directory
@@ -0,0 +1,9 @@
+CREATE TABLE multi_jobs_tmp (
+    jid TEXT PRIMARY KEY NOT NULL,
+    started_at DATETIME NOT NULL,
+    created_by TEXT NOT NULL,
+    details TEXT NOT NULL
+) WITHOUT ROWID;
+INSERT INTO multi_jobs_tmp SELECT jid, started_at, created_by, details FROM multi_jobs;
+DROP TABLE multi_jobs;
+ALTER TABLE multi_jobs_tmp RENAME TO multi_jobs;
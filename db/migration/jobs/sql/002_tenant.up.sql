@@ -0,0 +1 @@
+ALTER TABLE multi_jobs ADD COLUMN tenant TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,9 @@
+CREATE TABLE schedules (
+    id TEXT PRIMARY KEY NOT NULL,
+    name TEXT NOT NULL,
+    schedule TEXT NOT NULL,
+    created_at DATETIME NOT NULL,
+    created_by TEXT NOT NULL,
+    tenant TEXT NOT NULL DEFAULT '',
+    details TEXT NOT NULL
+) WITHOUT ROWID;
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"math"
 	"net"
 	"net/http"
 	"regexp"
@@ -20,6 +21,7 @@ import (
 	"github.com/cloudradar-monitoring/rport/server/api"
 	"github.com/cloudradar-monitoring/rport/server/api/jobs"
 	"github.com/cloudradar-monitoring/rport/server/api/middleware"
+	"github.com/cloudradar-monitoring/rport/server/auditlog"
 	"github.com/cloudradar-monitoring/rport/server/cgroups"
 	"github.com/cloudradar-monitoring/rport/server/clients"
 	"github.com/cloudradar-monitoring/rport/server/clientsauth"
@@ -36,8 +38,10 @@ const (
 	queryParamSort = "sort"
 
 	routeParamClientID = "client_id"
+	routeParamTunnelID = "tunnel_id"
 	routeParamJobID    = "job_id"
 	routeParamGroupID  = "group_id"
+	routeParamPort     = "port"
 
 	ErrCodeMissingRouteVar = "ERR_CODE_MISSING_ROUTE_VAR"
 	ErrCodeInvalidRequest  = "ERR_CODE_INVALID_REQUEST"
@@ -46,6 +50,8 @@ const (
 
 var validInputShell = []string{"cmd", "powershell"}
 
+var validInputInterpreter = []string{"sh", "bash", "cmd", "powershell", "python"}
+
 var generateNewJobID = func() string {
 	return random.UUID4()
 }
@@ -64,6 +70,8 @@ type JobProvider interface {
 	SaveJob(job *models.Job) error
 	// CreateJob creates a new job. If already exist with a given JID - do nothing and return nil
 	CreateJob(job *models.Job) error
+	// AppendOutput appends a given chunk of output to a running job
+	AppendOutput(jid string, output *models.JobResult) error
 	GetMultiJob(jid string) (*models.MultiJob, error)
 	GetAllMultiJobSummaries() ([]*models.MultiJobSummary, error)
 	SaveMultiJob(multiJob *models.MultiJob) error
@@ -117,28 +125,52 @@ func (al *APIListener) handleBannedIPs(w http.ResponseWriter, r *http.Request, a
 
 func (al *APIListener) initRouter() {
 	r := mux.NewRouter()
+	if al.config.API.TunnelProxy {
+		al.initTunnelProxyRoutes(r)
+	}
 	sub := r.PathPrefix("/api/v1").Subrouter()
 	sub.HandleFunc("/login", al.handleGetLogin).Methods(http.MethodGet)
 	sub.HandleFunc("/status", al.handleGetStatus).Methods(http.MethodGet)
 	sub.HandleFunc("/me", al.handleGetMe).Methods(http.MethodGet)
 	sub.HandleFunc("/me/ip", al.handleGetIP).Methods(http.MethodGet)
 	sub.HandleFunc("/clients", al.handleGetClients).Methods(http.MethodGet)
-	sub.HandleFunc("/clients/{client_id}/tunnels", al.handlePutClientTunnel).Methods(http.MethodPut)
-	sub.HandleFunc("/clients/{client_id}/tunnels/{tunnel_id}", al.handleDeleteClientTunnel).Methods(http.MethodDelete)
-	sub.HandleFunc("/clients/{client_id}/commands", al.handlePostCommand).Methods(http.MethodPost)
-	sub.HandleFunc("/clients/{client_id}/commands", al.handleGetCommands).Methods(http.MethodGet)
-	sub.HandleFunc("/clients/{client_id}/commands/{job_id}", al.handleGetCommand).Methods(http.MethodGet)
+	sub.HandleFunc("/clients/{client_id}/tunnels", al.withPermission(PermissionTunnels, al.handlePutClientTunnel)).Methods(http.MethodPut)
+	sub.HandleFunc("/clients/{client_id}/tunnels/{tunnel_id}", al.withPermission(PermissionTunnels, al.handleDeleteClientTunnel)).Methods(http.MethodDelete)
+	sub.HandleFunc("/clients/{client_id}/tunnels/{tunnel_id}/connections", al.handleGetClientTunnelConnections).Methods(http.MethodGet)
+	sub.HandleFunc("/clients/{client_id}/tunnels/{tunnel_id}/proxy-url", al.withPermission(PermissionTunnels, al.handleGetTunnelProxyURL)).Methods(http.MethodGet)
+	sub.HandleFunc("/clients/{client_id}/tunnel-conflicts", al.withPermission(PermissionTunnels, al.handleDeleteClientTunnelConflicts)).Methods(http.MethodDelete)
+	sub.HandleFunc("/clients/{client_id}/port-reservations", al.handleGetClientPortReservations).Methods(http.MethodGet)
+	sub.HandleFunc("/clients/{client_id}/port-reservations", al.withPermission(PermissionTunnels, al.handleDeleteClientPortReservations)).Methods(http.MethodDelete)
+	sub.HandleFunc("/clients/{client_id}/port-reservations/{port}", al.withPermission(PermissionTunnels, al.handleDeleteClientPortReservation)).Methods(http.MethodDelete)
+	sub.HandleFunc("/clients/{client_id}/commands", al.withPermission(PermissionCommands, al.handlePostCommand)).Methods(http.MethodPost)
+	sub.HandleFunc("/clients/{client_id}/commands", al.withPermission(PermissionCommands, al.handleGetCommands)).Methods(http.MethodGet)
+	sub.HandleFunc("/clients/{client_id}/commands/{job_id}", al.withPermission(PermissionCommands, al.handleGetCommand)).Methods(http.MethodGet)
+	sub.HandleFunc("/clients/{client_id}/commands/{job_id}", al.withPermission(PermissionCommands, al.handleDeleteCommand)).Methods(http.MethodDelete)
+	sub.HandleFunc("/clients/{client_id}/files", al.withPermission(PermissionFiles, al.handlePostClientFile)).Methods(http.MethodPost).Name(routeNameFileUpload)
+	sub.HandleFunc("/clients/{client_id}/files", al.withPermission(PermissionFiles, al.handleGetClientFile)).Methods(http.MethodGet)
 	sub.HandleFunc("/client-groups", al.handleGetClientGroups).Methods(http.MethodGet)
-	sub.HandleFunc("/client-groups", al.handlePostClientGroups).Methods(http.MethodPost)
-	sub.HandleFunc("/client-groups/{group_id}", al.handlePutClientGroup).Methods(http.MethodPut)
+	sub.HandleFunc("/client-groups", al.withPermission(PermissionClientGroups, al.handlePostClientGroups)).Methods(http.MethodPost)
+	sub.HandleFunc("/client-groups/{group_id}", al.withPermission(PermissionClientGroups, al.handlePutClientGroup)).Methods(http.MethodPut)
 	sub.HandleFunc("/client-groups/{group_id}", al.handleGetClientGroup).Methods(http.MethodGet)
-	sub.HandleFunc("/client-groups/{group_id}", al.handleDeleteClientGroup).Methods(http.MethodDelete)
-	sub.HandleFunc("/commands", al.handlePostMultiClientCommand).Methods(http.MethodPost)
-	sub.HandleFunc("/commands", al.handleGetMultiClientCommands).Methods(http.MethodGet)
-	sub.HandleFunc("/commands/{job_id}", al.handleGetMultiClientCommand).Methods(http.MethodGet)
-	sub.HandleFunc("/clients-auth", al.handleGetClientsAuth).Methods(http.MethodGet)
-	sub.HandleFunc("/clients-auth", al.handlePostClientsAuth).Methods(http.MethodPost)
-	sub.HandleFunc("/clients-auth/{client_auth_id}", al.handleDeleteClientAuth).Methods(http.MethodDelete)
+	sub.HandleFunc("/client-groups/{group_id}", al.withPermission(PermissionClientGroups, al.handleDeleteClientGroup)).Methods(http.MethodDelete)
+	sub.HandleFunc("/commands", al.withPermission(PermissionCommands, al.handlePostMultiClientCommand)).Methods(http.MethodPost)
+	sub.HandleFunc("/commands", al.withPermission(PermissionCommands, al.handleGetMultiClientCommands)).Methods(http.MethodGet)
+	sub.HandleFunc("/commands/{job_id}", al.withPermission(PermissionCommands, al.handleGetMultiClientCommand)).Methods(http.MethodGet)
+	sub.HandleFunc("/commands/{job_id}", al.withPermission(PermissionCommands, al.handleDeleteMultiClientCommand)).Methods(http.MethodDelete)
+	sub.HandleFunc("/schedules", al.withPermission(PermissionCommands, al.handleGetSchedules)).Methods(http.MethodGet)
+	sub.HandleFunc("/schedules", al.withPermission(PermissionCommands, al.handlePostSchedules)).Methods(http.MethodPost)
+	sub.HandleFunc("/schedules/{schedule_id}", al.withPermission(PermissionCommands, al.handleGetSchedule)).Methods(http.MethodGet)
+	sub.HandleFunc("/schedules/{schedule_id}", al.withPermission(PermissionCommands, al.handlePutSchedule)).Methods(http.MethodPut)
+	sub.HandleFunc("/schedules/{schedule_id}", al.withPermission(PermissionCommands, al.handleDeleteSchedule)).Methods(http.MethodDelete)
+	sub.HandleFunc("/clients-auth", al.withPermission(PermissionClientsAuth, al.handleGetClientsAuth)).Methods(http.MethodGet)
+	sub.HandleFunc("/clients-auth", al.withPermission(PermissionClientsAuth, al.handlePostClientsAuth)).Methods(http.MethodPost)
+	sub.HandleFunc("/clients-auth/{client_auth_id}", al.withPermission(PermissionClientsAuth, al.handleDeleteClientAuth)).Methods(http.MethodDelete)
+	sub.HandleFunc("/client-certs", al.withPermission(PermissionClientsAuth, al.handleGetClientCerts)).Methods(http.MethodGet)
+	sub.HandleFunc("/client-certs", al.withPermission(PermissionClientsAuth, al.handlePostClientCert)).Methods(http.MethodPost)
+	sub.HandleFunc("/client-certs/ca", al.withPermission(PermissionClientsAuth, al.handleGetClientCA)).Methods(http.MethodGet)
+	sub.HandleFunc("/client-certs/{cert_id}", al.withPermission(PermissionClientsAuth, al.handleDeleteClientCert)).Methods(http.MethodDelete)
+	sub.HandleFunc("/audit-log", al.withPermission(PermissionAuditLog, al.handleGetAuditLog)).Methods(http.MethodGet)
+	sub.HandleFunc("/port-pools", al.handleGetPortPools).Methods(http.MethodGet)
 
 	// add authorization middleware
 	if !al.insecureForTests {
@@ -154,7 +186,7 @@ func (al *APIListener) initRouter() {
 
 	// web sockets
 	// common auth middleware is not used due to JS issue https://stackoverflow.com/questions/22383089/is-it-possible-to-use-bearer-authentication-for-websocket-upgrade-requests
-	sub.HandleFunc("/ws/commands", al.wsAuth(http.HandlerFunc(al.handleCommandsWS))).Methods(http.MethodGet)
+	sub.HandleFunc("/ws/commands", al.wsAuth(al.withPermission(PermissionCommands, al.handleCommandsWS))).Methods(http.MethodGet)
 
 	// only for test purpose
 	// TODO: uncomment when needed
@@ -171,6 +203,10 @@ func (al *APIListener) initRouter() {
 
 	// add max bytes middleware
 	_ = sub.Walk(func(route *mux.Route, router *mux.Router, ancestors []*mux.Route) error {
+		// uploaded files are streamed to clients, so they are not limited
+		if route.GetName() == routeNameFileUpload {
+			return nil
+		}
 		route.HandlerFunc(middleware.MaxBytes(route.GetHandler(), al.config.Server.MaxRequestBytes))
 		return nil
 	})
@@ -223,7 +259,7 @@ func (al *APIListener) handleGetLogin(w http.ResponseWriter, req *http.Request)
 		return
 	}
 
-	tokenStr, err := al.createAuthToken(lifetime, api.GetUser(req.Context(), al.Logger))
+	tokenStr, err := al.createAuthToken(lifetime, api.GetUser(req.Context(), al.Logger), realip.FromRequest(req))
 	if err != nil {
 		al.jsonErrorResponse(w, http.StatusInternalServerError, err)
 		return
@@ -271,7 +307,7 @@ func (al *APIListener) handlePostLogin(w http.ResponseWriter, req *http.Request)
 		return
 	}
 
-	tokenStr, err := al.createAuthToken(lifetime, user)
+	tokenStr, err := al.createAuthToken(lifetime, user, realip.FromRequest(req))
 	if err != nil {
 		al.jsonErrorResponse(w, http.StatusInternalServerError, err)
 		return
@@ -380,6 +416,7 @@ func (al *APIListener) handleGetStatus(w http.ResponseWriter, req *http.Request)
 		"clients_connected":    countActive,
 		"clients_disconnected": countDisconnected,
 		"fingerprint":          al.fingerprint,
+		"next_fingerprints":    al.nextFingerprints,
 		"connect_url":          al.config.Server.URL,
 		"clients_auth_source":  al.clientAuthProvider.Source(),
 		"clients_auth_mode":    al.getClientsAuthMode(),
@@ -394,11 +431,18 @@ func (al *APIListener) handleGetClients(w http.ResponseWriter, req *http.Request
 		return
 	}
 
+	access, err := al.getClientAccess(req.Context())
+	if err != nil {
+		al.jsonErrorResponse(w, http.StatusInternalServerError, err)
+		return
+	}
+
 	clients, err := al.clientService.GetAll()
 	if err != nil {
 		al.jsonErrorResponse(w, http.StatusInternalServerError, err)
 		return
 	}
+	clients = access.Filter(clients)
 
 	sortFunc(clients, desc)
 
@@ -407,22 +451,24 @@ func (al *APIListener) handleGetClients(w http.ResponseWriter, req *http.Request
 }
 
 type ClientPayload struct {
-	ID              string                  `json:"id"`
-	Name            string                  `json:"name"`
-	OS              string                  `json:"os"`
-	OSArch          string                  `json:"os_arch"`
-	OSFamily        string                  `json:"os_family"`
-	OSKernel        string                  `json:"os_kernel"`
-	Hostname        string                  `json:"hostname"`
-	IPv4            []string                `json:"ipv4"`
-	IPv6            []string                `json:"ipv6"`
-	Tags            []string                `json:"tags"`
-	Version         string                  `json:"version"`
-	Address         string                  `json:"address"`
-	Tunnels         []*clients.Tunnel       `json:"tunnels"`
-	DisconnectedAt  *time.Time              `json:"disconnected_at"`
-	ConnectionState clients.ConnectionState `json:"connection_state"`
-	ClientAuthID    string                  `json:"client_auth_id"`
+	ID              string                    `json:"id"`
+	Name            string                    `json:"name"`
+	OS              string                    `json:"os"`
+	OSArch          string                    `json:"os_arch"`
+	OSFamily        string                    `json:"os_family"`
+	OSKernel        string                    `json:"os_kernel"`
+	Hostname        string                    `json:"hostname"`
+	IPv4            []string                  `json:"ipv4"`
+	IPv6            []string                  `json:"ipv6"`
+	Tags            []string                  `json:"tags"`
+	Version         string                    `json:"version"`
+	Address         string                    `json:"address"`
+	Tunnels         []*clients.Tunnel         `json:"tunnels"`
+	TunnelConflicts []*clients.TunnelConflict `json:"tunnel_conflicts"`
+	DisconnectedAt  *time.Time                `json:"disconnected_at"`
+	ConnectionState clients.ConnectionState   `json:"connection_state"`
+	ClientAuthID    string                    `json:"client_auth_id"`
+	Tenant          string                    `json:"tenant,omitempty"`
 }
 
 func convertToClientsPayload(clients []*clients.Client) []ClientPayload {
@@ -442,9 +488,11 @@ func convertToClientsPayload(clients []*clients.Client) []ClientPayload {
 			Version:         cur.Version,
 			Address:         cur.Address,
 			Tunnels:         cur.Tunnels,
+			TunnelConflicts: cur.TunnelConflicts,
 			DisconnectedAt:  cur.DisconnectedAt,
 			ConnectionState: cur.ConnectionState(),
 			ClientAuthID:    cur.ClientAuthID,
+			Tenant:          cur.Tenant,
 		})
 	}
 	return r
@@ -485,13 +533,22 @@ const (
 	idleTimeoutMin               = 0
 	idleTimeoutMax               = 7 * 24 * 60 // week
 
+	maxBytesPerSecQueryParam = "max_bytes_per_sec"
+	maxConnectionsQueryParam = "max_connections"
+
+	expiresAtQueryParam   = "expires_at"
+	maxLifetimeQueryParam = "max_lifetime"
+
 	ErrCodeLocalPortInUse        = "ERR_CODE_LOCAL_PORT_IN_USE"
+	ErrCodeLocalPortReserved     = "ERR_CODE_LOCAL_PORT_RESERVED"
 	ErrCodeRemotePortNotOpen     = "ERR_CODE_REMOTE_PORT_NOT_OPEN"
 	ErrCodeTunnelExist           = "ERR_CODE_TUNNEL_EXIST"
 	ErrCodeTunnelToPortExist     = "ERR_CODE_TUNNEL_TO_PORT_EXIST"
 	ErrCodeURISchemeLengthExceed = "ERR_CODE_URI_SCHEME_LENGTH_EXCEED"
 	ErrCodeInvalidACL            = "ERR_CODE_INVALID_ACL"
 	ErrCodeInvalidIdleTimeout    = "ERR_CODE_INVALID_IDLE_TIMEOUT"
+	ErrCodeInvalidTunnelLimit    = "ERR_CODE_INVALID_TUNNEL_LIMIT"
+	ErrCodeInvalidExpiry         = "ERR_CODE_INVALID_EXPIRY"
 )
 
 func (al *APIListener) handlePutClientTunnel(w http.ResponseWriter, req *http.Request) {
@@ -511,19 +568,39 @@ func (al *APIListener) handlePutClientTunnel(w http.ResponseWriter, req *http.Re
 		al.jsonErrorResponseWithTitle(w, http.StatusNotFound, fmt.Sprintf("client with id %s not found", clientID))
 		return
 	}
+	if !al.checkClientAccess(w, req, client) {
+		return
+	}
 
 	localAddr := req.URL.Query().Get("local")
 	remoteAddr := req.URL.Query().Get("remote")
+	reverse := req.URL.Query().Get("reverse") == "1" || strings.HasPrefix(remoteAddr, chshare.ReversePrefix)
+	remoteAddr = strings.TrimPrefix(remoteAddr, chshare.ReversePrefix)
 	remoteStr := localAddr + ":" + remoteAddr
 	if localAddr == "" {
 		remoteStr = remoteAddr
 	}
+	if reverse {
+		remoteStr = chshare.ReversePrefix + remoteStr
+	}
 	remote, err := chshare.DecodeRemote(remoteStr)
 	if err != nil {
 		al.jsonErrorResponseWithTitle(w, http.StatusBadRequest, fmt.Sprintf("failed to decode %q: %v", remoteStr, err))
 		return
 	}
 
+	if protocol := req.URL.Query().Get("protocol"); protocol != "" {
+		if !chshare.IsValidProtocol(protocol) {
+			al.jsonErrorResponseWithTitle(w, http.StatusBadRequest, fmt.Sprintf("Invalid protocol %q, expected %q or %q.", protocol, chshare.ProtocolTCP, chshare.ProtocolUDP))
+			return
+		}
+		if strings.Contains(remoteStr, "/") && protocol != remote.Protocol {
+			al.jsonErrorResponseWithTitle(w, http.StatusBadRequest, fmt.Sprintf("Protocol %q doesn't match the protocol of the remote %q.", protocol, remoteStr))
+			return
+		}
+		remote.Protocol = protocol
+	}
+
 	idleTimeoutMinutesStr := req.URL.Query().Get(idleTimeoutMinutesQueryParam)
 	var idleTimeoutMinutes int
 	if idleTimeoutMinutesStr != "" {
@@ -540,7 +617,32 @@ func (al *APIListener) handlePutClientTunnel(w http.ResponseWriter, req *http.Re
 	}
 	remote.IdleTimeoutMinutes = idleTimeoutMinutes
 
+	var ok bool
+	if remote.MaxBytesPerSec, ok = al.parseTunnelLimit(w, req, maxBytesPerSecQueryParam, math.MaxInt64); !ok {
+		return
+	}
+	maxConnections, ok := al.parseTunnelLimit(w, req, maxConnectionsQueryParam, math.MaxInt32)
+	if !ok {
+		return
+	}
+	remote.MaxConnections = int(maxConnections)
+
+	if remote.ExpiresAt, ok = al.parseTunnelExpiry(w, req); !ok {
+		return
+	}
+
+	if remote.Reverse && !al.config.ReverseTunnelDestinations().Allows(remote.RemoteHost, remote.RemotePort) {
+		al.jsonErrorResponseWithTitle(w, http.StatusForbidden, fmt.Sprintf("Reverse tunnel destination %s is not allowed.", remote.Remote()))
+		return
+	}
+
 	aclStr := req.URL.Query().Get("acl")
+	aclSession := req.URL.Query().Get("acl_session") == "1"
+	if (aclStr != "" || aclSession) && remote.Reverse {
+		// connections of reverse tunnels are accepted by clients
+		al.jsonErrorResponseWithErrCode(w, http.StatusBadRequest, ErrCodeInvalidACL, "ACL is not supported for reverse tunnels.")
+		return
+	}
 	if _, err = clients.ParseTunnelACL(aclStr); err != nil {
 		al.jsonErrorResponseWithErrCode(w, http.StatusBadRequest, ErrCodeInvalidACL, fmt.Sprintf("Invalid ACL: %s", err))
 		return
@@ -548,6 +650,18 @@ func (al *APIListener) handlePutClientTunnel(w http.ResponseWriter, req *http.Re
 	if aclStr != "" {
 		remote.ACL = &aclStr
 	}
+	if aclSession {
+		if aclStr != "" {
+			al.jsonErrorResponseWithErrCode(w, http.StatusBadRequest, ErrCodeInvalidACL, `"acl" and "acl_session" params can't be used together.`)
+			return
+		}
+		// the IP of the user is refreshed by requests with a bearer token only
+		if _, ok := getBearerToken(req); !ok {
+			al.jsonErrorResponseWithErrCode(w, http.StatusBadRequest, ErrCodeInvalidACL, "ACL locked to the API session requires authorization with a bearer token.")
+			return
+		}
+		remote.ACLSessionUser = api.GetUser(req.Context(), al.Logger)
+	}
 
 	schemeStr := req.URL.Query().Get("scheme")
 	if len(schemeStr) > URISchemeMaxLength {
@@ -558,19 +672,31 @@ func (al *APIListener) handlePutClientTunnel(w http.ResponseWriter, req *http.Re
 		remote.Scheme = &schemeStr
 	}
 
+	if req.URL.Query().Get("http_proxy") == "1" {
+		if !al.validateTunnelProxy(w, localAddr, remote) {
+			return
+		}
+		remote.HTTPProxy = true
+	}
+
+	remote.Persistent = req.URL.Query().Get("persistent") == "1"
+
 	if existing := client.FindTunnelByRemote(remote); existing != nil {
 		al.jsonErrorResponseWithErrCode(w, http.StatusBadRequest, ErrCodeTunnelExist, "Tunnel already exist.")
 		return
 	}
 
 	for _, t := range client.Tunnels {
-		if t.Remote.Remote() == remote.Remote() && t.EqualACL(remote.ACL) {
+		// SOCKS tunnels aren't bound to a remote port, each connection requests its own destination
+		if !remote.Socks && t.Remote.Remote() == remote.Remote() && t.Reverse == remote.Reverse && t.HTTPProxy == remote.HTTPProxy && t.EqualACL(remote.ACL) && t.ACLSessionUser == remote.ACLSessionUser {
 			al.jsonErrorResponseWithErrCode(w, http.StatusBadRequest, ErrCodeTunnelToPortExist, fmt.Sprintf("Tunnel to port %s already exist.", remote.RemotePort))
 			return
 		}
 	}
 
-	if checkPortStr := req.URL.Query().Get("check_port"); checkPortStr != "0" {
+	// UDP ports can't be checked without sending a datagram to the remote,
+	// remotes of reverse tunnels are connected from the server, SOCKS tunnels don't have a remote
+	if checkPortStr := req.URL.Query().Get("check_port"); checkPortStr != "0" && !remote.IsUDP() && !remote.Reverse && !remote.Socks {
 		if !al.checkRemotePort(w, *remote, client.Connection) {
 			return
 		}
@@ -580,33 +706,116 @@ func (al *APIListener) handlePutClientTunnel(w http.ResponseWriter, req *http.Re
 	client.Lock()
 	defer client.Unlock()
 
-	if remote.IsLocalSpecified() && !al.checkLocalPort(w, remote.LocalPort) {
+	pool, err := al.selectPortPool(req.Context(), client)
+	if err != nil {
+		al.jsonErrorResponse(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	// local ports of reverse tunnels are listened by clients
+	if remote.IsLocalSpecified() && !remote.Reverse && !al.checkLocalPort(w, remote.LocalPort, pool) {
 		return
 	}
 
-	tunnels, err := al.clientService.StartClientTunnels(client, []*chshare.Remote{remote})
+	remote.CreatedBy = api.GetUser(req.Context(), al.Logger)
+	tunnels, err := al.clientService.StartClientTunnels(client, []*chshare.Remote{remote}, pool)
 	if err != nil {
 		al.jsonErrorResponse(w, http.StatusConflict, fmt.Errorf("can't create tunnel: %s", err))
 		return
 	}
+	al.saveAuditLog(req, auditlog.ActionTunnelCreate, client.ID, auditlog.Params{
+		"tunnel_id":            tunnels[0].ID,
+		"local":                tunnels[0].Local(),
+		"remote":               tunnels[0].Remote.Remote(),
+		"protocol":             tunnels[0].Protocol,
+		"reverse":              tunnels[0].Reverse,
+		"http_proxy":           tunnels[0].HTTPProxy,
+		"scheme":               schemeStr,
+		"acl":                  aclStr,
+		"acl_session_user":     remote.ACLSessionUser,
+		"idle_timeout_minutes": idleTimeoutMinutes,
+		"max_bytes_per_sec":    remote.MaxBytesPerSec,
+		"max_connections":      remote.MaxConnections,
+		"expires_at":           remote.ExpiresAt,
+		"persistent":           remote.Persistent,
+	})
 	response := api.NewSuccessPayload(tunnels[0])
 	al.writeJSONResponse(w, http.StatusOK, response)
 }
 
-func (al *APIListener) checkLocalPort(w http.ResponseWriter, localPort string) bool {
+// parseTunnelLimit returns a value of a given query param that limits a tunnel, zero means no limit.
+func (al *APIListener) parseTunnelLimit(w http.ResponseWriter, req *http.Request, param string, max int64) (int64, bool) {
+	str := req.URL.Query().Get(param)
+	if str == "" {
+		return 0, true
+	}
+	limit, err := strconv.ParseInt(str, 10, 64)
+	if err != nil {
+		al.jsonErrorResponseWithError(w, http.StatusBadRequest, ErrCodeInvalidTunnelLimit, fmt.Sprintf("invalid %q param", param), err)
+		return 0, false
+	}
+	if limit < 0 || limit > max {
+		al.jsonErrorResponseWithErrCode(w, http.StatusBadRequest, ErrCodeInvalidTunnelLimit, fmt.Sprintf("%q param should be in range [0,%d]", param, max))
+		return 0, false
+	}
+	return limit, true
+}
+
+// parseTunnelExpiry returns a time when a tunnel should be terminated or nil if it never expires. It's either given
+// as an absolute time or as a max lifetime starting now.
+func (al *APIListener) parseTunnelExpiry(w http.ResponseWriter, req *http.Request) (*time.Time, bool) {
+	expiresAtStr := req.URL.Query().Get(expiresAtQueryParam)
+	maxLifetimeStr := req.URL.Query().Get(maxLifetimeQueryParam)
+	if expiresAtStr != "" && maxLifetimeStr != "" {
+		al.jsonErrorResponseWithErrCode(w, http.StatusBadRequest, ErrCodeInvalidExpiry, fmt.Sprintf("%q and %q params can't be used together", expiresAtQueryParam, maxLifetimeQueryParam))
+		return nil, false
+	}
+
+	var expiresAt time.Time
+	switch {
+	case expiresAtStr != "":
+		var err error
+		expiresAt, err = time.Parse(time.RFC3339, expiresAtStr)
+		if err != nil {
+			al.jsonErrorResponseWithError(w, http.StatusBadRequest, ErrCodeInvalidExpiry, fmt.Sprintf("invalid %q param", expiresAtQueryParam), err)
+			return nil, false
+		}
+		if !expiresAt.After(time.Now()) {
+			al.jsonErrorResponseWithErrCode(w, http.StatusBadRequest, ErrCodeInvalidExpiry, fmt.Sprintf("%q param should be in the future", expiresAtQueryParam))
+			return nil, false
+		}
+	case maxLifetimeStr != "":
+		maxLifetime, err := time.ParseDuration(maxLifetimeStr)
+		if err != nil {
+			al.jsonErrorResponseWithError(w, http.StatusBadRequest, ErrCodeInvalidExpiry, fmt.Sprintf("invalid %q param", maxLifetimeQueryParam), err)
+			return nil, false
+		}
+		if maxLifetime <= 0 {
+			al.jsonErrorResponseWithErrCode(w, http.StatusBadRequest, ErrCodeInvalidExpiry, fmt.Sprintf("%q param should be positive", maxLifetimeQueryParam))
+			return nil, false
+		}
+		expiresAt = time.Now().Add(maxLifetime).Round(time.Second)
+	default:
+		return nil, true
+	}
+	return &expiresAt, true
+}
+
+// checkLocalPort writes an error response and returns false if a given local port can't be used for a tunnel
+// with random ports from a given port pool.
+func (al *APIListener) checkLocalPort(w http.ResponseWriter, localPort, pool string) bool {
 	lport, err := strconv.Atoi(localPort)
 	if err != nil {
 		al.jsonErrorResponseWithError(w, http.StatusBadRequest, "", fmt.Sprintf("Invalid port: %s.", localPort), err)
 		return false
 	}
 
-	busyPorts, err := ports.ListBusyPorts()
-	if err != nil {
-		al.jsonErrorResponse(w, http.StatusInternalServerError, err)
+	if reserved := al.clientService.PortPoolOf(lport); reserved != "" && reserved != pool {
+		al.jsonErrorResponseWithErrCode(w, http.StatusBadRequest, ErrCodeLocalPortReserved, fmt.Sprintf("Port %d is reserved for port pool %q.", lport, reserved))
 		return false
 	}
 
-	if busyPorts.Contains(lport) {
+	if !ports.IsPortFree(lport) {
 		al.jsonErrorResponseWithErrCode(w, http.StatusBadRequest, ErrCodeLocalPortInUse, fmt.Sprintf("Port %d already in use.", lport))
 		return false
 	}
@@ -644,6 +853,36 @@ func (al *APIListener) checkRemotePort(w http.ResponseWriter, remote chshare.Rem
 	return true
 }
 
+// handleGetClientTunnelConnections returns active connections of a tunnel, e.g. to see who is using it before deleting.
+func (al *APIListener) handleGetClientTunnelConnections(w http.ResponseWriter, req *http.Request) {
+	vars := mux.Vars(req)
+	clientID := vars[routeParamClientID]
+	tunnelID := vars[routeParamTunnelID]
+
+	client, err := al.clientService.GetActiveByID(clientID)
+	if err != nil {
+		al.jsonErrorResponse(w, http.StatusInternalServerError, err)
+		return
+	}
+	if client == nil {
+		al.jsonErrorResponseWithTitle(w, http.StatusNotFound, fmt.Sprintf("client with id %s not found", clientID))
+		return
+	}
+	if !al.checkClientAccess(w, req, client) {
+		return
+	}
+
+	client.Lock()
+	tunnel := client.FindTunnel(tunnelID)
+	client.Unlock()
+	if tunnel == nil {
+		al.jsonErrorResponseWithTitle(w, http.StatusNotFound, "tunnel not found")
+		return
+	}
+
+	al.writeJSONResponse(w, http.StatusOK, api.NewSuccessPayload(tunnel.Connections()))
+}
+
 func (al *APIListener) handleDeleteClientTunnel(w http.ResponseWriter, req *http.Request) {
 	vars := mux.Vars(req)
 	clientID, exists := vars[routeParamClientID]
@@ -672,8 +911,11 @@ func (al *APIListener) handleDeleteClientTunnel(w http.ResponseWriter, req *http
 		al.jsonErrorResponseWithTitle(w, http.StatusNotFound, fmt.Sprintf("client with id %s not found", clientID))
 		return
 	}
+	if !al.checkClientAccess(w, req, client) {
+		return
+	}
 
-	tunnelID, exists := vars["tunnel_id"]
+	tunnelID, exists := vars[routeParamTunnelID]
 	if !exists || tunnelID == "" {
 		al.jsonErrorResponseWithTitle(w, http.StatusBadRequest, "tunnel id is missing")
 		return
@@ -694,6 +936,39 @@ func (al *APIListener) handleDeleteClientTunnel(w http.ResponseWriter, req *http
 		al.jsonErrorResponseWithTitle(w, http.StatusConflict, err.Error())
 		return
 	}
+	al.saveAuditLog(req, auditlog.ActionTunnelDelete, client.ID, auditlog.Params{
+		"tunnel_id": tunnelID,
+		"force":     force,
+	})
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleDeleteClientTunnelConflicts deletes persistent tunnels of a client that failed to be re-established, so they
+// are not retried anymore.
+func (al *APIListener) handleDeleteClientTunnelConflicts(w http.ResponseWriter, req *http.Request) {
+	clientID := mux.Vars(req)[routeParamClientID]
+	client, err := al.clientService.GetByID(clientID)
+	if err != nil {
+		al.jsonErrorResponse(w, http.StatusInternalServerError, err)
+		return
+	}
+	if client == nil {
+		al.jsonErrorResponseWithTitle(w, http.StatusNotFound, fmt.Sprintf("client with id %s not found", clientID))
+		return
+	}
+	if !al.checkClientAccess(w, req, client) {
+		return
+	}
+
+	client.Lock()
+	deleted := len(client.TunnelConflicts)
+	client.TunnelConflicts = nil
+	client.Unlock()
+
+	al.saveAuditLog(req, auditlog.ActionTunnelDelete, client.ID, auditlog.Params{
+		"tunnel_conflicts": deleted,
+	})
 
 	w.WriteHeader(http.StatusNoContent)
 }
@@ -741,18 +1016,32 @@ const (
 
 	ErrCodeClientAuthSingleClient = "ERR_CODE_CLIENT_AUTH_SINGLE"
 	ErrCodeClientAuthRO           = "ERR_CODE_CLIENT_AUTH_RO"
+	ErrCodeClientAuthDisabled     = "ERR_CODE_CLIENT_AUTH_DISABLED"
 
 	ErrCodeClientAuthHasClient = "ERR_CODE_CLIENT_AUTH_HAS_CLIENT"
 	ErrCodeClientAuthNotFound  = "ERR_CODE_CLIENT_AUTH_NOT_FOUND"
 )
 
 func (al *APIListener) handleGetClientsAuth(w http.ResponseWriter, req *http.Request) {
-	rClients, err := al.clientAuthProvider.GetAll()
+	tenant, err := al.getTenant(req.Context())
 	if err != nil {
 		al.jsonErrorResponse(w, http.StatusInternalServerError, err)
 		return
 	}
 
+	all, err := al.clientAuthProvider.GetAll()
+	if err != nil {
+		al.jsonErrorResponse(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	rClients := make([]*clientsauth.ClientAuth, 0, len(all))
+	for _, cur := range all {
+		if matchesTenant(tenant, cur.Tenant) {
+			rClients = append(rClients, cur)
+		}
+	}
+
 	clientsauth.SortByID(rClients, false)
 
 	al.writeJSONResponse(w, http.StatusOK, api.NewSuccessPayload(rClients))
@@ -783,6 +1072,17 @@ func (al *APIListener) handlePostClientsAuth(w http.ResponseWriter, req *http.Re
 		return
 	}
 
+	tenant, err := al.getTenant(req.Context())
+	if err != nil {
+		al.jsonErrorResponse(w, http.StatusInternalServerError, err)
+		return
+	}
+	if tenant != nil && *tenant == "" {
+		al.jsonErrorResponseWithErrCode(w, http.StatusForbidden, ErrCodeInsufficientPermissions, "User without a tenant can't create Client Auth.")
+		return
+	}
+	newClient.Tenant = tenantOf(tenant)
+
 	added, err := al.clientAuthProvider.Add(&newClient)
 	if err != nil {
 		al.jsonErrorResponse(w, http.StatusInternalServerError, err)
@@ -794,6 +1094,7 @@ func (al *APIListener) handlePostClientsAuth(w http.ResponseWriter, req *http.Re
 	}
 
 	al.Infof("ClientAuth %q created.", newClient.ID)
+	al.saveAuditLog(req, auditlog.ActionClientAuthCreate, "", auditlog.Params{"client_auth_id": newClient.ID})
 
 	w.WriteHeader(http.StatusCreated)
 }
@@ -821,12 +1122,18 @@ func (al *APIListener) handleDeleteClientAuth(w http.ResponseWriter, req *http.R
 		}
 	}
 
+	tenant, err := al.getTenant(req.Context())
+	if err != nil {
+		al.jsonErrorResponse(w, http.StatusInternalServerError, err)
+		return
+	}
+
 	existing, err := al.clientAuthProvider.Get(clientAuthID)
 	if err != nil {
 		al.jsonErrorResponse(w, http.StatusInternalServerError, err)
 		return
 	}
-	if existing == nil {
+	if existing == nil || !matchesTenant(tenant, existing.Tenant) {
 		al.jsonErrorResponseWithErrCode(w, http.StatusNotFound, ErrCodeClientAuthNotFound, fmt.Sprintf("Client Auth with ID=%q not found.", clientAuthID))
 		return
 	}
@@ -850,6 +1157,10 @@ func (al *APIListener) handleDeleteClientAuth(w http.ResponseWriter, req *http.R
 		return
 	}
 	al.Infof("ClientAuth %q deleted.", clientAuthID)
+	al.saveAuditLog(req, auditlog.ActionClientAuthDelete, "", auditlog.Params{
+		"client_auth_id": clientAuthID,
+		"force":          force,
+	})
 
 	w.WriteHeader(http.StatusNoContent)
 }
@@ -873,6 +1184,11 @@ func (al *APIListener) isClientsAuthWriteable() bool {
 }
 
 func (al *APIListener) allowClientAuthWrite(w http.ResponseWriter) bool {
+	if al.clientAuthProvider.Source() == clientsauth.ProviderSourceNone {
+		al.jsonErrorResponseWithErrCode(w, http.StatusMethodNotAllowed, ErrCodeClientAuthDisabled, "Password authentication of clients is disabled.")
+		return false
+	}
+
 	if !al.clientAuthProvider.IsWriteable() {
 		al.jsonErrorResponseWithErrCode(w, http.StatusMethodNotAllowed, ErrCodeClientAuthSingleClient, "Client authentication is enabled only for a single user.")
 		return false
@@ -895,9 +1211,11 @@ func (al *APIListener) handlePostCommand(w http.ResponseWriter, req *http.Reques
 	}
 
 	reqBody := struct {
-		Command    string `json:"command"`
-		Shell      string `json:"shell"`
-		TimeoutSec int    `json:"timeout_sec"`
+		Command     string `json:"command"`
+		Shell       string `json:"shell"`
+		Script      string `json:"script"`
+		Interpreter string `json:"interpreter"`
+		TimeoutSec  int    `json:"timeout_sec"`
 	}{}
 	dec := json.NewDecoder(req.Body)
 	dec.DisallowUnknownFields()
@@ -909,12 +1227,8 @@ func (al *APIListener) handlePostCommand(w http.ResponseWriter, req *http.Reques
 		al.jsonErrorResponseWithError(w, http.StatusBadRequest, "", "Invalid JSON data.", err)
 		return
 	}
-	if reqBody.Command == "" {
-		al.jsonErrorResponseWithTitle(w, http.StatusBadRequest, "Command cannot be empty.")
-		return
-	}
-	if err := validateShell(reqBody.Shell); err != nil {
-		al.jsonErrorResponseWithError(w, http.StatusBadRequest, "", "Invalid shell.", err)
+	if title, err := validateCmd(reqBody.Command, reqBody.Shell, reqBody.Script, reqBody.Interpreter); title != "" {
+		al.jsonErrorResponseWithError(w, http.StatusBadRequest, "", title, err)
 		return
 	}
 
@@ -931,6 +1245,9 @@ func (al *APIListener) handlePostCommand(w http.ResponseWriter, req *http.Reques
 		al.jsonErrorResponseWithTitle(w, http.StatusNotFound, fmt.Sprintf("Active client with id=%q not found.", cid))
 		return
 	}
+	if !al.checkClientAccess(w, req, client) {
+		return
+	}
 
 	// send the command to the client
 	// Send a job with all possible info in order to get the full-populated job back (in client-listener) when it's done.
@@ -940,13 +1257,15 @@ func (al *APIListener) handlePostCommand(w http.ResponseWriter, req *http.Reques
 			JID:        generateNewJobID(),
 			FinishedAt: nil,
 		},
-		ClientID:   cid,
-		ClientName: client.Name,
-		Command:    reqBody.Command,
-		Shell:      reqBody.Shell,
-		CreatedBy:  api.GetUser(req.Context(), al.Logger),
-		TimeoutSec: reqBody.TimeoutSec,
-		Result:     nil,
+		ClientID:    cid,
+		ClientName:  client.Name,
+		Command:     reqBody.Command,
+		Shell:       reqBody.Shell,
+		Interpreter: reqBody.Interpreter,
+		Script:      reqBody.Script,
+		CreatedBy:   api.GetUser(req.Context(), al.Logger),
+		TimeoutSec:  reqBody.TimeoutSec,
+		Result:      nil,
 	}
 	sshResp := &comm.RunCmdResponse{}
 	err = comm.SendRequestAndGetResponse(client.Connection, comm.RequestTypeRunCmd, curJob, sshResp)
@@ -960,9 +1279,7 @@ func (al *APIListener) handlePostCommand(w http.ResponseWriter, req *http.Reques
 	}
 
 	// set fields received in response
-	curJob.PID = &sshResp.Pid
-	curJob.StartedAt = sshResp.StartedAt
-	curJob.Status = models.JobStatusRunning
+	setRunCmdResponse(&curJob, sshResp)
 
 	if err := al.jobProvider.CreateJob(&curJob); err != nil {
 		al.jsonErrorResponseWithError(w, http.StatusInternalServerError, "", "Failed to persist a new job.", err)
@@ -977,6 +1294,68 @@ func (al *APIListener) handlePostCommand(w http.ResponseWriter, req *http.Reques
 	al.writeJSONResponse(w, http.StatusOK, api.NewSuccessPayload(resp))
 
 	al.Debugf("Job[id=%q] created to execute remote command on client with id=%q: %q.", curJob.JID, cid, reqBody.Command)
+	al.saveAuditLog(req, auditlog.ActionCommandRun, cid, auditlog.Params{
+		"jid":         curJob.JID,
+		"command":     curJob.Command,
+		"shell":       curJob.Shell,
+		"interpreter": curJob.Interpreter,
+		"script":      curJob.Script,
+		"timeout_sec": curJob.TimeoutSec,
+	})
+}
+
+// setRunCmdResponse sets job fields received in a response to a run cmd request.
+func setRunCmdResponse(job *models.Job, resp *comm.RunCmdResponse) {
+	if resp.Queued {
+		// PID and start time are sent by the client when the command is started
+		job.Status = models.JobStatusQueued
+		job.StartedAt = time.Now()
+		return
+	}
+	job.PID = &resp.Pid
+	job.StartedAt = resp.StartedAt // override with the start time of the command
+	job.Status = models.JobStatusRunning
+}
+
+// validateCmd validates a command or a script requested to execute. If it's invalid an error title and optionally
+// an error with details are returned. Otherwise the returned title is empty.
+func validateCmd(command, shell, script, interpreter string) (string, error) {
+	if command == "" && script == "" {
+		return "Command cannot be empty.", nil
+	}
+	if command != "" && script != "" {
+		return "Either command or script should be set, not both.", nil
+	}
+
+	if script == "" {
+		if interpreter != "" {
+			return "Interpreter can be set only for a script.", nil
+		}
+		if err := validateShell(shell); err != nil {
+			return "Invalid shell.", err
+		}
+		return "", nil
+	}
+
+	if shell != "" {
+		return "Shell can be set only for a command, use interpreter for a script.", nil
+	}
+	if err := validateInterpreter(interpreter); err != nil {
+		return "Invalid interpreter.", err
+	}
+	return "", nil
+}
+
+func validateInterpreter(interpreter string) error {
+	if interpreter == "" {
+		return nil
+	}
+	for _, v := range validInputInterpreter {
+		if interpreter == v {
+			return nil
+		}
+	}
+	return fmt.Errorf("expected interpreter to be one of: %s, actual: %s", validInputInterpreter, interpreter)
 }
 
 func validateShell(shell string) error {
@@ -998,6 +1377,9 @@ func (al *APIListener) handleGetCommands(w http.ResponseWriter, req *http.Reques
 		al.jsonErrorResponseWithTitle(w, http.StatusBadRequest, fmt.Sprintf("Missing %q route param.", routeParamClientID))
 		return
 	}
+	if !al.checkClientIDAccess(w, req, cid) {
+		return
+	}
 
 	res, err := al.jobProvider.GetSummariesByClientID(cid)
 	if err != nil {
@@ -1021,6 +1403,9 @@ func (al *APIListener) handleGetCommand(w http.ResponseWriter, req *http.Request
 		al.jsonErrorResponseWithTitle(w, http.StatusBadRequest, fmt.Sprintf("Missing %q route param.", routeParamJobID))
 		return
 	}
+	if !al.checkClientIDAccess(w, req, cid) {
+		return
+	}
 
 	job, err := al.jobProvider.GetByJID(cid, jid)
 	if err != nil {
@@ -1035,6 +1420,78 @@ func (al *APIListener) handleGetCommand(w http.ResponseWriter, req *http.Request
 	al.writeJSONResponse(w, http.StatusOK, api.NewSuccessPayload(job))
 }
 
+func (al *APIListener) handleDeleteCommand(w http.ResponseWriter, req *http.Request) {
+	vars := mux.Vars(req)
+	cid := vars[routeParamClientID]
+	if cid == "" {
+		al.jsonErrorResponseWithTitle(w, http.StatusBadRequest, fmt.Sprintf("Missing %q route param.", routeParamClientID))
+		return
+	}
+	jid := vars[routeParamJobID]
+	if jid == "" {
+		al.jsonErrorResponseWithTitle(w, http.StatusBadRequest, fmt.Sprintf("Missing %q route param.", routeParamJobID))
+		return
+	}
+	if !al.checkClientIDAccess(w, req, cid) {
+		return
+	}
+
+	job, err := al.jobProvider.GetByJID(cid, jid)
+	if err != nil {
+		al.jsonErrorResponseWithError(w, http.StatusInternalServerError, "", fmt.Sprintf("Failed to find a job[id=%q].", jid), err)
+		return
+	}
+	if job == nil {
+		al.jsonErrorResponseWithTitle(w, http.StatusNotFound, fmt.Sprintf("Job[id=%q] not found.", jid))
+		return
+	}
+	if !isJobCancellable(job) {
+		al.jsonErrorResponseWithTitle(w, http.StatusConflict, fmt.Sprintf("Job[id=%q] is not running.", jid))
+		return
+	}
+
+	client, err := al.clientService.GetActiveByID(cid)
+	if err != nil {
+		al.jsonErrorResponseWithError(w, http.StatusInternalServerError, "", fmt.Sprintf("Failed to find an active client with id=%q.", cid), err)
+		return
+	}
+	if client == nil {
+		al.jsonErrorResponseWithTitle(w, http.StatusNotFound, fmt.Sprintf("Active client with id=%q not found.", cid))
+		return
+	}
+
+	err = sendCancelCmd(client, jid)
+	if err != nil {
+		if _, ok := err.(*comm.ClientError); ok {
+			al.jsonErrorResponseWithTitle(w, http.StatusConflict, err.Error())
+		} else {
+			al.jsonErrorResponseWithError(w, http.StatusInternalServerError, "", "Failed to cancel remote command.", err)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+
+	al.Debugf("Job[id=%q] on client with id=%q cancelled.", jid, cid)
+	al.saveAuditLog(req, auditlog.ActionCommandCancel, cid, auditlog.Params{"jid": jid})
+}
+
+// isJobCancellable returns true if a command of a given job might still be queued or running on the client. A command
+// that exceeded its timeout has the unknown status but the client keeps tracking it until it's finished.
+func isJobCancellable(job *models.Job) bool {
+	switch job.Status {
+	case models.JobStatusRunning, models.JobStatusQueued, models.JobStatusUnknown:
+		return true
+	}
+	return false
+}
+
+// sendCancelCmd requests a given client to kill a running command of a given job or to drop a queued one.
+// The job is saved with the cancelled status when the client sends back its result.
+func sendCancelCmd(client *clients.Client, jid string) error {
+	return comm.SendRequestAndGetResponse(client.Connection, comm.RequestTypeCancelCmd, comm.CancelCmdRequest{JID: jid}, &comm.CancelCmdResponse{})
+}
+
 type newJobResponse struct {
 	JID string `json:"jid"`
 }
@@ -1044,6 +1501,8 @@ type multiClientCmdRequest struct {
 	GroupIDs            []string `json:"group_ids"`
 	Command             string   `json:"command"`
 	Shell               string   `json:"shell"`
+	Script              string   `json:"script"`
+	Interpreter         string   `json:"interpreter"`
 	TimeoutSec          int      `json:"timeout_sec"`
 	ExecuteConcurrently bool     `json:"execute_concurrently"`
 	AbortOnError        *bool    `json:"abort_on_error"` // pointer is used because it's default value is true. Otherwise it would be more difficult to check whether this field is missing or not
@@ -1063,12 +1522,8 @@ func (al *APIListener) handlePostMultiClientCommand(w http.ResponseWriter, req *
 		al.jsonErrorResponseWithError(w, http.StatusBadRequest, "", "Invalid JSON data.", err)
 		return
 	}
-	if reqBody.Command == "" {
-		al.jsonErrorResponseWithTitle(w, http.StatusBadRequest, "Command cannot be empty.")
-		return
-	}
-	if err := validateShell(reqBody.Shell); err != nil {
-		al.jsonErrorResponseWithError(w, http.StatusBadRequest, "", "Invalid shell.", err)
+	if title, err := validateCmd(reqBody.Command, reqBody.Shell, reqBody.Script, reqBody.Interpreter); title != "" {
+		al.jsonErrorResponseWithError(w, http.StatusBadRequest, "", title, err)
 		return
 	}
 
@@ -1076,6 +1531,12 @@ func (al *APIListener) handlePostMultiClientCommand(w http.ResponseWriter, req *
 		reqBody.TimeoutSec = al.config.Server.RunRemoteCmdTimeoutSec
 	}
 
+	access, err := al.getClientAccess(ctx)
+	if err != nil {
+		al.jsonErrorResponse(w, http.StatusInternalServerError, err)
+		return
+	}
+
 	var groups []*cgroups.ClientGroup
 	for _, groupID := range reqBody.GroupIDs {
 		group, err := al.clientGroupProvider.Get(ctx, groupID)
@@ -1083,7 +1544,7 @@ func (al *APIListener) handlePostMultiClientCommand(w http.ResponseWriter, req *
 			al.jsonErrorResponseWithError(w, http.StatusInternalServerError, "", fmt.Sprintf("Failed to get a client group with id=%q.", groupID), err)
 			return
 		}
-		if group == nil {
+		if group == nil || !matchesTenant(access.tenant, group.Tenant) {
 			al.jsonErrorResponseWithTitle(w, http.StatusBadRequest, fmt.Sprintf("Unknown group with id=%q.", groupID))
 			return
 		}
@@ -1110,7 +1571,7 @@ func (al *APIListener) handlePostMultiClientCommand(w http.ResponseWriter, req *
 			al.jsonErrorResponseWithError(w, http.StatusInternalServerError, "", fmt.Sprintf("Failed to find a client with id=%q.", cid), err)
 			return
 		}
-		if client == nil {
+		if client == nil || !access.IsVisible(client) {
 			al.jsonErrorResponseWithTitle(w, http.StatusNotFound, fmt.Sprintf("Client with id=%q not found.", cid))
 			return
 		}
@@ -1130,6 +1591,13 @@ func (al *APIListener) handlePostMultiClientCommand(w http.ResponseWriter, req *
 		}
 	}
 
+	for _, client := range orderedClients {
+		if !access.IsAllowed(client) {
+			al.jsonErrorResponseWithErrCode(w, http.StatusForbidden, ErrCodeClientAccessDenied, fmt.Sprintf("Access to client with id=%q is denied.", client.ID))
+			return
+		}
+	}
+
 	// by default abortOnErr is true
 	abortOnErr := true
 	if reqBody.AbortOnError != nil {
@@ -1141,14 +1609,17 @@ func (al *APIListener) handlePostMultiClientCommand(w http.ResponseWriter, req *
 			JID:       generateNewJobID(),
 			StartedAt: time.Now(),
 			CreatedBy: api.GetUser(req.Context(), al.Logger),
+			Tenant:    tenantOf(access.tenant),
 		},
-		ClientIDs:  reqBody.ClientIDs,
-		GroupIDs:   reqBody.GroupIDs,
-		Command:    reqBody.Command,
-		Shell:      reqBody.Shell,
-		TimeoutSec: reqBody.TimeoutSec,
-		Concurrent: reqBody.ExecuteConcurrently,
-		AbortOnErr: abortOnErr,
+		ClientIDs:   reqBody.ClientIDs,
+		GroupIDs:    reqBody.GroupIDs,
+		Command:     reqBody.Command,
+		Shell:       reqBody.Shell,
+		Interpreter: reqBody.Interpreter,
+		Script:      reqBody.Script,
+		TimeoutSec:  reqBody.TimeoutSec,
+		Concurrent:  reqBody.ExecuteConcurrently,
+		AbortOnErr:  abortOnErr,
 	}
 	if err := al.jobProvider.SaveMultiJob(multiJob); err != nil {
 		al.jsonErrorResponseWithError(w, http.StatusInternalServerError, "", "Failed to persist a new multi-client job.", err)
@@ -1161,10 +1632,26 @@ func (al *APIListener) handlePostMultiClientCommand(w http.ResponseWriter, req *
 	al.writeJSONResponse(w, http.StatusOK, api.NewSuccessPayload(resp))
 
 	al.Debugf("Multi-client Job[id=%q] created to execute remote command on clients %s, groups %s: %q.", multiJob.JID, reqBody.ClientIDs, reqBody.GroupIDs, reqBody.Command)
+	al.saveAuditLog(req, auditlog.ActionMultiCommandRun, "", multiJobAuditLogParams(multiJob))
 
 	go al.executeMultiClientJob(multiJob, orderedClients)
 }
 
+func multiJobAuditLogParams(job *models.MultiJob) auditlog.Params {
+	return auditlog.Params{
+		"jid":          job.JID,
+		"client_ids":   job.ClientIDs,
+		"group_ids":    job.GroupIDs,
+		"command":      job.Command,
+		"shell":        job.Shell,
+		"interpreter":  job.Interpreter,
+		"script":       job.Script,
+		"timeout_sec":  job.TimeoutSec,
+		"concurrent":   job.Concurrent,
+		"abort_on_err": job.AbortOnErr,
+	}
+}
+
 func (al *APIListener) executeMultiClientJob(job *models.MultiJob, orderedClients []*clients.Client) {
 	// for sequential execution - create a channel to get the job result
 	var curJobDoneChannel chan *models.Job
@@ -1178,9 +1665,9 @@ func (al *APIListener) executeMultiClientJob(job *models.MultiJob, orderedClient
 	}
 	for _, client := range orderedClients {
 		if job.Concurrent {
-			go al.createAndRunJob(job.JID, job.Command, job.Shell, job.CreatedBy, job.TimeoutSec, client)
+			go al.createAndRunJob(job.JID, job.Command, job.Shell, job.Script, job.Interpreter, job.CreatedBy, job.TimeoutSec, client)
 		} else {
-			success := al.createAndRunJob(job.JID, job.Command, job.Shell, job.CreatedBy, job.TimeoutSec, client)
+			success := al.createAndRunJob(job.JID, job.Command, job.Shell, job.Script, job.Interpreter, job.CreatedBy, job.TimeoutSec, client)
 			if !success {
 				if job.AbortOnErr {
 					break
@@ -1195,6 +1682,9 @@ func (al *APIListener) executeMultiClientJob(job *models.MultiJob, orderedClient
 
 			// wait until command is finished
 			jobResult := <-curJobDoneChannel
+			if jobResult.Status == models.JobStatusCancelled {
+				break
+			}
 			if job.AbortOnErr && jobResult.Status == models.JobStatusFailed {
 				break
 			}
@@ -1205,20 +1695,22 @@ func (al *APIListener) executeMultiClientJob(job *models.MultiJob, orderedClient
 	}
 }
 
-func (al *APIListener) createAndRunJob(jid, cmd, shell, createdBy string, timeoutSec int, client *clients.Client) bool {
+func (al *APIListener) createAndRunJob(jid, cmd, shell, script, interpreter, createdBy string, timeoutSec int, client *clients.Client) bool {
 	// send the command to the client
 	curJob := models.Job{
 		JobSummary: models.JobSummary{
 			JID: generateNewJobID(),
 		},
-		StartedAt:  time.Now(),
-		ClientID:   client.ID,
-		ClientName: client.Name,
-		Command:    cmd,
-		Shell:      shell,
-		CreatedBy:  createdBy,
-		TimeoutSec: timeoutSec,
-		MultiJobID: &jid,
+		StartedAt:   time.Now(),
+		ClientID:    client.ID,
+		ClientName:  client.Name,
+		Command:     cmd,
+		Shell:       shell,
+		Interpreter: interpreter,
+		Script:      script,
+		CreatedBy:   createdBy,
+		TimeoutSec:  timeoutSec,
+		MultiJobID:  &jid,
 	}
 	sshResp := &comm.RunCmdResponse{}
 	err := comm.SendRequestAndGetResponse(client.Connection, comm.RequestTypeRunCmd, curJob, sshResp)
@@ -1232,9 +1724,7 @@ func (al *APIListener) createAndRunJob(jid, cmd, shell, createdBy string, timeou
 		curJob.Error = err.Error()
 	} else {
 		// success, set fields received in response
-		curJob.PID = &sshResp.Pid
-		curJob.StartedAt = sshResp.StartedAt // override with the start time of the command
-		curJob.Status = models.JobStatusRunning
+		setRunCmdResponse(&curJob, sshResp)
 	}
 
 	if dbErr := al.jobProvider.CreateJob(&curJob); dbErr != nil {
@@ -1263,12 +1753,8 @@ func (al *APIListener) handleCommandsWS(w http.ResponseWriter, req *http.Request
 		return
 	}
 
-	if inboundMsg.Command == "" {
-		uiConnTS.WriteError("Command cannot be empty.", nil)
-		return
-	}
-	if err := validateShell(inboundMsg.Shell); err != nil {
-		uiConnTS.WriteError("Invalid shell.", err)
+	if title, err := validateCmd(inboundMsg.Command, inboundMsg.Shell, inboundMsg.Script, inboundMsg.Interpreter); title != "" {
+		uiConnTS.WriteError(title, err)
 		return
 	}
 
@@ -1276,6 +1762,12 @@ func (al *APIListener) handleCommandsWS(w http.ResponseWriter, req *http.Request
 		inboundMsg.TimeoutSec = al.config.Server.RunRemoteCmdTimeoutSec
 	}
 
+	access, err := al.getClientAccess(ctx)
+	if err != nil {
+		uiConnTS.WriteError("Failed to check access to clients.", err)
+		return
+	}
+
 	var groups []*cgroups.ClientGroup
 	for _, groupID := range inboundMsg.GroupIDs {
 		group, err := al.clientGroupProvider.Get(ctx, groupID)
@@ -1283,7 +1775,7 @@ func (al *APIListener) handleCommandsWS(w http.ResponseWriter, req *http.Request
 			uiConnTS.WriteError(fmt.Sprintf("Failed to get a client group with id=%q.", groupID), err)
 			return
 		}
-		if group == nil {
+		if group == nil || !matchesTenant(access.tenant, group.Tenant) {
 			uiConnTS.WriteError(fmt.Sprintf("Unknown group with id=%q.", groupID), nil)
 			return
 		}
@@ -1309,7 +1801,7 @@ func (al *APIListener) handleCommandsWS(w http.ResponseWriter, req *http.Request
 			uiConnTS.WriteError(fmt.Sprintf("Failed to find a client with id=%q.", cid), err)
 			return
 		}
-		if client == nil {
+		if client == nil || !access.IsVisible(client) {
 			uiConnTS.WriteError(fmt.Sprintf("Client with id=%q not found.", cid), nil)
 			return
 		}
@@ -1329,6 +1821,13 @@ func (al *APIListener) handleCommandsWS(w http.ResponseWriter, req *http.Request
 		}
 	}
 
+	for _, client := range orderedClients {
+		if !access.IsAllowed(client) {
+			uiConnTS.WriteError(fmt.Sprintf("Access to client with id=%q is denied.", client.ID), nil)
+			return
+		}
+	}
+
 	jid := generateNewJobID()
 	al.Server.uiJobWebSockets.Set(jid, uiConnTS)
 	defer al.Server.uiJobWebSockets.Delete(jid)
@@ -1346,14 +1845,17 @@ func (al *APIListener) handleCommandsWS(w http.ResponseWriter, req *http.Request
 				JID:       jid,
 				StartedAt: time.Now(),
 				CreatedBy: createdBy,
+				Tenant:    tenantOf(access.tenant),
 			},
-			ClientIDs:  inboundMsg.ClientIDs,
-			GroupIDs:   inboundMsg.GroupIDs,
-			Command:    inboundMsg.Command,
-			Shell:      inboundMsg.Shell,
-			TimeoutSec: inboundMsg.TimeoutSec,
-			Concurrent: inboundMsg.ExecuteConcurrently,
-			AbortOnErr: abortOnErr,
+			ClientIDs:   inboundMsg.ClientIDs,
+			GroupIDs:    inboundMsg.GroupIDs,
+			Command:     inboundMsg.Command,
+			Shell:       inboundMsg.Shell,
+			Interpreter: inboundMsg.Interpreter,
+			Script:      inboundMsg.Script,
+			TimeoutSec:  inboundMsg.TimeoutSec,
+			Concurrent:  inboundMsg.ExecuteConcurrently,
+			AbortOnErr:  abortOnErr,
 		}
 		if err := al.jobProvider.SaveMultiJob(multiJob); err != nil {
 			uiConnTS.WriteError("Failed to persist a new multi-client job.", err)
@@ -1361,6 +1863,7 @@ func (al *APIListener) handleCommandsWS(w http.ResponseWriter, req *http.Request
 		}
 
 		al.Debugf("Multi-client Job[id=%q] created to execute remote command on clients %s, groups %s: %q.", multiJob.JID, inboundMsg.ClientIDs, inboundMsg.GroupIDs, inboundMsg.Command)
+		al.saveAuditLog(req, auditlog.ActionMultiCommandRun, "", multiJobAuditLogParams(multiJob))
 		uiConnTS.SetWritesBeforeClose(len(orderedClients))
 
 		// for sequential execution - create a channel to get the job result
@@ -1377,9 +1880,9 @@ func (al *APIListener) handleCommandsWS(w http.ResponseWriter, req *http.Request
 		for _, client := range orderedClients {
 			curJID := generateNewJobID()
 			if multiJob.Concurrent {
-				go al.createAndRunJobWS(uiConnTS, &jid, curJID, multiJob.Command, multiJob.Shell, createdBy, multiJob.TimeoutSec, client)
+				go al.createAndRunJobWS(uiConnTS, &jid, curJID, multiJob.Command, multiJob.Shell, multiJob.Script, multiJob.Interpreter, createdBy, multiJob.TimeoutSec, client)
 			} else {
-				success := al.createAndRunJobWS(uiConnTS, &jid, curJID, multiJob.Command, multiJob.Shell, createdBy, multiJob.TimeoutSec, client)
+				success := al.createAndRunJobWS(uiConnTS, &jid, curJID, multiJob.Command, multiJob.Shell, multiJob.Script, multiJob.Interpreter, createdBy, multiJob.TimeoutSec, client)
 				if !success {
 					if multiJob.AbortOnErr {
 						uiConnTS.Close()
@@ -1389,14 +1892,23 @@ func (al *APIListener) handleCommandsWS(w http.ResponseWriter, req *http.Request
 				}
 				// wait until command is finished
 				jobResult := <-curJobDoneChannel
-				if multiJob.AbortOnErr && jobResult.Status == models.JobStatusFailed {
+				if jobResult.Status == models.JobStatusCancelled || multiJob.AbortOnErr && jobResult.Status == models.JobStatusFailed {
 					uiConnTS.Close()
 					return
 				}
 			}
 		}
 	} else {
-		al.createAndRunJobWS(uiConnTS, nil, jid, inboundMsg.Command, inboundMsg.Shell, createdBy, inboundMsg.TimeoutSec, orderedClients[0])
+		if al.createAndRunJobWS(uiConnTS, nil, jid, inboundMsg.Command, inboundMsg.Shell, inboundMsg.Script, inboundMsg.Interpreter, createdBy, inboundMsg.TimeoutSec, orderedClients[0]) {
+			al.saveAuditLog(req, auditlog.ActionCommandRun, orderedClients[0].ID, auditlog.Params{
+				"jid":         jid,
+				"command":     inboundMsg.Command,
+				"shell":       inboundMsg.Shell,
+				"interpreter": inboundMsg.Interpreter,
+				"script":      inboundMsg.Script,
+				"timeout_sec": inboundMsg.TimeoutSec,
+			})
+		}
 	}
 
 	// check for Close message from client to close the connection
@@ -1414,19 +1926,21 @@ func (al *APIListener) handleCommandsWS(w http.ResponseWriter, req *http.Request
 	uiConnTS.Close()
 }
 
-func (al *APIListener) createAndRunJobWS(uiConnTS *ws.ConcurrentWebSocket, multiJobID *string, jid, cmd, shell, createdBy string, timeoutSec int, client *clients.Client) bool {
+func (al *APIListener) createAndRunJobWS(uiConnTS *ws.ConcurrentWebSocket, multiJobID *string, jid, cmd, shell, script, interpreter, createdBy string, timeoutSec int, client *clients.Client) bool {
 	curJob := models.Job{
 		JobSummary: models.JobSummary{
 			JID: jid,
 		},
-		StartedAt:  time.Now(),
-		ClientID:   client.ID,
-		ClientName: client.Name,
-		Command:    cmd,
-		Shell:      shell,
-		CreatedBy:  createdBy,
-		TimeoutSec: timeoutSec,
-		MultiJobID: multiJobID,
+		StartedAt:   time.Now(),
+		ClientID:    client.ID,
+		ClientName:  client.Name,
+		Command:     cmd,
+		Shell:       shell,
+		Interpreter: interpreter,
+		Script:      script,
+		CreatedBy:   createdBy,
+		TimeoutSec:  timeoutSec,
+		MultiJobID:  multiJobID,
 	}
 	logPrefix := curJob.LogPrefix()
 
@@ -1447,9 +1961,7 @@ func (al *APIListener) createAndRunJobWS(uiConnTS *ws.ConcurrentWebSocket, multi
 		al.Debugf("%s, Job was sent to execute remote command: %q.", logPrefix, curJob.Command)
 
 		// success, set fields received in response
-		curJob.PID = &sshResp.Pid
-		curJob.StartedAt = sshResp.StartedAt // override with the start time of the command
-		curJob.Status = models.JobStatusRunning
+		setRunCmdResponse(&curJob, sshResp)
 	}
 
 	// do not save the failed job if it's a single-client job
@@ -1473,12 +1985,18 @@ func (al *APIListener) handleGetMultiClientCommand(w http.ResponseWriter, req *h
 		return
 	}
 
+	tenant, err := al.getTenant(req.Context())
+	if err != nil {
+		al.jsonErrorResponse(w, http.StatusInternalServerError, err)
+		return
+	}
+
 	job, err := al.jobProvider.GetMultiJob(jid)
 	if err != nil {
 		al.jsonErrorResponseWithError(w, http.StatusInternalServerError, "", fmt.Sprintf("Failed to find a multi-client job[id=%q].", jid), err)
 		return
 	}
-	if job == nil {
+	if job == nil || !matchesTenant(tenant, job.Tenant) {
 		al.jsonErrorResponseWithTitle(w, http.StatusNotFound, fmt.Sprintf("Multi-client Job[id=%q] not found.", jid))
 		return
 	}
@@ -1486,13 +2004,104 @@ func (al *APIListener) handleGetMultiClientCommand(w http.ResponseWriter, req *h
 	al.writeJSONResponse(w, http.StatusOK, api.NewSuccessPayload(job))
 }
 
+func (al *APIListener) handleDeleteMultiClientCommand(w http.ResponseWriter, req *http.Request) {
+	vars := mux.Vars(req)
+	jid := vars[routeParamJobID]
+	if jid == "" {
+		al.jsonErrorResponseWithTitle(w, http.StatusBadRequest, fmt.Sprintf("Missing %q route param.", routeParamJobID))
+		return
+	}
+
+	access, err := al.getClientAccess(req.Context())
+	if err != nil {
+		al.jsonErrorResponse(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	multiJob, err := al.jobProvider.GetMultiJob(jid)
+	if err != nil {
+		al.jsonErrorResponseWithError(w, http.StatusInternalServerError, "", fmt.Sprintf("Failed to find a multi-client job[id=%q].", jid), err)
+		return
+	}
+	if multiJob == nil || !matchesTenant(access.tenant, multiJob.Tenant) {
+		al.jsonErrorResponseWithTitle(w, http.StatusNotFound, fmt.Sprintf("Multi-client Job[id=%q] not found.", jid))
+		return
+	}
+
+	var runningJobs []*models.Job
+	for _, job := range multiJob.Jobs {
+		if isJobCancellable(job) {
+			runningJobs = append(runningJobs, job)
+		}
+	}
+	if len(runningJobs) == 0 {
+		al.jsonErrorResponseWithTitle(w, http.StatusConflict, fmt.Sprintf("Multi-client Job[id=%q] is not running.", jid))
+		return
+	}
+
+	var runningClients []*clients.Client
+	for _, job := range runningJobs {
+		client, err := al.clientService.GetByID(job.ClientID)
+		if err != nil {
+			al.jsonErrorResponseWithError(w, http.StatusInternalServerError, "", fmt.Sprintf("Failed to find a client with id=%q.", job.ClientID), err)
+			return
+		}
+		if client != nil && !access.IsAllowed(client) {
+			al.jsonErrorResponseWithErrCode(w, http.StatusForbidden, ErrCodeClientAccessDenied, fmt.Sprintf("Access to client with id=%q is denied.", client.ID))
+			return
+		}
+		runningClients = append(runningClients, client)
+	}
+
+	var failed []string
+	for i, job := range runningJobs {
+		client := runningClients[i]
+		if client == nil || client.DisconnectedAt != nil {
+			al.Debugf("%s, client is not active, job can't be cancelled.", job.LogPrefix())
+			continue
+		}
+		err := sendCancelCmd(client, job.JID)
+		if err != nil {
+			if _, ok := err.(*comm.ClientError); ok {
+				// most likely the job has just finished
+				al.Debugf("%s, failed to cancel job: %v", job.LogPrefix(), err)
+				continue
+			}
+			al.Errorf("%s, failed to cancel job: %v", job.LogPrefix(), err)
+			failed = append(failed, job.ClientID)
+		}
+	}
+	if len(failed) > 0 {
+		al.jsonErrorResponseWithTitle(w, http.StatusInternalServerError, fmt.Sprintf("Failed to cancel remote command on clients: %s.", strings.Join(failed, ", ")))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+
+	al.Debugf("Multi-client Job[id=%q] cancelled.", jid)
+	al.saveAuditLog(req, auditlog.ActionMultiCommandCancel, "", auditlog.Params{"jid": jid})
+}
+
 func (al *APIListener) handleGetMultiClientCommands(w http.ResponseWriter, req *http.Request) {
-	res, err := al.jobProvider.GetAllMultiJobSummaries()
+	tenant, err := al.getTenant(req.Context())
+	if err != nil {
+		al.jsonErrorResponse(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	all, err := al.jobProvider.GetAllMultiJobSummaries()
 	if err != nil {
 		al.jsonErrorResponseWithError(w, http.StatusInternalServerError, "", "Failed to get multi-client jobs.", err)
 		return
 	}
 
+	res := make([]*models.MultiJobSummary, 0, len(all))
+	for _, cur := range all {
+		if matchesTenant(tenant, cur.Tenant) {
+			res = append(res, cur)
+		}
+	}
+
 	al.writeJSONResponse(w, http.StatusOK, api.NewSuccessPayload(res))
 }
 
@@ -1514,6 +2123,13 @@ func (al *APIListener) handlePostClientGroups(w http.ResponseWriter, req *http.R
 		return
 	}
 
+	tenant, err := al.getTenant(req.Context())
+	if err != nil {
+		al.jsonErrorResponse(w, http.StatusInternalServerError, err)
+		return
+	}
+	group.Tenant = tenantOf(tenant)
+
 	if err := al.clientGroupProvider.Create(req.Context(), &group); err != nil {
 		al.jsonErrorResponseWithError(w, http.StatusInternalServerError, "", "Failed to persist a new client group.", err)
 		return
@@ -1521,6 +2137,11 @@ func (al *APIListener) handlePostClientGroups(w http.ResponseWriter, req *http.R
 
 	w.WriteHeader(http.StatusCreated)
 	al.Debugf("Client Group [id=%q] created.", group.ID)
+	al.saveAuditLog(req, auditlog.ActionClientGroupCreate, "", auditlog.Params{
+		"group_id":    group.ID,
+		"description": group.Description,
+		"params":      group.Params,
+	})
 }
 
 func (al *APIListener) handlePutClientGroup(w http.ResponseWriter, req *http.Request) {
@@ -1553,6 +2174,24 @@ func (al *APIListener) handlePutClientGroup(w http.ResponseWriter, req *http.Req
 		return
 	}
 
+	tenant, err := al.getTenant(req.Context())
+	if err != nil {
+		al.jsonErrorResponse(w, http.StatusInternalServerError, err)
+		return
+	}
+	if tenant != nil {
+		existing, err := al.clientGroupProvider.Get(req.Context(), id)
+		if err != nil {
+			al.jsonErrorResponseWithError(w, http.StatusInternalServerError, "", fmt.Sprintf("Failed to find client group[id=%q].", id), err)
+			return
+		}
+		if existing != nil && !matchesTenant(tenant, existing.Tenant) {
+			al.jsonErrorResponseWithTitle(w, http.StatusNotFound, fmt.Sprintf("Client Group[id=%q] not found.", id))
+			return
+		}
+	}
+	group.Tenant = tenantOf(tenant)
+
 	if err := al.clientGroupProvider.Update(req.Context(), &group); err != nil {
 		al.jsonErrorResponseWithError(w, http.StatusInternalServerError, "", "Failed to persist client group.", err)
 		return
@@ -1560,6 +2199,11 @@ func (al *APIListener) handlePutClientGroup(w http.ResponseWriter, req *http.Req
 
 	w.WriteHeader(http.StatusNoContent)
 	al.Debugf("Client Group [id=%q] updated.", group.ID)
+	al.saveAuditLog(req, auditlog.ActionClientGroupUpdate, "", auditlog.Params{
+		"group_id":    group.ID,
+		"description": group.Description,
+		"params":      group.Params,
+	})
 }
 
 const groupIDMaxLength = 30
@@ -1588,12 +2232,18 @@ func (al *APIListener) handleGetClientGroup(w http.ResponseWriter, req *http.Req
 		return
 	}
 
+	tenant, err := al.getTenant(req.Context())
+	if err != nil {
+		al.jsonErrorResponse(w, http.StatusInternalServerError, err)
+		return
+	}
+
 	group, err := al.clientGroupProvider.Get(req.Context(), id)
 	if err != nil {
 		al.jsonErrorResponseWithError(w, http.StatusInternalServerError, "", fmt.Sprintf("Failed to find client group[id=%q].", id), err)
 		return
 	}
-	if group == nil {
+	if group == nil || !matchesTenant(tenant, group.Tenant) {
 		al.jsonErrorResponseWithTitle(w, http.StatusNotFound, fmt.Sprintf("Client Group[id=%q] not found.", id))
 		return
 	}
@@ -1603,12 +2253,25 @@ func (al *APIListener) handleGetClientGroup(w http.ResponseWriter, req *http.Req
 }
 
 func (al *APIListener) handleGetClientGroups(w http.ResponseWriter, req *http.Request) {
-	res, err := al.clientGroupProvider.GetAll(req.Context())
+	tenant, err := al.getTenant(req.Context())
+	if err != nil {
+		al.jsonErrorResponse(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	all, err := al.clientGroupProvider.GetAll(req.Context())
 	if err != nil {
 		al.jsonErrorResponseWithError(w, http.StatusInternalServerError, "", "Failed to get client groups.", err)
 		return
 	}
 
+	res := make([]*cgroups.ClientGroup, 0, len(all))
+	for _, cur := range all {
+		if matchesTenant(tenant, cur.Tenant) {
+			res = append(res, cur)
+		}
+	}
+
 	al.clientService.PopulateGroupsWithClients(res)
 	al.writeJSONResponse(w, http.StatusOK, api.NewSuccessPayload(res))
 }
@@ -1621,7 +2284,25 @@ func (al *APIListener) handleDeleteClientGroup(w http.ResponseWriter, req *http.
 		return
 	}
 
-	err := al.clientGroupProvider.Delete(req.Context(), id)
+	tenant, err := al.getTenant(req.Context())
+	if err != nil {
+		al.jsonErrorResponse(w, http.StatusInternalServerError, err)
+		return
+	}
+	if tenant != nil {
+		existing, err := al.clientGroupProvider.Get(req.Context(), id)
+		if err != nil {
+			al.jsonErrorResponseWithError(w, http.StatusInternalServerError, "", fmt.Sprintf("Failed to find client group[id=%q].", id), err)
+			return
+		}
+		// a group of another tenant is treated as non-existent
+		if existing != nil && !matchesTenant(tenant, existing.Tenant) {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+	}
+
+	err = al.clientGroupProvider.Delete(req.Context(), id)
 	if err != nil {
 		al.jsonErrorResponseWithError(w, http.StatusInternalServerError, "", fmt.Sprintf("Failed to delete client group[id=%q].", id), err)
 		return
@@ -1629,4 +2310,5 @@ func (al *APIListener) handleDeleteClientGroup(w http.ResponseWriter, req *http.
 
 	w.WriteHeader(http.StatusNoContent)
 	al.Debugf("Client Group [id=%q] deleted.", id)
+	al.saveAuditLog(req, auditlog.ActionClientGroupDelete, "", auditlog.Params{"group_id": id})
 }
@@ -90,6 +90,38 @@ func (p *SqliteProvider) CreateJob(job *models.Job) error {
 	return err
 }
 
+// AppendOutput appends a given chunk of output to a running job. Does nothing if the job doesn't exist or is already finished.
+func (p *SqliteProvider) AppendOutput(jid string, output *models.JobResult) error {
+	tx, err := p.db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	res := &jobSqlite{}
+	err = tx.Get(res, "SELECT * FROM jobs WHERE jid=?", jid)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil
+		}
+		return err
+	}
+	if res.Status != models.JobStatusRunning {
+		return nil
+	}
+
+	if res.Details.Result == nil {
+		res.Details.Result = &models.JobResult{}
+	}
+	res.Details.Result.StdOut += output.StdOut
+	res.Details.Result.StdErr += output.StdErr
+	_, err = tx.Exec("UPDATE jobs SET details=? WHERE jid=?", res.Details, jid)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
 func (p *SqliteProvider) Close() error {
 	return p.db.Close()
 }
@@ -110,13 +142,15 @@ type jobSummarySqlite struct {
 }
 
 type jobDetails struct {
-	Command    string            `json:"command"`
-	Shell      string            `json:"shell"`
-	PID        *int              `json:"pid"`
-	TimeoutSec int               `json:"timeout_sec"`
-	Error      string            `json:"error"`
-	Result     *models.JobResult `json:"result"`
-	ClientName string            `json:"client_name"`
+	Command     string            `json:"command"`
+	Shell       string            `json:"shell"`
+	Interpreter string            `json:"interpreter,omitempty"`
+	Script      string            `json:"script,omitempty"`
+	PID         *int              `json:"pid"`
+	TimeoutSec  int               `json:"timeout_sec"`
+	Error       string            `json:"error"`
+	Result      *models.JobResult `json:"result"`
+	ClientName  string            `json:"client_name"`
 }
 
 func (d *jobDetails) Scan(value interface{}) error {
@@ -167,17 +201,19 @@ func convertJSs(list []*jobSummarySqlite) []*models.JobSummary {
 func (j *jobSqlite) convert() *models.Job {
 	js := j.jobSummarySqlite.convert()
 	res := &models.Job{
-		JobSummary: *js,
-		ClientID:   j.ClientID,
-		ClientName: j.Details.ClientName,
-		StartedAt:  j.StartedAt,
-		CreatedBy:  j.CreatedBy,
-		Command:    j.Details.Command,
-		Shell:      j.Details.Shell,
-		PID:        j.Details.PID,
-		TimeoutSec: j.Details.TimeoutSec,
-		Result:     j.Details.Result,
-		Error:      j.Details.Error,
+		JobSummary:  *js,
+		ClientID:    j.ClientID,
+		ClientName:  j.Details.ClientName,
+		StartedAt:   j.StartedAt,
+		CreatedBy:   j.CreatedBy,
+		Command:     j.Details.Command,
+		Shell:       j.Details.Shell,
+		Interpreter: j.Details.Interpreter,
+		Script:      j.Details.Script,
+		PID:         j.Details.PID,
+		TimeoutSec:  j.Details.TimeoutSec,
+		Result:      j.Details.Result,
+		Error:       j.Details.Error,
 	}
 	if j.MultiJobID.Valid {
 		res.MultiJobID = &j.MultiJobID.String
@@ -203,13 +239,15 @@ func convertToSqlite(job *models.Job) *jobSqlite {
 		CreatedBy: job.CreatedBy,
 		ClientID:  job.ClientID,
 		Details: &jobDetails{
-			Command:    job.Command,
-			Shell:      job.Shell,
-			PID:        job.PID,
-			TimeoutSec: job.TimeoutSec,
-			Result:     job.Result,
-			Error:      job.Error,
-			ClientName: job.ClientName,
+			Command:     job.Command,
+			Shell:       job.Shell,
+			Interpreter: job.Interpreter,
+			Script:      job.Script,
+			PID:         job.PID,
+			TimeoutSec:  job.TimeoutSec,
+			Result:      job.Result,
+			Error:       job.Error,
+			ClientName:  job.ClientName,
 		},
 	}
 	if job.MultiJobID != nil {
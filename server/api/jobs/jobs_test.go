@@ -24,7 +24,7 @@ func TestJobsSqliteProvider(t *testing.T) {
 	// add jobs
 	job1 := jb.New(t).Status(models.JobStatusRunning).Result(nil).Build()
 	job2 := jb.New(t).ClientID(job1.ClientID).Build()
-	job3 := jb.New(t).Build() // different client ID
+	job3 := jb.New(t).Script("bash", "#!/bin/bash\necho test\n").Build() // different client ID
 	require.NoError(t, p.SaveJob(job1))
 	require.NoError(t, p.SaveJob(job2))
 	require.NoError(t, p.SaveJob(job3))
@@ -129,3 +129,29 @@ func TestCreateJob(t *testing.T) {
 	require.NoError(t, err)
 	require.Equal(t, job, gotJob)
 }
+
+func TestAppendOutput(t *testing.T) {
+	p, err := NewSqliteProvider(":memory:", testLog)
+	require.NoError(t, err)
+	defer p.Close()
+
+	runningJob := jb.New(t).Status(models.JobStatusRunning).Result(nil).Build()
+	finishedJob := jb.New(t).Status(models.JobStatusSuccessful).Build()
+	require.NoError(t, p.CreateJob(runningJob))
+	require.NoError(t, p.CreateJob(finishedJob))
+
+	// append output
+	require.NoError(t, p.AppendOutput(runningJob.JID, &models.JobResult{StdOut: "out1\n"}))
+	require.NoError(t, p.AppendOutput(runningJob.JID, &models.JobResult{StdOut: "out2\n", StdErr: "err1\n"}))
+	require.NoError(t, p.AppendOutput(finishedJob.JID, &models.JobResult{StdOut: "late output"}))
+	require.NoError(t, p.AppendOutput("unknown-jid", &models.JobResult{StdOut: "output"}))
+
+	// verify
+	gotJob, err := p.GetByJID(runningJob.ClientID, runningJob.JID)
+	require.NoError(t, err)
+	assert.Equal(t, &models.JobResult{StdOut: "out1\nout2\n", StdErr: "err1\n"}, gotJob.Result)
+
+	gotJob, err = p.GetByJID(finishedJob.ClientID, finishedJob.JID)
+	require.NoError(t, err)
+	assert.Equal(t, finishedJob, gotJob)
+}
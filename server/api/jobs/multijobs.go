@@ -36,7 +36,7 @@ func (p *SqliteProvider) GetMultiJob(jid string) (*models.MultiJob, error) {
 // GetAllMultiJobSummaries returns a list of summaries of all multi-clients jobs sorted by started_at(desc), jid order.
 func (p *SqliteProvider) GetAllMultiJobSummaries() ([]*models.MultiJobSummary, error) {
 	var res []*multiJobSummarySqlite
-	err := p.db.Select(&res, "SELECT jid, started_at, created_by FROM multi_jobs ORDER BY DATETIME(started_at) DESC, jid")
+	err := p.db.Select(&res, "SELECT jid, started_at, created_by, tenant FROM multi_jobs ORDER BY DATETIME(started_at) DESC, jid")
 	if err != nil {
 		return nil, err
 	}
@@ -45,8 +45,8 @@ func (p *SqliteProvider) GetAllMultiJobSummaries() ([]*models.MultiJobSummary, e
 
 // SaveMultiJob creates a new or updates an existing multi-client job (without child jobs).
 func (p *SqliteProvider) SaveMultiJob(job *models.MultiJob) error {
-	_, err := p.db.NamedExec(`INSERT OR REPLACE INTO multi_jobs (jid, started_at, created_by, details)
-															  VALUES (:jid, :started_at, :created_by, :details)`,
+	_, err := p.db.NamedExec(`INSERT OR REPLACE INTO multi_jobs (jid, started_at, created_by, tenant, details)
+															  VALUES (:jid, :started_at, :created_by, :tenant, :details)`,
 		convertMultiJobToSqlite(job))
 	if err == nil {
 		p.log.Debugf("Multi-client Job saved successfully: %v", *job)
@@ -63,16 +63,20 @@ type multiJobSummarySqlite struct {
 	JID       string    `db:"jid"`
 	StartedAt time.Time `db:"started_at"`
 	CreatedBy string    `db:"created_by"`
+	Tenant    string    `db:"tenant"`
 }
 
 type multiJobDetailSqlite struct {
-	ClientIDs  []string `json:"client_ids"`
-	GroupIDs   []string `json:"group_ids"`
-	Command    string   `json:"command"`
-	Shell      string   `json:"shell"`
-	TimeoutSec int      `json:"timeout_sec"`
-	Concurrent bool     `json:"concurrent"`
-	AbortOnErr bool     `json:"abort_on_err"`
+	ClientIDs   []string `json:"client_ids"`
+	GroupIDs    []string `json:"group_ids"`
+	Command     string   `json:"command"`
+	Shell       string   `json:"shell"`
+	Interpreter string   `json:"interpreter,omitempty"`
+	Script      string   `json:"script,omitempty"`
+	TimeoutSec  int      `json:"timeout_sec"`
+	Concurrent  bool     `json:"concurrent"`
+	AbortOnErr  bool     `json:"abort_on_err"`
+	ScheduleID  *string  `json:"schedule_id,omitempty"`
 }
 
 func (d *multiJobDetailSqlite) Scan(value interface{}) error {
@@ -106,6 +110,7 @@ func (js *multiJobSummarySqlite) convert() *models.MultiJobSummary {
 		JID:       js.JID,
 		StartedAt: js.StartedAt,
 		CreatedBy: js.CreatedBy,
+		Tenant:    js.Tenant,
 	}
 }
 
@@ -126,9 +131,12 @@ func (j *multiJobSqlite) convert() *models.MultiJob {
 		GroupIDs:        d.GroupIDs,
 		Command:         d.Command,
 		Shell:           d.Shell,
+		Interpreter:     d.Interpreter,
+		Script:          d.Script,
 		TimeoutSec:      d.TimeoutSec,
 		Concurrent:      d.Concurrent,
 		AbortOnErr:      d.AbortOnErr,
+		ScheduleID:      d.ScheduleID,
 	}
 }
 
@@ -138,15 +146,19 @@ func convertMultiJobToSqlite(job *models.MultiJob) *multiJobSqlite {
 			JID:       job.JID,
 			StartedAt: job.StartedAt,
 			CreatedBy: job.CreatedBy,
+			Tenant:    job.Tenant,
 		},
 		Details: &multiJobDetailSqlite{
-			ClientIDs:  job.ClientIDs,
-			GroupIDs:   job.GroupIDs,
-			Command:    job.Command,
-			Shell:      job.Shell,
-			TimeoutSec: job.TimeoutSec,
-			Concurrent: job.Concurrent,
-			AbortOnErr: job.AbortOnErr,
+			ClientIDs:   job.ClientIDs,
+			GroupIDs:    job.GroupIDs,
+			Command:     job.Command,
+			Shell:       job.Shell,
+			Interpreter: job.Interpreter,
+			Script:      job.Script,
+			TimeoutSec:  job.TimeoutSec,
+			Concurrent:  job.Concurrent,
+			AbortOnErr:  job.AbortOnErr,
+			ScheduleID:  job.ScheduleID,
 		},
 	}
 }
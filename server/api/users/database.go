@@ -11,13 +11,17 @@ type UserDatabase struct {
 	db              *sqlx.DB
 	usersTableName  string
 	groupsTableName string
+	multiTenancy    bool
 }
 
-func NewUserDatabase(DB *sqlx.DB, usersTableName, groupsTableName string) (*UserDatabase, error) {
+// NewUserDatabase returns a user provider backed by given database tables.
+// If multiTenancy is true, both tables are expected to have a 'tenant' column.
+func NewUserDatabase(DB *sqlx.DB, usersTableName, groupsTableName string, multiTenancy bool) (*UserDatabase, error) {
 	d := &UserDatabase{
 		db:              DB,
 		usersTableName:  usersTableName,
 		groupsTableName: groupsTableName,
+		multiTenancy:    multiTenancy,
 	}
 	if err := d.checkDatabaseTables(); err != nil {
 		return nil, err
@@ -26,27 +30,38 @@ func NewUserDatabase(DB *sqlx.DB, usersTableName, groupsTableName string) (*User
 }
 
 func (d *UserDatabase) checkDatabaseTables() error {
-	_, err := d.db.Exec(fmt.Sprintf("SELECT username, password FROM `%s` LIMIT 0", d.usersTableName))
+	_, err := d.db.Exec(fmt.Sprintf("SELECT username, password%s FROM `%s` LIMIT 0", d.tenantColumn(), d.usersTableName))
 	if err != nil {
 		return err
 	}
-	_, err = d.db.Exec(fmt.Sprintf("SELECT username, `group` FROM `%s` LIMIT 0", d.groupsTableName))
+	_, err = d.db.Exec(fmt.Sprintf("SELECT username, `group`%s FROM `%s` LIMIT 0", d.tenantColumn(), d.groupsTableName))
 	if err != nil {
 		return err
 	}
 	return nil
 }
 
+func (d *UserDatabase) tenantColumn() string {
+	if d.multiTenancy {
+		return ", tenant"
+	}
+	return ""
+}
+
 func (d *UserDatabase) GetByUsername(username string) (*User, error) {
 	user := &User{}
-	err := d.db.Get(user, fmt.Sprintf("SELECT username, password FROM `%s` WHERE username = ? LIMIT 1", d.usersTableName), username)
+	err := d.db.Get(user, fmt.Sprintf("SELECT username, password%s FROM `%s` WHERE username = ? LIMIT 1", d.tenantColumn(), d.usersTableName), username)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil
 		}
 		return nil, err
 	}
-	err = d.db.Select(&user.Groups, fmt.Sprintf("SELECT DISTINCT(`group`) FROM `%s` WHERE username = ?", d.groupsTableName), username)
+	if d.multiTenancy {
+		err = d.db.Select(&user.Groups, fmt.Sprintf("SELECT DISTINCT(`group`) FROM `%s` WHERE username = ? AND tenant = ?", d.groupsTableName), username, user.Tenant)
+	} else {
+		err = d.db.Select(&user.Groups, fmt.Sprintf("SELECT DISTINCT(`group`) FROM `%s` WHERE username = ?", d.groupsTableName), username)
+	}
 	if err != nil && err != sql.ErrNoRows {
 		return nil, err
 	}
@@ -57,7 +57,7 @@ func TestNewUserDatabase(t *testing.T) {
 
 	for _, tc := range testCases {
 		t.Run(tc.Name, func(t *testing.T) {
-			_, err := NewUserDatabase(db, tc.UsersTable, tc.GroupsTable)
+			_, err := NewUserDatabase(db, tc.UsersTable, tc.GroupsTable, false)
 			if tc.ExpectedError == "" {
 				require.NoError(t, err)
 			} else {
@@ -88,7 +88,7 @@ func TestGetByUsername(t *testing.T) {
 	require.NoError(t, err)
 	_, err = db.Exec("INSERT INTO `groups` (username, `group`) VALUES (\"user3\", \"group2\")")
 	require.NoError(t, err)
-	d, err := NewUserDatabase(db, "users", "groups")
+	d, err := NewUserDatabase(db, "users", "groups", false)
 	require.NoError(t, err)
 
 	testCases := []struct {
@@ -136,3 +136,40 @@ func TestGetByUsername(t *testing.T) {
 	}
 
 }
+
+func TestGetByUsernameWithTenant(t *testing.T) {
+	db, err := sqlx.Connect("sqlite3", ":memory:")
+	require.NoError(t, err)
+	defer db.Close()
+	_, err = db.Exec("CREATE TABLE `users` (username TEXT PRIMARY KEY, password TEXT, tenant TEXT)")
+	require.NoError(t, err)
+	_, err = db.Exec("CREATE TABLE `groups` (username TEXT, `group` TEXT, tenant TEXT)")
+	require.NoError(t, err)
+	_, err = db.Exec("INSERT INTO `users` (username, password, tenant) VALUES (\"user1\", \"pass1\", \"tenant1\")")
+	require.NoError(t, err)
+	_, err = db.Exec("INSERT INTO `groups` (username, `group`, tenant) VALUES (\"user1\", \"group1\", \"tenant1\")")
+	require.NoError(t, err)
+	_, err = db.Exec("INSERT INTO `groups` (username, `group`, tenant) VALUES (\"user1\", \"group2\", \"tenant2\")")
+	require.NoError(t, err)
+
+	_, err = NewUserDatabase(db, "users", "groups", true)
+	require.NoError(t, err)
+
+	_, err = db.Exec("CREATE TABLE `users_no_tenant` (username TEXT PRIMARY KEY, password TEXT)")
+	require.NoError(t, err)
+	_, err = NewUserDatabase(db, "users_no_tenant", "groups", true)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "no such column: tenant")
+
+	d, err := NewUserDatabase(db, "users", "groups", true)
+	require.NoError(t, err)
+
+	u, err := d.GetByUsername("user1")
+	require.NoError(t, err)
+	assert.Equal(t, &User{
+		Username: "user1",
+		Password: "pass1",
+		Groups:   []string{"group1"},
+		Tenant:   "tenant1",
+	}, u)
+}
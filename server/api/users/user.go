@@ -5,4 +5,18 @@ type User struct {
 	Username string
 	Password string
 	Groups   []string
+	// Tenant is set only when multi-tenancy is enabled.
+	Tenant string
+}
+
+// BelongsToOneOf returns true if the user is a member of at least one of given user groups.
+func (u *User) BelongsToOneOf(groups []string) bool {
+	for _, cur := range u.Groups {
+		for _, group := range groups {
+			if cur == group {
+				return true
+			}
+		}
+	}
+	return false
 }
@@ -0,0 +1,17 @@
+package users
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUserBelongsToOneOf(t *testing.T) {
+	u := &User{Username: "u1", Groups: []string{"Admins", "Bunnies"}}
+
+	assert.True(t, u.BelongsToOneOf([]string{"Bunnies"}))
+	assert.True(t, u.BelongsToOneOf([]string{"Operators", "Admins"}))
+	assert.False(t, u.BelongsToOneOf([]string{"admins"}))
+	assert.False(t, u.BelongsToOneOf(nil))
+	assert.False(t, (&User{Username: "u2"}).BelongsToOneOf([]string{"Admins"}))
+}
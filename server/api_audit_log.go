@@ -0,0 +1,133 @@
+package chserver
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/cloudradar-monitoring/rport/server/api"
+	"github.com/cloudradar-monitoring/rport/server/auditlog"
+)
+
+const (
+	ErrCodeAuditLogDisabled = "ERR_CODE_AUDIT_LOG_DISABLED"
+
+	auditLogDefaultLimit = 50
+	auditLogMaxLimit     = 1000
+)
+
+// saveAuditLog records a mutating API action of the current user to the audit log. Errors are only logged, so a failed
+// audit log write never fails the action itself.
+func (al *APIListener) saveAuditLog(req *http.Request, action, clientID string, params auditlog.Params) {
+	if al.auditLogProvider == nil {
+		return
+	}
+
+	tenant, err := al.getTenant(req.Context())
+	if err != nil {
+		al.Errorf("Failed to save audit log entry %q: %v", action, err)
+		return
+	}
+
+	// Proxy headers like X-Forwarded-For are set by the client, so they are not trusted here.
+	remoteIP, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		remoteIP = req.RemoteAddr
+	}
+
+	entry := &auditlog.Entry{
+		Timestamp: time.Now().UTC(),
+		Username:  api.GetUser(req.Context(), al.Logger),
+		RemoteIP:  remoteIP,
+		Action:    action,
+		ClientID:  clientID,
+		Params:    params,
+		Tenant:    tenantOf(tenant),
+	}
+	if err := al.auditLogProvider.Save(entry); err != nil {
+		al.Errorf("Failed to save audit log entry %q: %v", action, err)
+	}
+}
+
+func (al *APIListener) handleGetAuditLog(w http.ResponseWriter, req *http.Request) {
+	if al.auditLogProvider == nil {
+		al.jsonErrorResponseWithErrCode(w, http.StatusNotFound, ErrCodeAuditLogDisabled, "Audit log is disabled.")
+		return
+	}
+
+	filter, err := parseAuditLogFilter(req)
+	if err != nil {
+		al.jsonErrorResponseWithError(w, http.StatusBadRequest, ErrCodeInvalidRequest, "Invalid query params.", err)
+		return
+	}
+
+	filter.Tenant, err = al.getTenant(req.Context())
+	if err != nil {
+		al.jsonErrorResponse(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	entries, total, err := al.auditLogProvider.List(filter)
+	if err != nil {
+		al.jsonErrorResponseWithError(w, http.StatusInternalServerError, "", "Failed to get audit log.", err)
+		return
+	}
+
+	al.writeJSONResponse(w, http.StatusOK, api.SuccessPayload{
+		Data: entries,
+		Meta: map[string]int{
+			"count":  total,
+			"offset": filter.Offset,
+			"limit":  filter.Limit,
+		},
+	})
+}
+
+func parseAuditLogFilter(req *http.Request) (*auditlog.Filter, error) {
+	query := req.URL.Query()
+	filter := &auditlog.Filter{
+		Username: query.Get("username"),
+		RemoteIP: query.Get("remote_ip"),
+		Action:   query.Get("action"),
+		ClientID: query.Get("client_id"),
+		Limit:    auditLogDefaultLimit,
+	}
+
+	var err error
+	if filter.Since, err = parseTimeQueryParam(req, "since"); err != nil {
+		return nil, err
+	}
+	if filter.Until, err = parseTimeQueryParam(req, "until"); err != nil {
+		return nil, err
+	}
+
+	if offsetStr := query.Get("offset"); offsetStr != "" {
+		filter.Offset, err = strconv.Atoi(offsetStr)
+		if err != nil || filter.Offset < 0 {
+			return nil, fmt.Errorf("invalid 'offset' %q: expected a non-negative integer", offsetStr)
+		}
+	}
+	if limitStr := query.Get("limit"); limitStr != "" {
+		filter.Limit, err = strconv.Atoi(limitStr)
+		if err != nil || filter.Limit < 1 || filter.Limit > auditLogMaxLimit {
+			return nil, fmt.Errorf("invalid 'limit' %q: expected an integer in range [1, %d]", limitStr, auditLogMaxLimit)
+		}
+	}
+
+	return filter, nil
+}
+
+func parseTimeQueryParam(req *http.Request, name string) (*time.Time, error) {
+	value := req.URL.Query().Get(name)
+	if value == "" {
+		return nil, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %q: expected RFC3339 time, got %q", name, value)
+	}
+	t = t.UTC()
+	return &t, nil
+}
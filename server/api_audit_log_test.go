@@ -0,0 +1,112 @@
+package chserver
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cloudradar-monitoring/rport/server/api"
+	"github.com/cloudradar-monitoring/rport/server/api/users"
+	"github.com/cloudradar-monitoring/rport/server/auditlog"
+	"github.com/cloudradar-monitoring/rport/server/cgroups"
+	"github.com/cloudradar-monitoring/rport/server/clients"
+)
+
+func TestAuditLog(t *testing.T) {
+	ctx := api.WithUser(context.Background(), "admin")
+
+	dir, err := ioutil.TempDir("", "auditlog")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	groupProvider, err := cgroups.NewSqliteProvider("file:audit-log?mode=memory&cache=shared")
+	require.NoError(t, err)
+	defer groupProvider.Close()
+
+	al := APIListener{
+		insecureForTests: true,
+		Server: &Server{
+			clientService: NewClientService(nil, clients.NewClientRepository(nil, &hour)),
+			config: &Config{
+				Server: ServerConfig{MaxRequestBytes: 1024 * 1024},
+			},
+			clientGroupProvider: groupProvider,
+			auditLogProvider:    auditlog.NewFileProvider(path.Join(dir, "audit.log")),
+		},
+		userSrv: users.NewUserCache([]*users.User{{Username: "admin"}}),
+		Logger:  testLog,
+	}
+	al.initRouter()
+
+	serve := func(method, url, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, url, strings.NewReader(body))
+		req = req.WithContext(ctx)
+		req.RemoteAddr = "192.0.2.1:1234"
+		req.Header.Set("X-Forwarded-For", "203.0.113.1")
+		w := httptest.NewRecorder()
+		al.router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := serve(http.MethodPost, "/api/v1/client-groups", `{"id": "group-1", "params": {"client_id": ["client-1"]}}`)
+	require.Equal(t, http.StatusCreated, w.Code)
+	w = serve(http.MethodDelete, "/api/v1/client-groups/group-1", "")
+	require.Equal(t, http.StatusNoContent, w.Code)
+
+	t.Run("list", func(t *testing.T) {
+		w := serve(http.MethodGet, "/api/v1/audit-log", "")
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var gotResp struct {
+			Data []*auditlog.Entry `json:"data"`
+			Meta map[string]int    `json:"meta"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &gotResp))
+		assert.Equal(t, map[string]int{"count": 2, "offset": 0, "limit": auditLogDefaultLimit}, gotResp.Meta)
+		require.Len(t, gotResp.Data, 2)
+		assert.Equal(t, auditlog.ActionClientGroupDelete, gotResp.Data[0].Action)
+		assert.Equal(t, auditlog.Params{"group_id": "group-1"}, gotResp.Data[0].Params)
+		assert.Equal(t, auditlog.ActionClientGroupCreate, gotResp.Data[1].Action)
+		assert.Equal(t, "admin", gotResp.Data[1].Username)
+		assert.Equal(t, "192.0.2.1", gotResp.Data[1].RemoteIP)
+	})
+
+	t.Run("filter and paginate", func(t *testing.T) {
+		w := serve(http.MethodGet, "/api/v1/audit-log?action=client_group_create&limit=1", "")
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var gotResp struct {
+			Data []*auditlog.Entry `json:"data"`
+			Meta map[string]int    `json:"meta"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &gotResp))
+		assert.Equal(t, 1, gotResp.Meta["count"])
+		require.Len(t, gotResp.Data, 1)
+		assert.Equal(t, auditlog.ActionClientGroupCreate, gotResp.Data[0].Action)
+	})
+
+	t.Run("invalid params", func(t *testing.T) {
+		for _, query := range []string{"limit=0", "limit=abc", "offset=-1", "since=yesterday"} {
+			w := serve(http.MethodGet, "/api/v1/audit-log?"+query, "")
+			assert.Equal(t, http.StatusBadRequest, w.Code, query)
+		}
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		al.auditLogProvider = nil
+		defer func() { al.auditLogProvider = auditlog.NewFileProvider(path.Join(dir, "audit.log")) }()
+
+		w := serve(http.MethodGet, "/api/v1/audit-log", "")
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		assert.Contains(t, w.Body.String(), ErrCodeAuditLogDisabled)
+	})
+}
@@ -0,0 +1,133 @@
+package chserver
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/cloudradar-monitoring/rport/server/cgroups"
+	"github.com/cloudradar-monitoring/rport/server/clients"
+)
+
+const ErrCodeClientAccessDenied = "ERR_CODE_CLIENT_ACCESS_DENIED"
+
+// clientAccess defines what clients the current API user can access.
+type clientAccess struct {
+	// restricted is false when the user can access all clients.
+	restricted bool
+	groups     []*cgroups.ClientGroup
+	// tenant is nil when multi-tenancy is disabled.
+	tenant *string
+}
+
+// IsVisible returns true if a given client belongs to the tenant of the current user.
+func (a *clientAccess) IsVisible(client *clients.Client) bool {
+	return matchesTenant(a.tenant, client.Tenant)
+}
+
+// IsAllowed returns true if a given client can be accessed.
+func (a *clientAccess) IsAllowed(client *clients.Client) bool {
+	return a.IsVisible(client) && (!a.restricted || client.BelongsToOneOf(a.groups))
+}
+
+// Filter returns only clients that can be accessed.
+func (a *clientAccess) Filter(all []*clients.Client) []*clients.Client {
+	if !a.restricted && a.tenant == nil {
+		return all
+	}
+	res := make([]*clients.Client, 0, len(all))
+	for _, cur := range all {
+		if a.IsAllowed(cur) {
+			res = append(res, cur)
+		}
+	}
+	return res
+}
+
+// getClientAccess returns what clients the current API user can access. A user is restricted to clients of
+// client groups that are assigned to their user groups in 'client_access' rules. Users that don't belong to any of
+// user groups listed in the rules can access all clients. When multi-tenancy is enabled a user can access only clients
+// of their tenant.
+func (al *APIListener) getClientAccess(ctx context.Context) (*clientAccess, error) {
+	rules := al.config.API.ClientAccess
+	if len(rules) == 0 && !al.config.Server.MultiTenancy {
+		return &clientAccess{}, nil
+	}
+
+	user, err := al.getCurrentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &clientAccess{tenant: al.userTenant(user)}
+	usedGroupIDs := make(map[string]bool)
+	for _, rule := range rules {
+		if !user.BelongsToOneOf([]string{rule.UserGroup}) {
+			continue
+		}
+		res.restricted = true
+		for _, groupID := range rule.ClientGroups {
+			if usedGroupIDs[groupID] {
+				continue
+			}
+			usedGroupIDs[groupID] = true
+
+			group, err := al.clientGroupProvider.Get(ctx, groupID)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get a client group with id=%q: %v", groupID, err)
+			}
+			// unknown group does not grant an access to any client
+			if group != nil {
+				res.groups = append(res.groups, group)
+			}
+		}
+	}
+
+	return res, nil
+}
+
+// checkClientAccess writes an error response and returns false if the current API user can't access a given client.
+func (al *APIListener) checkClientAccess(w http.ResponseWriter, req *http.Request, client *clients.Client) bool {
+	access, err := al.getClientAccess(req.Context())
+	if err != nil {
+		al.jsonErrorResponse(w, http.StatusInternalServerError, err)
+		return false
+	}
+	if !access.IsVisible(client) {
+		al.jsonErrorResponseWithTitle(w, http.StatusNotFound, fmt.Sprintf("Client with id=%q not found.", client.ID))
+		return false
+	}
+	if !access.IsAllowed(client) {
+		al.jsonErrorResponseWithErrCode(w, http.StatusForbidden, ErrCodeClientAccessDenied, fmt.Sprintf("Access to client with id=%q is denied.", client.ID))
+		return false
+	}
+	return true
+}
+
+// checkClientIDAccess is the same as checkClientAccess but for a client that is referred only by its id and might be
+// already deleted. A deleted client can't be accessed if the current API user has restricted access.
+func (al *APIListener) checkClientIDAccess(w http.ResponseWriter, req *http.Request, clientID string) bool {
+	access, err := al.getClientAccess(req.Context())
+	if err != nil {
+		al.jsonErrorResponse(w, http.StatusInternalServerError, err)
+		return false
+	}
+	if !access.restricted && access.tenant == nil {
+		return true
+	}
+
+	client, err := al.clientService.GetByID(clientID)
+	if err != nil {
+		al.jsonErrorResponse(w, http.StatusInternalServerError, err)
+		return false
+	}
+	if client == nil || !access.IsVisible(client) {
+		al.jsonErrorResponseWithTitle(w, http.StatusNotFound, fmt.Sprintf("Client with id=%q not found.", clientID))
+		return false
+	}
+	if !access.IsAllowed(client) {
+		al.jsonErrorResponseWithErrCode(w, http.StatusForbidden, ErrCodeClientAccessDenied, fmt.Sprintf("Access to client with id=%q is denied.", client.ID))
+		return false
+	}
+	return true
+}
@@ -0,0 +1,159 @@
+package chserver
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cloudradar-monitoring/rport/server/api"
+	"github.com/cloudradar-monitoring/rport/server/api/users"
+	"github.com/cloudradar-monitoring/rport/server/cgroups"
+	"github.com/cloudradar-monitoring/rport/server/clients"
+)
+
+func TestClientAccess(t *testing.T) {
+	ctx := context.Background()
+	c1 := clients.New(t).ID("client-1").Build()
+	c2 := clients.New(t).ID("client-2").Build()
+	c3 := clients.New(t).ID("client-3").Build()
+
+	groupProvider, err := cgroups.NewSqliteProvider("file:client-access?mode=memory&cache=shared")
+	require.NoError(t, err)
+	defer groupProvider.Close()
+	require.NoError(t, groupProvider.Create(ctx, &cgroups.ClientGroup{
+		ID:     "group-1",
+		Params: &cgroups.ClientParams{ClientID: &cgroups.ParamValues{"client-1"}},
+	}))
+	require.NoError(t, groupProvider.Create(ctx, &cgroups.ClientGroup{
+		ID:     "group-2",
+		Params: &cgroups.ClientParams{ClientID: &cgroups.ParamValues{"client-2"}},
+	}))
+
+	admin := &users.User{Username: "admin", Groups: []string{"Admins"}}
+	support := &users.User{Username: "support", Groups: []string{"Support"}}
+	ops := &users.User{Username: "ops", Groups: []string{"Support", "Operations"}}
+
+	al := APIListener{
+		insecureForTests: true,
+		Server: &Server{
+			clientService: NewClientService(nil, clients.NewClientRepository([]*clients.Client{c1, c2, c3}, &hour)),
+			config: &Config{
+				Server: ServerConfig{MaxRequestBytes: 1024 * 1024},
+				API: APIConfig{
+					ClientAccess: []ClientAccessRule{
+						{UserGroup: "Support", ClientGroups: []string{"group-1"}},
+						{UserGroup: "Operations", ClientGroups: []string{"group-2", "unknown-group"}},
+					},
+				},
+			},
+			clientGroupProvider: groupProvider,
+		},
+		userSrv: users.NewUserCache([]*users.User{admin, support, ops}),
+		Logger:  testLog,
+	}
+	al.initRouter()
+
+	t.Run("get clients", func(t *testing.T) {
+		testCases := []struct {
+			username      string
+			wantClientIDs []string
+		}{
+			{
+				username:      admin.Username,
+				wantClientIDs: []string{c1.ID, c2.ID, c3.ID},
+			},
+			{
+				username:      support.Username,
+				wantClientIDs: []string{c1.ID},
+			},
+			{
+				username:      ops.Username,
+				wantClientIDs: []string{c1.ID, c2.ID},
+			},
+		}
+
+		for _, tc := range testCases {
+			t.Run(tc.username, func(t *testing.T) {
+				req := httptest.NewRequest(http.MethodGet, "/api/v1/clients", nil)
+				req = req.WithContext(api.WithUser(ctx, tc.username))
+
+				w := httptest.NewRecorder()
+				al.router.ServeHTTP(w, req)
+
+				require.Equal(t, http.StatusOK, w.Code)
+				var gotResp struct {
+					Data []ClientPayload `json:"data"`
+				}
+				require.NoError(t, json.Unmarshal(w.Body.Bytes(), &gotResp))
+				var gotClientIDs []string
+				for _, cur := range gotResp.Data {
+					gotClientIDs = append(gotClientIDs, cur.ID)
+				}
+				assert.ElementsMatch(t, tc.wantClientIDs, gotClientIDs)
+			})
+		}
+	})
+
+	t.Run("act on clients", func(t *testing.T) {
+		testCases := []struct {
+			descr    string
+			username string
+			method   string
+			url      string
+			body     string
+		}{
+			{
+				descr:    "create tunnel",
+				username: support.Username,
+				method:   http.MethodPut,
+				url:      "/api/v1/clients/client-2/tunnels?remote=22",
+			},
+			{
+				descr:    "delete tunnel",
+				username: support.Username,
+				method:   http.MethodDelete,
+				url:      "/api/v1/clients/client-3/tunnels/1",
+			},
+			{
+				descr:    "run command",
+				username: ops.Username,
+				method:   http.MethodPost,
+				url:      "/api/v1/clients/client-3/commands",
+				body:     `{"command": "/bin/date"}`,
+			},
+			{
+				descr:    "run multi-client command",
+				username: support.Username,
+				method:   http.MethodPost,
+				url:      "/api/v1/commands",
+				body:     `{"command": "/bin/date", "client_ids": ["client-1", "client-2"]}`,
+			},
+			{
+				descr:    "run multi-client command on client group",
+				username: support.Username,
+				method:   http.MethodPost,
+				url:      "/api/v1/commands",
+				body:     `{"command": "/bin/date", "group_ids": ["group-2"]}`,
+			},
+		}
+
+		for _, tc := range testCases {
+			t.Run(tc.descr, func(t *testing.T) {
+				req := httptest.NewRequest(tc.method, tc.url, strings.NewReader(tc.body))
+				req = req.WithContext(api.WithUser(ctx, tc.username))
+
+				w := httptest.NewRecorder()
+				al.router.ServeHTTP(w, req)
+
+				assert.Equal(t, http.StatusForbidden, w.Code)
+				assert.Contains(t, w.Body.String(), ErrCodeClientAccessDenied)
+			})
+		}
+	})
+}
@@ -0,0 +1,240 @@
+package chserver
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"golang.org/x/crypto/ssh"
+
+	"github.com/cloudradar-monitoring/rport/server/api"
+	"github.com/cloudradar-monitoring/rport/server/auditlog"
+	"github.com/cloudradar-monitoring/rport/server/clientcerts"
+)
+
+const (
+	ErrCodeClientCertsDisabled = "ERR_CODE_CLIENT_CERTS_DISABLED"
+	ErrCodeClientCertNotFound  = "ERR_CODE_CLIENT_CERT_NOT_FOUND"
+
+	routeParamClientCertID = "cert_id"
+)
+
+type clientCertRequest struct {
+	ClientAuthID string `json:"client_auth_id"`
+	PublicKey    string `json:"public_key"`
+}
+
+type clientCertPayload struct {
+	*clientcerts.ClientCert
+	// Certificate is in the authorized_keys format, it's returned only when it's signed.
+	Certificate string `json:"certificate"`
+}
+
+type clientCAPayload struct {
+	PublicKey   string `json:"public_key"`
+	Fingerprint string `json:"fingerprint"`
+}
+
+func (al *APIListener) checkClientCertsEnabled(w http.ResponseWriter) bool {
+	if al.clientCertAuthority == nil {
+		al.jsonErrorResponseWithErrCode(w, http.StatusNotFound, ErrCodeClientCertsDisabled, "Certificate authentication of clients is disabled.")
+		return false
+	}
+	return true
+}
+
+// handleGetClientCA returns the public key of the CA that signs client certificates.
+func (al *APIListener) handleGetClientCA(w http.ResponseWriter, req *http.Request) {
+	if !al.checkClientCertsEnabled(w) {
+		return
+	}
+
+	key := al.clientCertAuthority.PublicKey()
+	al.writeJSONResponse(w, http.StatusOK, api.NewSuccessPayload(clientCAPayload{
+		PublicKey:   string(ssh.MarshalAuthorizedKey(key)),
+		Fingerprint: ssh.FingerprintSHA256(key),
+	}))
+}
+
+// handleGetClientCerts returns records of all signed client certificates including revoked and expired ones.
+func (al *APIListener) handleGetClientCerts(w http.ResponseWriter, req *http.Request) {
+	if !al.checkClientCertsEnabled(w) {
+		return
+	}
+
+	tenant, err := al.getTenant(req.Context())
+	if err != nil {
+		al.jsonErrorResponse(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	all, err := al.clientCertProvider.GetAll(req.Context())
+	if err != nil {
+		al.jsonErrorResponse(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	res := make([]*clientcerts.ClientCert, 0, len(all))
+	for _, cur := range all {
+		if matchesTenant(tenant, cur.Tenant) {
+			res = append(res, cur)
+		}
+	}
+
+	al.writeJSONResponse(w, http.StatusOK, api.NewSuccessPayload(res))
+}
+
+// handlePostClientCert signs a certificate of a given public key, so a client can authenticate with the key instead of
+// a password.
+func (al *APIListener) handlePostClientCert(w http.ResponseWriter, req *http.Request) {
+	if !al.checkClientCertsEnabled(w) {
+		return
+	}
+
+	var certReq clientCertRequest
+	err := json.NewDecoder(req.Body).Decode(&certReq)
+	if err == io.EOF {
+		al.jsonErrorResponseWithErrCode(w, http.StatusBadRequest, ErrCodeInvalidRequest, "Missing data.")
+		return
+	} else if err != nil {
+		al.jsonErrorResponseWithError(w, http.StatusBadRequest, ErrCodeInvalidRequest, "Invalid JSON data.", err)
+		return
+	}
+
+	if len(certReq.ClientAuthID) < MinCredentialsLength {
+		al.jsonErrorResponseWithDetail(w, http.StatusBadRequest, ErrCodeInvalidRequest, "Invalid or missing client_auth_id.", fmt.Sprintf("Min size is %d.", MinCredentialsLength))
+		return
+	}
+
+	key, _, _, _, err := ssh.ParseAuthorizedKey([]byte(certReq.PublicKey))
+	if err != nil {
+		al.jsonErrorResponseWithError(w, http.StatusBadRequest, ErrCodeInvalidRequest, "Invalid or missing public_key.", err)
+		return
+	}
+	if _, ok := key.(*ssh.Certificate); ok {
+		al.jsonErrorResponseWithErrCode(w, http.StatusBadRequest, ErrCodeInvalidRequest, "Invalid public_key: a public key is expected, got a certificate.")
+		return
+	}
+
+	tenant, err := al.getTenant(req.Context())
+	if err != nil {
+		al.jsonErrorResponse(w, http.StatusInternalServerError, err)
+		return
+	}
+	if tenant != nil && *tenant == "" {
+		al.jsonErrorResponseWithErrCode(w, http.StatusForbidden, ErrCodeInsufficientPermissions, "User without a tenant can't create a client certificate.")
+		return
+	}
+	if !al.isClientAuthIDOfTenant(w, req, certReq.ClientAuthID, tenant) {
+		return
+	}
+
+	record, cert, err := al.clientCertAuthority.Sign(req.Context(), certReq.ClientAuthID, key, tenantOf(tenant), api.GetUser(req.Context(), al.Logger))
+	if err != nil {
+		al.jsonErrorResponse(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	al.Infof("Client certificate %d for client auth %q created.", record.ID, record.ClientAuthID)
+	al.saveAuditLog(req, auditlog.ActionClientCertCreate, "", auditlog.Params{
+		"client_auth_id": record.ClientAuthID,
+		"cert_id":        record.ID,
+		"fingerprint":    record.Fingerprint,
+	})
+
+	al.writeJSONResponse(w, http.StatusCreated, api.NewSuccessPayload(clientCertPayload{
+		ClientCert:  record,
+		Certificate: string(ssh.MarshalAuthorizedKey(cert)),
+	}))
+}
+
+// isClientAuthIDOfTenant writes an error response and returns false if a given client auth id is already used by
+// password credentials or certificates of another tenant.
+func (al *APIListener) isClientAuthIDOfTenant(w http.ResponseWriter, req *http.Request, clientAuthID string, tenant *string) bool {
+	if tenant == nil {
+		return true
+	}
+
+	clientAuth, err := al.clientAuthProvider.Get(clientAuthID)
+	if err != nil {
+		al.jsonErrorResponse(w, http.StatusInternalServerError, err)
+		return false
+	}
+	used := clientAuth != nil && !matchesTenant(tenant, clientAuth.Tenant)
+
+	all, err := al.clientCertProvider.GetAll(req.Context())
+	if err != nil {
+		al.jsonErrorResponse(w, http.StatusInternalServerError, err)
+		return false
+	}
+	for _, cur := range all {
+		if cur.ClientAuthID == clientAuthID && !matchesTenant(tenant, cur.Tenant) {
+			used = true
+		}
+	}
+
+	if used {
+		al.jsonErrorResponseWithErrCode(w, http.StatusConflict, ErrCodeAlreadyExist, fmt.Sprintf("Client Auth with ID %q is used by another tenant.", clientAuthID))
+		return false
+	}
+	return true
+}
+
+// handleDeleteClientCert revokes a client certificate. Connected clients of the client auth id are disconnected, so
+// they need to authenticate again.
+func (al *APIListener) handleDeleteClientCert(w http.ResponseWriter, req *http.Request) {
+	if !al.checkClientCertsEnabled(w) {
+		return
+	}
+
+	idStr := mux.Vars(req)[routeParamClientCertID]
+	id, err := strconv.ParseUint(idStr, 10, 64)
+	if err != nil {
+		al.jsonErrorResponseWithErrCode(w, http.StatusBadRequest, ErrCodeInvalidRequest, fmt.Sprintf("Invalid certificate id: %q.", idStr))
+		return
+	}
+
+	tenant, err := al.getTenant(req.Context())
+	if err != nil {
+		al.jsonErrorResponse(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	existing, err := al.clientCertProvider.Get(req.Context(), id)
+	if err != nil {
+		al.jsonErrorResponse(w, http.StatusInternalServerError, err)
+		return
+	}
+	if existing == nil || !matchesTenant(tenant, existing.Tenant) {
+		al.jsonErrorResponseWithErrCode(w, http.StatusNotFound, ErrCodeClientCertNotFound, fmt.Sprintf("Client certificate with id=%d not found.", id))
+		return
+	}
+
+	revoked, err := al.clientCertProvider.Revoke(req.Context(), id, time.Now().UTC())
+	if err != nil {
+		al.jsonErrorResponse(w, http.StatusInternalServerError, err)
+		return
+	}
+	if revoked {
+		for _, c := range al.clientService.GetAllByClientID(existing.ClientAuthID) {
+			if c.DisconnectedAt != nil {
+				continue
+			}
+			if err := c.Close(); err != nil {
+				al.Errorf("Failed to disconnect client %q: %v", c.ID, err)
+			}
+		}
+
+		al.Infof("Client certificate %d for client auth %q revoked.", id, existing.ClientAuthID)
+		al.saveAuditLog(req, auditlog.ActionClientCertRevoke, "", auditlog.Params{
+			"client_auth_id": existing.ClientAuthID,
+			"cert_id":        id,
+		})
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
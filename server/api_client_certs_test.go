@@ -0,0 +1,116 @@
+package chserver
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	mapset "github.com/deckarep/golang-set"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh"
+
+	"github.com/cloudradar-monitoring/rport/server/api"
+	"github.com/cloudradar-monitoring/rport/server/api/users"
+	"github.com/cloudradar-monitoring/rport/server/clientcerts"
+	"github.com/cloudradar-monitoring/rport/server/clients"
+	"github.com/cloudradar-monitoring/rport/server/clientsauth"
+	"github.com/cloudradar-monitoring/rport/server/ports"
+)
+
+func TestHandleClientCerts(t *testing.T) {
+	ctx := api.WithUser(context.Background(), "admin")
+	certProvider, err := clientcerts.NewSqliteProvider(":memory:")
+	require.NoError(t, err)
+	defer certProvider.Close()
+	authority := clientcerts.NewAuthority(newTestSigner(t), certProvider, time.Hour)
+	al := APIListener{
+		insecureForTests: true,
+		Server: &Server{
+			clientService: NewClientService(
+				ports.NewPortDistributor(mapset.NewThreadUnsafeSet()),
+				clients.NewClientRepository(nil, &hour),
+			),
+			clientAuthProvider:  clientsauth.NewNoneProvider(),
+			clientCertProvider:  certProvider,
+			clientCertAuthority: authority,
+			config: &Config{
+				Server: ServerConfig{MaxRequestBytes: 1024 * 1024},
+			},
+		},
+		userSrv: users.NewUserCache([]*users.User{{Username: "admin"}}),
+		Logger:  testLog,
+	}
+	al.initRouter()
+
+	do := func(method, url, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(method, url, strings.NewReader(body)).WithContext(ctx)
+		al.router.ServeHTTP(w, req)
+		return w
+	}
+	clientKey := newTestSigner(t).PublicKey()
+	publicKey := strings.TrimSpace(string(ssh.MarshalAuthorizedKey(clientKey)))
+
+	w := do(http.MethodPost, "/api/v1/client-certs", `{"client_auth_id":"client-1","public_key":"invalid"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// when
+	w = do(http.MethodPost, "/api/v1/client-certs", `{"client_auth_id":"client-1","public_key":"`+publicKey+`"}`)
+
+	// then
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	var res struct {
+		Data struct {
+			ID           uint64 `json:"id"`
+			ClientAuthID string `json:"client_auth_id"`
+			Fingerprint  string `json:"fingerprint"`
+			CreatedBy    string `json:"created_by"`
+			Certificate  string `json:"certificate"`
+		} `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+	assert.EqualValues(t, 1, res.Data.ID)
+	assert.Equal(t, "client-1", res.Data.ClientAuthID)
+	assert.Equal(t, ssh.FingerprintSHA256(clientKey), res.Data.Fingerprint)
+	assert.Equal(t, "admin", res.Data.CreatedBy)
+	cert, _, _, _, err := ssh.ParseAuthorizedKey([]byte(res.Data.Certificate))
+	require.NoError(t, err)
+	_, err = authority.Authenticate(ctx, "client-1", cert)
+	assert.NoError(t, err)
+
+	w = do(http.MethodGet, "/api/v1/client-certs", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"revoked_at":null`)
+
+	w = do(http.MethodGet, "/api/v1/client-certs/ca", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), ssh.FingerprintSHA256(authority.PublicKey()))
+
+	// when
+	w = do(http.MethodDelete, "/api/v1/client-certs/1", "")
+
+	// then
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	_, err = authority.Authenticate(ctx, "client-1", cert)
+	assert.Error(t, err)
+	w = do(http.MethodDelete, "/api/v1/client-certs/2", "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	// password credentials can't be added when only certificates are used
+	w = do(http.MethodPost, "/api/v1/clients-auth", `{"id":"client-2","password":"secret"}`)
+	assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+	assert.Contains(t, w.Body.String(), ErrCodeClientAuthDisabled)
+
+	// when
+	al.clientCertAuthority = nil
+
+	// then
+	w = do(http.MethodGet, "/api/v1/client-certs", "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Contains(t, w.Body.String(), ErrCodeClientCertsDisabled)
+}
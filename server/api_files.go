@@ -0,0 +1,165 @@
+package chserver
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httputil"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"golang.org/x/crypto/ssh"
+
+	"github.com/cloudradar-monitoring/rport/server/api"
+	"github.com/cloudradar-monitoring/rport/server/auditlog"
+	"github.com/cloudradar-monitoring/rport/server/clients"
+	"github.com/cloudradar-monitoring/rport/share/comm"
+)
+
+const (
+	queryParamPath = "path"
+
+	// routeNameFileUpload is used to exclude the upload route from the max request bytes limit.
+	routeNameFileUpload = "file_upload"
+)
+
+// handlePostClientFile uploads a file from the request body to a given path on a given client.
+// The body is streamed to the client, so it's not limited by max_request_bytes.
+func (al *APIListener) handlePostClientFile(w http.ResponseWriter, req *http.Request) {
+	client, path := al.getFileTransferParams(w, req)
+	if client == nil {
+		return
+	}
+
+	ch, err := openFileChannel(client, comm.FileOpUpload, path)
+	if err != nil {
+		al.fileChannelErrorResponse(w, err)
+		return
+	}
+	defer ch.Close()
+
+	cw := httputil.NewChunkedWriter(ch)
+	if _, err := io.Copy(cw, req.Body); err != nil {
+		al.jsonErrorResponseWithError(w, http.StatusInternalServerError, "", "Failed to upload a file.", err)
+		return
+	}
+	if err := cw.Close(); err != nil {
+		al.jsonErrorResponseWithError(w, http.StatusInternalServerError, "", "Failed to upload a file.", err)
+		return
+	}
+
+	res := comm.FileUploadResult{}
+	if err := json.NewDecoder(ch).Decode(&res); err != nil {
+		al.jsonErrorResponseWithError(w, http.StatusInternalServerError, "", "Failed to get an upload result from client.", err)
+		return
+	}
+	if res.ErrMsg != "" {
+		al.jsonErrorResponseWithTitle(w, http.StatusConflict, fmt.Sprintf("client error: %s", res.ErrMsg))
+		return
+	}
+
+	resp := struct {
+		Path string `json:"path"`
+		Size int64  `json:"size"`
+	}{
+		Path: path,
+		Size: res.Size,
+	}
+	al.writeJSONResponse(w, http.StatusOK, api.NewSuccessPayload(resp))
+
+	al.Debugf("File %q (%d bytes) uploaded to client with id=%q.", path, res.Size, client.ID)
+	al.saveAuditLog(req, auditlog.ActionFileUpload, client.ID, auditlog.Params{
+		"path": path,
+		"size": res.Size,
+	})
+}
+
+// handleGetClientFile streams a file with a given path from a given client.
+func (al *APIListener) handleGetClientFile(w http.ResponseWriter, req *http.Request) {
+	client, path := al.getFileTransferParams(w, req)
+	if client == nil {
+		return
+	}
+
+	ch, err := openFileChannel(client, comm.FileOpDownload, path)
+	if err != nil {
+		al.fileChannelErrorResponse(w, err)
+		return
+	}
+	defer ch.Close()
+
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", fileName(path)))
+	w.WriteHeader(http.StatusOK)
+	n, err := io.Copy(w, httputil.NewChunkedReader(ch))
+	if err != nil {
+		al.Errorf("Failed to download file %q from client with id=%q: %v", path, client.ID, err)
+		// the status is already sent, abort the response to let the requester know the file is incomplete
+		panic(http.ErrAbortHandler)
+	}
+
+	al.Debugf("File %q (%d bytes) downloaded from client with id=%q.", path, n, client.ID)
+}
+
+// getFileTransferParams returns an active client and a file path of a given file transfer request.
+// If any of them is invalid, an error response is written and nil client is returned.
+func (al *APIListener) getFileTransferParams(w http.ResponseWriter, req *http.Request) (*clients.Client, string) {
+	cid := mux.Vars(req)[routeParamClientID]
+	if cid == "" {
+		al.jsonErrorResponseWithTitle(w, http.StatusBadRequest, fmt.Sprintf("Missing %q route param.", routeParamClientID))
+		return nil, ""
+	}
+	path := req.URL.Query().Get(queryParamPath)
+	if path == "" {
+		al.jsonErrorResponseWithTitle(w, http.StatusBadRequest, fmt.Sprintf("Missing %q query param.", queryParamPath))
+		return nil, ""
+	}
+
+	client, err := al.clientService.GetActiveByID(cid)
+	if err != nil {
+		al.jsonErrorResponseWithError(w, http.StatusInternalServerError, "", fmt.Sprintf("Failed to find an active client with id=%q.", cid), err)
+		return nil, ""
+	}
+	if client == nil {
+		al.jsonErrorResponseWithTitle(w, http.StatusNotFound, fmt.Sprintf("Active client with id=%q not found.", cid))
+		return nil, ""
+	}
+	if !al.checkClientAccess(w, req, client) {
+		return nil, ""
+	}
+	return client, path
+}
+
+// fileChannelErrorResponse writes an error response for an error returned on opening a file channel.
+func (al *APIListener) fileChannelErrorResponse(w http.ResponseWriter, err error) {
+	if openErr, ok := err.(*ssh.OpenChannelError); ok {
+		title := fmt.Sprintf("client error: %s", openErr.Message)
+		if openErr.Reason == ssh.Prohibited {
+			al.jsonErrorResponseWithTitle(w, http.StatusForbidden, title)
+		} else {
+			al.jsonErrorResponseWithTitle(w, http.StatusConflict, title)
+		}
+		return
+	}
+	al.jsonErrorResponseWithError(w, http.StatusInternalServerError, "", "Failed to open a file channel.", err)
+}
+
+// openFileChannel opens a channel on a given client to transfer a file with a given path.
+func openFileChannel(client *clients.Client, op, path string) (ssh.Channel, error) {
+	reqBytes, err := json.Marshal(comm.FileRequest{Op: op, Path: path})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode file request: %v", err)
+	}
+	ch, reqs, err := client.Connection.OpenChannel(comm.ChannelTypeFile, reqBytes)
+	if err != nil {
+		return nil, err
+	}
+	go ssh.DiscardRequests(reqs)
+	return ch, nil
+}
+
+// fileName returns the last element of a given path of a client that can be either unix or windows path.
+func fileName(path string) string {
+	return path[strings.LastIndexAny(path, `/\`)+1:]
+}
@@ -0,0 +1,210 @@
+package chserver
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httputil"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh"
+
+	"github.com/cloudradar-monitoring/rport/server/api"
+	"github.com/cloudradar-monitoring/rport/server/clients"
+	"github.com/cloudradar-monitoring/rport/share/comm"
+	"github.com/cloudradar-monitoring/rport/share/test"
+)
+
+func newFilesTestAPIListener(c *clients.Client) *APIListener {
+	al := &APIListener{
+		insecureForTests: true,
+		Server: &Server{
+			clientService: NewClientService(nil, clients.NewClientRepository([]*clients.Client{c}, &hour)),
+			config: &Config{
+				Server: ServerConfig{
+					// smaller than uploaded files to check that uploads are not limited
+					MaxRequestBytes: 4,
+				},
+			},
+		},
+		Logger: testLog,
+	}
+	al.initRouter()
+	return al
+}
+
+func TestHandlePostClientFile(t *testing.T) {
+	testCases := []struct {
+		name            string
+		path            string
+		openChannelErr  error
+		clientResult    *comm.FileUploadResult
+		wantStatusCode  int
+		wantErrTitle    string
+		wantContent     string
+		wantSuccessResp string
+	}{
+		{
+			name:            "success",
+			path:            "/etc/test.conf",
+			clientResult:    &comm.FileUploadResult{Size: 12},
+			wantStatusCode:  http.StatusOK,
+			wantContent:     "test content",
+			wantSuccessResp: `{"data":{"path":"/etc/test.conf","size":12}}`,
+		},
+		{
+			name:           "missing path",
+			wantStatusCode: http.StatusBadRequest,
+			wantErrTitle:   `Missing "path" query param.`,
+		},
+		{
+			name:           "path is not allowed",
+			path:           "/etc/passwd",
+			openChannelErr: &ssh.OpenChannelError{Reason: ssh.Prohibited, Message: `path is not allowed: "/etc/passwd"`},
+			wantStatusCode: http.StatusForbidden,
+			wantErrTitle:   `client error: path is not allowed: "/etc/passwd"`,
+		},
+		{
+			name:           "failed to open file",
+			path:           "/etc/test.conf",
+			openChannelErr: &ssh.OpenChannelError{Reason: ssh.ConnectionFailed, Message: "permission denied"},
+			wantStatusCode: http.StatusConflict,
+			wantErrTitle:   "client error: permission denied",
+		},
+		{
+			name:           "failed to store file",
+			path:           "/etc/test.conf",
+			clientResult:   &comm.FileUploadResult{ErrMsg: "no space left on device"},
+			wantStatusCode: http.StatusConflict,
+			wantErrTitle:   "client error: no space left on device",
+			wantContent:    "test content",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// given
+			serverCh, clientCh := test.NewChannelPair()
+			connMock := test.NewConnMock()
+			connMock.ReturnChannel = serverCh
+			connMock.ReturnOpenChannelErr = tc.openChannelErr
+			c := clients.New(t).Connection(connMock).Build()
+			al := newFilesTestAPIListener(c)
+
+			gotContent := make(chan string, 1)
+			if tc.clientResult != nil {
+				go func() {
+					b, err := ioutil.ReadAll(httputil.NewChunkedReader(clientCh))
+					assert.NoError(t, err)
+					gotContent <- string(b)
+					assert.NoError(t, json.NewEncoder(clientCh).Encode(tc.clientResult))
+				}()
+			}
+
+			url := fmt.Sprintf("/api/v1/clients/%s/files", c.ID)
+			if tc.path != "" {
+				url += "?path=" + tc.path
+			}
+			req := httptest.NewRequest(http.MethodPost, url, strings.NewReader("test content"))
+
+			// when
+			w := httptest.NewRecorder()
+			al.router.ServeHTTP(w, req)
+
+			// then
+			assert.Equal(t, tc.wantStatusCode, w.Code)
+			if tc.wantErrTitle == "" {
+				assert.Equal(t, tc.wantSuccessResp, w.Body.String())
+			} else {
+				wantResp := api.NewErrorPayloadWithCode("", tc.wantErrTitle, "")
+				wantRespBytes, err := json.Marshal(wantResp)
+				require.NoError(t, err)
+				assert.Equal(t, string(wantRespBytes), w.Body.String())
+			}
+			if tc.wantContent != "" {
+				assert.Equal(t, tc.wantContent, <-gotContent)
+				gotType, gotData := connMock.InputOpenChannel()
+				assert.Equal(t, comm.ChannelTypeFile, gotType)
+				assert.JSONEq(t, `{"Op":"upload","Path":"/etc/test.conf"}`, string(gotData))
+			}
+		})
+	}
+}
+
+func TestHandleGetClientFile(t *testing.T) {
+	testCases := []struct {
+		name           string
+		path           string
+		openChannelErr error
+		wantStatusCode int
+		wantErrTitle   string
+	}{
+		{
+			name:           "success",
+			path:           `C:\ProgramData\test.conf`,
+			wantStatusCode: http.StatusOK,
+		},
+		{
+			name:           "path is not allowed",
+			path:           "/etc/passwd",
+			openChannelErr: &ssh.OpenChannelError{Reason: ssh.Prohibited, Message: `path is not allowed: "/etc/passwd"`},
+			wantStatusCode: http.StatusForbidden,
+			wantErrTitle:   `client error: path is not allowed: "/etc/passwd"`,
+		},
+		{
+			name:           "file not found",
+			path:           "/etc/not-found.conf",
+			openChannelErr: &ssh.OpenChannelError{Reason: ssh.ConnectionFailed, Message: "open /etc/not-found.conf: no such file or directory"},
+			wantStatusCode: http.StatusConflict,
+			wantErrTitle:   "client error: open /etc/not-found.conf: no such file or directory",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// given
+			serverCh, clientCh := test.NewChannelPair()
+			connMock := test.NewConnMock()
+			connMock.ReturnChannel = serverCh
+			connMock.ReturnOpenChannelErr = tc.openChannelErr
+			c := clients.New(t).Connection(connMock).Build()
+			al := newFilesTestAPIListener(c)
+
+			if tc.openChannelErr == nil {
+				go func() {
+					w := httputil.NewChunkedWriter(clientCh)
+					_, err := w.Write([]byte("test content"))
+					assert.NoError(t, err)
+					assert.NoError(t, w.Close())
+				}()
+			}
+
+			req := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/api/v1/clients/%s/files?path=%s", c.ID, tc.path), nil)
+
+			// when
+			w := httptest.NewRecorder()
+			al.router.ServeHTTP(w, req)
+
+			// then
+			assert.Equal(t, tc.wantStatusCode, w.Code)
+			if tc.wantErrTitle == "" {
+				assert.Equal(t, "test content", w.Body.String())
+				assert.Equal(t, "application/octet-stream", w.Header().Get("Content-Type"))
+				assert.Equal(t, `attachment; filename="test.conf"`, w.Header().Get("Content-Disposition"))
+				gotType, gotData := connMock.InputOpenChannel()
+				assert.Equal(t, comm.ChannelTypeFile, gotType)
+				assert.JSONEq(t, `{"Op":"download","Path":"C:\\ProgramData\\test.conf"}`, string(gotData))
+			} else {
+				wantResp := api.NewErrorPayloadWithCode("", tc.wantErrTitle, "")
+				wantRespBytes, err := json.Marshal(wantResp)
+				require.NoError(t, err)
+				assert.Equal(t, string(wantRespBytes), w.Body.String())
+			}
+		})
+	}
+}
@@ -14,6 +14,7 @@ import (
 	"github.com/gorilla/handlers"
 	"github.com/gorilla/mux"
 	"github.com/jpillora/requestlog"
+	"github.com/tomasen/realip"
 	"golang.org/x/crypto/bcrypt"
 	"golang.org/x/sync/errgroup"
 
@@ -33,7 +34,6 @@ type APIListener struct {
 	*Server
 
 	fingerprint       string
-	apiSessionRepo    *APISessionRepository
 	router            *mux.Router
 	httpServer        *chshare.HTTPServer
 	requestLogOptions *requestlog.Options
@@ -70,7 +70,7 @@ func NewAPIListener(
 		}
 		userService = users.NewUserCache([]*users.User{authUser})
 	} else if config.API.AuthUserTable != "" {
-		userDB, err := users.NewUserDatabase(server.db, config.API.AuthUserTable, config.API.AuthGroupTable)
+		userDB, err := users.NewUserDatabase(server.db, config.API.AuthUserTable, config.API.AuthGroupTable, config.Server.MultiTenancy)
 		if err != nil {
 			return nil, err
 		}
@@ -85,7 +85,6 @@ func NewAPIListener(
 		Server:            server,
 		Logger:            chshare.NewLogger("api-listener", config.Logging.LogOutput, config.Logging.LogLevel),
 		fingerprint:       fingerprint,
-		apiSessionRepo:    NewAPISessionRepository(),
 		httpServer:        chshare.NewHTTPServer(int(config.Server.MaxRequestBytes), chshare.WithTLS(config.API.CertFile, config.API.KeyFile)),
 		requestLogOptions: config.InitRequestLogOptions(),
 		userSrv:           userService,
@@ -194,7 +193,7 @@ func (al *APIListener) lookupUser(r *http.Request) (authorized bool, username st
 	}
 
 	if bearerToken, bearerAuthProvided := getBearerToken(r); bearerAuthProvided {
-		authorized, username, err = al.handleBearerToken(bearerToken)
+		authorized, username, err = al.handleBearerToken(bearerToken, realip.FromRequest(r))
 		return
 	}
 
@@ -206,13 +205,13 @@ func (al *APIListener) lookupUser(r *http.Request) (authorized bool, username st
 	return
 }
 
-func (al *APIListener) handleBearerToken(bearerToken string) (bool, string, error) {
+func (al *APIListener) handleBearerToken(bearerToken, ip string) (bool, string, error) {
 	authorized, username, apiSession, err := al.validateBearerToken(bearerToken)
 	if err != nil {
 		return false, username, err
 	}
 	if authorized {
-		if err := al.increaseSessionLifetime(apiSession); err != nil {
+		if err := al.increaseSessionLifetime(apiSession, ip); err != nil {
 			// do not return error since it should respond with 401 instead of 500, just log it
 			al.Errorf("Failed to increase jwt token lifetime: %v", err)
 		}
@@ -277,7 +276,7 @@ func (al *APIListener) wsAuth(f http.Handler) http.HandlerFunc {
 			return
 		}
 
-		authorized, username, err := al.handleBearerToken(token)
+		authorized, username, err := al.handleBearerToken(token, realip.FromRequest(r))
 		if err != nil {
 			if errors.Is(err, ErrTooManyRequests) {
 				al.jsonErrorResponse(w, http.StatusTooManyRequests, err)
@@ -0,0 +1,68 @@
+package chserver
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/cloudradar-monitoring/rport/server/api"
+)
+
+const (
+	PermissionTunnels      = "tunnels"
+	PermissionCommands     = "commands"
+	PermissionClientsAuth  = "clients_auth"
+	PermissionClientGroups = "client_groups"
+	PermissionAuditLog     = "audit_log"
+	PermissionFiles        = "files"
+
+	ErrCodeInsufficientPermissions = "ERR_CODE_INSUFFICIENT_PERMISSIONS"
+)
+
+// AllPermissions is a list of all permissions that can be mapped to user groups.
+var AllPermissions = []string{
+	PermissionTunnels,
+	PermissionCommands,
+	PermissionClientsAuth,
+	PermissionClientGroups,
+	PermissionAuditLog,
+	PermissionFiles,
+}
+
+// withPermission returns a handler that calls a given handler only if the current user is granted a given permission.
+// A permission is granted if the user belongs to at least one of the user groups the permission is mapped to.
+// If a permission is not mapped to any user group, it's granted to all authenticated users.
+func (al *APIListener) withPermission(permission string, f http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		if len(al.config.API.Permissions[permission]) == 0 {
+			f(w, req)
+			return
+		}
+
+		granted, err := al.hasPermission(api.GetUser(req.Context(), al.Logger), permission)
+		if err != nil {
+			al.jsonErrorResponse(w, http.StatusInternalServerError, err)
+			return
+		}
+
+		if !granted {
+			al.jsonErrorResponseWithErrCode(w, http.StatusForbidden, ErrCodeInsufficientPermissions, fmt.Sprintf("Permission %q is required.", permission))
+			return
+		}
+
+		f(w, req)
+	}
+}
+
+// hasPermission returns true if a user with a given username is granted a given permission.
+func (al *APIListener) hasPermission(username, permission string) (bool, error) {
+	allowedGroups := al.config.API.Permissions[permission]
+	if len(allowedGroups) == 0 {
+		return true, nil
+	}
+
+	user, err := al.userSrv.GetByUsername(username)
+	if err != nil {
+		return false, err
+	}
+	return user != nil && user.BelongsToOneOf(allowedGroups), nil
+}
@@ -0,0 +1,108 @@
+package chserver
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cloudradar-monitoring/rport/server/api"
+	"github.com/cloudradar-monitoring/rport/server/api/users"
+	"github.com/cloudradar-monitoring/rport/server/clientsauth"
+)
+
+func TestWithPermission(t *testing.T) {
+	admin := &users.User{Username: "admin", Groups: []string{"Admins"}}
+	helpdesk := &users.User{Username: "helpdesk", Groups: []string{"Helpdesk"}}
+	noGroups := &users.User{Username: "no-groups"}
+
+	testCases := []struct {
+		descr string
+
+		permissions map[string][]string
+		username    string
+
+		wantStatusCode int
+	}{
+		{
+			descr:          "no permissions configured",
+			permissions:    nil,
+			username:       helpdesk.Username,
+			wantStatusCode: http.StatusOK,
+		},
+		{
+			descr:          "permission is not mapped",
+			permissions:    map[string][]string{PermissionCommands: {"Admins"}},
+			username:       helpdesk.Username,
+			wantStatusCode: http.StatusOK,
+		},
+		{
+			descr:          "user belongs to allowed group",
+			permissions:    map[string][]string{PermissionClientsAuth: {"Operators", "Admins"}},
+			username:       admin.Username,
+			wantStatusCode: http.StatusOK,
+		},
+		{
+			descr:          "user does not belong to allowed group",
+			permissions:    map[string][]string{PermissionClientsAuth: {"Admins"}},
+			username:       helpdesk.Username,
+			wantStatusCode: http.StatusForbidden,
+		},
+		{
+			descr:          "user without groups",
+			permissions:    map[string][]string{PermissionClientsAuth: {"Admins"}},
+			username:       noGroups.Username,
+			wantStatusCode: http.StatusForbidden,
+		},
+		{
+			descr:          "unknown user",
+			permissions:    map[string][]string{PermissionClientsAuth: {"Admins"}},
+			username:       "unknown",
+			wantStatusCode: http.StatusForbidden,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.descr, func(t *testing.T) {
+			// given
+			al := APIListener{
+				insecureForTests: true,
+				Server: &Server{
+					config: &Config{
+						API: APIConfig{
+							Permissions: tc.permissions,
+						},
+						Server: ServerConfig{
+							MaxRequestBytes: 1024 * 1024,
+						},
+					},
+					clientAuthProvider: clientsauth.NewMockProvider(nil),
+				},
+				userSrv: users.NewUserCache([]*users.User{admin, helpdesk, noGroups}),
+				Logger:  testLog,
+			}
+			al.initRouter()
+
+			ctx := api.WithUser(context.Background(), tc.username)
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/clients-auth", nil)
+			req = req.WithContext(ctx)
+
+			// when
+			w := httptest.NewRecorder()
+			al.router.ServeHTTP(w, req)
+
+			// then
+			assert.Equal(t, tc.wantStatusCode, w.Code)
+			if tc.wantStatusCode == http.StatusForbidden {
+				wantResp := api.NewErrorPayloadWithCode(ErrCodeInsufficientPermissions, `Permission "clients_auth" is required.`, "")
+				wantRespBytes, err := json.Marshal(wantResp)
+				require.NoError(t, err)
+				assert.Equal(t, string(wantRespBytes), w.Body.String())
+			}
+		})
+	}
+}
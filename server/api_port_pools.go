@@ -0,0 +1,55 @@
+package chserver
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/cloudradar-monitoring/rport/server/api"
+	"github.com/cloudradar-monitoring/rport/server/clients"
+)
+
+// PortPoolPayload shows a configured port pool and how many of its ports are in use.
+type PortPoolPayload struct {
+	Name         string   `json:"name"`
+	Ports        []string `json:"ports"`
+	ClientGroups []string `json:"client_groups"`
+	UserGroups   []string `json:"user_groups"`
+	Total        int      `json:"total"`
+	Used         int      `json:"used"`
+	Available    int      `json:"available"`
+}
+
+// selectPortPool returns a port pool for random ports of tunnels that the current API user creates for a given client.
+func (al *APIListener) selectPortPool(ctx context.Context, client *clients.Client) (string, error) {
+	if len(al.config.Server.PortPools) == 0 {
+		return "", nil
+	}
+
+	user, err := al.getCurrentUser(ctx)
+	if err != nil {
+		return "", err
+	}
+	return al.clientService.SelectPortPool(ctx, client, user)
+}
+
+func (al *APIListener) handleGetPortPools(w http.ResponseWriter, req *http.Request) {
+	usage := al.clientService.PortPoolsUsage()
+	res := make([]PortPoolPayload, 0, len(usage))
+	for _, cur := range usage {
+		payload := PortPoolPayload{
+			Name:      cur.Name,
+			Total:     cur.Total,
+			Used:      cur.Total - cur.Available,
+			Available: cur.Available,
+		}
+		for _, pool := range al.config.Server.PortPools {
+			if pool.Name == cur.Name {
+				payload.Ports = pool.Ports
+				payload.ClientGroups = pool.ClientGroups
+				payload.UserGroups = pool.UserGroups
+			}
+		}
+		res = append(res, payload)
+	}
+	al.writeJSONResponse(w, http.StatusOK, api.NewSuccessPayload(res))
+}
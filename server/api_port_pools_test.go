@@ -0,0 +1,126 @@
+package chserver
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	mapset "github.com/deckarep/golang-set"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cloudradar-monitoring/rport/server/api"
+	"github.com/cloudradar-monitoring/rport/server/api/users"
+	"github.com/cloudradar-monitoring/rport/server/cgroups"
+	"github.com/cloudradar-monitoring/rport/server/clients"
+	"github.com/cloudradar-monitoring/rport/server/ports"
+)
+
+func TestPortPools(t *testing.T) {
+	ctx := context.Background()
+	c1 := clients.New(t).ID("client-1").Build()
+	c2 := clients.New(t).ID("client-2").Build()
+
+	groupProvider, err := cgroups.NewSqliteProvider("file:port-pools?mode=memory&cache=shared")
+	require.NoError(t, err)
+	defer groupProvider.Close()
+	require.NoError(t, groupProvider.Create(ctx, &cgroups.ClientGroup{
+		ID:     "monitoring",
+		Params: &cgroups.ClientParams{ClientID: &cgroups.ParamValues{"client-2"}},
+	}))
+
+	support := &users.User{Username: "support", Groups: []string{"Support"}}
+	admin := &users.User{Username: "admin", Groups: []string{"Admins"}}
+
+	config := &Config{
+		Server: ServerConfig{
+			MaxRequestBytes: 1024 * 1024,
+			PortPools: []PortPoolConfig{
+				{Name: "support", Ports: []string{"20000-20009"}, UserGroups: []string{"Support"}},
+				{Name: "monitoring", Ports: []string{"20010-20014"}, ClientGroups: []string{"monitoring", "unknown-group"}},
+			},
+		},
+	}
+	require.NoError(t, config.parseAndValidatePortPools())
+	clientService := NewClientService(
+		ports.NewPortDistributor(mapset.NewThreadUnsafeSet(), config.PortPools()...),
+		clients.NewClientRepository([]*clients.Client{c1, c2}, &hour),
+	)
+	clientService.portPools = config.Server.PortPools
+	clientService.clientGroupProvider = groupProvider
+	al := APIListener{
+		insecureForTests: true,
+		Server: &Server{
+			clientService:       clientService,
+			config:              config,
+			clientGroupProvider: groupProvider,
+		},
+		userSrv: users.NewUserCache([]*users.User{support, admin}),
+		Logger:  testLog,
+	}
+	al.initRouter()
+
+	t.Run("select port pool", func(t *testing.T) {
+		testCases := []struct {
+			username string
+			client   *clients.Client
+			wantPool string
+		}{
+			{
+				username: support.Username,
+				client:   c1,
+				wantPool: "support",
+			},
+			{
+				username: support.Username,
+				client:   c2,
+				wantPool: "support",
+			},
+			{
+				username: admin.Username,
+				client:   c2,
+				wantPool: "monitoring",
+			},
+			{
+				username: admin.Username,
+				client:   c1,
+				wantPool: "",
+			},
+		}
+
+		for _, tc := range testCases {
+			t.Run(tc.username+" "+tc.client.ID, func(t *testing.T) {
+				gotPool, err := al.selectPortPool(api.WithUser(ctx, tc.username), tc.client)
+
+				require.NoError(t, err)
+				assert.Equal(t, tc.wantPool, gotPool)
+			})
+		}
+	})
+
+	t.Run("local port reserved by another pool", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPut, "/api/v1/clients/client-1/tunnels?local=20010&remote=3389&check_port=0", nil)
+		req = req.WithContext(api.WithUser(ctx, support.Username))
+
+		w := httptest.NewRecorder()
+		al.router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.JSONEq(t, `{"errors":[{"code":"ERR_CODE_LOCAL_PORT_RESERVED","title":"Port 20010 is reserved for port pool \"monitoring\".","detail":""}]}`, w.Body.String())
+	})
+
+	t.Run("get port pools", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/port-pools", nil)
+		req = req.WithContext(api.WithUser(ctx, admin.Username))
+
+		w := httptest.NewRecorder()
+		al.router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.JSONEq(t, `{"data":[
+			{"name":"support","ports":["20000-20009"],"client_groups":null,"user_groups":["Support"],"total":10,"used":0,"available":10},
+			{"name":"monitoring","ports":["20010-20014"],"client_groups":["monitoring","unknown-group"],"user_groups":null,"total":5,"used":0,"available":5}
+		]}`, w.Body.String())
+	})
+}
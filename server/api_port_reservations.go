@@ -0,0 +1,77 @@
+package chserver
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+
+	"github.com/cloudradar-monitoring/rport/server/api"
+	"github.com/cloudradar-monitoring/rport/server/auditlog"
+)
+
+// handleGetClientPortReservations returns random ports that are kept for tunnels of a client. The client might be
+// already deleted, its reservations are kept until they are released.
+func (al *APIListener) handleGetClientPortReservations(w http.ResponseWriter, req *http.Request) {
+	clientID := mux.Vars(req)[routeParamClientID]
+	if !al.checkClientIDAccess(w, req, clientID) {
+		return
+	}
+
+	res, err := al.clientService.GetPortReservations(clientID)
+	if err != nil {
+		al.jsonErrorResponse(w, http.StatusInternalServerError, err)
+		return
+	}
+	al.writeJSONResponse(w, http.StatusOK, api.NewSuccessPayload(res))
+}
+
+// handleDeleteClientPortReservations releases all ports reserved by a client.
+func (al *APIListener) handleDeleteClientPortReservations(w http.ResponseWriter, req *http.Request) {
+	clientID := mux.Vars(req)[routeParamClientID]
+	if !al.checkClientIDAccess(w, req, clientID) {
+		return
+	}
+
+	if err := al.clientService.DeleteClientPortReservations(clientID); err != nil {
+		al.jsonErrorResponse(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	al.saveAuditLog(req, auditlog.ActionTunnelDelete, clientID, auditlog.Params{
+		"port_reservations": "all",
+	})
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleDeleteClientPortReservation releases a port reserved by a client, so it can be given out to other tunnels.
+func (al *APIListener) handleDeleteClientPortReservation(w http.ResponseWriter, req *http.Request) {
+	vars := mux.Vars(req)
+	clientID := vars[routeParamClientID]
+	port, err := strconv.Atoi(vars[routeParamPort])
+	if err != nil {
+		al.jsonErrorResponseWithErrCode(w, http.StatusBadRequest, ErrCodeInvalidRequest, fmt.Sprintf("Invalid port: %q.", vars[routeParamPort]))
+		return
+	}
+	if !al.checkClientIDAccess(w, req, clientID) {
+		return
+	}
+
+	deleted, err := al.clientService.DeletePortReservation(clientID, port)
+	if err != nil {
+		al.jsonErrorResponse(w, http.StatusInternalServerError, err)
+		return
+	}
+	if !deleted {
+		al.jsonErrorResponseWithTitle(w, http.StatusNotFound, fmt.Sprintf("Port %d is not reserved by client with id=%q.", port, clientID))
+		return
+	}
+
+	al.saveAuditLog(req, auditlog.ActionTunnelDelete, clientID, auditlog.Params{
+		"port_reservation": port,
+	})
+
+	w.WriteHeader(http.StatusNoContent)
+}
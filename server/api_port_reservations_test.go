@@ -0,0 +1,87 @@
+package chserver
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	mapset "github.com/deckarep/golang-set"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cloudradar-monitoring/rport/server/api"
+	"github.com/cloudradar-monitoring/rport/server/api/users"
+	"github.com/cloudradar-monitoring/rport/server/clients"
+	"github.com/cloudradar-monitoring/rport/server/ports"
+	chshare "github.com/cloudradar-monitoring/rport/share"
+)
+
+func TestHandlePortReservations(t *testing.T) {
+	ctx := api.WithUser(context.Background(), "admin")
+	provider, err := clients.NewSqliteProvider(":memory:", hour)
+	require.NoError(t, err)
+	defer provider.Close()
+	createdAt := time.Date(2021, 5, 1, 10, 0, 0, 0, time.UTC)
+	r1 := clients.NewPortReservation("client-1", &chshare.Remote{RemoteHost: "192.168.1.1", RemotePort: "22"}, 20001)
+	r2 := clients.NewPortReservation("client-1", &chshare.Remote{RemoteHost: "192.168.1.1", RemotePort: "53", Protocol: chshare.ProtocolUDP}, 20002)
+	r3 := clients.NewPortReservation("client-2", &chshare.Remote{RemoteHost: "192.168.1.1", RemotePort: "22"}, 20003)
+	for _, r := range []*clients.PortReservation{r1, r2, r3} {
+		r.CreatedAt = createdAt
+		require.NoError(t, provider.SavePortReservation(ctx, r))
+	}
+
+	clientService := NewClientService(
+		ports.NewPortDistributor(mapset.NewThreadUnsafeSet()),
+		clients.NewClientRepository(nil, &hour),
+	)
+	clientService.portReservations = provider
+	al := APIListener{
+		insecureForTests: true,
+		Server: &Server{
+			clientService: clientService,
+			config:        &Config{},
+		},
+		userSrv: users.NewUserCache([]*users.User{{Username: "admin"}}),
+		Logger:  testLog,
+	}
+	al.initRouter()
+
+	do := func(method, url string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(method, url, nil).WithContext(ctx)
+		al.router.ServeHTTP(w, req)
+		return w
+	}
+
+	// reservations of a deleted client are returned too
+	w := do(http.MethodGet, "/api/v1/clients/client-1/port-reservations")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"data":[
+		{"client_id":"client-1","remote":"192.168.1.1:22","protocol":"tcp","port":20001,"created_at":"2021-05-01T10:00:00Z"},
+		{"client_id":"client-1","remote":"192.168.1.1:53","protocol":"udp","port":20002,"created_at":"2021-05-01T10:00:00Z"}
+	]}`, w.Body.String())
+
+	w = do(http.MethodDelete, "/api/v1/clients/client-1/port-reservations/abc")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = do(http.MethodDelete, "/api/v1/clients/client-1/port-reservations/20003")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w = do(http.MethodDelete, "/api/v1/clients/client-1/port-reservations/20001")
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	got, err := clientService.GetPortReservations("client-1")
+	require.NoError(t, err)
+	require.Len(t, got, 1)
+	assert.Equal(t, 20002, got[0].Port)
+
+	w = do(http.MethodDelete, "/api/v1/clients/client-1/port-reservations")
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	w = do(http.MethodGet, "/api/v1/clients/client-1/port-reservations")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"data":[]}`, w.Body.String())
+	got, err = clientService.GetPortReservations("client-2")
+	require.NoError(t, err)
+	assert.Len(t, got, 1, "reservations of other clients should be kept")
+}
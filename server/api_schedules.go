@@ -0,0 +1,352 @@
+package chserver
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/cloudradar-monitoring/rport/server/api"
+	"github.com/cloudradar-monitoring/rport/server/auditlog"
+	"github.com/cloudradar-monitoring/rport/server/cgroups"
+	"github.com/cloudradar-monitoring/rport/server/clients"
+	"github.com/cloudradar-monitoring/rport/server/schedules"
+	"github.com/cloudradar-monitoring/rport/share/models"
+	"github.com/cloudradar-monitoring/rport/share/random"
+)
+
+const (
+	routeParamScheduleID = "schedule_id"
+
+	// scheduleCheckInterval defines how often schedules are checked. It's less than a minute to run them in time.
+	scheduleCheckInterval = 10 * time.Second
+)
+
+var generateNewScheduleID = func() string {
+	return random.UUID4()
+}
+
+type scheduleRequest struct {
+	Name     string `json:"name"`
+	Schedule string `json:"schedule"`
+	multiClientCmdRequest
+}
+
+func (al *APIListener) handleGetSchedules(w http.ResponseWriter, req *http.Request) {
+	tenant, err := al.getTenant(req.Context())
+	if err != nil {
+		al.jsonErrorResponse(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	all, err := al.scheduleProvider.GetAll(req.Context())
+	if err != nil {
+		al.jsonErrorResponseWithError(w, http.StatusInternalServerError, "", "Failed to get schedules.", err)
+		return
+	}
+
+	res := make([]*schedules.Schedule, 0, len(all))
+	for _, cur := range all {
+		if matchesTenant(tenant, cur.Tenant) {
+			res = append(res, cur)
+		}
+	}
+
+	al.writeJSONResponse(w, http.StatusOK, api.NewSuccessPayload(res))
+}
+
+func (al *APIListener) handleGetSchedule(w http.ResponseWriter, req *http.Request) {
+	schedule := al.getExistingSchedule(w, req)
+	if schedule == nil {
+		return
+	}
+	al.writeJSONResponse(w, http.StatusOK, api.NewSuccessPayload(schedule))
+}
+
+func (al *APIListener) handlePostSchedules(w http.ResponseWriter, req *http.Request) {
+	schedule := al.parseScheduleRequest(w, req)
+	if schedule == nil {
+		return
+	}
+	schedule.ID = generateNewScheduleID()
+	schedule.CreatedAt = time.Now()
+
+	if err := al.scheduleProvider.Save(req.Context(), schedule); err != nil {
+		al.jsonErrorResponseWithError(w, http.StatusInternalServerError, "", "Failed to persist a new schedule.", err)
+		return
+	}
+
+	al.writeJSONResponse(w, http.StatusCreated, api.NewSuccessPayload(schedule))
+	al.Debugf("Schedule[id=%q] created.", schedule.ID)
+	al.saveAuditLog(req, auditlog.ActionScheduleCreate, "", scheduleAuditLogParams(schedule))
+}
+
+func (al *APIListener) handlePutSchedule(w http.ResponseWriter, req *http.Request) {
+	existing := al.getExistingSchedule(w, req)
+	if existing == nil {
+		return
+	}
+	schedule := al.parseScheduleRequest(w, req)
+	if schedule == nil {
+		return
+	}
+	schedule.ID = existing.ID
+	schedule.CreatedAt = existing.CreatedAt
+
+	if err := al.scheduleProvider.Save(req.Context(), schedule); err != nil {
+		al.jsonErrorResponseWithError(w, http.StatusInternalServerError, "", "Failed to persist schedule.", err)
+		return
+	}
+
+	al.writeJSONResponse(w, http.StatusOK, api.NewSuccessPayload(schedule))
+	al.Debugf("Schedule[id=%q] updated.", schedule.ID)
+	al.saveAuditLog(req, auditlog.ActionScheduleUpdate, "", scheduleAuditLogParams(schedule))
+}
+
+func (al *APIListener) handleDeleteSchedule(w http.ResponseWriter, req *http.Request) {
+	existing := al.getExistingSchedule(w, req)
+	if existing == nil {
+		return
+	}
+
+	if err := al.scheduleProvider.Delete(req.Context(), existing.ID); err != nil {
+		al.jsonErrorResponseWithError(w, http.StatusInternalServerError, "", fmt.Sprintf("Failed to delete schedule[id=%q].", existing.ID), err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+	al.Debugf("Schedule[id=%q] deleted.", existing.ID)
+	al.saveAuditLog(req, auditlog.ActionScheduleDelete, "", auditlog.Params{"schedule_id": existing.ID})
+}
+
+// getExistingSchedule returns a schedule of the current tenant with an id from the route params.
+// If it's not found, an error response is written and nil is returned.
+func (al *APIListener) getExistingSchedule(w http.ResponseWriter, req *http.Request) *schedules.Schedule {
+	id := mux.Vars(req)[routeParamScheduleID]
+	if id == "" {
+		al.jsonErrorResponseWithTitle(w, http.StatusBadRequest, fmt.Sprintf("Missing %q route param.", routeParamScheduleID))
+		return nil
+	}
+
+	tenant, err := al.getTenant(req.Context())
+	if err != nil {
+		al.jsonErrorResponse(w, http.StatusInternalServerError, err)
+		return nil
+	}
+
+	schedule, err := al.scheduleProvider.Get(req.Context(), id)
+	if err != nil {
+		al.jsonErrorResponseWithError(w, http.StatusInternalServerError, "", fmt.Sprintf("Failed to find schedule[id=%q].", id), err)
+		return nil
+	}
+	if schedule == nil || !matchesTenant(tenant, schedule.Tenant) {
+		al.jsonErrorResponseWithTitle(w, http.StatusNotFound, fmt.Sprintf("Schedule[id=%q] not found.", id))
+		return nil
+	}
+	return schedule
+}
+
+// parseScheduleRequest returns a schedule from a given request body that is validated with the access rights of
+// the current user. If it's invalid, an error response is written and nil is returned.
+func (al *APIListener) parseScheduleRequest(w http.ResponseWriter, req *http.Request) *schedules.Schedule {
+	ctx := req.Context()
+	reqBody := scheduleRequest{}
+	dec := json.NewDecoder(req.Body)
+	dec.DisallowUnknownFields()
+	err := dec.Decode(&reqBody)
+	if err == io.EOF { // is handled separately to return an informative error message
+		al.jsonErrorResponseWithTitle(w, http.StatusBadRequest, "Missing body with json data.")
+		return nil
+	} else if err != nil {
+		al.jsonErrorResponseWithError(w, http.StatusBadRequest, "", "Invalid JSON data.", err)
+		return nil
+	}
+
+	if _, err := schedules.ParseCron(reqBody.Schedule); err != nil {
+		al.jsonErrorResponseWithError(w, http.StatusBadRequest, "", "Invalid schedule.", err)
+		return nil
+	}
+	if title, err := validateCmd(reqBody.Command, reqBody.Shell, reqBody.Script, reqBody.Interpreter); title != "" {
+		al.jsonErrorResponseWithError(w, http.StatusBadRequest, "", title, err)
+		return nil
+	}
+	if len(reqBody.ClientIDs) == 0 && len(reqBody.GroupIDs) == 0 {
+		al.jsonErrorResponseWithTitle(w, http.StatusBadRequest, "At least one client or group should be specified.")
+		return nil
+	}
+
+	access, err := al.getClientAccess(ctx)
+	if err != nil {
+		al.jsonErrorResponse(w, http.StatusInternalServerError, err)
+		return nil
+	}
+	for _, groupID := range reqBody.GroupIDs {
+		group, err := al.clientGroupProvider.Get(ctx, groupID)
+		if err != nil {
+			al.jsonErrorResponseWithError(w, http.StatusInternalServerError, "", fmt.Sprintf("Failed to get a client group with id=%q.", groupID), err)
+			return nil
+		}
+		if group == nil || !matchesTenant(access.tenant, group.Tenant) {
+			al.jsonErrorResponseWithTitle(w, http.StatusBadRequest, fmt.Sprintf("Unknown group with id=%q.", groupID))
+			return nil
+		}
+	}
+	// clients don't need to be active, they are checked on each run
+	for _, cid := range reqBody.ClientIDs {
+		client, err := al.clientService.GetByID(cid)
+		if err != nil {
+			al.jsonErrorResponseWithError(w, http.StatusInternalServerError, "", fmt.Sprintf("Failed to find a client with id=%q.", cid), err)
+			return nil
+		}
+		if client == nil || !access.IsVisible(client) {
+			al.jsonErrorResponseWithTitle(w, http.StatusNotFound, fmt.Sprintf("Client with id=%q not found.", cid))
+			return nil
+		}
+		if !access.IsAllowed(client) {
+			al.jsonErrorResponseWithErrCode(w, http.StatusForbidden, ErrCodeClientAccessDenied, fmt.Sprintf("Access to client with id=%q is denied.", client.ID))
+			return nil
+		}
+	}
+
+	if reqBody.TimeoutSec <= 0 {
+		reqBody.TimeoutSec = al.config.Server.RunRemoteCmdTimeoutSec
+	}
+	// by default abortOnErr is true
+	abortOnErr := true
+	if reqBody.AbortOnError != nil {
+		abortOnErr = *reqBody.AbortOnError
+	}
+
+	return &schedules.Schedule{
+		Name:      reqBody.Name,
+		Schedule:  reqBody.Schedule,
+		CreatedBy: api.GetUser(ctx, al.Logger),
+		Details: schedules.Details{
+			ClientIDs:   reqBody.ClientIDs,
+			GroupIDs:    reqBody.GroupIDs,
+			Command:     reqBody.Command,
+			Shell:       reqBody.Shell,
+			Interpreter: reqBody.Interpreter,
+			Script:      reqBody.Script,
+			TimeoutSec:  reqBody.TimeoutSec,
+			Concurrent:  reqBody.ExecuteConcurrently,
+			AbortOnErr:  abortOnErr,
+		},
+		Tenant: tenantOf(access.tenant),
+	}
+}
+
+func scheduleAuditLogParams(s *schedules.Schedule) auditlog.Params {
+	return auditlog.Params{
+		"schedule_id":  s.ID,
+		"name":         s.Name,
+		"schedule":     s.Schedule,
+		"client_ids":   s.ClientIDs,
+		"group_ids":    s.GroupIDs,
+		"command":      s.Command,
+		"shell":        s.Shell,
+		"interpreter":  s.Interpreter,
+		"script":       s.Script,
+		"timeout_sec":  s.TimeoutSec,
+		"concurrent":   s.Concurrent,
+		"abort_on_err": s.AbortOnErr,
+	}
+}
+
+// runSchedule creates and executes a multi-client job of a given schedule. Clients are resolved on each run with
+// the access rights of the user that saved the schedule. Clients that are not active are skipped. The run is skipped
+// if the user is not granted the commands permission anymore.
+func (al *APIListener) runSchedule(ctx context.Context, s *schedules.Schedule) error {
+	granted, err := al.hasPermission(s.CreatedBy, PermissionCommands)
+	if err != nil {
+		return err
+	}
+	if !granted {
+		return fmt.Errorf("user %q is not granted the %q permission", s.CreatedBy, PermissionCommands)
+	}
+
+	access, err := al.getClientAccess(api.WithUser(ctx, s.CreatedBy))
+	if err != nil {
+		return err
+	}
+
+	orderedClients, err := al.getScheduleClients(ctx, s, access)
+	if err != nil {
+		return err
+	}
+	if len(orderedClients) == 0 {
+		return errors.New("no active clients found")
+	}
+
+	scheduleID := s.ID
+	multiJob := &models.MultiJob{
+		MultiJobSummary: models.MultiJobSummary{
+			JID:       generateNewJobID(),
+			StartedAt: time.Now(),
+			CreatedBy: s.CreatedBy,
+			Tenant:    s.Tenant,
+		},
+		ClientIDs:   s.ClientIDs,
+		GroupIDs:    s.GroupIDs,
+		Command:     s.Command,
+		Shell:       s.Shell,
+		Interpreter: s.Interpreter,
+		Script:      s.Script,
+		TimeoutSec:  s.TimeoutSec,
+		Concurrent:  s.Concurrent,
+		AbortOnErr:  s.AbortOnErr,
+		ScheduleID:  &scheduleID,
+	}
+	if err := al.jobProvider.SaveMultiJob(multiJob); err != nil {
+		return fmt.Errorf("failed to persist a new multi-client job: %v", err)
+	}
+
+	al.Debugf("Multi-client Job[id=%q] created by schedule[id=%q] to execute remote command on %d clients.", multiJob.JID, s.ID, len(orderedClients))
+
+	go al.executeMultiClientJob(multiJob, orderedClients)
+	return nil
+}
+
+// getScheduleClients returns active clients of a given schedule that can be accessed with a given access.
+func (al *APIListener) getScheduleClients(ctx context.Context, s *schedules.Schedule, access *clientAccess) ([]*clients.Client, error) {
+	var groups []*cgroups.ClientGroup
+	for _, groupID := range s.GroupIDs {
+		group, err := al.clientGroupProvider.Get(ctx, groupID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get a client group with id=%q: %v", groupID, err)
+		}
+		if group == nil || !matchesTenant(access.tenant, group.Tenant) {
+			al.Infof("Schedule[id=%q]: unknown group with id=%q is skipped.", s.ID, groupID)
+			continue
+		}
+		groups = append(groups, group)
+	}
+
+	var res []*clients.Client
+	usedClientIDs := make(map[string]bool)
+	for _, cid := range s.ClientIDs {
+		client, err := al.clientService.GetByID(cid)
+		if err != nil {
+			return nil, fmt.Errorf("failed to find a client with id=%q: %v", cid, err)
+		}
+		if client == nil || client.DisconnectedAt != nil || !access.IsAllowed(client) {
+			al.Infof("Schedule[id=%q]: client with id=%q is skipped, it's not active or can't be accessed.", s.ID, cid)
+			continue
+		}
+		usedClientIDs[cid] = true
+		res = append(res, client)
+	}
+
+	for _, groupClient := range al.clientService.GetActiveByGroups(groups) {
+		if !usedClientIDs[groupClient.ID] && access.IsAllowed(groupClient) {
+			usedClientIDs[groupClient.ID] = true
+			res = append(res, groupClient)
+		}
+	}
+	return res, nil
+}
@@ -0,0 +1,319 @@
+package chserver
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cloudradar-monitoring/rport/server/api"
+	"github.com/cloudradar-monitoring/rport/server/api/jobs"
+	"github.com/cloudradar-monitoring/rport/server/api/users"
+	"github.com/cloudradar-monitoring/rport/server/cgroups"
+	"github.com/cloudradar-monitoring/rport/server/clients"
+	"github.com/cloudradar-monitoring/rport/server/schedules"
+	"github.com/cloudradar-monitoring/rport/share/comm"
+	"github.com/cloudradar-monitoring/rport/share/models"
+	"github.com/cloudradar-monitoring/rport/share/test"
+)
+
+func newSchedulesTestAPIListener(t *testing.T, clientList []*clients.Client) *APIListener {
+	ctx := context.Background()
+	groupProvider, err := cgroups.NewSqliteProvider(":memory:")
+	require.NoError(t, err)
+	require.NoError(t, groupProvider.Create(ctx, &cgroups.ClientGroup{ID: "group-1", Params: &cgroups.ClientParams{ClientID: &cgroups.ParamValues{"client-1"}}}))
+	scheduleProvider, err := schedules.NewSqliteProvider(":memory:")
+	require.NoError(t, err)
+	jobProvider, err := jobs.NewSqliteProvider(":memory:", testLog)
+	require.NoError(t, err)
+
+	al := &APIListener{
+		insecureForTests: true,
+		Server: &Server{
+			clientService: NewClientService(nil, clients.NewClientRepository(clientList, &hour)),
+			config: &Config{
+				Server: ServerConfig{
+					RunRemoteCmdTimeoutSec: 60,
+					MaxRequestBytes:        1024 * 1024,
+				},
+			},
+			clientGroupProvider: groupProvider,
+			scheduleProvider:    scheduleProvider,
+			jobProvider:         jobProvider,
+			jobsDoneChannel: jobResultChanMap{
+				m: make(map[string]chan *models.Job),
+			},
+		},
+		Logger: testLog,
+	}
+	al.initRouter()
+	return al
+}
+
+func TestHandlePostSchedules(t *testing.T) {
+	c1 := clients.New(t).ID("client-1").Build()
+	c2 := clients.New(t).ID("client-2").DisconnectedDuration(5 * time.Minute).Build()
+	generateNewScheduleID = func() string {
+		return "schedule-1"
+	}
+
+	testCases := []struct {
+		name           string
+		requestBody    string
+		wantStatusCode int
+		wantErrTitle   string
+		wantErrDetail  string
+		wantSchedule   *schedules.Schedule
+	}{
+		{
+			name:           "valid command",
+			requestBody:    `{"name":"uptime","schedule":"*/5 * * * *","command":"uptime","client_ids":["client-1","client-2"],"group_ids":["group-1"]}`,
+			wantStatusCode: http.StatusCreated,
+			wantSchedule: &schedules.Schedule{
+				ID:        "schedule-1",
+				Name:      "uptime",
+				Schedule:  "*/5 * * * *",
+				CreatedBy: "test-user",
+				Details: schedules.Details{
+					ClientIDs:  []string{"client-1", "client-2"},
+					GroupIDs:   []string{"group-1"},
+					Command:    "uptime",
+					TimeoutSec: 60,
+					AbortOnErr: true,
+				},
+			},
+		},
+		{
+			name:           "valid script",
+			requestBody:    `{"schedule":"@daily","script":"echo hello","interpreter":"bash","group_ids":["group-1"],"timeout_sec":10,"execute_concurrently":true,"abort_on_error":false}`,
+			wantStatusCode: http.StatusCreated,
+			wantSchedule: &schedules.Schedule{
+				ID:        "schedule-1",
+				Schedule:  "@daily",
+				CreatedBy: "test-user",
+				Details: schedules.Details{
+					GroupIDs:    []string{"group-1"},
+					Script:      "echo hello",
+					Interpreter: "bash",
+					TimeoutSec:  10,
+					Concurrent:  true,
+				},
+			},
+		},
+		{
+			name:           "missing schedule",
+			requestBody:    `{"command":"uptime","client_ids":["client-1"]}`,
+			wantStatusCode: http.StatusBadRequest,
+			wantErrTitle:   "Invalid schedule.",
+			wantErrDetail:  "expected 5 fields, got 0",
+		},
+		{
+			name:           "invalid schedule",
+			requestBody:    `{"schedule":"61 * * * *","command":"uptime","client_ids":["client-1"]}`,
+			wantStatusCode: http.StatusBadRequest,
+			wantErrTitle:   "Invalid schedule.",
+			wantErrDetail:  "minute should be between 0 and 59, got 61",
+		},
+		{
+			name:           "missing command",
+			requestBody:    `{"schedule":"* * * * *","client_ids":["client-1"]}`,
+			wantStatusCode: http.StatusBadRequest,
+			wantErrTitle:   "Command cannot be empty.",
+		},
+		{
+			name:           "no clients",
+			requestBody:    `{"schedule":"* * * * *","command":"uptime"}`,
+			wantStatusCode: http.StatusBadRequest,
+			wantErrTitle:   "At least one client or group should be specified.",
+		},
+		{
+			name:           "unknown group",
+			requestBody:    `{"schedule":"* * * * *","command":"uptime","group_ids":["group-2"]}`,
+			wantStatusCode: http.StatusBadRequest,
+			wantErrTitle:   `Unknown group with id="group-2".`,
+		},
+		{
+			name:           "unknown client",
+			requestBody:    `{"schedule":"* * * * *","command":"uptime","client_ids":["client-3"]}`,
+			wantStatusCode: http.StatusNotFound,
+			wantErrTitle:   `Client with id="client-3" not found.`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// given
+			al := newSchedulesTestAPIListener(t, []*clients.Client{c1, c2})
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/schedules", strings.NewReader(tc.requestBody))
+			req = req.WithContext(api.WithUser(context.Background(), "test-user"))
+
+			// when
+			w := httptest.NewRecorder()
+			al.router.ServeHTTP(w, req)
+
+			// then
+			require.Equal(t, tc.wantStatusCode, w.Code)
+			if tc.wantSchedule == nil {
+				wantResp := api.NewErrorPayloadWithCode("", tc.wantErrTitle, tc.wantErrDetail)
+				wantRespBytes, err := json.Marshal(wantResp)
+				require.NoError(t, err)
+				assert.Equal(t, string(wantRespBytes), w.Body.String())
+				return
+			}
+
+			gotSchedule, err := al.scheduleProvider.Get(context.Background(), "schedule-1")
+			require.NoError(t, err)
+			require.NotNil(t, gotSchedule)
+			assert.WithinDuration(t, time.Now(), gotSchedule.CreatedAt, time.Minute)
+			tc.wantSchedule.CreatedAt = gotSchedule.CreatedAt
+			assert.Equal(t, tc.wantSchedule, gotSchedule)
+
+			wantResp, err := json.Marshal(api.NewSuccessPayload(tc.wantSchedule))
+			require.NoError(t, err)
+			assert.JSONEq(t, string(wantResp), w.Body.String())
+		})
+	}
+}
+
+func TestHandleUpdateAndDeleteSchedule(t *testing.T) {
+	// given
+	ctx := api.WithUser(context.Background(), "test-user")
+	c1 := clients.New(t).ID("client-1").Build()
+	al := newSchedulesTestAPIListener(t, []*clients.Client{c1})
+	createdAt := time.Date(2021, 3, 15, 10, 30, 0, 0, time.UTC)
+	require.NoError(t, al.scheduleProvider.Save(ctx, &schedules.Schedule{
+		ID:        "schedule-1",
+		Schedule:  "* * * * *",
+		CreatedAt: createdAt,
+		CreatedBy: "admin",
+		Details: schedules.Details{
+			ClientIDs: []string{"client-1"},
+			Command:   "uptime",
+		},
+	}))
+
+	// when
+	req := httptest.NewRequest(http.MethodPut, "/api/v1/schedules/schedule-1", strings.NewReader(`{"schedule":"@hourly","command":"df -h","client_ids":["client-1"]}`))
+	w := httptest.NewRecorder()
+	al.router.ServeHTTP(w, req.WithContext(ctx))
+
+	// then
+	require.Equal(t, http.StatusOK, w.Code)
+	gotSchedule, err := al.scheduleProvider.Get(ctx, "schedule-1")
+	require.NoError(t, err)
+	assert.Equal(t, &schedules.Schedule{
+		ID:        "schedule-1",
+		Schedule:  "@hourly",
+		CreatedAt: createdAt,
+		CreatedBy: "test-user",
+		Details: schedules.Details{
+			ClientIDs:  []string{"client-1"},
+			Command:    "df -h",
+			TimeoutSec: 60,
+			AbortOnErr: true,
+		},
+	}, gotSchedule)
+
+	// when
+	req = httptest.NewRequest(http.MethodDelete, "/api/v1/schedules/schedule-1", nil)
+	w = httptest.NewRecorder()
+	al.router.ServeHTTP(w, req.WithContext(ctx))
+
+	// then
+	require.Equal(t, http.StatusNoContent, w.Code)
+	gotSchedule, err = al.scheduleProvider.Get(ctx, "schedule-1")
+	require.NoError(t, err)
+	assert.Nil(t, gotSchedule)
+
+	// when
+	req = httptest.NewRequest(http.MethodGet, "/api/v1/schedules/schedule-1", nil)
+	w = httptest.NewRecorder()
+	al.router.ServeHTTP(w, req.WithContext(ctx))
+
+	// then
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestRunSchedule(t *testing.T) {
+	// given
+	connMock := test.NewConnMock()
+	connMock.ReturnOk = true
+	respBytes, err := json.Marshal(comm.RunCmdResponse{Pid: 1, StartedAt: time.Date(2020, 10, 10, 10, 10, 1, 0, time.UTC)})
+	require.NoError(t, err)
+	connMock.ReturnResponsePayload = respBytes
+	c1 := clients.New(t).ID("client-1").Connection(connMock).Build()
+	c2 := clients.New(t).ID("client-2").DisconnectedDuration(5 * time.Minute).Build()
+	al := newSchedulesTestAPIListener(t, []*clients.Client{c1, c2})
+	done := make(chan bool)
+	al.testDone = done
+
+	s := &schedules.Schedule{
+		ID:        "schedule-1",
+		Schedule:  "* * * * *",
+		CreatedBy: "admin",
+		Details: schedules.Details{
+			ClientIDs:  []string{"client-2", "client-3"},
+			GroupIDs:   []string{"group-1"},
+			Command:    "uptime",
+			TimeoutSec: 30,
+		},
+	}
+
+	// when
+	err = al.runSchedule(context.Background(), s)
+	<-done
+
+	// then
+	require.NoError(t, err)
+	multiJobs, err := al.jobProvider.GetAllMultiJobSummaries()
+	require.NoError(t, err)
+	require.Len(t, multiJobs, 1)
+	gotMultiJob, err := al.jobProvider.GetMultiJob(multiJobs[0].JID)
+	require.NoError(t, err)
+	require.NotNil(t, gotMultiJob)
+	require.NotNil(t, gotMultiJob.ScheduleID)
+	assert.Equal(t, s.ID, *gotMultiJob.ScheduleID)
+	assert.Equal(t, "admin", gotMultiJob.CreatedBy)
+	assert.Equal(t, "uptime", gotMultiJob.Command)
+	require.Len(t, gotMultiJob.Jobs, 1)
+	assert.Equal(t, c1.ID, gotMultiJob.Jobs[0].ClientID)
+}
+
+func TestRunScheduleNoActiveClients(t *testing.T) {
+	c2 := clients.New(t).ID("client-2").DisconnectedDuration(5 * time.Minute).Build()
+	al := newSchedulesTestAPIListener(t, []*clients.Client{c2})
+
+	err := al.runSchedule(context.Background(), &schedules.Schedule{
+		ID:      "schedule-1",
+		Details: schedules.Details{ClientIDs: []string{c2.ID}, Command: "uptime"},
+	})
+
+	assert.EqualError(t, err, "no active clients found")
+	multiJobs, err := al.jobProvider.GetAllMultiJobSummaries()
+	require.NoError(t, err)
+	assert.Empty(t, multiJobs)
+}
+
+func TestRunScheduleWithoutPermission(t *testing.T) {
+	c1 := clients.New(t).ID("client-1").Build()
+	al := newSchedulesTestAPIListener(t, []*clients.Client{c1})
+	al.config.API.Permissions = map[string][]string{PermissionCommands: {"Admins"}}
+	al.userSrv = users.NewUserCache([]*users.User{{Username: "user1", Groups: []string{"Users"}}})
+
+	err := al.runSchedule(context.Background(), &schedules.Schedule{
+		ID:        "schedule-1",
+		CreatedBy: "user1",
+		Details:   schedules.Details{ClientIDs: []string{c1.ID}, Command: "uptime"},
+	})
+
+	assert.EqualError(t, err, `user "user1" is not granted the "commands" permission`)
+	multiJobs, err := al.jobProvider.GetAllMultiJobSummaries()
+	require.NoError(t, err)
+	assert.Empty(t, multiJobs)
+}
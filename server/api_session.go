@@ -1,6 +1,7 @@
 package chserver
 
 import (
+	"net"
 	"sync"
 	"time"
 )
@@ -8,6 +9,9 @@ import (
 type APISession struct {
 	Token     string
 	ExpiresAt time.Time
+	Username  string
+	// IP is the source IP of the last request of the session.
+	IP string
 }
 
 type APISessionRepository struct {
@@ -35,6 +39,23 @@ func (r *APISessionRepository) Delete(session *APISession) error {
 	return nil
 }
 
+// GetActiveIPsByUsername returns source IPs of the last requests of valid sessions of a given user.
+func (r *APISessionRepository) GetActiveIPsByUsername(username string) []net.IP {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	now := time.Now()
+	var res []net.IP
+	for _, s := range r.sessions {
+		if s.Username != username || !s.ExpiresAt.After(now) {
+			continue
+		}
+		if ip := net.ParseIP(s.IP); ip != nil {
+			res = append(res, ip)
+		}
+	}
+	return res
+}
+
 func (r *APISessionRepository) FindOne(id string) (*APISession, error) {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
@@ -0,0 +1,46 @@
+package chserver
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cloudradar-monitoring/rport/share/security"
+)
+
+func TestAPISessionRepositoryGetActiveIPsByUsername(t *testing.T) {
+	repo := NewAPISessionRepository()
+	future := time.Now().Add(time.Hour)
+	past := time.Now().Add(-time.Minute)
+	require.NoError(t, repo.Save(&APISession{Token: "1", ExpiresAt: future, Username: "admin", IP: "192.0.2.1"}))
+	require.NoError(t, repo.Save(&APISession{Token: "2", ExpiresAt: past, Username: "admin", IP: "192.0.2.2"}))
+	require.NoError(t, repo.Save(&APISession{Token: "3", ExpiresAt: future, Username: "user1", IP: "192.0.2.3"}))
+
+	assert.Equal(t, []net.IP{net.ParseIP("192.0.2.1")}, repo.GetActiveIPsByUsername("admin"))
+	assert.Empty(t, repo.GetActiveIPsByUsername("user2"))
+}
+
+func TestHandleBearerTokenRefreshesSessionIP(t *testing.T) {
+	al := APIListener{
+		Server: &Server{
+			config: &Config{
+				API: APIConfig{JWTSecret: "secret"},
+			},
+			apiSessionRepo: NewAPISessionRepository(),
+		},
+		bannedUsers: security.NewBanList(0),
+	}
+	token, err := al.createAuthToken(time.Hour, "admin", "192.0.2.1")
+	require.NoError(t, err)
+	assert.Equal(t, []net.IP{net.ParseIP("192.0.2.1")}, al.apiSessionRepo.GetActiveIPsByUsername("admin"))
+
+	authorized, username, err := al.handleBearerToken(token, "192.0.2.2")
+
+	require.NoError(t, err)
+	assert.True(t, authorized)
+	assert.Equal(t, "admin", username)
+	assert.Equal(t, []net.IP{net.ParseIP("192.0.2.2")}, al.apiSessionRepo.GetActiveIPsByUsername("admin"))
+}
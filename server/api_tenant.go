@@ -0,0 +1,60 @@
+package chserver
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/cloudradar-monitoring/rport/server/api"
+	"github.com/cloudradar-monitoring/rport/server/api/users"
+)
+
+// getCurrentUser returns the currently logged in API user.
+func (al *APIListener) getCurrentUser(ctx context.Context) (*users.User, error) {
+	username := api.GetUser(ctx, al.Logger)
+	user, err := al.userSrv.GetByUsername(username)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, fmt.Errorf("user %q not found", username)
+	}
+	return user, nil
+}
+
+// userTenant returns a tenant of a given user or nil if multi-tenancy is disabled.
+func (al *APIListener) userTenant(user *users.User) *string {
+	if !al.config.Server.MultiTenancy {
+		return nil
+	}
+	return &user.Tenant
+}
+
+// getTenant returns a tenant of the current API user or nil if multi-tenancy is disabled.
+func (al *APIListener) getTenant(ctx context.Context) (*string, error) {
+	if !al.config.Server.MultiTenancy {
+		return nil, nil
+	}
+	user, err := al.getCurrentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return al.userTenant(user), nil
+}
+
+// matchesTenant returns true if an object with a given tenant is visible to a user of a given tenant.
+// Nil tenant means multi-tenancy is disabled and everything is visible. Objects without a tenant are orphaned
+// and a user without a tenant can't see anything.
+func matchesTenant(tenant *string, objTenant string) bool {
+	if tenant == nil {
+		return true
+	}
+	return *tenant != "" && objTenant == *tenant
+}
+
+// tenantOf returns a tenant to be stored with newly created objects.
+func tenantOf(tenant *string) string {
+	if tenant == nil {
+		return ""
+	}
+	return *tenant
+}
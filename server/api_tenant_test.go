@@ -0,0 +1,178 @@
+package chserver
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cloudradar-monitoring/rport/server/api"
+	"github.com/cloudradar-monitoring/rport/server/api/jobs"
+	"github.com/cloudradar-monitoring/rport/server/api/users"
+	"github.com/cloudradar-monitoring/rport/server/cgroups"
+	"github.com/cloudradar-monitoring/rport/server/clients"
+	"github.com/cloudradar-monitoring/rport/server/clientsauth"
+	"github.com/cloudradar-monitoring/rport/server/schedules"
+	"github.com/cloudradar-monitoring/rport/share/models"
+)
+
+func TestMultiTenancy(t *testing.T) {
+	ctx := context.Background()
+	c1 := clients.New(t).ID("client-1").Build()
+	c1.Tenant = "tenant-1"
+	c2 := clients.New(t).ID("client-2").Build()
+	c2.Tenant = "tenant-2"
+	c3 := clients.New(t).ID("client-3").Build()
+
+	groupProvider, err := cgroups.NewSqliteProvider("file:multi-tenancy?mode=memory&cache=shared")
+	require.NoError(t, err)
+	defer groupProvider.Close()
+	allClientsParams := &cgroups.ClientParams{ClientID: &cgroups.ParamValues{"*"}}
+	require.NoError(t, groupProvider.Create(ctx, &cgroups.ClientGroup{ID: "group-1", Params: allClientsParams, Tenant: "tenant-1"}))
+	require.NoError(t, groupProvider.Create(ctx, &cgroups.ClientGroup{ID: "group-2", Params: allClientsParams, Tenant: "tenant-2"}))
+
+	jobProvider, err := jobs.NewSqliteProvider("file:multi-tenancy-jobs?mode=memory&cache=shared", testLog)
+	require.NoError(t, err)
+	defer jobProvider.Close()
+	require.NoError(t, jobProvider.SaveMultiJob(&models.MultiJob{MultiJobSummary: models.MultiJobSummary{JID: "multi-job-1", Tenant: "tenant-1"}}))
+	require.NoError(t, jobProvider.SaveMultiJob(&models.MultiJob{MultiJobSummary: models.MultiJobSummary{JID: "multi-job-2", Tenant: "tenant-2"}}))
+
+	scheduleProvider, err := schedules.NewSqliteProvider(":memory:")
+	require.NoError(t, err)
+	defer scheduleProvider.Close()
+	require.NoError(t, scheduleProvider.Save(ctx, &schedules.Schedule{ID: "schedule-1", Tenant: "tenant-1"}))
+	require.NoError(t, scheduleProvider.Save(ctx, &schedules.Schedule{ID: "schedule-2", Tenant: "tenant-2"}))
+
+	user1 := &users.User{Username: "user1", Tenant: "tenant-1"}
+	user2 := &users.User{Username: "user2", Tenant: "tenant-2"}
+	noTenantUser := &users.User{Username: "no-tenant"}
+
+	al := APIListener{
+		insecureForTests: true,
+		Server: &Server{
+			clientService: NewClientService(nil, clients.NewClientRepository([]*clients.Client{c1, c2, c3}, &hour)),
+			config: &Config{
+				Server: ServerConfig{MaxRequestBytes: 1024 * 1024, MultiTenancy: true},
+			},
+			clientGroupProvider: groupProvider,
+			jobProvider:         jobProvider,
+			scheduleProvider:    scheduleProvider,
+			clientAuthProvider: clientsauth.NewMockProvider([]*clientsauth.ClientAuth{
+				{ID: "auth-1", Password: "pass-1", Tenant: "tenant-1"},
+				{ID: "auth-2", Password: "pass-2", Tenant: "tenant-2"},
+			}),
+		},
+		userSrv: users.NewUserCache([]*users.User{user1, user2, noTenantUser}),
+		Logger:  testLog,
+	}
+	al.initRouter()
+
+	getIDs := func(t *testing.T, username, url string) []string {
+		req := httptest.NewRequest(http.MethodGet, url, nil)
+		req = req.WithContext(api.WithUser(ctx, username))
+
+		w := httptest.NewRecorder()
+		al.router.ServeHTTP(w, req)
+
+		require.Equal(t, http.StatusOK, w.Code)
+		var gotResp struct {
+			Data []struct {
+				ID  string `json:"id"`
+				JID string `json:"jid"`
+			} `json:"data"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &gotResp))
+		var res []string
+		for _, cur := range gotResp.Data {
+			res = append(res, cur.ID+cur.JID)
+		}
+		return res
+	}
+
+	testCases := []struct {
+		username        string
+		wantClientIDs   []string
+		wantAuthIDs     []string
+		wantGroupIDs    []string
+		wantMultiJobIDs []string
+		wantScheduleIDs []string
+		wantStatusCodes map[string]int
+	}{
+		{
+			username:        user1.Username,
+			wantClientIDs:   []string{c1.ID},
+			wantAuthIDs:     []string{"auth-1"},
+			wantGroupIDs:    []string{"group-1"},
+			wantMultiJobIDs: []string{"multi-job-1"},
+			wantScheduleIDs: []string{"schedule-1"},
+			wantStatusCodes: map[string]int{
+				"/api/v1/client-groups/group-1":     http.StatusOK,
+				"/api/v1/client-groups/group-2":     http.StatusNotFound,
+				"/api/v1/commands/multi-job-1":      http.StatusOK,
+				"/api/v1/commands/multi-job-2":      http.StatusNotFound,
+				"/api/v1/schedules/schedule-1":      http.StatusOK,
+				"/api/v1/schedules/schedule-2":      http.StatusNotFound,
+				"/api/v1/clients/client-1/commands": http.StatusOK,
+				"/api/v1/clients/client-2/commands": http.StatusNotFound,
+			},
+		},
+		{
+			username:        user2.Username,
+			wantClientIDs:   []string{c2.ID},
+			wantAuthIDs:     []string{"auth-2"},
+			wantGroupIDs:    []string{"group-2"},
+			wantMultiJobIDs: []string{"multi-job-2"},
+			wantScheduleIDs: []string{"schedule-2"},
+			wantStatusCodes: map[string]int{
+				"/api/v1/client-groups/group-1":     http.StatusNotFound,
+				"/api/v1/clients/client-1/commands": http.StatusNotFound,
+			},
+		},
+		{
+			username: noTenantUser.Username,
+			wantStatusCodes: map[string]int{
+				"/api/v1/client-groups/group-1":     http.StatusNotFound,
+				"/api/v1/clients/client-3/commands": http.StatusNotFound,
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.username, func(t *testing.T) {
+			assert.ElementsMatch(t, tc.wantClientIDs, getIDs(t, tc.username, "/api/v1/clients"))
+			assert.ElementsMatch(t, tc.wantAuthIDs, getIDs(t, tc.username, "/api/v1/clients-auth"))
+			assert.ElementsMatch(t, tc.wantGroupIDs, getIDs(t, tc.username, "/api/v1/client-groups"))
+			assert.ElementsMatch(t, tc.wantMultiJobIDs, getIDs(t, tc.username, "/api/v1/commands"))
+			assert.ElementsMatch(t, tc.wantScheduleIDs, getIDs(t, tc.username, "/api/v1/schedules"))
+
+			for url, wantCode := range tc.wantStatusCodes {
+				req := httptest.NewRequest(http.MethodGet, url, nil)
+				req = req.WithContext(api.WithUser(ctx, tc.username))
+
+				w := httptest.NewRecorder()
+				al.router.ServeHTTP(w, req)
+
+				assert.Equal(t, wantCode, w.Code, url)
+			}
+		})
+	}
+
+	t.Run("group includes only clients of its tenant", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/client-groups/group-1", nil)
+		req = req.WithContext(api.WithUser(ctx, user1.Username))
+
+		w := httptest.NewRecorder()
+		al.router.ServeHTTP(w, req)
+
+		require.Equal(t, http.StatusOK, w.Code)
+		var gotResp struct {
+			Data cgroups.ClientGroup `json:"data"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &gotResp))
+		assert.Equal(t, []string{c1.ID}, gotResp.Data.ClientIDs)
+	})
+}
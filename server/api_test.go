@@ -41,12 +41,14 @@ type JobProviderMock struct {
 	JobProvider
 	ReturnJob          *models.Job
 	ReturnJobSummaries []*models.JobSummary
+	ReturnMultiJob     *models.MultiJob
 	ReturnErr          error
 
 	InputCID       string
 	InputJID       string
 	InputSaveJob   *models.Job
 	InputCreateJob *models.Job
+	InputOutput    *models.JobResult
 }
 
 func NewJobProviderMock() *JobProviderMock {
@@ -74,6 +76,17 @@ func (p *JobProviderMock) CreateJob(job *models.Job) error {
 	return p.ReturnErr
 }
 
+func (p *JobProviderMock) GetMultiJob(jid string) (*models.MultiJob, error) {
+	p.InputJID = jid
+	return p.ReturnMultiJob, p.ReturnErr
+}
+
+func (p *JobProviderMock) AppendOutput(jid string, output *models.JobResult) error {
+	p.InputJID = jid
+	p.InputOutput = output
+	return p.ReturnErr
+}
+
 func (p *JobProviderMock) Close() error {
 	return nil
 }
@@ -665,12 +678,15 @@ func TestHandlePostCommand(t *testing.T) {
 		runningJob      *models.Job
 		clients         []*clients.Client
 
-		wantStatusCode int
-		wantTimeout    int
-		wantErrCode    string
-		wantErrTitle   string
-		wantErrDetail  string
-		wantShell      string
+		wantStatusCode  int
+		wantTimeout     int
+		wantErrCode     string
+		wantErrTitle    string
+		wantErrDetail   string
+		wantShell       string
+		wantScript      string
+		wantInterpreter string
+		wantQueued      bool
 	}{
 		{
 			name:           "valid cmd",
@@ -698,6 +714,49 @@ func TestHandlePostCommand(t *testing.T) {
 			wantErrTitle:   "Invalid shell.",
 			wantErrDetail:  "expected shell to be one of: [cmd powershell], actual: unsupported",
 		},
+		{
+			name:            "valid script with interpreter",
+			requestBody:     `{"script": "#!/bin/bash\necho test\n", "interpreter": "bash"}`,
+			cid:             c1.ID,
+			clients:         []*clients.Client{c1},
+			wantStatusCode:  http.StatusOK,
+			wantTimeout:     defaultTimeout,
+			wantScript:      "#!/bin/bash\necho test\n",
+			wantInterpreter: "bash",
+		},
+		{
+			name:           "both cmd and script",
+			requestBody:    `{"command": "` + gotCmd + `", "script": "echo test"}`,
+			cid:            c1.ID,
+			clients:        []*clients.Client{c1},
+			wantStatusCode: http.StatusBadRequest,
+			wantErrTitle:   "Either command or script should be set, not both.",
+		},
+		{
+			name:           "script with shell",
+			requestBody:    `{"script": "echo test", "shell": "powershell"}`,
+			cid:            c1.ID,
+			clients:        []*clients.Client{c1},
+			wantStatusCode: http.StatusBadRequest,
+			wantErrTitle:   "Shell can be set only for a command, use interpreter for a script.",
+		},
+		{
+			name:           "cmd with interpreter",
+			requestBody:    `{"command": "` + gotCmd + `", "interpreter": "bash"}`,
+			cid:            c1.ID,
+			clients:        []*clients.Client{c1},
+			wantStatusCode: http.StatusBadRequest,
+			wantErrTitle:   "Interpreter can be set only for a script.",
+		},
+		{
+			name:           "invalid interpreter",
+			requestBody:    `{"script": "echo test", "interpreter": "perl"}`,
+			cid:            c1.ID,
+			clients:        []*clients.Client{c1},
+			wantStatusCode: http.StatusBadRequest,
+			wantErrTitle:   "Invalid interpreter.",
+			wantErrDetail:  "expected interpreter to be one of: [sh bash cmd powershell python], actual: perl",
+		},
 		{
 			name:           "valid cmd with no timeout",
 			requestBody:    `{"command": "/bin/date;foo;whoami"}`,
@@ -811,6 +870,16 @@ func TestHandlePostCommand(t *testing.T) {
 			wantStatusCode:  http.StatusConflict,
 			wantErrTitle:    "client error: fake failure msg",
 		},
+		{
+			name:           "queued cmd",
+			requestBody:    validReqBody,
+			connReturnResp: []byte(`{"Queued":true}`),
+			cid:            c1.ID,
+			clients:        []*clients.Client{c1},
+			wantStatusCode: http.StatusOK,
+			wantTimeout:    gotCmdTimeoutSec,
+			wantQueued:     true,
+		},
 	}
 
 	for _, tc := range testCases {
@@ -857,13 +926,24 @@ func TestHandlePostCommand(t *testing.T) {
 				gotRunningJob := jp.InputCreateJob
 				assert.NotNil(t, gotRunningJob)
 				assert.Equal(t, testJID, gotRunningJob.JID)
-				assert.Equal(t, models.JobStatusRunning, gotRunningJob.Status)
 				assert.Nil(t, gotRunningJob.FinishedAt)
 				assert.Equal(t, tc.cid, gotRunningJob.ClientID)
-				assert.Equal(t, gotCmd, gotRunningJob.Command)
+				if tc.wantScript != "" {
+					assert.Empty(t, gotRunningJob.Command)
+				} else {
+					assert.Equal(t, gotCmd, gotRunningJob.Command)
+				}
 				assert.Equal(t, tc.wantShell, gotRunningJob.Shell)
-				assert.Equal(t, &sshSuccessResp.Pid, gotRunningJob.PID)
-				assert.Equal(t, sshSuccessResp.StartedAt, gotRunningJob.StartedAt)
+				assert.Equal(t, tc.wantScript, gotRunningJob.Script)
+				assert.Equal(t, tc.wantInterpreter, gotRunningJob.Interpreter)
+				if tc.wantQueued {
+					assert.Equal(t, models.JobStatusQueued, gotRunningJob.Status)
+					assert.Nil(t, gotRunningJob.PID)
+				} else {
+					assert.Equal(t, models.JobStatusRunning, gotRunningJob.Status)
+					assert.Equal(t, &sshSuccessResp.Pid, gotRunningJob.PID)
+					assert.Equal(t, sshSuccessResp.StartedAt, gotRunningJob.StartedAt)
+				}
 				assert.Equal(t, testUser, gotRunningJob.CreatedBy)
 				assert.Equal(t, tc.wantTimeout, gotRunningJob.TimeoutSec)
 				assert.Nil(t, gotRunningJob.Result)
@@ -959,6 +1039,223 @@ func TestHandleGetCommand(t *testing.T) {
 	}
 }
 
+func TestHandleDeleteCommand(t *testing.T) {
+	connMock := test.NewConnMock()
+	c1 := clients.New(t).ID("cid-1234").Connection(connMock).Build()
+	runningJob := jb.New(t).ClientID(c1.ID).JID("jid-1234").Status(models.JobStatusRunning).Build()
+	finishedJob := jb.New(t).ClientID(c1.ID).JID("jid-1234").Status(models.JobStatusSuccessful).Build()
+
+	testCases := []struct {
+		name string
+
+		jpReturnJob     *models.Job
+		clients         []*clients.Client
+		connReturnErr   error
+		connReturnNotOk bool
+
+		wantStatusCode int
+		wantErrTitle   string
+		wantErrDetail  string
+	}{
+		{
+			name:           "running job",
+			jpReturnJob:    runningJob,
+			clients:        []*clients.Client{c1},
+			wantStatusCode: http.StatusNoContent,
+		},
+		{
+			name:           "queued job",
+			jpReturnJob:    jb.New(t).ClientID(c1.ID).JID("jid-1234").Status(models.JobStatusQueued).Build(),
+			clients:        []*clients.Client{c1},
+			wantStatusCode: http.StatusNoContent,
+		},
+		{
+			name:           "job with timeout exceeded",
+			jpReturnJob:    jb.New(t).ClientID(c1.ID).JID("jid-1234").Status(models.JobStatusUnknown).Build(),
+			clients:        []*clients.Client{c1},
+			wantStatusCode: http.StatusNoContent,
+		},
+		{
+			name:           "not found",
+			jpReturnJob:    nil,
+			clients:        []*clients.Client{c1},
+			wantStatusCode: http.StatusNotFound,
+			wantErrTitle:   fmt.Sprintf("Job[id=%q] not found.", runningJob.JID),
+		},
+		{
+			name:           "finished job",
+			jpReturnJob:    finishedJob,
+			clients:        []*clients.Client{c1},
+			wantStatusCode: http.StatusConflict,
+			wantErrTitle:   fmt.Sprintf("Job[id=%q] is not running.", runningJob.JID),
+		},
+		{
+			name:           "no active client",
+			jpReturnJob:    runningJob,
+			clients:        []*clients.Client{},
+			wantStatusCode: http.StatusNotFound,
+			wantErrTitle:   fmt.Sprintf("Active client with id=%q not found.", c1.ID),
+		},
+		{
+			name:            "failure response on send request",
+			jpReturnJob:     runningJob,
+			clients:         []*clients.Client{c1},
+			connReturnNotOk: true,
+			wantStatusCode:  http.StatusConflict,
+			wantErrTitle:    `client error: command with jid "jid-1234" is not running`,
+		},
+		{
+			name:           "error on send request",
+			jpReturnJob:    runningJob,
+			clients:        []*clients.Client{c1},
+			connReturnErr:  errors.New("send fake error"),
+			wantStatusCode: http.StatusInternalServerError,
+			wantErrTitle:   "Failed to cancel remote command.",
+			wantErrDetail:  "failed to send request: send fake error",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// given
+			al := APIListener{
+				insecureForTests: true,
+				Logger:           testLog,
+				Server: &Server{
+					clientService: NewClientService(nil, clients.NewClientRepository(tc.clients, &hour)),
+					config: &Config{
+						Server: ServerConfig{MaxRequestBytes: 1024 * 1024},
+					},
+				},
+			}
+			al.initRouter()
+
+			jp := NewJobProviderMock()
+			jp.ReturnJob = tc.jpReturnJob
+			al.jobProvider = jp
+
+			connMock.ReturnErr = tc.connReturnErr
+			connMock.ReturnOk = !tc.connReturnNotOk
+			if tc.connReturnNotOk {
+				connMock.ReturnResponsePayload = []byte(`command with jid "jid-1234" is not running`)
+			} else {
+				connMock.ReturnResponsePayload = []byte(`{"Pid":123}`)
+			}
+
+			req := httptest.NewRequest(http.MethodDelete, fmt.Sprintf("/api/v1/clients/%s/commands/%s", c1.ID, runningJob.JID), nil)
+
+			// when
+			w := httptest.NewRecorder()
+			al.router.ServeHTTP(w, req)
+
+			// then
+			assert.Equal(t, tc.wantStatusCode, w.Code)
+			if tc.wantErrTitle == "" {
+				// success case
+				assert.Empty(t, w.Body.String())
+				gotName, gotWantReply, gotPayload := connMock.InputSendRequest()
+				assert.Equal(t, comm.RequestTypeCancelCmd, gotName)
+				assert.True(t, gotWantReply)
+				assert.JSONEq(t, `{"JID":"jid-1234"}`, string(gotPayload))
+			} else {
+				// failure case
+				wantResp := api.NewErrorPayloadWithCode("", tc.wantErrTitle, tc.wantErrDetail)
+				wantRespBytes, err := json.Marshal(wantResp)
+				require.NoError(t, err)
+				require.Equal(t, string(wantRespBytes), w.Body.String())
+			}
+		})
+	}
+}
+
+func TestHandleDeleteMultiClientCommand(t *testing.T) {
+	connMock2 := test.NewConnMock()
+	c1 := clients.New(t).ID("client-1").Build()
+	c2 := clients.New(t).ID("client-2").Connection(connMock2).Build()
+	multiJobID := "multi-jid-1234"
+
+	testCases := []struct {
+		name string
+
+		jobs []*models.Job
+
+		wantStatusCode  int
+		wantErrTitle    string
+		wantCancelConn1 bool
+	}{
+		{
+			name: "running job",
+			jobs: []*models.Job{
+				jb.New(t).ClientID(c1.ID).MultiJobID(multiJobID).Status(models.JobStatusRunning).Build(),
+				jb.New(t).ClientID(c2.ID).MultiJobID(multiJobID).Status(models.JobStatusSuccessful).Build(),
+			},
+			wantStatusCode:  http.StatusNoContent,
+			wantCancelConn1: true,
+		},
+		{
+			name: "finished job",
+			jobs: []*models.Job{
+				jb.New(t).ClientID(c1.ID).MultiJobID(multiJobID).Status(models.JobStatusSuccessful).Build(),
+				jb.New(t).ClientID(c2.ID).MultiJobID(multiJobID).Status(models.JobStatusCancelled).Build(),
+			},
+			wantStatusCode: http.StatusConflict,
+			wantErrTitle:   fmt.Sprintf("Multi-client Job[id=%q] is not running.", multiJobID),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// given
+			al := APIListener{
+				insecureForTests: true,
+				Logger:           testLog,
+				Server: &Server{
+					clientService: NewClientService(nil, clients.NewClientRepository([]*clients.Client{c1, c2}, &hour)),
+					config: &Config{
+						Server: ServerConfig{MaxRequestBytes: 1024 * 1024},
+					},
+				},
+			}
+			al.initRouter()
+
+			jp := NewJobProviderMock()
+			jp.ReturnMultiJob = &models.MultiJob{
+				MultiJobSummary: models.MultiJobSummary{JID: multiJobID},
+				ClientIDs:       []string{c1.ID, c2.ID},
+				Jobs:            tc.jobs,
+			}
+			al.jobProvider = jp
+			connMock1 := test.NewConnMock()
+			connMock1.ReturnOk = true
+			connMock1.ReturnResponsePayload = []byte(`{"Pid":123}`)
+			c1.Connection = connMock1
+
+			req := httptest.NewRequest(http.MethodDelete, "/api/v1/commands/"+multiJobID, nil)
+
+			// when
+			w := httptest.NewRecorder()
+			al.router.ServeHTTP(w, req)
+
+			// then
+			assert.Equal(t, tc.wantStatusCode, w.Code)
+			if tc.wantErrTitle != "" {
+				wantRespBytes, err := json.Marshal(api.NewErrorPayloadWithCode("", tc.wantErrTitle, ""))
+				require.NoError(t, err)
+				assert.Equal(t, string(wantRespBytes), w.Body.String())
+			}
+			gotName, _, gotPayload := connMock1.InputSendRequest()
+			if tc.wantCancelConn1 {
+				assert.Equal(t, comm.RequestTypeCancelCmd, gotName)
+				assert.JSONEq(t, fmt.Sprintf(`{"JID":%q}`, tc.jobs[0].JID), string(gotPayload))
+			} else {
+				assert.Empty(t, gotName)
+			}
+			gotName, _, _ = connMock2.InputSendRequest()
+			assert.Empty(t, gotName)
+		})
+	}
+}
+
 func TestHandleGetCommands(t *testing.T) {
 	ft := time.Date(2020, 10, 10, 10, 10, 10, 0, time.UTC)
 	testCID := "cid-1234"
@@ -1098,7 +1395,22 @@ func TestHandleGetClients(t *testing.T) {
                "scheme":null,
                "acl":null,
 			   "idle_timeout_minutes": 0,
-               "id":"1"
+               "acl_session_user":"",
+               "protocol":"tcp",
+               "reverse":false,
+               "http_proxy":false,
+               "socks":false,
+               "max_bytes_per_sec":0,
+               "max_connections":0,
+               "expires_at":null,
+               "persistent":false,
+               "created_by":"",
+               "id":"1",
+               "bytes_in":0,
+               "bytes_out":0,
+               "active_connections":0,
+               "last_activity":null,
+               "connections":[]
             },
             {
                "lhost":"0.0.0.0",
@@ -1109,9 +1421,25 @@ func TestHandleGetClients(t *testing.T) {
                "scheme":null,
                "acl":null,
 			   "idle_timeout_minutes": 0,
-               "id":"2"
+               "acl_session_user":"",
+               "protocol":"tcp",
+               "reverse":false,
+               "http_proxy":false,
+               "socks":false,
+               "max_bytes_per_sec":0,
+               "max_connections":0,
+               "expires_at":null,
+               "persistent":false,
+               "created_by":"",
+               "id":"2",
+               "bytes_in":0,
+               "bytes_out":0,
+               "active_connections":0,
+               "last_activity":null,
+               "connections":[]
             }
          ],
+         "tunnel_conflicts":null,
          "connection_state":"connected",
          "disconnected_at":null,
          "client_auth_id":"user1"
@@ -1146,7 +1474,22 @@ func TestHandleGetClients(t *testing.T) {
                "scheme":null,
                "acl":null,
 			   "idle_timeout_minutes": 0,
-               "id":"1"
+               "acl_session_user":"",
+               "protocol":"tcp",
+               "reverse":false,
+               "http_proxy":false,
+               "socks":false,
+               "max_bytes_per_sec":0,
+               "max_connections":0,
+               "expires_at":null,
+               "persistent":false,
+               "created_by":"",
+               "id":"1",
+               "bytes_in":0,
+               "bytes_out":0,
+               "active_connections":0,
+               "last_activity":null,
+               "connections":[]
             },
             {
                "lhost":"0.0.0.0",
@@ -1157,9 +1500,25 @@ func TestHandleGetClients(t *testing.T) {
                "scheme":null,
                "acl":null,
 			   "idle_timeout_minutes": 0,
-               "id":"2"
+               "acl_session_user":"",
+               "protocol":"tcp",
+               "reverse":false,
+               "http_proxy":false,
+               "socks":false,
+               "max_bytes_per_sec":0,
+               "max_connections":0,
+               "expires_at":null,
+               "persistent":false,
+               "created_by":"",
+               "id":"2",
+               "bytes_in":0,
+               "bytes_out":0,
+               "active_connections":0,
+               "last_activity":null,
+               "connections":[]
             }
          ],
+         "tunnel_conflicts":null,
          "connection_state":"disconnected",
          "disconnected_at":"2020-08-19T13:04:23+03:00",
          "client_auth_id":"user1"
@@ -1171,6 +1530,186 @@ func TestHandleGetClients(t *testing.T) {
 	assert.JSONEq(t, expectedJSON, w.Body.String())
 }
 
+func TestHandleGetClientTunnelConnections(t *testing.T) {
+	c1 := clients.New(t).ID("client-1").ClientAuthID(cl1.ID).Build()
+	al := APIListener{
+		insecureForTests: true,
+		Server: &Server{
+			clientService: NewClientService(nil, clients.NewClientRepository([]*clients.Client{c1}, &hour)),
+			config: &Config{
+				Server: ServerConfig{MaxRequestBytes: 1024 * 1024},
+			},
+		},
+	}
+	al.initRouter()
+
+	testCases := []struct {
+		name       string
+		clientID   string
+		tunnelID   string
+		wantStatus int
+		wantJSON   string
+	}{
+		{
+			name:       "no connections",
+			clientID:   "client-1",
+			tunnelID:   "1",
+			wantStatus: http.StatusOK,
+			wantJSON:   `{"data":[]}`,
+		},
+		{
+			name:       "unknown client",
+			clientID:   "client-2",
+			tunnelID:   "1",
+			wantStatus: http.StatusNotFound,
+			wantJSON:   `{"errors":[{"code":"","title":"client with id client-2 not found","detail":""}]}`,
+		},
+		{
+			name:       "unknown tunnel",
+			clientID:   "client-1",
+			tunnelID:   "5",
+			wantStatus: http.StatusNotFound,
+			wantJSON:   `{"errors":[{"code":"","title":"tunnel not found","detail":""}]}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("GET", fmt.Sprintf("/api/v1/clients/%s/tunnels/%s/connections", tc.clientID, tc.tunnelID), nil)
+			al.router.ServeHTTP(w, req)
+
+			assert.Equal(t, tc.wantStatus, w.Code)
+			assert.JSONEq(t, tc.wantJSON, w.Body.String())
+		})
+	}
+}
+
+func TestHandleDeleteClientTunnelConflicts(t *testing.T) {
+	c1 := clients.New(t).ID("client-1").ClientAuthID(cl1.ID).Build()
+	c1.TunnelConflicts = []*clients.TunnelConflict{
+		{Remote: chshare.Remote{LocalHost: "0.0.0.0", LocalPort: "2222", RemoteHost: "0.0.0.0", RemotePort: "22", Persistent: true, CreatedBy: "admin"}, Error: "address already in use"},
+	}
+	al := APIListener{
+		insecureForTests: true,
+		Server: &Server{
+			clientService: NewClientService(nil, clients.NewClientRepository([]*clients.Client{c1}, &hour)),
+			config: &Config{
+				Server: ServerConfig{MaxRequestBytes: 1024 * 1024},
+			},
+		},
+	}
+	al.initRouter()
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest("DELETE", "/api/v1/clients/client-2/tunnel-conflicts", nil)
+	al.router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w = httptest.NewRecorder()
+	req = httptest.NewRequest("DELETE", "/api/v1/clients/client-1/tunnel-conflicts", nil)
+	al.router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Empty(t, c1.TunnelConflicts)
+}
+
+func TestHandlePutClientTunnelInvalidParams(t *testing.T) {
+	c1 := clients.New(t).ID("client-1").ClientAuthID(cl1.ID).Build()
+	al := APIListener{
+		insecureForTests: true,
+		Server: &Server{
+			clientService: NewClientService(nil, clients.NewClientRepository([]*clients.Client{c1}, &hour)),
+			config: &Config{
+				Server: ServerConfig{MaxRequestBytes: 1024 * 1024},
+			},
+		},
+	}
+	al.initRouter()
+
+	testCases := []struct {
+		name      string
+		query     string
+		wantCode  string
+		wantTitle string
+	}{
+		{
+			name:      "invalid max bytes per sec",
+			query:     "max_bytes_per_sec=1MB",
+			wantCode:  ErrCodeInvalidTunnelLimit,
+			wantTitle: `invalid "max_bytes_per_sec" param`,
+		},
+		{
+			name:      "negative max bytes per sec",
+			query:     "max_bytes_per_sec=-1",
+			wantCode:  ErrCodeInvalidTunnelLimit,
+			wantTitle: `"max_bytes_per_sec" param should be in range [0,9223372036854775807]`,
+		},
+		{
+			name:      "too many max connections",
+			query:     "max_connections=2147483648",
+			wantCode:  ErrCodeInvalidTunnelLimit,
+			wantTitle: `"max_connections" param should be in range [0,2147483647]`,
+		},
+		{
+			name:      "invalid expires at",
+			query:     "expires_at=tomorrow",
+			wantCode:  ErrCodeInvalidExpiry,
+			wantTitle: `invalid "expires_at" param`,
+		},
+		{
+			name:      "expires at in the past",
+			query:     "expires_at=2020-01-01T00:00:00Z",
+			wantCode:  ErrCodeInvalidExpiry,
+			wantTitle: `"expires_at" param should be in the future`,
+		},
+		{
+			name:      "invalid max lifetime",
+			query:     "max_lifetime=2",
+			wantCode:  ErrCodeInvalidExpiry,
+			wantTitle: `invalid "max_lifetime" param`,
+		},
+		{
+			name:      "negative max lifetime",
+			query:     "max_lifetime=-1h",
+			wantCode:  ErrCodeInvalidExpiry,
+			wantTitle: `"max_lifetime" param should be positive`,
+		},
+		{
+			name:      "acl session with acl",
+			query:     "acl_session=1&acl=192.0.2.1",
+			wantCode:  ErrCodeInvalidACL,
+			wantTitle: `"acl" and "acl_session" params can't be used together.`,
+		},
+		{
+			name:      "acl session without bearer token",
+			query:     "acl_session=1",
+			wantCode:  ErrCodeInvalidACL,
+			wantTitle: "ACL locked to the API session requires authorization with a bearer token.",
+		},
+		{
+			name:      "both expires at and max lifetime",
+			query:     "expires_at=2100-01-01T00:00:00Z&max_lifetime=1h",
+			wantCode:  ErrCodeInvalidExpiry,
+			wantTitle: `"expires_at" and "max_lifetime" params can't be used together`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("PUT", "/api/v1/clients/client-1/tunnels?remote=8080&"+tc.query, nil)
+			al.router.ServeHTTP(w, req)
+
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+			var got api.ErrorPayload
+			require.NoError(t, json.NewDecoder(w.Body).Decode(&got))
+			require.Len(t, got.Errors, 1)
+			assert.Equal(t, tc.wantCode, got.Errors[0].Code)
+			assert.Equal(t, tc.wantTitle, got.Errors[0].Title)
+		})
+	}
+}
+
 func TestHandlePostMultiClientCommand(t *testing.T) {
 	testUser := "test-user"
 
@@ -0,0 +1,314 @@
+package chserver
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/gorilla/mux"
+
+	"github.com/cloudradar-monitoring/rport/server/api"
+	"github.com/cloudradar-monitoring/rport/server/clients"
+	chshare "github.com/cloudradar-monitoring/rport/share"
+	"github.com/cloudradar-monitoring/rport/share/security"
+)
+
+const (
+	// tunnelProxyTokenParam is a query param of a proxy URL with a short-lived token that is exchanged for a cookie.
+	tunnelProxyTokenParam = "rport-proxy-token"
+	// tunnelProxyCookie is a cookie with a session token of a tunnel served by the HTTP proxy.
+	tunnelProxyCookie = "rport-proxy-session"
+
+	tunnelProxyAudienceLogin   = "rport-tunnel-proxy-login"
+	tunnelProxyAudienceSession = "rport-tunnel-proxy-session"
+
+	tunnelProxyLoginLifetime   = time.Minute
+	tunnelProxySessionLifetime = time.Hour
+)
+
+// tunnelProxyToken authorizes an API user to access a single tunnel served by the HTTP proxy.
+type tunnelProxyToken struct {
+	Username string `json:"username"`
+	ClientID string `json:"client_id"`
+	TunnelID string `json:"tunnel_id"`
+	jwt.StandardClaims
+}
+
+// initTunnelProxyRoutes serves tunnels on subdomains of the tunnel proxy domain, so device web interfaces run on
+// separate origins and can't access the API or the frontend. The route is added before API routes to take precedence.
+func (al *APIListener) initTunnelProxyRoutes(r *mux.Router) {
+	var h http.Handler = http.HandlerFunc(al.handleTunnelProxy)
+	if al.bannedIPs != nil {
+		h = security.RejectBannedIPs(h, al.bannedIPs)
+	}
+	r.MatcherFunc(func(req *http.Request, match *mux.RouteMatch) bool {
+		return al.tunnelProxyLabel(req.Host) != ""
+	}).Handler(h)
+}
+
+// tunnelProxyHost returns a host of a tunnel served by the HTTP proxy. The label is derived from the client and
+// tunnel ids, because they can contain characters that are not allowed in host names.
+func (al *APIListener) tunnelProxyHost(clientID, tunnelID string) string {
+	sum := sha256.Sum256([]byte(clientID + "/" + tunnelID))
+	return hex.EncodeToString(sum[:16]) + "." + al.config.API.TunnelProxyDomain
+}
+
+// tunnelProxyLabel returns the first label of a given host if it's a subdomain of the tunnel proxy domain.
+func (al *APIListener) tunnelProxyLabel(host string) string {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	suffix := "." + al.config.API.TunnelProxyDomain
+	if !strings.HasSuffix(strings.ToLower(host), suffix) {
+		return ""
+	}
+	label := host[:len(host)-len(suffix)]
+	if label == "" || strings.Contains(label, ".") {
+		return ""
+	}
+	return strings.ToLower(label)
+}
+
+// validateTunnelProxy returns true if a tunnel to a given remote can be served by the HTTP proxy.
+func (al *APIListener) validateTunnelProxy(w http.ResponseWriter, localAddr string, remote *chshare.Remote) bool {
+	if !al.config.API.TunnelProxy {
+		al.jsonErrorResponseWithTitle(w, http.StatusBadRequest, "HTTP proxy for tunnels is disabled.")
+		return false
+	}
+	if remote.Scheme == nil || (*remote.Scheme != "http" && *remote.Scheme != "https") {
+		al.jsonErrorResponseWithTitle(w, http.StatusBadRequest, `HTTP proxy requires scheme "http" or "https".`)
+		return false
+	}
+	if localAddr != "" {
+		al.jsonErrorResponseWithTitle(w, http.StatusBadRequest, "Local can't be specified for tunnels served by HTTP proxy.")
+		return false
+	}
+	if remote.Reverse || remote.IsUDP() || remote.Socks {
+		al.jsonErrorResponseWithTitle(w, http.StatusBadRequest, "HTTP proxy supports only TCP tunnels to a remote address.")
+		return false
+	}
+	return true
+}
+
+type tunnelProxyURLPayload struct {
+	URL string `json:"url"`
+}
+
+// handleGetTunnelProxyURL returns a URL of a tunnel served by the HTTP proxy. The URL contains a short-lived token
+// that logs the current user in to the tunnel.
+func (al *APIListener) handleGetTunnelProxyURL(w http.ResponseWriter, req *http.Request) {
+	vars := mux.Vars(req)
+	clientID := vars[routeParamClientID]
+	tunnelID := vars[routeParamTunnelID]
+
+	if !al.config.API.TunnelProxy {
+		al.jsonErrorResponseWithTitle(w, http.StatusNotFound, "HTTP proxy for tunnels is disabled.")
+		return
+	}
+	if _, ok := al.findTunnelProxyTunnel(w, req, clientID, tunnelID); !ok {
+		return
+	}
+
+	token, err := al.createTunnelProxyToken(api.GetUser(req.Context(), al.Logger), clientID, tunnelID, tunnelProxyAudienceLogin, tunnelProxyLoginLifetime)
+	if err != nil {
+		al.jsonErrorResponse(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	host := al.tunnelProxyHost(clientID, tunnelID)
+	// the proxy is served on the same port as the API
+	if _, port, err := net.SplitHostPort(req.Host); err == nil && port != "443" {
+		host = net.JoinHostPort(host, port)
+	}
+	u := url.URL{
+		Scheme:   "https",
+		Host:     host,
+		Path:     "/",
+		RawQuery: url.Values{tunnelProxyTokenParam: {token}}.Encode(),
+	}
+	al.writeJSONResponse(w, http.StatusOK, api.NewSuccessPayload(tunnelProxyURLPayload{URL: u.String()}))
+}
+
+// findTunnelProxyTunnel returns a tunnel served by the HTTP proxy if the current user can access it. Otherwise it
+// writes an error response and returns false.
+func (al *APIListener) findTunnelProxyTunnel(w http.ResponseWriter, req *http.Request, clientID, tunnelID string) (*clients.Tunnel, bool) {
+	client, err := al.clientService.GetActiveByID(clientID)
+	if err != nil {
+		al.jsonErrorResponse(w, http.StatusInternalServerError, err)
+		return nil, false
+	}
+	if client == nil {
+		al.jsonErrorResponseWithTitle(w, http.StatusNotFound, fmt.Sprintf("Client with id=%q not found.", clientID))
+		return nil, false
+	}
+	if !al.checkClientAccess(w, req, client) {
+		return nil, false
+	}
+
+	client.Lock()
+	tunnel := client.FindTunnel(tunnelID)
+	client.Unlock()
+	if tunnel == nil || tunnel.HTTPTransport() == nil {
+		al.jsonErrorResponseWithTitle(w, http.StatusNotFound, fmt.Sprintf("Tunnel with id=%q served by HTTP proxy not found.", tunnelID))
+		return nil, false
+	}
+	return tunnel, true
+}
+
+func (al *APIListener) createTunnelProxyToken(username, clientID, tunnelID, audience string, lifetime time.Duration) (string, error) {
+	claims := tunnelProxyToken{
+		Username: username,
+		ClientID: clientID,
+		TunnelID: tunnelID,
+		StandardClaims: jwt.StandardClaims{
+			Audience:  audience,
+			ExpiresAt: time.Now().Add(lifetime).Unix(),
+		},
+	}
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(al.config.API.JWTSecret))
+}
+
+// parseTunnelProxyToken returns claims of a given token if it's valid, has a given audience and is issued for
+// the tunnel of a given host label.
+func (al *APIListener) parseTunnelProxyToken(tokenStr, audience, label string) (*tunnelProxyToken, error) {
+	claims := &tunnelProxyToken{}
+	token, err := jwt.ParseWithClaims(tokenStr, claims, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		return []byte(al.config.API.JWTSecret), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !token.Valid || !claims.VerifyAudience(audience, true) || claims.Username == "" {
+		return nil, errors.New("invalid token")
+	}
+	if al.tunnelProxyLabel(al.tunnelProxyHost(claims.ClientID, claims.TunnelID)) != label {
+		return nil, errors.New("token is issued for another tunnel")
+	}
+	return claims, nil
+}
+
+// handleTunnelProxy authenticates a request to a tunnel host by the session cookie and forwards it to the remote of
+// the tunnel. A request with a login token gets the session cookie and is redirected to the same URL without it.
+func (al *APIListener) handleTunnelProxy(w http.ResponseWriter, req *http.Request) {
+	label := al.tunnelProxyLabel(req.Host)
+
+	if tokenStr := req.URL.Query().Get(tunnelProxyTokenParam); tokenStr != "" {
+		al.handleTunnelProxyLogin(w, req, tokenStr, label)
+		return
+	}
+
+	cookie, err := req.Cookie(tunnelProxyCookie)
+	if err != nil {
+		al.jsonErrorResponseWithTitle(w, http.StatusUnauthorized, "Open the tunnel via its proxy URL returned by the API.")
+		return
+	}
+	claims, err := al.parseTunnelProxyToken(cookie.Value, tunnelProxyAudienceSession, label)
+	if err != nil {
+		al.Debugf("Invalid tunnel proxy session: %v", err)
+		al.jsonErrorResponseWithTitle(w, http.StatusUnauthorized, "Session expired. Open the tunnel via its proxy URL returned by the API.")
+		return
+	}
+
+	// the user might be deleted or lose permissions and client access while the session is valid
+	user, err := al.userSrv.GetByUsername(claims.Username)
+	if err != nil {
+		al.jsonErrorResponse(w, http.StatusInternalServerError, err)
+		return
+	}
+	if user == nil {
+		al.jsonErrorResponse(w, http.StatusUnauthorized, errors.New("unauthorized"))
+		return
+	}
+	req = req.WithContext(api.WithUser(req.Context(), claims.Username))
+	al.withPermission(PermissionTunnels, func(w http.ResponseWriter, req *http.Request) {
+		al.serveTunnelProxy(w, req, claims.ClientID, claims.TunnelID)
+	})(w, req)
+}
+
+func (al *APIListener) handleTunnelProxyLogin(w http.ResponseWriter, req *http.Request, tokenStr, label string) {
+	claims, err := al.parseTunnelProxyToken(tokenStr, tunnelProxyAudienceLogin, label)
+	if err != nil {
+		al.Debugf("Invalid tunnel proxy token: %v", err)
+		al.handleBannedIPs(w, req, false)
+		al.jsonErrorResponseWithTitle(w, http.StatusUnauthorized, "Invalid or expired token. Get a new proxy URL from the API.")
+		return
+	}
+
+	session, err := al.createTunnelProxyToken(claims.Username, claims.ClientID, claims.TunnelID, tunnelProxyAudienceSession, tunnelProxySessionLifetime)
+	if err != nil {
+		al.jsonErrorResponse(w, http.StatusInternalServerError, err)
+		return
+	}
+	// the cookie is bound to the host of the tunnel, other tunnels and the API don't get it
+	http.SetCookie(w, &http.Cookie{
+		Name:     tunnelProxyCookie,
+		Value:    session,
+		Path:     "/",
+		MaxAge:   int(tunnelProxySessionLifetime.Seconds()),
+		Secure:   true,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	q := req.URL.Query()
+	q.Del(tunnelProxyTokenParam)
+	target := url.URL{Path: req.URL.Path, RawQuery: q.Encode()}
+	http.Redirect(w, req, target.String(), http.StatusFound)
+}
+
+func (al *APIListener) serveTunnelProxy(w http.ResponseWriter, req *http.Request, clientID, tunnelID string) {
+	tunnel, ok := al.findTunnelProxyTunnel(w, req, clientID, tunnelID)
+	if !ok {
+		return
+	}
+
+	host, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		al.jsonErrorResponse(w, http.StatusInternalServerError, fmt.Errorf("failed to split host port for %q: %v", req.RemoteAddr, err))
+		return
+	}
+	if !tunnel.IsAllowed(net.ParseIP(host)) {
+		al.jsonErrorResponseWithTitle(w, http.StatusForbidden, "Access to the tunnel is denied by its ACL.")
+		return
+	}
+
+	al.newTunnelProxy(tunnel).ServeHTTP(w, req)
+}
+
+func (al *APIListener) newTunnelProxy(tunnel *clients.Tunnel) *httputil.ReverseProxy {
+	return &httputil.ReverseProxy{
+		Director: func(req *http.Request) {
+			req.URL.Scheme = *tunnel.Scheme
+			req.URL.Host = tunnel.Remote.Remote()
+			req.Host = req.URL.Host
+			removeCookie(req, tunnelProxyCookie)
+		},
+		Transport: tunnel.HTTPTransport(),
+		ErrorHandler: func(w http.ResponseWriter, req *http.Request, err error) {
+			al.Debugf("Tunnel[id=%q] HTTP proxy error: %v", tunnel.ID, err)
+			al.jsonErrorResponseWithError(w, http.StatusBadGateway, "", "Failed to forward request to the tunnel remote.", err)
+		},
+	}
+}
+
+// removeCookie removes a cookie with a given name from a given request, other cookies are kept.
+func removeCookie(req *http.Request, name string) {
+	cookies := req.Cookies()
+	req.Header.Del("Cookie")
+	for _, c := range cookies {
+		if c.Name != name {
+			req.AddCookie(c)
+		}
+	}
+}
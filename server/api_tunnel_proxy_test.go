@@ -0,0 +1,524 @@
+package chserver
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cloudradar-monitoring/rport/server/api"
+	"github.com/cloudradar-monitoring/rport/server/api/users"
+	"github.com/cloudradar-monitoring/rport/server/clients"
+	chshare "github.com/cloudradar-monitoring/rport/share"
+	"github.com/cloudradar-monitoring/rport/share/security"
+	"github.com/cloudradar-monitoring/rport/share/test"
+)
+
+const testTunnelProxyDomain = "tunnels.example.com"
+
+func newTunnelProxyTestAPIListener(c *clients.Client) *APIListener {
+	al := &APIListener{
+		insecureForTests: true,
+		Server: &Server{
+			clientService: NewClientService(nil, clients.NewClientRepository([]*clients.Client{c}, &hour)),
+			config: &Config{
+				API: APIConfig{
+					JWTSecret:         "foobar",
+					TunnelProxy:       true,
+					TunnelProxyDomain: testTunnelProxyDomain,
+				},
+			},
+		},
+		userSrv: users.NewUserCache([]*users.User{{Username: "admin", Groups: []string{"Administrators"}}}),
+		Logger:  testLog,
+	}
+	al.initRouter()
+	return al
+}
+
+// newTunnelProxyRequest returns a request to a given tunnel with a session cookie of a given user.
+func newTunnelProxyRequest(t *testing.T, al *APIListener, username, clientID, tunnelID, path string) *http.Request {
+	session, err := al.createTunnelProxyToken(username, clientID, tunnelID, tunnelProxyAudienceSession, time.Minute)
+	require.NoError(t, err)
+	req := httptest.NewRequest(http.MethodGet, "https://"+al.tunnelProxyHost(clientID, tunnelID)+path, nil)
+	req.AddCookie(&http.Cookie{Name: tunnelProxyCookie, Value: session})
+	return req
+}
+
+func startTestTunnel(t *testing.T, c *clients.Client, conn *test.ConnMock, id string, remote *chshare.Remote, acl string) *clients.Tunnel {
+	var tunnelACL *clients.TunnelACL
+	if acl != "" {
+		var err error
+		tunnelACL, err = clients.ParseTunnelACL(acl)
+		require.NoError(t, err)
+	}
+	tunnel := clients.NewTunnel(testLog, conn, id, remote, tunnelACL)
+	_, err := tunnel.Start(context.Background())
+	require.NoError(t, err)
+	c.Tunnels = append(c.Tunnels, tunnel)
+	return tunnel
+}
+
+// serveHTTPOnce replies to a single request read from a given channel and returns the request.
+func serveHTTPOnce(ch *test.ChannelMock, body string) <-chan *http.Request {
+	reqs := make(chan *http.Request, 1)
+	go func() {
+		defer close(reqs)
+		defer ch.Close()
+		req, err := http.ReadRequest(bufio.NewReader(ch))
+		if err != nil {
+			return
+		}
+		reqs <- req
+		resp := &http.Response{
+			StatusCode:    http.StatusOK,
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        http.Header{"Content-Type": []string{"text/plain"}},
+			Body:          ioutil.NopCloser(strings.NewReader(body)),
+			ContentLength: int64(len(body)),
+			Close:         true,
+		}
+		_ = resp.Write(ch)
+	}()
+	return reqs
+}
+
+func TestHandleTunnelProxy(t *testing.T) {
+	connMock := test.NewConnMock()
+	serverSide, clientSide := test.NewChannelPair()
+	connMock.ReturnChannel = serverSide
+	c := clients.New(t).Connection(connMock).Build()
+
+	scheme := "http"
+	startTestTunnel(t, c, connMock, "3", &chshare.Remote{
+		RemoteHost: "192.168.1.1",
+		RemotePort: "80",
+		Scheme:     &scheme,
+		HTTPProxy:  true,
+	}, "")
+	al := newTunnelProxyTestAPIListener(c)
+
+	gotReqs := serveHTTPOnce(clientSide, "device page")
+
+	req := newTunnelProxyRequest(t, al, "admin", c.ID, "3", "/status?verbose=1")
+	req.SetBasicAuth("device-user", "device-password")
+	req.AddCookie(&http.Cookie{Name: "device-session", Value: "123"})
+	w := httptest.NewRecorder()
+	al.router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "device page", w.Body.String())
+
+	gotReq := <-gotReqs
+	require.NotNil(t, gotReq)
+	assert.Equal(t, "/status?verbose=1", gotReq.URL.RequestURI())
+	assert.Equal(t, "192.168.1.1:80", gotReq.Host)
+	gotUser, gotPassword, _ := gotReq.BasicAuth()
+	assert.Equal(t, "device-user", gotUser)
+	assert.Equal(t, "device-password", gotPassword)
+	assert.Equal(t, "device-session=123", gotReq.Header.Get("Cookie"))
+
+	channelType, extraData := connMock.InputOpenChannel()
+	assert.Equal(t, "rport", channelType)
+	assert.Equal(t, "192.168.1.1:80", string(extraData))
+}
+
+func TestHandleTunnelProxyErrors(t *testing.T) {
+	connMock := test.NewConnMock()
+	connMock.ReturnOpenChannelErr = errors.New("connection lost")
+	c := clients.New(t).Connection(connMock).Build()
+
+	scheme := "https"
+	startTestTunnel(t, c, connMock, "3", &chshare.Remote{
+		RemoteHost: "192.168.1.1",
+		RemotePort: "443",
+		Scheme:     &scheme,
+		HTTPProxy:  true,
+	}, "")
+	startTestTunnel(t, c, connMock, "4", &chshare.Remote{
+		RemoteHost: "192.168.1.1",
+		RemotePort: "443",
+		Scheme:     &scheme,
+		HTTPProxy:  true,
+	}, "10.0.0.0/8")
+	al := newTunnelProxyTestAPIListener(c)
+
+	testCases := []struct {
+		name           string
+		clientID       string
+		tunnelID       string
+		wantStatusCode int
+		wantErrTitle   string
+	}{
+		{
+			name:           "unknown client",
+			clientID:       "unknown",
+			tunnelID:       "1",
+			wantStatusCode: http.StatusNotFound,
+			wantErrTitle:   `Client with id="unknown" not found.`,
+		},
+		{
+			name:           "unknown tunnel",
+			clientID:       c.ID,
+			tunnelID:       "5",
+			wantStatusCode: http.StatusNotFound,
+			wantErrTitle:   `Tunnel with id="5" served by HTTP proxy not found.`,
+		},
+		{
+			name:           "tunnel with port",
+			clientID:       c.ID,
+			tunnelID:       "2",
+			wantStatusCode: http.StatusNotFound,
+			wantErrTitle:   `Tunnel with id="2" served by HTTP proxy not found.`,
+		},
+		{
+			name:           "denied by acl",
+			clientID:       c.ID,
+			tunnelID:       "4",
+			wantStatusCode: http.StatusForbidden,
+			wantErrTitle:   "Access to the tunnel is denied by its ACL.",
+		},
+		{
+			name:           "remote connection failed",
+			clientID:       c.ID,
+			tunnelID:       "3",
+			wantStatusCode: http.StatusBadGateway,
+			wantErrTitle:   "Failed to forward request to the tunnel remote.",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := newTunnelProxyRequest(t, al, "admin", tc.clientID, tc.tunnelID, "/")
+			w := httptest.NewRecorder()
+			al.router.ServeHTTP(w, req)
+
+			assert.Equal(t, tc.wantStatusCode, w.Code)
+			var gotResp api.ErrorPayload
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &gotResp))
+			require.Len(t, gotResp.Errors, 1)
+			assert.Equal(t, tc.wantErrTitle, gotResp.Errors[0].Title)
+		})
+	}
+}
+
+func TestHandleTunnelProxySession(t *testing.T) {
+	c := clients.New(t).Build()
+	al := newTunnelProxyTestAPIListener(c)
+	host := al.tunnelProxyHost(c.ID, "1")
+
+	loginToken, err := al.createTunnelProxyToken("admin", c.ID, "1", tunnelProxyAudienceLogin, time.Minute)
+	require.NoError(t, err)
+	otherTunnelSession, err := al.createTunnelProxyToken("admin", c.ID, "2", tunnelProxyAudienceSession, time.Minute)
+	require.NoError(t, err)
+	expiredSession, err := al.createTunnelProxyToken("admin", c.ID, "1", tunnelProxyAudienceSession, -time.Minute)
+	require.NoError(t, err)
+	unknownUserSession, err := al.createTunnelProxyToken("unknown", c.ID, "1", tunnelProxyAudienceSession, time.Minute)
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name           string
+		cookie         string
+		wantStatusCode int
+	}{
+		{
+			name:           "no cookie",
+			wantStatusCode: http.StatusUnauthorized,
+		},
+		{
+			name:           "session of another tunnel",
+			cookie:         otherTunnelSession,
+			wantStatusCode: http.StatusUnauthorized,
+		},
+		{
+			name:           "expired session",
+			cookie:         expiredSession,
+			wantStatusCode: http.StatusUnauthorized,
+		},
+		{
+			name:           "login token as session",
+			cookie:         loginToken,
+			wantStatusCode: http.StatusUnauthorized,
+		},
+		{
+			name:           "deleted user",
+			cookie:         unknownUserSession,
+			wantStatusCode: http.StatusUnauthorized,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "https://"+host+"/", nil)
+			if tc.cookie != "" {
+				req.AddCookie(&http.Cookie{Name: tunnelProxyCookie, Value: tc.cookie})
+			}
+			w := httptest.NewRecorder()
+			al.router.ServeHTTP(w, req)
+
+			assert.Equal(t, tc.wantStatusCode, w.Code)
+		})
+	}
+}
+
+func TestHandleTunnelProxyLogin(t *testing.T) {
+	c := clients.New(t).Build()
+	al := newTunnelProxyTestAPIListener(c)
+	host := al.tunnelProxyHost(c.ID, "1")
+
+	loginToken, err := al.createTunnelProxyToken("admin", c.ID, "1", tunnelProxyAudienceLogin, time.Minute)
+	require.NoError(t, err)
+
+	req := httptest.NewRequest(http.MethodGet, "https://"+host+"/index.html?page=1&"+tunnelProxyTokenParam+"="+loginToken, nil)
+	w := httptest.NewRecorder()
+	al.router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusFound, w.Code)
+	assert.Equal(t, "/index.html?page=1", w.Header().Get("Location"))
+	cookies := w.Result().Cookies()
+	require.Len(t, cookies, 1)
+	assert.Equal(t, tunnelProxyCookie, cookies[0].Name)
+	assert.Empty(t, cookies[0].Domain)
+	assert.True(t, cookies[0].Secure)
+	assert.True(t, cookies[0].HttpOnly)
+	claims, err := al.parseTunnelProxyToken(cookies[0].Value, tunnelProxyAudienceSession, al.tunnelProxyLabel(host))
+	require.NoError(t, err)
+	assert.Equal(t, "admin", claims.Username)
+
+	// a login token of one tunnel doesn't log in to another one
+	req = httptest.NewRequest(http.MethodGet, "https://"+al.tunnelProxyHost(c.ID, "2")+"/?"+tunnelProxyTokenParam+"="+loginToken, nil)
+	w = httptest.NewRecorder()
+	al.router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Empty(t, w.Result().Cookies())
+}
+
+func TestHandleTunnelProxyHosts(t *testing.T) {
+	c := clients.New(t).Build()
+	al := newTunnelProxyTestAPIListener(c)
+	label := al.tunnelProxyLabel(al.tunnelProxyHost(c.ID, "1"))
+	require.Len(t, label, 32)
+
+	testCases := []struct {
+		host      string
+		wantLabel string
+	}{
+		{
+			host:      label + "." + testTunnelProxyDomain,
+			wantLabel: label,
+		},
+		{
+			host:      strings.ToUpper(label+"."+testTunnelProxyDomain) + ":3000",
+			wantLabel: label,
+		},
+		{
+			host: testTunnelProxyDomain,
+		},
+		{
+			host: "a." + label + "." + testTunnelProxyDomain,
+		},
+		{
+			host: label + ".example.com",
+		},
+		{
+			host: "localhost:3000",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.host, func(t *testing.T) {
+			assert.Equal(t, tc.wantLabel, al.tunnelProxyLabel(tc.host))
+		})
+	}
+
+	// API routes are not served on tunnel hosts
+	req := httptest.NewRequest(http.MethodGet, "https://"+label+"."+testTunnelProxyDomain+"/api/v1/status", nil)
+	w := httptest.NewRecorder()
+	al.router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+func TestHandleGetTunnelProxyURL(t *testing.T) {
+	c := clients.New(t).Build()
+	scheme := "http"
+	startTestTunnel(t, c, test.NewConnMock(), "3", &chshare.Remote{
+		RemoteHost: "192.168.1.1",
+		RemotePort: "80",
+		Scheme:     &scheme,
+		HTTPProxy:  true,
+	}, "")
+	al := newTunnelProxyTestAPIListener(c)
+
+	req := httptest.NewRequest(http.MethodGet, "https://rport.example.com:3000/api/v1/clients/"+c.ID+"/tunnels/3/proxy-url", nil)
+	req = req.WithContext(api.WithUser(req.Context(), "admin"))
+	w := httptest.NewRecorder()
+	al.router.ServeHTTP(w, req)
+
+	require.Equal(t, http.StatusOK, w.Code)
+	var gotResp struct {
+		Data tunnelProxyURLPayload `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &gotResp))
+	gotURL, err := url.Parse(gotResp.Data.URL)
+	require.NoError(t, err)
+	assert.Equal(t, "https", gotURL.Scheme)
+	assert.Equal(t, al.tunnelProxyHost(c.ID, "3")+":3000", gotURL.Host)
+	assert.Equal(t, "/", gotURL.Path)
+	claims, err := al.parseTunnelProxyToken(gotURL.Query().Get(tunnelProxyTokenParam), tunnelProxyAudienceLogin, al.tunnelProxyLabel(gotURL.Host))
+	require.NoError(t, err)
+	assert.Equal(t, "admin", claims.Username)
+	assert.Equal(t, c.ID, claims.ClientID)
+	assert.Equal(t, "3", claims.TunnelID)
+
+	// tunnels with a port are not served by the proxy
+	req = httptest.NewRequest(http.MethodGet, "https://rport.example.com/api/v1/clients/"+c.ID+"/tunnels/1/proxy-url", nil)
+	req = req.WithContext(api.WithUser(req.Context(), "admin"))
+	w = httptest.NewRecorder()
+	al.router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestValidateTunnelProxy(t *testing.T) {
+	http := "http"
+	ftp := "ftp"
+	testCases := []struct {
+		name         string
+		disabled     bool
+		local        string
+		remote       chshare.Remote
+		wantErrTitle string
+	}{
+		{
+			name:   "valid",
+			remote: chshare.Remote{Scheme: &http, Protocol: chshare.ProtocolTCP},
+		},
+		{
+			name:         "disabled",
+			disabled:     true,
+			remote:       chshare.Remote{Scheme: &http, Protocol: chshare.ProtocolTCP},
+			wantErrTitle: "HTTP proxy for tunnels is disabled.",
+		},
+		{
+			name:         "no scheme",
+			remote:       chshare.Remote{Protocol: chshare.ProtocolTCP},
+			wantErrTitle: `HTTP proxy requires scheme "http" or "https".`,
+		},
+		{
+			name:         "unsupported scheme",
+			remote:       chshare.Remote{Scheme: &ftp, Protocol: chshare.ProtocolTCP},
+			wantErrTitle: `HTTP proxy requires scheme "http" or "https".`,
+		},
+		{
+			name:         "local is set",
+			local:        "3000",
+			remote:       chshare.Remote{Scheme: &http, Protocol: chshare.ProtocolTCP},
+			wantErrTitle: "Local can't be specified for tunnels served by HTTP proxy.",
+		},
+		{
+			name:         "reverse",
+			remote:       chshare.Remote{Scheme: &http, Protocol: chshare.ProtocolTCP, Reverse: true},
+			wantErrTitle: "HTTP proxy supports only TCP tunnels to a remote address.",
+		},
+		{
+			name:         "udp",
+			remote:       chshare.Remote{Scheme: &http, Protocol: chshare.ProtocolUDP},
+			wantErrTitle: "HTTP proxy supports only TCP tunnels to a remote address.",
+		},
+		{
+			name:         "socks",
+			remote:       chshare.Remote{Scheme: &http, Protocol: chshare.ProtocolTCP, Socks: true},
+			wantErrTitle: "HTTP proxy supports only TCP tunnels to a remote address.",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			al := &APIListener{
+				Server: &Server{
+					config: &Config{
+						API: APIConfig{
+							TunnelProxy: !tc.disabled,
+						},
+					},
+				},
+				Logger: testLog,
+			}
+			w := httptest.NewRecorder()
+
+			ok := al.validateTunnelProxy(w, tc.local, &tc.remote)
+
+			assert.Equal(t, tc.wantErrTitle == "", ok)
+			if tc.wantErrTitle != "" {
+				var gotResp api.ErrorPayload
+				require.NoError(t, json.Unmarshal(w.Body.Bytes(), &gotResp))
+				require.Len(t, gotResp.Errors, 1)
+				assert.Equal(t, tc.wantErrTitle, gotResp.Errors[0].Title)
+			}
+		})
+	}
+}
+
+func TestHandleTunnelProxyMiddlewares(t *testing.T) {
+	c := clients.New(t).Build()
+	al := &APIListener{
+		insecureForTests: true,
+		Server: &Server{
+			clientService: NewClientService(nil, clients.NewClientRepository([]*clients.Client{c}, &hour)),
+			config: &Config{
+				API: APIConfig{
+					JWTSecret:         "foobar",
+					TunnelProxy:       true,
+					TunnelProxyDomain: testTunnelProxyDomain,
+					Permissions:       map[string][]string{PermissionTunnels: {"Admins"}},
+				},
+			},
+		},
+		userSrv:   users.NewUserCache([]*users.User{{Username: "user1", Groups: []string{"Users"}}}),
+		bannedIPs: security.NewMaxBadAttemptsBanList(1, time.Hour, testLog),
+		Logger:    testLog,
+	}
+	al.initRouter()
+	al.bannedIPs.AddBadAttempt("192.0.2.2")
+
+	testCases := []struct {
+		name           string
+		remoteAddr     string
+		wantStatusCode int
+	}{
+		{
+			name:           "no permission",
+			remoteAddr:     "192.0.2.1:1234",
+			wantStatusCode: http.StatusForbidden,
+		},
+		{
+			name:           "banned ip",
+			remoteAddr:     "192.0.2.2:1234",
+			wantStatusCode: http.StatusLocked,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := newTunnelProxyRequest(t, al, "user1", c.ID, "1", "/")
+			req.RemoteAddr = tc.remoteAddr
+			w := httptest.NewRecorder()
+			al.router.ServeHTTP(w, req)
+
+			assert.Equal(t, tc.wantStatusCode, w.Code)
+		})
+	}
+}
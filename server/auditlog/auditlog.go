@@ -0,0 +1,122 @@
+package auditlog
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Actions that are recorded to the audit log.
+const (
+	ActionTunnelCreate       = "tunnel_create"
+	ActionTunnelDelete       = "tunnel_delete"
+	ActionCommandRun         = "command_run"
+	ActionCommandCancel      = "command_cancel"
+	ActionMultiCommandRun    = "multi_command_run"
+	ActionMultiCommandCancel = "multi_command_cancel"
+	ActionClientAuthCreate   = "client_auth_create"
+	ActionClientAuthDelete   = "client_auth_delete"
+	ActionClientCertCreate   = "client_cert_create"
+	ActionClientCertRevoke   = "client_cert_revoke"
+	ActionClientGroupCreate  = "client_group_create"
+	ActionClientGroupUpdate  = "client_group_update"
+	ActionClientGroupDelete  = "client_group_delete"
+	ActionFileUpload         = "file_upload"
+	ActionScheduleCreate     = "schedule_create"
+	ActionScheduleUpdate     = "schedule_update"
+	ActionScheduleDelete     = "schedule_delete"
+)
+
+// Entry represents a single mutating API action.
+type Entry struct {
+	Timestamp time.Time `json:"timestamp" db:"timestamp"`
+	Username  string    `json:"username" db:"username"`
+	RemoteIP  string    `json:"remote_ip" db:"remote_ip"`
+	Action    string    `json:"action" db:"action"`
+	// ClientID is empty if an action is not related to a single client.
+	ClientID string `json:"client_id" db:"client_id"`
+	Params   Params `json:"params" db:"params"`
+	// Tenant is set only when multi-tenancy is enabled.
+	Tenant string `json:"-" db:"tenant"`
+}
+
+// Params are action specific parameters.
+type Params map[string]interface{}
+
+func (p *Params) Scan(value interface{}) error {
+	if p == nil {
+		return errors.New("'params' cannot be nil")
+	}
+	var b []byte
+	switch v := value.(type) {
+	case string:
+		b = []byte(v)
+	case []byte:
+		b = v
+	default:
+		return fmt.Errorf("expected to have string, got %T", value)
+	}
+	err := json.Unmarshal(b, p)
+	if err != nil {
+		return fmt.Errorf("failed to decode 'params' field: %v", err)
+	}
+	return nil
+}
+
+func (p Params) Value() (driver.Value, error) {
+	b, err := json.Marshal(p)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode 'params' field: %v", err)
+	}
+	return string(b), nil
+}
+
+// Filter defines what entries to return. Empty fields match all entries.
+type Filter struct {
+	Username string
+	RemoteIP string
+	Action   string
+	ClientID string
+	Since    *time.Time
+	Until    *time.Time
+	// Tenant is nil when multi-tenancy is disabled.
+	Tenant *string
+
+	Offset int
+	Limit  int
+}
+
+// Match returns true if a given entry satisfies the filter. Pagination is not taken into account.
+func (f *Filter) Match(e *Entry) bool {
+	if f.Username != "" && e.Username != f.Username {
+		return false
+	}
+	if f.RemoteIP != "" && e.RemoteIP != f.RemoteIP {
+		return false
+	}
+	if f.Action != "" && e.Action != f.Action {
+		return false
+	}
+	if f.ClientID != "" && e.ClientID != f.ClientID {
+		return false
+	}
+	if f.Since != nil && e.Timestamp.Before(*f.Since) {
+		return false
+	}
+	if f.Until != nil && e.Timestamp.After(*f.Until) {
+		return false
+	}
+	if f.Tenant != nil && e.Tenant != *f.Tenant {
+		return false
+	}
+	return true
+}
+
+// Provider stores and reads audit log entries.
+type Provider interface {
+	Save(e *Entry) error
+	// List returns entries that match a given filter, newest first, and a total number of matching entries.
+	List(f *Filter) ([]*Entry, int, error)
+}
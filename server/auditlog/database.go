@@ -0,0 +1,95 @@
+package auditlog
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// DatabaseProvider stores audit log entries in a database table.
+type DatabaseProvider struct {
+	db        *sqlx.DB
+	tableName string
+}
+
+var _ Provider = &DatabaseProvider{}
+
+// NewDatabaseProvider returns a provider backed by a given database table.
+func NewDatabaseProvider(DB *sqlx.DB, tableName string) *DatabaseProvider {
+	return &DatabaseProvider{
+		db:        DB,
+		tableName: tableName,
+	}
+}
+
+func (p *DatabaseProvider) Save(e *Entry) error {
+	_, err := p.db.NamedExec(
+		fmt.Sprintf(
+			"INSERT INTO %s (timestamp, username, remote_ip, action, client_id, params, tenant) "+
+				"VALUES (:timestamp, :username, :remote_ip, :action, :client_id, :params, :tenant)",
+			p.tableName,
+		),
+		e,
+	)
+	return err
+}
+
+func (p *DatabaseProvider) List(f *Filter) ([]*Entry, int, error) {
+	where, args := p.where(f)
+
+	var total int
+	err := p.db.Get(&total, fmt.Sprintf("SELECT COUNT(*) FROM %s%s", p.tableName, where), args...)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	res := []*Entry{}
+	err = p.db.Select(
+		&res,
+		fmt.Sprintf(
+			"SELECT timestamp, username, remote_ip, action, client_id, params, tenant FROM %s%s ORDER BY timestamp DESC LIMIT ? OFFSET ?",
+			p.tableName,
+			where,
+		),
+		append(args, f.Limit, f.Offset)...,
+	)
+	if err != nil {
+		return nil, 0, err
+	}
+	return res, total, nil
+}
+
+func (p *DatabaseProvider) where(f *Filter) (string, []interface{}) {
+	var conditions []string
+	var args []interface{}
+	add := func(condition string, arg interface{}) {
+		conditions = append(conditions, condition)
+		args = append(args, arg)
+	}
+	if f.Username != "" {
+		add("username = ?", f.Username)
+	}
+	if f.RemoteIP != "" {
+		add("remote_ip = ?", f.RemoteIP)
+	}
+	if f.Action != "" {
+		add("action = ?", f.Action)
+	}
+	if f.ClientID != "" {
+		add("client_id = ?", f.ClientID)
+	}
+	if f.Since != nil {
+		add("timestamp >= ?", *f.Since)
+	}
+	if f.Until != nil {
+		add("timestamp <= ?", *f.Until)
+	}
+	if f.Tenant != nil {
+		add("tenant = ?", *f.Tenant)
+	}
+	if len(conditions) == 0 {
+		return "", nil
+	}
+	return " WHERE " + strings.Join(conditions, " AND "), args
+}
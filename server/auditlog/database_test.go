@@ -0,0 +1,19 @@
+package auditlog
+
+import (
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDatabaseProvider(t *testing.T) {
+	db, err := sqlx.Connect("sqlite3", ":memory:")
+	require.NoError(t, err)
+	defer db.Close()
+	_, err = db.Exec("CREATE TABLE `audit_log` (timestamp DATETIME, username TEXT, remote_ip TEXT, action TEXT, client_id TEXT, params TEXT, tenant TEXT)")
+	require.NoError(t, err)
+
+	testProvider(t, NewDatabaseProvider(db, "audit_log"))
+}
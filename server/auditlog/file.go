@@ -0,0 +1,98 @@
+package auditlog
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+)
+
+// maxLineSize is the max size of a single audit log entry that can be read from a file.
+const maxLineSize = 1024 * 1024
+
+// FileProvider stores audit log entries in a file as JSON lines.
+type FileProvider struct {
+	fileName string
+	mu       sync.Mutex
+}
+
+var _ Provider = &FileProvider{}
+
+// NewFileProvider returns a provider backed by a given file. The file is reopened on each write, so it can be
+// safely rotated, e.g. by logrotate.
+func NewFileProvider(fileName string) *FileProvider {
+	return &FileProvider{
+		fileName: fileName,
+	}
+}
+
+// fileEntry is used to store all entry fields including those that are hidden in API responses.
+type fileEntry struct {
+	*Entry
+	Tenant string `json:"tenant,omitempty"`
+}
+
+func (p *FileProvider) Save(e *Entry) error {
+	b, err := json.Marshal(fileEntry{Entry: e, Tenant: e.Tenant})
+	if err != nil {
+		return fmt.Errorf("failed to encode audit log entry: %v", err)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	file, err := os.OpenFile(p.fileName, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open audit log file: %v", err)
+	}
+	defer file.Close()
+
+	_, err = file.Write(append(b, '\n'))
+	if err != nil {
+		return fmt.Errorf("failed to write to audit log file: %v", err)
+	}
+	return nil
+}
+
+func (p *FileProvider) List(f *Filter) ([]*Entry, int, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	file, err := os.Open(p.fileName)
+	if os.IsNotExist(err) {
+		return nil, 0, nil
+	}
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to open audit log file: %v", err)
+	}
+	defer file.Close()
+
+	var matched []*Entry
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxLineSize)
+	for scanner.Scan() {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		cur := fileEntry{Entry: &Entry{}}
+		if err := json.Unmarshal(scanner.Bytes(), &cur); err != nil {
+			return nil, 0, fmt.Errorf("failed to decode audit log entry: %v", err)
+		}
+		cur.Entry.Tenant = cur.Tenant
+		if f.Match(cur.Entry) {
+			matched = append(matched, cur.Entry)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, 0, fmt.Errorf("failed to read audit log file: %v", err)
+	}
+
+	// entries are appended to the file, so the newest are at the end
+	total := len(matched)
+	res := make([]*Entry, 0, f.Limit)
+	for i := total - 1 - f.Offset; i >= 0 && len(res) < f.Limit; i-- {
+		res = append(res, matched[i])
+	}
+	return res, total, nil
+}
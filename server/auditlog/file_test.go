@@ -0,0 +1,114 @@
+package auditlog
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	t1 = time.Date(2021, 5, 1, 10, 0, 0, 0, time.UTC)
+	t2 = t1.Add(time.Minute)
+	t3 = t1.Add(2 * time.Minute)
+
+	e1 = &Entry{Timestamp: t1, Username: "admin", RemoteIP: "1.2.3.4", Action: ActionTunnelCreate, ClientID: "client-1", Params: Params{"remote": "22"}}
+	e2 = &Entry{Timestamp: t2, Username: "user1", RemoteIP: "1.2.3.5", Action: ActionCommandRun, ClientID: "client-2", Params: Params{"command": "/bin/date"}, Tenant: "tenant-1"}
+	e3 = &Entry{Timestamp: t3, Username: "admin", RemoteIP: "1.2.3.4", Action: ActionClientGroupDelete, Params: Params{"group_id": "group-1"}}
+)
+
+func testProvider(t *testing.T, p Provider) {
+	for _, e := range []*Entry{e1, e2, e3} {
+		require.NoError(t, p.Save(e))
+	}
+
+	tenant := "tenant-1"
+	testCases := []struct {
+		name      string
+		filter    Filter
+		wantRes   []*Entry
+		wantTotal int
+	}{
+		{
+			name:      "all",
+			filter:    Filter{Limit: 10},
+			wantRes:   []*Entry{e3, e2, e1},
+			wantTotal: 3,
+		},
+		{
+			name:      "by username",
+			filter:    Filter{Username: "admin", Limit: 10},
+			wantRes:   []*Entry{e3, e1},
+			wantTotal: 2,
+		},
+		{
+			name:      "by action and client",
+			filter:    Filter{Action: ActionCommandRun, ClientID: "client-2", Limit: 10},
+			wantRes:   []*Entry{e2},
+			wantTotal: 1,
+		},
+		{
+			name:      "by remote ip",
+			filter:    Filter{RemoteIP: "1.2.3.5", Limit: 10},
+			wantRes:   []*Entry{e2},
+			wantTotal: 1,
+		},
+		{
+			name:      "by time range",
+			filter:    Filter{Since: &t2, Until: &t3, Limit: 10},
+			wantRes:   []*Entry{e3, e2},
+			wantTotal: 2,
+		},
+		{
+			name:      "by tenant",
+			filter:    Filter{Tenant: &tenant, Limit: 10},
+			wantRes:   []*Entry{e2},
+			wantTotal: 1,
+		},
+		{
+			name:      "pagination",
+			filter:    Filter{Offset: 1, Limit: 1},
+			wantRes:   []*Entry{e2},
+			wantTotal: 3,
+		},
+		{
+			name:      "offset out of range",
+			filter:    Filter{Offset: 5, Limit: 1},
+			wantRes:   []*Entry{},
+			wantTotal: 3,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			gotRes, gotTotal, err := p.List(&tc.filter)
+			require.NoError(t, err)
+			assert.Equal(t, tc.wantTotal, gotTotal)
+			require.Len(t, gotRes, len(tc.wantRes))
+			for i := range tc.wantRes {
+				assert.True(t, tc.wantRes[i].Timestamp.Equal(gotRes[i].Timestamp))
+				gotRes[i].Timestamp = tc.wantRes[i].Timestamp
+				assert.Equal(t, tc.wantRes[i], gotRes[i])
+			}
+		})
+	}
+}
+
+func TestFileProvider(t *testing.T) {
+	dir, err := ioutil.TempDir("", "auditlog")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	p := NewFileProvider(path.Join(dir, "audit.log"))
+
+	res, total, err := p.List(&Filter{Limit: 10})
+	require.NoError(t, err)
+	assert.Empty(t, res)
+	assert.Equal(t, 0, total)
+
+	testProvider(t, p)
+}
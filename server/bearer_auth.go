@@ -21,7 +21,7 @@ type Token struct {
 	jwt.StandardClaims
 }
 
-func (al *APIListener) createAuthToken(lifetime time.Duration, username string) (string, error) {
+func (al *APIListener) createAuthToken(lifetime time.Duration, username, ip string) (string, error) {
 	if username == "" {
 		return "", errors.New("username cannot be empty")
 	}
@@ -39,7 +39,7 @@ func (al *APIListener) createAuthToken(lifetime time.Duration, username string)
 	}
 
 	expiresAt := time.Now().Add(lifetime)
-	err = al.apiSessionRepo.Save(&APISession{tokenStr, expiresAt})
+	err = al.apiSessionRepo.Save(&APISession{Token: tokenStr, ExpiresAt: expiresAt, Username: username, IP: ip})
 	if err != nil {
 		return "", err
 	}
@@ -47,13 +47,17 @@ func (al *APIListener) createAuthToken(lifetime time.Duration, username string)
 	return tokenStr, nil
 }
 
-func (al *APIListener) increaseSessionLifetime(s *APISession) error {
+// increaseSessionLifetime extends a given session and updates its source IP with a given one. The session is copied,
+// because saved sessions are read concurrently by ACLs of tunnels.
+func (al *APIListener) increaseSessionLifetime(s *APISession, ip string) error {
 	newExpirationDate := s.ExpiresAt.Add(defaultTokenLifetime)
 	if time.Now().After(s.ExpiresAt) {
 		newExpirationDate = time.Now().Add(defaultTokenLifetime)
 	}
-	s.ExpiresAt = newExpirationDate
-	return al.apiSessionRepo.Save(s)
+	updated := *s
+	updated.ExpiresAt = newExpirationDate
+	updated.IP = ip
+	return al.apiSessionRepo.Save(&updated)
 }
 
 func (al *APIListener) validateBearerToken(tokenStr string) (bool, string, *APISession, error) {
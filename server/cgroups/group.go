@@ -15,6 +15,8 @@ type ClientGroup struct {
 	Params      *ClientParams `json:"params" db:"params"`
 	// ClientIDs shows what clients belong to a given group. Note: it's populated separately.
 	ClientIDs []string `json:"client_ids" db:"-"`
+	// Tenant is set only when multi-tenancy is enabled.
+	Tenant string `json:"-" db:"tenant"`
 }
 
 type ClientParams struct {
@@ -60,7 +60,7 @@ func (p *SqliteProvider) Get(ctx context.Context, id string) (*ClientGroup, erro
 func (p *SqliteProvider) Create(ctx context.Context, group *ClientGroup) error {
 	_, err := p.db.NamedExecContext(
 		ctx,
-		"INSERT INTO client_groups (id, description, params) VALUES (:id, :description, :params)",
+		"INSERT INTO client_groups (id, description, params, tenant) VALUES (:id, :description, :params, :tenant)",
 		group,
 	)
 	return err
@@ -69,7 +69,7 @@ func (p *SqliteProvider) Create(ctx context.Context, group *ClientGroup) error {
 func (p *SqliteProvider) Update(ctx context.Context, group *ClientGroup) error {
 	_, err := p.db.NamedExecContext(
 		ctx,
-		"INSERT OR REPLACE INTO client_groups (id, description, params) VALUES (:id, :description, :params)",
+		"INSERT OR REPLACE INTO client_groups (id, description, params, tenant) VALUES (:id, :description, :params, :tenant)",
 		group,
 	)
 	return err
@@ -41,6 +41,9 @@ type ClientListener struct {
 	clientIndexAutoIncrement int32
 }
 
+// sshPermissionTenant is a key of ssh permissions extension that holds a tenant of an authenticated client.
+const sshPermissionTenant = "tenant"
+
 var upgrader = websocket.Upgrader{
 	ReadBufferSize:  1024,
 	WriteBufferSize: 1024,
@@ -70,6 +73,9 @@ func NewClientListener(server *Server, privateKey ssh.Signer) (*ClientListener,
 		ServerVersion:    "SSH-" + chshare.ProtocolVersion + "-server",
 		PasswordCallback: cl.authUser,
 	}
+	if server.clientCertAuthority != nil {
+		cl.sshConfig.PublicKeyCallback = cl.authPublicKey
+	}
 	cl.sshConfig.AddHostKey(privateKey)
 	//setup reverse proxy
 	if config.Server.Proxy != "" {
@@ -95,14 +101,8 @@ func NewClientListener(server *Server, privateKey ssh.Signer) (*ClientListener,
 // authUser is responsible for validating the ssh user / password combination
 func (cl *ClientListener) authUser(c ssh.ConnMetadata, password []byte) (*ssh.Permissions, error) {
 	clientAuthID := c.User()
-
-	if cl.bannedClientAuths.IsBanned(clientAuthID) {
-		cl.Infof("Failed login attempt for client auth id %q, forcing to wait for %vs (%s)",
-			clientAuthID,
-			cl.config.Server.ClientLoginWait,
-			cl.getIP(c.RemoteAddr()),
-		)
-		return nil, ErrTooManyRequests
+	if err := cl.checkBannedClientAuth(c); err != nil {
+		return nil, err
 	}
 
 	clientAuth, err := cl.clientAuthProvider.Get(clientAuthID)
@@ -110,21 +110,61 @@ func (cl *ClientListener) authUser(c ssh.ConnMetadata, password []byte) (*ssh.Pe
 		return nil, err
 	}
 
-	ip := cl.getIP(c.RemoteAddr())
 	// constant time compare is used for security reasons
 	if clientAuth == nil || subtle.ConstantTimeCompare([]byte(clientAuth.Password), password) != 1 {
 		cl.Debugf("Login failed for client auth id: %s", clientAuthID)
 		cl.bannedClientAuths.Add(clientAuthID)
 		if cl.bannedIPs != nil {
-			cl.bannedIPs.AddBadAttempt(ip)
+			cl.bannedIPs.AddBadAttempt(cl.getIP(c.RemoteAddr()))
 		}
 		return nil, fmt.Errorf("invalid authentication for client auth id: %s", clientAuthID)
 	}
 
+	return cl.loginSucceeded(c, clientAuth.Tenant), nil
+}
+
+// authPublicKey is responsible for validating client certificates signed by the client CA and public keys that
+// certificates were signed for.
+func (cl *ClientListener) authPublicKey(c ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
+	clientAuthID := c.User()
+	if err := cl.checkBannedClientAuth(c); err != nil {
+		return nil, err
+	}
+
+	// a rejected key is not counted as a failed login: keys can't be guessed and a password might be tried next
+	cert, err := cl.clientCertAuthority.Authenticate(context.Background(), clientAuthID, key)
+	if err != nil {
+		cl.Debugf("Login with %s key failed for client auth id %s: %v", key.Type(), clientAuthID, err)
+		return nil, fmt.Errorf("invalid authentication for client auth id: %s", clientAuthID)
+	}
+
+	return cl.loginSucceeded(c, cert.Tenant), nil
+}
+
+func (cl *ClientListener) checkBannedClientAuth(c ssh.ConnMetadata) error {
+	if cl.bannedClientAuths.IsBanned(c.User()) {
+		cl.Infof("Failed login attempt for client auth id %q, forcing to wait for %vs (%s)",
+			c.User(),
+			cl.config.Server.ClientLoginWait,
+			cl.getIP(c.RemoteAddr()),
+		)
+		return ErrTooManyRequests
+	}
+	return nil
+}
+
+// loginSucceeded returns ssh permissions of a client that is authenticated as a given tenant.
+func (cl *ClientListener) loginSucceeded(c ssh.ConnMetadata, tenant string) *ssh.Permissions {
 	if cl.bannedIPs != nil {
-		cl.bannedIPs.AddSuccessAttempt(ip)
+		cl.bannedIPs.AddSuccessAttempt(cl.getIP(c.RemoteAddr()))
 	}
-	return nil, nil
+
+	if cl.config.Server.MultiTenancy {
+		return &ssh.Permissions{
+			Extensions: map[string]string{sshPermissionTenant: tenant},
+		}
+	}
+	return nil
 }
 
 func (cl *ClientListener) getIP(addr net.Addr) string {
@@ -243,18 +283,25 @@ func (cl *ClientListener) handleWebsocket(w http.ResponseWriter, req *http.Reque
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	client, err := cl.clientService.StartClient(ctx, clientAuthID, cid, sshConn, cl.config.Server.AuthMultiuseCreds, connRequest, clog)
+	// tenant is set on authentication when multi-tenancy is enabled
+	var tenant string
+	if sshConn.Permissions != nil {
+		tenant = sshConn.Permissions.Extensions[sshPermissionTenant]
+	}
+
+	client, err := cl.clientService.StartClient(ctx, clientAuthID, tenant, cid, sshConn, cl.config.Server.AuthMultiuseCreds, connRequest, clog)
 	if err != nil {
 		failed(err)
 		return
 	}
 
 	cl.replyConnectionSuccess(r, connRequest.Remotes)
+	cl.announceHostKeys(clog, sshConn)
 
 	clientBanner := client.Banner()
 	clog.Debugf("Open %s", clientBanner)
 	go cl.handleSSHRequests(clog, reqs)
-	go cl.handleSSHChannels(clog, chans)
+	go cl.handleSSHChannels(client, chans)
 	_ = sshConn.Wait()
 	clog.Debugf("Close %s", clientBanner)
 
@@ -299,6 +346,42 @@ func getRemotes(tunnels []*clients.Tunnel) []*chshare.Remote {
 	return r
 }
 
+func getConflictRemotes(conflicts []*clients.TunnelConflict) []*chshare.Remote {
+	r := make([]*chshare.Remote, 0, len(conflicts))
+	for _, c := range conflicts {
+		r = append(r, &c.Remote)
+	}
+	return r
+}
+
+func containsRemote(remotes []*chshare.Remote, r *chshare.Remote) bool {
+	for _, cur := range remotes {
+		if cur == r {
+			return true
+		}
+	}
+	return false
+}
+
+// GetPersistentTunnelsToReestablish returns old persistent tunnels created with the API that should be re-established
+// regardless of new tunnels requested by the client, except those that are requested again.
+func GetPersistentTunnelsToReestablish(old, new []*chshare.Remote) []*chshare.Remote {
+	var res []*chshare.Remote
+loop:
+	for _, curOld := range old {
+		if !curOld.Persistent || curOld.CreatedBy == "" || curOld.Expired() {
+			continue
+		}
+		for _, curNew := range new {
+			if curNew.Equals(curOld) {
+				continue loop
+			}
+		}
+		res = append(res, curOld)
+	}
+	return res
+}
+
 // GetTunnelsToReestablish returns old tunnels that should be re-establish taking into account new tunnels.
 func GetTunnelsToReestablish(old, new []*chshare.Remote) []*chshare.Remote {
 	if len(new) > len(old) {
@@ -341,7 +424,8 @@ loop2:
 	// add tunnels that left among old
 	var res []*chshare.Remote
 	for i, marked := range oldMarked {
-		if !marked {
+		// expired tunnels are not re-established, they could expire while the client or the server was down
+		if !marked && !old[i].Expired() {
 			r := *old[i]
 			// if it was random then set up zero values
 			if r.LocalPortRandom {
@@ -370,11 +454,34 @@ func (cl *ClientListener) replyConnectionError(r *ssh.Request, err error) {
 	_ = r.Reply(false, []byte(err.Error()))
 }
 
+// announceHostKeys sends fingerprints of next host keys to a connected client. No reply is expected, so clients that
+// don't support it just ignore the request.
+func (cl *ClientListener) announceHostKeys(clientLog *chshare.Logger, sshConn ssh.Conn) {
+	if len(cl.nextFingerprints) == 0 {
+		return
+	}
+	payload, err := json.Marshal(&comm.HostKeysRequest{NextFingerprints: cl.nextFingerprints})
+	if err != nil {
+		clientLog.Errorf("Failed to encode host keys request: %v", err)
+		return
+	}
+	if _, _, err := sshConn.SendRequest(comm.RequestTypeHostKeys, false, payload); err != nil {
+		clientLog.Errorf("Failed to announce host keys: %v", err)
+	}
+}
+
 func (cl *ClientListener) handleSSHRequests(clientLog *chshare.Logger, reqs <-chan *ssh.Request) {
 	for r := range reqs {
 		switch r.Type {
 		case comm.RequestTypePing:
 			_ = r.Reply(true, nil)
+		case comm.RequestTypeCmdOutput:
+			output, err := cl.saveCmdOutput(r.Payload)
+			if err != nil {
+				clientLog.Errorf("Failed to save cmd output: %s", err)
+				continue
+			}
+			clientLog.Debugf("%s, Command output saved successfully.", output.LogPrefix())
 		case comm.RequestTypeCmdResult:
 			job, err := cl.saveCmdResult(r.Payload)
 			if err != nil {
@@ -383,7 +490,8 @@ func (cl *ClientListener) handleSSHRequests(clientLog *chshare.Logger, reqs <-ch
 			}
 			clientLog.Debugf("%s, Command result saved successfully.", job.LogPrefix())
 
-			if job.MultiJobID != nil {
+			// a queued job that is started is sent with the running status, it's not done yet
+			if job.MultiJobID != nil && job.Status != models.JobStatusRunning {
 				done := cl.jobsDoneChannel.Get(*job.MultiJobID)
 				if done != nil {
 					// to avoid blocking the exec - send job result in a new goroutine
@@ -413,7 +521,11 @@ func (cl *ClientListener) saveCmdResult(respBytes []byte) (*models.Job, error) {
 	}
 	ws := cl.Server.uiJobWebSockets.Get(wsJID)
 	if ws != nil {
-		err := ws.WriteMessage(websocket.TextMessage, respBytes)
+		if resp.Status == models.JobStatusRunning {
+			err = ws.WriteIntermediateMessage(websocket.TextMessage, respBytes)
+		} else {
+			err = ws.WriteMessage(websocket.TextMessage, respBytes)
+		}
 		if err != nil {
 			cl.Errorf("%s, failed to write message to UI Web Socket: %v", resp.LogPrefix(), err)
 			// proceed further
@@ -430,18 +542,66 @@ func (cl *ClientListener) saveCmdResult(respBytes []byte) (*models.Job, error) {
 	return &resp, nil
 }
 
-func (cl *ClientListener) handleSSHChannels(clientLog *chshare.Logger, chans <-chan ssh.NewChannel) {
-	for ch := range chans {
-		remote := string(ch.ExtraData())
-		//accept rest
-		stream, reqs, err := ch.Accept()
+// saveCmdOutput sends a chunk of output of a running command to a UI Web Socket and appends it to the stored job.
+func (cl *ClientListener) saveCmdOutput(outputBytes []byte) (*models.JobOutput, error) {
+	output := models.JobOutput{}
+	err := json.Unmarshal(outputBytes, &output)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode cmd output request: %s", err)
+	}
+	if output.Output == nil {
+		return nil, errors.New("cmd output request has no output")
+	}
+
+	var wsJID string
+	if output.MultiJobID != nil {
+		wsJID = *output.MultiJobID
+	} else {
+		wsJID = output.JID
+	}
+	ws := cl.Server.uiJobWebSockets.Get(wsJID)
+	if ws != nil {
+		err := ws.WriteIntermediateMessage(websocket.TextMessage, outputBytes)
 		if err != nil {
-			clientLog.Debugf("Failed to accept stream: %s", err)
+			cl.Errorf("%s, failed to write message to UI Web Socket: %v", output.LogPrefix(), err)
+			// proceed further
+		}
+	}
+
+	err = cl.jobProvider.AppendOutput(output.JID, output.Output)
+	if err != nil {
+		return nil, fmt.Errorf("failed to append job output: %s", err)
+	}
+
+	return &output, nil
+}
+
+// handleSSHChannels handles channels opened by a client for connections of its reverse tunnels.
+// Other channels are rejected, so the client can't connect to arbitrary addresses.
+func (cl *ClientListener) handleSSHChannels(client *clients.Client, chans <-chan ssh.NewChannel) {
+	for ch := range chans {
+		if ch.ChannelType() != comm.ChannelTypeReverse {
+			cl.rejectChannel(client.Logger, ch, ssh.UnknownChannelType, fmt.Sprintf("unsupported channel type: %s", ch.ChannelType()))
 			continue
 		}
-		go ssh.DiscardRequests(reqs)
-		//handle stream type
-		connID := cl.connStats.New()
-		go chshare.HandleTCPStream(clientLog.Fork("conn#%d", connID), &cl.connStats, stream, remote)
+		// handle in a goroutine to not block the connection while waiting for the client lock
+		go cl.handleReverseChannel(client, ch)
+	}
+}
+
+func (cl *ClientListener) handleReverseChannel(client *clients.Client, ch ssh.NewChannel) {
+	remote := string(ch.ExtraData())
+	t := client.FindReverseTunnel(remote)
+	if t == nil {
+		cl.rejectChannel(client.Logger, ch, ssh.Prohibited, fmt.Sprintf("no reverse tunnel to %s", remote))
+		return
+	}
+	t.HandleReverseChannel(ch, &cl.connStats)
+}
+
+func (cl *ClientListener) rejectChannel(clientLog *chshare.Logger, ch ssh.NewChannel, reason ssh.RejectionReason, msg string) {
+	clientLog.Debugf("Rejecting channel: %s", msg)
+	if err := ch.Reject(reason, msg); err != nil {
+		clientLog.Errorf("Failed to reject channel: %s", err)
 	}
 }
@@ -1,13 +1,21 @@
 package chserver
 
 import (
+	"context"
 	"fmt"
+	"net"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh"
 
+	"github.com/cloudradar-monitoring/rport/server/clientcerts"
 	chshare "github.com/cloudradar-monitoring/rport/share"
+	"github.com/cloudradar-monitoring/rport/share/models"
+	"github.com/cloudradar-monitoring/rport/share/security"
+	"github.com/cloudradar-monitoring/rport/share/ws"
 )
 
 func TestGetTunnelsToReestablish(t *testing.T) {
@@ -423,3 +431,126 @@ func TestGetTunnelsToReestablish(t *testing.T) {
 		assert.ElementsMatch(t, tc.wantResStr, gotResStr, msg)
 	}
 }
+
+func TestGetTunnelsToReestablishSkipsExpired(t *testing.T) {
+	past := time.Now().Add(-time.Minute)
+	future := time.Now().Add(time.Hour)
+	old := []*chshare.Remote{
+		{LocalHost: "0.0.0.0", LocalPort: "3000", RemoteHost: "0.0.0.0", RemotePort: "22", ExpiresAt: &past},
+		{LocalHost: "0.0.0.0", LocalPort: "3001", RemoteHost: "0.0.0.0", RemotePort: "80", ExpiresAt: &future},
+		{LocalHost: "0.0.0.0", LocalPort: "3002", RemoteHost: "0.0.0.0", RemotePort: "443"},
+	}
+
+	gotRes := GetTunnelsToReestablish(old, nil)
+
+	require.Len(t, gotRes, 2)
+	assert.Equal(t, "0.0.0.0:3001:0.0.0.0:80", gotRes[0].String())
+	assert.Equal(t, &future, gotRes[0].ExpiresAt)
+	assert.Equal(t, "0.0.0.0:3002:0.0.0.0:443", gotRes[1].String())
+}
+
+func TestSaveCmdOutput(t *testing.T) {
+	jp := NewJobProviderMock()
+	cl := ClientListener{
+		Server: &Server{
+			jobProvider:     jp,
+			uiJobWebSockets: ws.NewWebSocketCache(),
+		},
+		Logger: testLog,
+	}
+
+	output, err := cl.saveCmdOutput([]byte(`{"jid":"job-1","client_id":"client-1","output":{"stdout":"out","stderr":""}}`))
+	require.NoError(t, err)
+
+	assert.Equal(t, "job-1", output.JID)
+	assert.Equal(t, "job-1", jp.InputJID)
+	assert.Equal(t, &models.JobResult{StdOut: "out"}, jp.InputOutput)
+
+	_, err = cl.saveCmdOutput([]byte(`{"jid":"job-1","client_id":"client-1"}`))
+	assert.EqualError(t, err, "cmd output request has no output")
+}
+
+func TestGetPersistentTunnelsToReestablish(t *testing.T) {
+	past := time.Now().Add(-time.Minute)
+	old := []*chshare.Remote{
+		{LocalHost: "0.0.0.0", LocalPort: "3000", RemoteHost: "0.0.0.0", RemotePort: "22", Persistent: true, CreatedBy: "admin"},
+		{LocalHost: "0.0.0.0", LocalPort: "3001", RemoteHost: "0.0.0.0", RemotePort: "80", Persistent: true, CreatedBy: "admin", ExpiresAt: &past},
+		{LocalHost: "0.0.0.0", LocalPort: "3002", RemoteHost: "0.0.0.0", RemotePort: "443", CreatedBy: "admin"},
+		{LocalHost: "0.0.0.0", LocalPort: "3003", RemoteHost: "0.0.0.0", RemotePort: "3389", Persistent: true},
+		{LocalHost: "0.0.0.0", LocalPort: "3004", RemoteHost: "0.0.0.0", RemotePort: "5432", Persistent: true, CreatedBy: "admin"},
+	}
+	new := []*chshare.Remote{
+		{LocalHost: "0.0.0.0", LocalPort: "3004", RemoteHost: "0.0.0.0", RemotePort: "5432"},
+		{LocalHost: "0.0.0.0", LocalPort: "3005", RemoteHost: "0.0.0.0", RemotePort: "8080"},
+	}
+
+	gotRes := GetPersistentTunnelsToReestablish(old, new)
+
+	require.Len(t, gotRes, 1)
+	assert.Equal(t, old[0], gotRes[0])
+	assert.Nil(t, GetTunnelsToReestablish(old, new), "persistent tunnels are restored even if new tunnels are not a subset of old")
+}
+
+type connMetadataMock struct {
+	ssh.ConnMetadata
+	user string
+}
+
+func (m *connMetadataMock) User() string {
+	return m.user
+}
+
+func (m *connMetadataMock) RemoteAddr() net.Addr {
+	return &net.TCPAddr{IP: net.IPv4(192, 0, 2, 1), Port: 1234}
+}
+
+func TestAuthPublicKey(t *testing.T) {
+	// given
+	certProvider, err := clientcerts.NewSqliteProvider(":memory:")
+	require.NoError(t, err)
+	defer certProvider.Close()
+	authority := clientcerts.NewAuthority(newTestSigner(t), certProvider, time.Hour)
+	clientKey := newTestSigner(t).PublicKey()
+	record, cert, err := authority.Sign(context.Background(), "client-1", clientKey, "tenant-1", "admin")
+	require.NoError(t, err)
+	cl := ClientListener{
+		Server: &Server{
+			config:              &Config{Server: ServerConfig{MultiTenancy: true}},
+			clientCertAuthority: authority,
+		},
+		Logger:            testLog,
+		bannedClientAuths: security.NewBanList(0),
+	}
+
+	// when
+	perms, err := cl.authPublicKey(&connMetadataMock{user: "client-1"}, cert)
+
+	// then
+	require.NoError(t, err)
+	assert.Equal(t, &ssh.Permissions{Extensions: map[string]string{sshPermissionTenant: "tenant-1"}}, perms)
+
+	perms, err = cl.authPublicKey(&connMetadataMock{user: "client-1"}, clientKey)
+	require.NoError(t, err)
+	assert.Equal(t, "tenant-1", perms.Extensions[sshPermissionTenant])
+
+	_, err = cl.authPublicKey(&connMetadataMock{user: "client-2"}, cert)
+	assert.EqualError(t, err, "invalid authentication for client auth id: client-2")
+
+	// when
+	_, err = certProvider.Revoke(context.Background(), record.ID, time.Now())
+	require.NoError(t, err)
+
+	// then
+	_, err = cl.authPublicKey(&connMetadataMock{user: "client-1"}, cert)
+	assert.EqualError(t, err, "invalid authentication for client auth id: client-1")
+	_, err = cl.authPublicKey(&connMetadataMock{user: "client-1"}, clientKey)
+	assert.EqualError(t, err, "invalid authentication for client auth id: client-1")
+}
+
+func newTestSigner(t *testing.T) ssh.Signer {
+	key, err := chshare.GenerateKey("")
+	require.NoError(t, err)
+	signer, err := ssh.ParsePrivateKey(key)
+	require.NoError(t, err)
+	return signer
+}
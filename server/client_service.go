@@ -11,6 +11,7 @@ import (
 
 	"golang.org/x/crypto/ssh"
 
+	"github.com/cloudradar-monitoring/rport/server/api/users"
 	"github.com/cloudradar-monitoring/rport/server/cgroups"
 	"github.com/cloudradar-monitoring/rport/server/clients"
 	"github.com/cloudradar-monitoring/rport/server/ports"
@@ -20,6 +21,16 @@ import (
 type ClientService struct {
 	repo            *clients.ClientRepository
 	portDistributor *ports.PortDistributor
+	// reverseTunnelDestinations restricts remotes of reverse tunnels, nil disables reverse tunnels
+	reverseTunnelDestinations *chshare.TunnelDestinations
+	// apiSessions is used by ACLs of tunnels locked to sessions of API users
+	apiSessions *APISessionRepository
+	// portPools select pools of the port distributor for random ports, client groups of the pools are looked up
+	// with clientGroupProvider
+	portPools           []PortPoolConfig
+	clientGroupProvider cgroups.ClientGroupProvider
+	// portReservations keep random ports of tunnels per client and remote, nil disables reservations
+	portReservations clients.PortReservationProvider
 
 	mu sync.Mutex
 }
@@ -93,7 +104,7 @@ func (s *ClientService) GetAll() ([]*clients.Client, error) {
 }
 
 func (s *ClientService) StartClient(
-	ctx context.Context, clientAuthID, clientID string, sshConn ssh.Conn, authMultiuseCreds bool,
+	ctx context.Context, clientAuthID, tenant, clientID string, sshConn ssh.Conn, authMultiuseCreds bool,
 	req *chshare.ConnectionRequest, clog *chshare.Logger,
 ) (*clients.Client, error) {
 	s.mu.Lock()
@@ -104,16 +115,33 @@ func (s *ClientService) StartClient(
 	if err != nil {
 		return nil, fmt.Errorf("failed to get client by id %q", clientID)
 	}
+	if oldClient != nil && oldClient.DisconnectedAt == nil {
+		return nil, fmt.Errorf("client id %q is already in use", clientID)
+	}
+	// a disconnected client of another tenant is not taken over, otherwise its tunnels would lead to this client
+	if oldClient != nil && oldClient.Tenant != tenant {
+		return nil, fmt.Errorf("client id %q is already in use", clientID)
+	}
+
+	// tunnels requested by the client are requested again on every connect
+	for _, remote := range req.Remotes {
+		remote.Persistent = true
+		remote.CreatedBy = ""
+	}
+
+	var oldTunnels []*chshare.Remote
 	if oldClient != nil {
-		if oldClient.DisconnectedAt == nil {
-			return nil, fmt.Errorf("client id %q is already in use", clientID)
+		old := append(getRemotes(oldClient.Tunnels), getConflictRemotes(oldClient.TunnelConflicts)...)
+		s.reuseLocalPorts(old, req.Remotes)
+		oldTunnels = GetTunnelsToReestablish(old, req.Remotes)
+		for _, persistent := range GetPersistentTunnelsToReestablish(old, req.Remotes) {
+			if !containsRemote(oldTunnels, persistent) {
+				oldTunnels = append(oldTunnels, persistent)
+			}
 		}
-
-		oldTunnels := GetTunnelsToReestablish(getRemotes(oldClient.Tunnels), req.Remotes)
 		clog.Infof("Tunnels to create %d: %v", len(req.Remotes), req.Remotes)
 		if len(oldTunnels) > 0 {
 			clog.Infof("Old tunnels to re-establish %d: %v", len(oldTunnels), oldTunnels)
-			req.Remotes = append(req.Remotes, oldTunnels...)
 		}
 	}
 
@@ -131,6 +159,7 @@ func (s *ClientService) StartClient(
 	client := &clients.Client{
 		ID:           clientID,
 		ClientAuthID: clientAuthID,
+		Tenant:       tenant,
 		Name:         req.Name,
 		Tags:         req.Tags,
 		OS:           req.OS,
@@ -148,10 +177,16 @@ func (s *ClientService) StartClient(
 		Logger:       clog,
 	}
 
-	_, err = s.startClientTunnels(client, req.Remotes)
+	pool, err := s.SelectPortPool(ctx, client, nil)
 	if err != nil {
 		return nil, err
 	}
+	_, err = s.startClientTunnels(client, req.Remotes, pool)
+	if err != nil {
+		return nil, err
+	}
+	// old tunnels are added to the reply, so the client starts listeners of old reverse tunnels too
+	req.Remotes = append(req.Remotes, s.restoreClientTunnels(client, oldTunnels)...)
 
 	err = s.repo.Save(client)
 	if err != nil {
@@ -160,26 +195,65 @@ func (s *ClientService) StartClient(
 	return client, nil
 }
 
-// StartClientTunnels returns a new tunnel for each requested remote or nil if error occurred
-func (s *ClientService) StartClientTunnels(client *clients.Client, remotes []*chshare.Remote) ([]*clients.Tunnel, error) {
+// StartClientTunnels returns a new tunnel for each requested remote or nil if error occurred.
+// Random ports are taken from a given port pool. The client is asked to listen for reverse tunnels.
+func (s *ClientService) StartClientTunnels(client *clients.Client, remotes []*chshare.Remote, pool string) ([]*clients.Tunnel, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	return s.startClientTunnels(client, remotes)
+	tunnels, err := s.startClientTunnels(client, remotes, pool)
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range tunnels {
+		if !t.Reverse {
+			continue
+		}
+		if err = t.StartReverseListener(); err != nil {
+			if termErr := client.TerminateTunnel(t, true); termErr != nil {
+				client.Logger.Errorf("Failed to terminate tunnel %s: %v", t.ID, termErr)
+			}
+			return nil, err
+		}
+	}
+	return tunnels, nil
 }
 
-func (s *ClientService) startClientTunnels(client *clients.Client, remotes []*chshare.Remote) ([]*clients.Tunnel, error) {
-	err := s.portDistributor.Refresh()
+func (s *ClientService) startClientTunnels(client *clients.Client, remotes []*chshare.Remote, pool string) ([]*clients.Tunnel, error) {
+	reservations, err := s.getAllPortReservations()
 	if err != nil {
 		return nil, err
 	}
 
+	// ports given out by the port distributor are released when their tunnels are stopped or if they fail to start.
+	// Random ports of old tunnels are taken first, so they are not given out to other tunnels.
+	taken := make(map[*chshare.Remote]int)
+	defer func() {
+		for _, port := range taken {
+			s.portDistributor.Release(port)
+		}
+	}()
+	for _, remote := range remotes {
+		if !remote.LocalPortRandom || !remote.IsLocalSpecified() {
+			continue
+		}
+		port, err := strconv.Atoi(remote.LocalPort)
+		if err == nil && s.portDistributor.TakePortFromPool(s.portDistributor.PoolOf(port), port) {
+			taken[remote] = port
+		}
+	}
+
 	tunnels := make([]*clients.Tunnel, 0, len(remotes))
 	for _, remote := range remotes {
-		if !remote.IsLocalSpecified() {
-			port, err := s.portDistributor.GetRandomPort()
+		if remote.Reverse && !s.reverseTunnelDestinations.Allows(remote.RemoteHost, remote.RemotePort) {
+			return nil, fmt.Errorf("reverse tunnel destination %s is not allowed", remote.Remote())
+		}
+		// tunnels served by the HTTP proxy don't need a server port
+		if !remote.IsLocalSpecified() && !remote.HTTPProxy {
+			port, err := s.getRandomPort(client.ID, remote, pool, reservations)
 			if err != nil {
 				return nil, err
 			}
+			taken[remote] = port
 			remote.LocalPort = strconv.Itoa(port)
 			remote.LocalHost = "0.0.0.0"
 			remote.LocalPortRandom = true
@@ -193,16 +267,227 @@ func (s *ClientService) startClientTunnels(client *clients.Client, remotes []*ch
 				return nil, err
 			}
 		}
+		if remote.ACLSessionUser != "" {
+			acl = s.sessionACL(remote.ACLSessionUser)
+		}
 
 		t, err := client.StartTunnel(remote, acl)
 		if err != nil {
 			return nil, err
 		}
+		if port, ok := taken[remote]; ok {
+			delete(taken, remote)
+			s.releasePortOnStop(t, port)
+		}
+		reservations = s.reservePort(client, remote, reservations)
 		tunnels = append(tunnels, t)
 	}
 	return tunnels, nil
 }
 
+// releasePortOnStop releases a port given out by the port distributor when a given tunnel is stopped.
+func (s *ClientService) releasePortOnStop(t *clients.Tunnel, port int) {
+	go func() {
+		<-t.Done()
+		s.portDistributor.Release(port)
+	}()
+}
+
+// SelectPortPool returns a name of the first port pool that reserves ports for a given client or for a given API user.
+// The user is nil for tunnels requested by the client. An empty name means ports that are not reserved by any pool.
+func (s *ClientService) SelectPortPool(ctx context.Context, client *clients.Client, user *users.User) (string, error) {
+	for _, pool := range s.portPools {
+		if user != nil && user.BelongsToOneOf(pool.UserGroups) {
+			return pool.Name, nil
+		}
+		for _, groupID := range pool.ClientGroups {
+			group, err := s.clientGroupProvider.Get(ctx, groupID)
+			if err != nil {
+				return "", fmt.Errorf("failed to get a client group with id=%q: %v", groupID, err)
+			}
+			// unknown group doesn't match any client
+			if group != nil && client.BelongsTo(group) {
+				return pool.Name, nil
+			}
+		}
+	}
+	return "", nil
+}
+
+// PortPoolOf returns a name of the port pool that reserves a given port or an empty string if it's not reserved.
+func (s *ClientService) PortPoolOf(port int) string {
+	return s.portDistributor.PoolOf(port)
+}
+
+// PortPoolsUsage returns the current usage of port pools.
+func (s *ClientService) PortPoolsUsage() []ports.PortPoolUsage {
+	return s.portDistributor.PoolsUsage()
+}
+
+// restoreClientTunnels starts given old tunnels of a reconnected client one by one and returns remotes of started ones.
+// Persistent tunnels created with the API that fail to start are added to tunnel conflicts of the client, so they are
+// reported with the client and retried on the next connect. Other old tunnels that fail to start are dropped.
+func (s *ClientService) restoreClientTunnels(client *clients.Client, remotes []*chshare.Remote) []*chshare.Remote {
+	var restored []*chshare.Remote
+	for _, remote := range remotes {
+		// old tunnels have their ports already
+		_, err := s.startClientTunnels(client, []*chshare.Remote{remote}, "")
+		if err == nil {
+			restored = append(restored, remote)
+			continue
+		}
+		if remote.Persistent && remote.CreatedBy != "" {
+			client.Logger.Errorf("Failed to re-establish persistent tunnel %s: %v", remote, err)
+			client.TunnelConflicts = append(client.TunnelConflicts, clients.NewTunnelConflict(remote, err))
+			continue
+		}
+		client.Logger.Infof("Old tunnel %s is dropped: %v", remote, err)
+	}
+	return restored
+}
+
+// reuseLocalPorts assigns local ports of old tunnels with random ports to new tunnels that are requested by the client
+// without a local port, so the client gets its original ports back. A new random port is used later if an old port
+// is not available anymore.
+func (s *ClientService) reuseLocalPorts(old, new []*chshare.Remote) {
+	oldMarked := make([]bool, len(old))
+	for _, curNew := range new {
+		// local ports of reverse tunnels are listened by the client
+		if curNew.IsLocalSpecified() || curNew.Reverse {
+			continue
+		}
+		for i, curOld := range old {
+			if oldMarked[i] || !curOld.LocalPortRandom || curOld.CreatedBy != "" || curOld.Reverse ||
+				curNew.Remote() != curOld.Remote() || curNew.IsUDP() != curOld.IsUDP() || !curNew.EqualACL(curOld.ACL) {
+				continue
+			}
+			port, err := strconv.Atoi(curOld.LocalPort)
+			if err != nil || !s.portDistributor.IsPortAvailable(port) {
+				continue
+			}
+			oldMarked[i] = true
+			curNew.LocalHost = curOld.LocalHost
+			curNew.LocalPort = curOld.LocalPort
+			curNew.LocalPortRandom = true
+			break
+		}
+	}
+}
+
+// getRandomPort returns a port of a given pool that is reserved for a given remote of the client if it's available.
+// Otherwise it returns a random port of the pool that is not reserved for other remotes.
+func (s *ClientService) getRandomPort(clientID string, remote *chshare.Remote, pool string, reservations []*clients.PortReservation) (int, error) {
+	reserved := make(map[int]bool, len(reservations))
+	for _, r := range reservations {
+		if r.Matches(clientID, remote) && s.portDistributor.TakePortFromPool(pool, r.Port) {
+			return r.Port, nil
+		}
+		reserved[r.Port] = true
+	}
+	// ports reserved for other remotes are held until a port is found, so they are not returned again
+	var skipped []int
+	defer func() {
+		for _, port := range skipped {
+			s.portDistributor.Release(port)
+		}
+	}()
+	for {
+		port, err := s.portDistributor.GetRandomPortFromPool(pool)
+		if err != nil {
+			return 0, err
+		}
+		if !reserved[port] {
+			return port, nil
+		}
+		skipped = append(skipped, port)
+	}
+}
+
+// reservePort reserves a random local port of a given started tunnel for its remote and returns updated reservations.
+// A failure to save the reservation doesn't fail the tunnel.
+func (s *ClientService) reservePort(client *clients.Client, remote *chshare.Remote, reservations []*clients.PortReservation) []*clients.PortReservation {
+	if s.portReservations == nil || !remote.LocalPortRandom || remote.Reverse {
+		return reservations
+	}
+	port, err := strconv.Atoi(remote.LocalPort)
+	if err != nil {
+		return reservations
+	}
+	for _, r := range reservations {
+		if r.Port == port && r.Matches(client.ID, remote) {
+			return reservations
+		}
+	}
+
+	r := clients.NewPortReservation(client.ID, remote, port)
+	if err := s.portReservations.SavePortReservation(context.Background(), r); err != nil {
+		client.Logger.Errorf("Failed to reserve port %d for %s: %v", port, remote.Remote(), err)
+		return reservations
+	}
+	return append(reservations, r)
+}
+
+func (s *ClientService) getAllPortReservations() ([]*clients.PortReservation, error) {
+	if s.portReservations == nil {
+		return nil, nil
+	}
+	res, err := s.portReservations.GetAllPortReservations(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get port reservations: %v", err)
+	}
+	return res, nil
+}
+
+// GetPortReservations returns port reservations of a given client ordered by port.
+func (s *ClientService) GetPortReservations(clientID string) ([]*clients.PortReservation, error) {
+	all, err := s.getAllPortReservations()
+	if err != nil {
+		return nil, err
+	}
+	res := make([]*clients.PortReservation, 0)
+	for _, r := range all {
+		if r.ClientID == clientID {
+			res = append(res, r)
+		}
+	}
+	return res, nil
+}
+
+// DeletePortReservation releases a given port reserved by a given client, so it can be given out to other tunnels.
+// It returns false if the port is not reserved by the client.
+func (s *ClientService) DeletePortReservation(clientID string, port int) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.portReservations == nil {
+		return false, nil
+	}
+	return s.portReservations.DeletePortReservation(context.Background(), clientID, port)
+}
+
+// DeleteClientPortReservations releases all ports reserved by a given client.
+func (s *ClientService) DeleteClientPortReservations(clientID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.deleteClientPortReservations(clientID)
+}
+
+func (s *ClientService) deleteClientPortReservations(clientID string) error {
+	if s.portReservations == nil {
+		return nil
+	}
+	return s.portReservations.DeleteClientPortReservations(context.Background(), clientID)
+}
+
+// sessionACL returns an ACL that allows only source IPs of valid API sessions of a given user. The IPs are looked up
+// on each access, so the ACL follows the user to a new IP and denies all connections when the sessions end.
+func (s *ClientService) sessionACL(username string) *clients.TunnelACL {
+	return &clients.TunnelACL{
+		SessionIPs: func() []net.IP {
+			return s.apiSessions.GetActiveIPsByUsername(username)
+		},
+	}
+}
+
 func (s *ClientService) Terminate(client *clients.Client) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
@@ -234,6 +519,9 @@ func (s *ClientService) ForceDelete(client *clients.Client) error {
 			return err
 		}
 	}
+	if err := s.deleteClientPortReservations(client.ID); err != nil {
+		return fmt.Errorf("failed to delete port reservations: %v", err)
+	}
 	return s.repo.Delete(client)
 }
 
@@ -4,10 +4,13 @@ import (
 	"context"
 	"errors"
 	"net"
+	"strconv"
 	"testing"
+	"time"
 
 	mapset "github.com/deckarep/golang-set"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"github.com/cloudradar-monitoring/rport/server/clients"
 	"github.com/cloudradar-monitoring/rport/server/ports"
@@ -83,9 +86,173 @@ func TestStartClient(t *testing.T) {
 				portDistributor: ports.NewPortDistributor(mapset.NewThreadUnsafeSet()),
 			}
 			_, err := cs.StartClient(
-				context.Background(), tc.ClientAuthID, tc.ClientID, connMock, tc.AuthMultiuseCreds,
+				context.Background(), tc.ClientAuthID, "", tc.ClientID, connMock, tc.AuthMultiuseCreds,
 				&chshare.ConnectionRequest{}, testLog)
 			assert.Equal(t, tc.ExpectedError, err)
 		})
 	}
 }
+
+func TestStartClientRestoresPersistentTunnels(t *testing.T) {
+	// given
+	connMock := test.NewConnMock()
+	connMock.ReturnRemoteAddr = &net.TCPAddr{IP: net.IPv4(192, 0, 2, 1), Port: 2345}
+	busy, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer busy.Close()
+	_, busyPort, err := net.SplitHostPort(busy.Addr().String())
+	require.NoError(t, err)
+	persistentPort := freePort(t)
+	randomPort := freePort(t)
+	disconnectedAt := time.Now().Add(-time.Minute)
+	oldClient := &clients.Client{
+		ID:             "test-client",
+		ClientAuthID:   "test-client-auth",
+		DisconnectedAt: &disconnectedAt,
+		Tunnels: []*clients.Tunnel{
+			{ID: "1", Remote: chshare.Remote{LocalHost: "127.0.0.1", LocalPort: persistentPort, RemoteHost: "192.168.1.1", RemotePort: "22", Persistent: true, CreatedBy: "admin"}},
+			{ID: "2", Remote: chshare.Remote{LocalHost: "127.0.0.1", LocalPort: busyPort, RemoteHost: "192.168.1.1", RemotePort: "80", Persistent: true, CreatedBy: "admin"}},
+			{ID: "3", Remote: chshare.Remote{LocalHost: "127.0.0.1", LocalPort: randomPort, LocalPortRandom: true, RemoteHost: "192.168.1.1", RemotePort: "3389", Persistent: true}},
+		},
+	}
+	cs := &ClientService{
+		repo:            clients.NewClientRepository([]*clients.Client{oldClient}, nil),
+		portDistributor: ports.NewPortDistributor(mapset.NewThreadUnsafeSet()),
+	}
+	req := &chshare.ConnectionRequest{
+		Remotes: []*chshare.Remote{
+			{RemoteHost: "192.168.1.1", RemotePort: "3389"},
+			{RemoteHost: "192.168.1.1", RemotePort: "443"},
+		},
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// when
+	client, err := cs.StartClient(ctx, "test-client-auth", "", "test-client", connMock, false, req, testLog)
+
+	// then
+	require.NoError(t, err)
+	defer func() {
+		for _, tunnel := range client.Tunnels {
+			_ = tunnel.Terminate(true)
+		}
+	}()
+	require.Len(t, client.Tunnels, 3)
+	assert.Equal(t, "127.0.0.1:"+randomPort, client.Tunnels[0].Local(), "random port of the client tunnel should be reused")
+	assert.True(t, client.Tunnels[0].LocalPortRandom)
+	assert.True(t, client.Tunnels[0].Persistent)
+	assert.Equal(t, "192.168.1.1:443", client.Tunnels[1].Remote.Remote())
+	assert.Equal(t, "127.0.0.1:"+persistentPort, client.Tunnels[2].Local())
+	assert.Equal(t, "admin", client.Tunnels[2].CreatedBy)
+	require.Len(t, client.TunnelConflicts, 1)
+	assert.Equal(t, "127.0.0.1:"+busyPort, client.TunnelConflicts[0].Local())
+	assert.Contains(t, client.TunnelConflicts[0].Error, "address already in use")
+	assert.Len(t, req.Remotes, 3, "restored tunnels should be sent to the client")
+}
+
+func TestStartClientOfAnotherTenant(t *testing.T) {
+	connMock := test.NewConnMock()
+	connMock.ReturnRemoteAddr = &net.TCPAddr{IP: net.IPv4(192, 0, 2, 1), Port: 2345}
+	disconnectedAt := time.Now().Add(-time.Minute)
+	oldClient := &clients.Client{
+		ID:             "test-client",
+		ClientAuthID:   "tenant-a-auth",
+		Tenant:         "tenant-a",
+		DisconnectedAt: &disconnectedAt,
+		Tunnels: []*clients.Tunnel{
+			{ID: "1", Remote: chshare.Remote{LocalHost: "127.0.0.1", LocalPort: freePort(t), RemoteHost: "192.168.1.1", RemotePort: "22", Persistent: true, CreatedBy: "admin"}},
+		},
+	}
+	cs := &ClientService{
+		repo:            clients.NewClientRepository([]*clients.Client{oldClient}, nil),
+		portDistributor: ports.NewPortDistributor(mapset.NewThreadUnsafeSet()),
+	}
+
+	_, err := cs.StartClient(context.Background(), "tenant-b-auth", "tenant-b", "test-client", connMock, false, &chshare.ConnectionRequest{}, testLog)
+
+	assert.EqualError(t, err, `client id "test-client" is already in use`)
+	got, err := cs.GetByID("test-client")
+	require.NoError(t, err)
+	assert.Equal(t, oldClient, got)
+}
+
+func TestStartClientTunnelsReservesRandomPorts(t *testing.T) {
+	// given
+	reservations, err := clients.NewSqliteProvider(":memory:", 0)
+	require.NoError(t, err)
+	defer reservations.Close()
+	port1, err := strconv.Atoi(freePort(t))
+	require.NoError(t, err)
+	port2, err := strconv.Atoi(freePort(t))
+	require.NoError(t, err)
+	pool := &ports.PortPool{Name: "test", Ports: mapset.NewThreadUnsafeSetFromSlice([]interface{}{port1, port2})}
+	cs := &ClientService{
+		portDistributor:  ports.NewPortDistributor(mapset.NewThreadUnsafeSet(), pool),
+		portReservations: reservations,
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	client1 := &clients.Client{ID: "client-1", Connection: test.NewConnMock(), Context: ctx, Logger: testLog}
+	client2 := &clients.Client{ID: "client-2", Connection: test.NewConnMock(), Context: ctx, Logger: testLog}
+	newRemotes := func() []*chshare.Remote {
+		return []*chshare.Remote{{RemoteHost: "192.168.1.1", RemotePort: "22"}}
+	}
+
+	// when
+	tunnels, err := cs.StartClientTunnels(client1, newRemotes(), "test")
+	require.NoError(t, err)
+	reservedPort := tunnels[0].LocalPort
+	port, err := strconv.Atoi(reservedPort)
+	require.NoError(t, err)
+	assert.False(t, cs.portDistributor.IsPortAvailable(port))
+	require.NoError(t, client1.TerminateTunnel(tunnels[0], true))
+	assert.Eventually(t, func() bool { return cs.portDistributor.IsPortAvailable(port) }, time.Second, 10*time.Millisecond, "port should be released when the tunnel is stopped")
+
+	// then
+	got, err := cs.GetPortReservations("client-1")
+	require.NoError(t, err)
+	require.Len(t, got, 1)
+	assert.Equal(t, reservedPort, strconv.Itoa(got[0].Port))
+	assert.Equal(t, "192.168.1.1:22", got[0].Remote)
+	assert.Equal(t, chshare.ProtocolTCP, got[0].Protocol)
+
+	// when
+	otherTunnels, err := cs.StartClientTunnels(client2, newRemotes(), "test")
+
+	// then
+	require.NoError(t, err)
+	defer otherTunnels[0].Terminate(true)
+	assert.NotEqual(t, reservedPort, otherTunnels[0].LocalPort, "reserved port should not be given out to other clients")
+
+	// when
+	tunnels, err = cs.StartClientTunnels(client1, newRemotes(), "test")
+
+	// then
+	require.NoError(t, err)
+	defer tunnels[0].Terminate(true)
+	assert.Equal(t, reservedPort, tunnels[0].LocalPort, "reserved port should be reused")
+	assert.True(t, tunnels[0].LocalPortRandom)
+
+	// when
+	deleted, err := cs.DeletePortReservation("client-1", port)
+
+	// then
+	require.NoError(t, err)
+	assert.True(t, deleted)
+	got, err = cs.GetPortReservations("client-1")
+	require.NoError(t, err)
+	assert.Empty(t, got)
+	deleted, err = cs.DeletePortReservation("client-1", port)
+	require.NoError(t, err)
+	assert.False(t, deleted)
+}
+
+func freePort(t *testing.T) string {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer l.Close()
+	_, port, err := net.SplitHostPort(l.Addr().String())
+	require.NoError(t, err)
+	return port
+}
@@ -0,0 +1,153 @@
+package clientcerts
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+
+	chshare "github.com/cloudradar-monitoring/rport/share"
+)
+
+// ClientCert is a record of a certificate that was signed by the rportd CA for a client auth id. The id is used as
+// a serial of the certificate. Only records are stored, certificates are returned once when they are signed.
+type ClientCert struct {
+	ID           uint64 `json:"id" db:"id"`
+	ClientAuthID string `json:"client_auth_id" db:"client_auth_id"`
+	// Fingerprint is a SHA256 fingerprint of the client public key.
+	Fingerprint string     `json:"fingerprint" db:"fingerprint"`
+	CreatedBy   string     `json:"created_by" db:"created_by"`
+	CreatedAt   time.Time  `json:"created_at" db:"created_at"`
+	ValidBefore time.Time  `json:"valid_before" db:"valid_before"`
+	RevokedAt   *time.Time `json:"revoked_at" db:"revoked_at"`
+	// Tenant is set only when multi-tenancy is enabled.
+	Tenant string `json:"-" db:"tenant"`
+}
+
+// IsValid returns true if the certificate is not revoked and not expired at a given time.
+func (c *ClientCert) IsValid(now time.Time) bool {
+	return c.RevokedAt == nil && now.Before(c.ValidBefore)
+}
+
+var ErrInvalidKey = errors.New("public key is not trusted")
+
+// Authority signs certificates of client public keys with the CA key and checks keys that clients authenticate with.
+type Authority struct {
+	signer   ssh.Signer
+	provider Provider
+	validity time.Duration
+	now      func() time.Time
+}
+
+func NewAuthority(signer ssh.Signer, provider Provider, validity time.Duration) *Authority {
+	return &Authority{
+		signer:   signer,
+		provider: provider,
+		validity: validity,
+		now:      time.Now,
+	}
+}
+
+// LoadOrGenerateCAKey reads a CA private key from a given file. A new key is generated and saved to the file if it
+// doesn't exist.
+func LoadOrGenerateCAKey(path string) (ssh.Signer, error) {
+	key, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		key, err = chshare.GenerateKey("")
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate client CA key: %v", err)
+		}
+		if err := ioutil.WriteFile(path, key, 0600); err != nil {
+			return nil, fmt.Errorf("failed to save client CA key: %v", err)
+		}
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to read client CA key: %v", err)
+	}
+	signer, err := ssh.ParsePrivateKey(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse client CA key %q: %v", path, err)
+	}
+	return signer, nil
+}
+
+// PublicKey returns the CA public key.
+func (a *Authority) PublicKey() ssh.PublicKey {
+	return a.signer.PublicKey()
+}
+
+// Sign signs a certificate of a given public key for a given client auth id and saves its record.
+func (a *Authority) Sign(ctx context.Context, clientAuthID string, key ssh.PublicKey, tenant, createdBy string) (*ClientCert, *ssh.Certificate, error) {
+	if _, ok := key.(*ssh.Certificate); ok {
+		return nil, nil, errors.New("a public key is expected, got a certificate")
+	}
+	now := a.now().UTC().Truncate(time.Second)
+	record := &ClientCert{
+		ClientAuthID: clientAuthID,
+		Fingerprint:  ssh.FingerprintSHA256(key),
+		Tenant:       tenant,
+		CreatedBy:    createdBy,
+		CreatedAt:    now,
+		ValidBefore:  now.Add(a.validity),
+	}
+	if err := a.provider.Create(ctx, record); err != nil {
+		return nil, nil, fmt.Errorf("failed to save client certificate: %v", err)
+	}
+
+	cert := &ssh.Certificate{
+		Key:             key,
+		Serial:          record.ID,
+		CertType:        ssh.UserCert,
+		KeyId:           clientAuthID,
+		ValidPrincipals: []string{clientAuthID},
+		ValidAfter:      uint64(now.Unix()),
+		ValidBefore:     uint64(record.ValidBefore.Unix()),
+	}
+	if err := cert.SignCert(rand.Reader, a.signer); err != nil {
+		return nil, nil, fmt.Errorf("failed to sign client certificate: %v", err)
+	}
+	return record, cert, nil
+}
+
+// Authenticate returns a record of a valid certificate that allows a given client auth id to log in with a given key.
+// The key is either a certificate signed by the CA or a public key that a certificate was signed for.
+func (a *Authority) Authenticate(ctx context.Context, clientAuthID string, key ssh.PublicKey) (*ClientCert, error) {
+	now := a.now()
+	cert, ok := key.(*ssh.Certificate)
+	if !ok {
+		records, err := a.provider.GetByFingerprint(ctx, clientAuthID, ssh.FingerprintSHA256(key))
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range records {
+			if r.IsValid(now) {
+				return r, nil
+			}
+		}
+		return nil, ErrInvalidKey
+	}
+
+	if cert.CertType != ssh.UserCert || !bytes.Equal(cert.SignatureKey.Marshal(), a.signer.PublicKey().Marshal()) {
+		return nil, ErrInvalidKey
+	}
+	checker := &ssh.CertChecker{Clock: a.now}
+	if err := checker.CheckCert(clientAuthID, cert); err != nil {
+		return nil, err
+	}
+	record, err := a.provider.Get(ctx, cert.Serial)
+	if err != nil {
+		return nil, err
+	}
+	if record == nil || record.ClientAuthID != clientAuthID || record.Fingerprint != ssh.FingerprintSHA256(cert.Key) {
+		return nil, ErrInvalidKey
+	}
+	if !record.IsValid(now) {
+		return nil, fmt.Errorf("certificate %d is revoked or expired", record.ID)
+	}
+	return record, nil
+}
@@ -0,0 +1,112 @@
+package clientcerts
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh"
+
+	chshare "github.com/cloudradar-monitoring/rport/share"
+)
+
+func newTestSigner(t *testing.T) ssh.Signer {
+	key, err := chshare.GenerateKey("")
+	require.NoError(t, err)
+	signer, err := ssh.ParsePrivateKey(key)
+	require.NoError(t, err)
+	return signer
+}
+
+func TestAuthority(t *testing.T) {
+	// given
+	ctx := context.Background()
+	p, err := NewSqliteProvider(":memory:")
+	require.NoError(t, err)
+	defer p.Close()
+	now := time.Date(2021, 3, 1, 10, 0, 0, 0, time.UTC)
+	a := NewAuthority(newTestSigner(t), p, 24*time.Hour)
+	a.now = func() time.Time { return now }
+	clientKey := newTestSigner(t).PublicKey()
+
+	// when
+	record, cert, err := a.Sign(ctx, "client-1", clientKey, "tenant-1", "admin")
+
+	// then
+	require.NoError(t, err)
+	assert.Equal(t, &ClientCert{
+		ID:           1,
+		ClientAuthID: "client-1",
+		Fingerprint:  ssh.FingerprintSHA256(clientKey),
+		Tenant:       "tenant-1",
+		CreatedBy:    "admin",
+		CreatedAt:    now,
+		ValidBefore:  now.Add(24 * time.Hour),
+	}, record)
+	assert.EqualValues(t, 1, cert.Serial)
+	assert.Equal(t, []string{"client-1"}, cert.ValidPrincipals)
+
+	got, err := a.Authenticate(ctx, "client-1", cert)
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, got.ID)
+	got, err = a.Authenticate(ctx, "client-1", clientKey)
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, got.ID)
+
+	_, err = a.Authenticate(ctx, "client-2", cert)
+	assert.Error(t, err, "certificate of another client auth id")
+	_, err = a.Authenticate(ctx, "client-2", clientKey)
+	assert.Equal(t, ErrInvalidKey, err)
+	_, err = a.Authenticate(ctx, "client-1", newTestSigner(t).PublicKey())
+	assert.Equal(t, ErrInvalidKey, err)
+
+	other := NewAuthority(newTestSigner(t), p, time.Hour)
+	_, otherCert, err := other.Sign(ctx, "client-1", newTestSigner(t).PublicKey(), "", "admin")
+	require.NoError(t, err)
+	_, err = a.Authenticate(ctx, "client-1", otherCert)
+	assert.Equal(t, ErrInvalidKey, err, "certificate signed by another CA")
+
+	// when
+	now = now.Add(25 * time.Hour)
+
+	// then
+	_, err = a.Authenticate(ctx, "client-1", cert)
+	assert.Error(t, err, "expired certificate")
+	_, err = a.Authenticate(ctx, "client-1", clientKey)
+	assert.Equal(t, ErrInvalidKey, err)
+
+	// when
+	now = now.Add(-25 * time.Hour)
+	revoked, err := p.Revoke(ctx, record.ID, now)
+	require.NoError(t, err)
+
+	// then
+	assert.True(t, revoked)
+	_, err = a.Authenticate(ctx, "client-1", cert)
+	assert.EqualError(t, err, "certificate 1 is revoked or expired")
+	_, err = a.Authenticate(ctx, "client-1", clientKey)
+	assert.Equal(t, ErrInvalidKey, err)
+
+	revoked, err = p.Revoke(ctx, record.ID, now)
+	require.NoError(t, err)
+	assert.False(t, revoked)
+}
+
+func TestLoadOrGenerateCAKey(t *testing.T) {
+	dir, err := ioutil.TempDir("", "clientcerts")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "client-ca.key")
+
+	generated, err := LoadOrGenerateCAKey(path)
+	require.NoError(t, err)
+	loaded, err := LoadOrGenerateCAKey(path)
+	require.NoError(t, err)
+
+	assert.Equal(t, generated.PublicKey().Marshal(), loaded.PublicKey().Marshal())
+}
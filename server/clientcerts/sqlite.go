@@ -0,0 +1,107 @@
+package clientcerts
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/cloudradar-monitoring/rport/db/migration/client_certs"
+	"github.com/cloudradar-monitoring/rport/db/sqlite"
+)
+
+type Provider interface {
+	Get(ctx context.Context, id uint64) (*ClientCert, error)
+	GetAll(ctx context.Context) ([]*ClientCert, error)
+	// GetByFingerprint returns not revoked certificates of a given client auth id that are issued for a public key with
+	// a given fingerprint.
+	GetByFingerprint(ctx context.Context, clientAuthID, fingerprint string) ([]*ClientCert, error)
+	// Create saves a given certificate and sets its id.
+	Create(ctx context.Context, cert *ClientCert) error
+	// Revoke returns false if a certificate with a given id doesn't exist or it's already revoked.
+	Revoke(ctx context.Context, id uint64, revokedAt time.Time) (bool, error)
+	Close() error
+}
+
+type SqliteProvider struct {
+	db *sqlx.DB
+}
+
+func NewSqliteProvider(dbPath string) (*SqliteProvider, error) {
+	db, err := sqlite.New(dbPath, client_certs.AssetNames(), client_certs.Asset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create client_certs DB instance: %v", err)
+	}
+	return &SqliteProvider{db: db}, nil
+}
+
+func (p *SqliteProvider) Get(ctx context.Context, id uint64) (*ClientCert, error) {
+	res := &ClientCert{}
+	err := p.db.GetContext(ctx, res, "SELECT * FROM client_certs WHERE id = ?", id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return res, nil
+}
+
+func (p *SqliteProvider) GetAll(ctx context.Context) ([]*ClientCert, error) {
+	var res []*ClientCert
+	err := p.db.SelectContext(ctx, &res, "SELECT * FROM client_certs ORDER BY id")
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (p *SqliteProvider) GetByFingerprint(ctx context.Context, clientAuthID, fingerprint string) ([]*ClientCert, error) {
+	var res []*ClientCert
+	err := p.db.SelectContext(
+		ctx,
+		&res,
+		"SELECT * FROM client_certs WHERE client_auth_id = ? AND fingerprint = ? AND revoked_at IS NULL ORDER BY id",
+		clientAuthID,
+		fingerprint,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (p *SqliteProvider) Create(ctx context.Context, cert *ClientCert) error {
+	res, err := p.db.NamedExecContext(
+		ctx,
+		"INSERT INTO client_certs (client_auth_id, fingerprint, tenant, created_by, created_at, valid_before) VALUES (:client_auth_id, :fingerprint, :tenant, :created_by, :created_at, :valid_before)",
+		cert,
+	)
+	if err != nil {
+		return err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+	cert.ID = uint64(id)
+	return nil
+}
+
+func (p *SqliteProvider) Revoke(ctx context.Context, id uint64, revokedAt time.Time) (bool, error) {
+	res, err := p.db.ExecContext(ctx, "UPDATE client_certs SET revoked_at = ? WHERE id = ? AND revoked_at IS NULL", revokedAt, id)
+	if err != nil {
+		return false, err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected > 0, nil
+}
+
+func (p *SqliteProvider) Close() error {
+	return p.db.Close()
+}
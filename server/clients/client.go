@@ -39,9 +39,13 @@ type Client struct {
 	Version  string    `json:"version"`
 	Address  string    `json:"address"`
 	Tunnels  []*Tunnel `json:"tunnels"`
+	// TunnelConflicts are persistent tunnels that failed to be re-established on the last connect.
+	TunnelConflicts []*TunnelConflict `json:"tunnel_conflicts"`
 	// DisconnectedAt is a time when a client was disconnected. If nil - it's connected.
 	DisconnectedAt *time.Time `json:"disconnected_at"`
 	ClientAuthID   string     `json:"client_auth_id"`
+	// Tenant is set only when multi-tenancy is enabled.
+	Tenant string `json:"tenant,omitempty"`
 
 	Connection ssh.Conn        `json:"-"`
 	Context    context.Context `json:"-"`
@@ -104,10 +108,28 @@ func (c *Client) StartTunnel(r *chshare.Remote, acl *TunnelACL) (*Tunnel, error)
 		}()
 	}
 
+	if t.ExpiresAt != nil {
+		t.expiryTimer = time.AfterFunc(time.Until(*t.ExpiresAt), func() { c.terminateExpiredTunnel(t) })
+	}
+
 	c.Tunnels = append(c.Tunnels, t)
 	return t, nil
 }
 
+// terminateExpiredTunnel force-terminates a given tunnel that reached its expiry time even if it's in use.
+func (c *Client) terminateExpiredTunnel(t *Tunnel) {
+	c.Lock()
+	defer c.Unlock()
+	// tunnels of disconnected clients are already closed, the expired ones aren't re-established on reconnect
+	if c.Context.Err() != nil || c.FindTunnel(t.ID) != t {
+		return
+	}
+	c.Logger.Infof("Tunnel %s expired at %s", t.ID, t.ExpiresAt.Format(time.RFC3339))
+	if err := c.TerminateTunnel(t, true); err != nil {
+		c.Logger.Errorf("Failed to terminate expired tunnel %s: %v", t.ID, err)
+	}
+}
+
 func (c *Client) TerminateTunnel(t *Tunnel, force bool) error {
 	c.Logger.Infof("Terminating tunnel %s (force: %v) ...", t.ID, force)
 	err := t.Terminate(force)
@@ -169,6 +191,10 @@ func (c *Client) BelongsToOneOf(groups []*cgroups.ClientGroup) bool {
 }
 
 func (c *Client) BelongsTo(group *cgroups.ClientGroup) bool {
+	// groups of one tenant never include clients of another one
+	if c.Tenant != group.Tenant {
+		return false
+	}
 	p := group.Params
 	if p.HasNoParams() {
 		return false
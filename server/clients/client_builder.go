@@ -89,6 +89,7 @@ func (b ClientBuilder) Build() *Client {
 					LocalPort:  "2222",
 					RemoteHost: "0.0.0.0",
 					RemotePort: "22",
+					Protocol:   chshare.ProtocolTCP,
 				},
 			},
 			{
@@ -98,6 +99,7 @@ func (b ClientBuilder) Build() *Client {
 					LocalPort:  "4000",
 					RemoteHost: "0.0.0.0",
 					RemotePort: "80",
+					Protocol:   chshare.ProtocolTCP,
 				},
 			},
 		},
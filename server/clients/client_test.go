@@ -1,11 +1,15 @@
 package clients
 
 import (
+	"context"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"github.com/cloudradar-monitoring/rport/server/cgroups"
+	chshare "github.com/cloudradar-monitoring/rport/share"
 )
 
 func TestClientBelongsToGroup(t *testing.T) {
@@ -252,3 +256,28 @@ func TestClientBelongsToGroup(t *testing.T) {
 		})
 	}
 }
+
+func TestStartTunnelExpiry(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	c := &Client{Context: ctx, Logger: testLog}
+	expiresAt := time.Now().Add(100 * time.Millisecond)
+	expiring := &chshare.Remote{LocalHost: "127.0.0.1", LocalPort: "5432", RemoteHost: "127.0.0.1", RemotePort: "5432", Reverse: true, ExpiresAt: &expiresAt}
+	permanent := &chshare.Remote{LocalHost: "127.0.0.1", LocalPort: "5433", RemoteHost: "127.0.0.1", RemotePort: "5433", Reverse: true}
+
+	c.Lock()
+	expiringTunnel, err := c.StartTunnel(expiring, nil)
+	require.NoError(t, err)
+	permanentTunnel, err := c.StartTunnel(permanent, nil)
+	require.NoError(t, err)
+	c.Unlock()
+
+	assert.Eventually(t, func() bool {
+		c.Lock()
+		defer c.Unlock()
+		return c.FindTunnel(expiringTunnel.ID) == nil
+	}, time.Second, 10*time.Millisecond)
+	c.Lock()
+	defer c.Unlock()
+	assert.Equal(t, []*Tunnel{permanentTunnel}, c.Tunnels)
+}
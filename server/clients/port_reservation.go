@@ -0,0 +1,88 @@
+package clients
+
+import (
+	"context"
+	"time"
+
+	chshare "github.com/cloudradar-monitoring/rport/share"
+)
+
+// PortReservation keeps a random server port of a tunnel, so the tunnel gets the same port when it's created again for
+// the same client and remote, e.g. when the client reconnects after a server restart.
+type PortReservation struct {
+	ClientID  string    `json:"client_id" db:"client_id"`
+	Remote    string    `json:"remote" db:"remote"`
+	Protocol  string    `json:"protocol" db:"protocol"`
+	Port      int       `json:"port" db:"port"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
+
+type PortReservationProvider interface {
+	GetAllPortReservations(ctx context.Context) ([]*PortReservation, error)
+	SavePortReservation(ctx context.Context, r *PortReservation) error
+	DeletePortReservation(ctx context.Context, clientID string, port int) (bool, error)
+	DeleteClientPortReservations(ctx context.Context, clientID string) error
+}
+
+// NewPortReservation returns a reservation of a local port of a given tunnel remote.
+func NewPortReservation(clientID string, r *chshare.Remote, port int) *PortReservation {
+	return &PortReservation{
+		ClientID:  clientID,
+		Remote:    r.Remote(),
+		Protocol:  remoteProtocol(r),
+		Port:      port,
+		CreatedAt: now(),
+	}
+}
+
+// Matches returns true if the reservation is made for a given tunnel remote of a given client.
+func (r *PortReservation) Matches(clientID string, remote *chshare.Remote) bool {
+	return r.ClientID == clientID && r.Remote == remote.Remote() && r.Protocol == remoteProtocol(remote)
+}
+
+func remoteProtocol(r *chshare.Remote) string {
+	if r.IsUDP() {
+		return chshare.ProtocolUDP
+	}
+	return chshare.ProtocolTCP
+}
+
+// GetAllPortReservations returns all port reservations ordered by client id and port.
+func (p *SqliteProvider) GetAllPortReservations(ctx context.Context) ([]*PortReservation, error) {
+	var res []*PortReservation
+	err := p.db.SelectContext(ctx, &res, "SELECT * FROM port_reservations ORDER BY client_id, port")
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// SavePortReservation saves a given reservation. It replaces a reservation of the same remote of the client and
+// a reservation of the same port by another remote.
+func (p *SqliteProvider) SavePortReservation(ctx context.Context, r *PortReservation) error {
+	_, err := p.db.NamedExecContext(
+		ctx,
+		"INSERT OR REPLACE INTO port_reservations (client_id, remote, protocol, port, created_at) VALUES (:client_id, :remote, :protocol, :port, :created_at)",
+		r,
+	)
+	return err
+}
+
+// DeletePortReservation deletes a reservation of a given port by a given client and returns false if it doesn't exist.
+func (p *SqliteProvider) DeletePortReservation(ctx context.Context, clientID string, port int) (bool, error) {
+	res, err := p.db.ExecContext(ctx, "DELETE FROM port_reservations WHERE client_id = ? AND port = ?", clientID, port)
+	if err != nil {
+		return false, err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected > 0, nil
+}
+
+// DeleteClientPortReservations deletes all port reservations of a given client.
+func (p *SqliteProvider) DeleteClientPortReservations(ctx context.Context, clientID string) error {
+	_, err := p.db.ExecContext(ctx, "DELETE FROM port_reservations WHERE client_id = ?", clientID)
+	return err
+}
@@ -0,0 +1,63 @@
+package clients
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	chshare "github.com/cloudradar-monitoring/rport/share"
+)
+
+func TestPortReservationsSqliteProvider(t *testing.T) {
+	ctx := context.Background()
+	p := newFakeClientProvider(t, hour)
+	defer p.Close()
+	now = nowMockF
+	ssh := &chshare.Remote{RemoteHost: "192.168.1.1", RemotePort: "22"}
+	dns := &chshare.Remote{RemoteHost: "192.168.1.1", RemotePort: "53", Protocol: chshare.ProtocolUDP}
+	r1 := NewPortReservation("client-1", ssh, 20001)
+	r2 := NewPortReservation("client-1", dns, 20002)
+	r3 := NewPortReservation("client-2", ssh, 20003)
+
+	// verify save
+	require.NoError(t, p.SavePortReservation(ctx, r3))
+	require.NoError(t, p.SavePortReservation(ctx, r2))
+	require.NoError(t, p.SavePortReservation(ctx, r1))
+	got, err := p.GetAllPortReservations(ctx)
+	require.NoError(t, err)
+	require.Len(t, got, 3)
+	assert.Equal(t, []int{20001, 20002, 20003}, []int{got[0].Port, got[1].Port, got[2].Port})
+	assert.Equal(t, "192.168.1.1:53", got[1].Remote)
+	assert.Equal(t, chshare.ProtocolUDP, got[1].Protocol)
+	assert.True(t, got[1].CreatedAt.Equal(clientsNow))
+	assert.True(t, got[0].Matches("client-1", ssh))
+	assert.False(t, got[0].Matches("client-2", ssh))
+	assert.False(t, got[0].Matches("client-1", dns))
+
+	// verify the same remote and the same port are replaced
+	require.NoError(t, p.SavePortReservation(ctx, NewPortReservation("client-1", ssh, 20004)))
+	require.NoError(t, p.SavePortReservation(ctx, NewPortReservation("client-2", dns, 20002)))
+	got, err = p.GetAllPortReservations(ctx)
+	require.NoError(t, err)
+	require.Len(t, got, 3)
+	assert.Equal(t, "client-1", got[0].ClientID)
+	assert.Equal(t, 20004, got[0].Port)
+	assert.Equal(t, "client-2", got[1].ClientID)
+	assert.Equal(t, 20002, got[1].Port)
+	assert.Equal(t, 20003, got[2].Port)
+
+	// verify delete
+	deleted, err := p.DeletePortReservation(ctx, "client-1", 20002)
+	require.NoError(t, err)
+	assert.False(t, deleted)
+	deleted, err = p.DeletePortReservation(ctx, "client-2", 20002)
+	require.NoError(t, err)
+	assert.True(t, deleted)
+	require.NoError(t, p.DeleteClientPortReservations(ctx, "client-2"))
+	got, err = p.GetAllPortReservations(ctx)
+	require.NoError(t, err)
+	require.Len(t, got, 1)
+	assert.Equal(t, "client-1", got[0].ClientID)
+}
@@ -91,18 +91,20 @@ func convertToSqlite(v *Client) *clientSqlite {
 		ID:           v.ID,
 		ClientAuthID: v.ClientAuthID,
 		Details: &clientDetails{
-			Name:     v.Name,
-			OS:       v.OS,
-			OSArch:   v.OSArch,
-			OSFamily: v.OSFamily,
-			OSKernel: v.OSKernel,
-			Hostname: v.Hostname,
-			Version:  v.Version,
-			Address:  v.Address,
-			IPv4:     v.IPv4,
-			IPv6:     v.IPv6,
-			Tags:     v.Tags,
-			Tunnels:  v.Tunnels,
+			Name:            v.Name,
+			OS:              v.OS,
+			OSArch:          v.OSArch,
+			OSFamily:        v.OSFamily,
+			OSKernel:        v.OSKernel,
+			Hostname:        v.Hostname,
+			Version:         v.Version,
+			Address:         v.Address,
+			IPv4:            v.IPv4,
+			IPv6:            v.IPv6,
+			Tags:            v.Tags,
+			Tunnels:         v.Tunnels,
+			TunnelConflicts: v.TunnelConflicts,
+			Tenant:          v.Tenant,
 		},
 	}
 	if v.DisconnectedAt != nil {
@@ -119,18 +121,20 @@ type clientSqlite struct {
 }
 
 type clientDetails struct {
-	Name     string    `json:"name"`
-	OS       string    `json:"os"`
-	OSArch   string    `json:"os_arch"`
-	OSFamily string    `json:"os_family"`
-	OSKernel string    `json:"os_kernel"`
-	Hostname string    `json:"hostname"`
-	Version  string    `json:"version"`
-	Address  string    `json:"address"`
-	IPv4     []string  `json:"ipv4"`
-	IPv6     []string  `json:"ipv6"`
-	Tags     []string  `json:"tags"`
-	Tunnels  []*Tunnel `json:"tunnels"`
+	Name            string            `json:"name"`
+	OS              string            `json:"os"`
+	OSArch          string            `json:"os_arch"`
+	OSFamily        string            `json:"os_family"`
+	OSKernel        string            `json:"os_kernel"`
+	Hostname        string            `json:"hostname"`
+	Version         string            `json:"version"`
+	Address         string            `json:"address"`
+	IPv4            []string          `json:"ipv4"`
+	IPv6            []string          `json:"ipv6"`
+	Tags            []string          `json:"tags"`
+	Tunnels         []*Tunnel         `json:"tunnels"`
+	TunnelConflicts []*TunnelConflict `json:"tunnel_conflicts,omitempty"`
+	Tenant          string            `json:"tenant,omitempty"`
 }
 
 func (d *clientDetails) Scan(value interface{}) error {
@@ -162,20 +166,22 @@ func (d *clientDetails) Value() (driver.Value, error) {
 func (s *clientSqlite) convert() *Client {
 	d := s.Details
 	res := &Client{
-		ID:           s.ID,
-		ClientAuthID: s.ClientAuthID,
-		Name:         d.Name,
-		OS:           d.OS,
-		OSArch:       d.OSArch,
-		OSFamily:     d.OSFamily,
-		OSKernel:     d.OSKernel,
-		Hostname:     d.Hostname,
-		IPv4:         d.IPv4,
-		IPv6:         d.IPv6,
-		Tags:         d.Tags,
-		Version:      d.Version,
-		Address:      d.Address,
-		Tunnels:      d.Tunnels,
+		ID:              s.ID,
+		ClientAuthID:    s.ClientAuthID,
+		Name:            d.Name,
+		OS:              d.OS,
+		OSArch:          d.OSArch,
+		OSFamily:        d.OSFamily,
+		OSKernel:        d.OSKernel,
+		Hostname:        d.Hostname,
+		IPv4:            d.IPv4,
+		IPv6:            d.IPv6,
+		Tags:            d.Tags,
+		Version:         d.Version,
+		Address:         d.Address,
+		Tunnels:         d.Tunnels,
+		TunnelConflicts: d.TunnelConflicts,
+		Tenant:          d.Tenant,
 	}
 	if s.DisconnectedAt.Valid {
 		res.DisconnectedAt = &s.DisconnectedAt.Time
@@ -3,8 +3,8 @@ package clients
 import (
 	"context"
 	"fmt"
-	"io"
 	"net"
+	"net/http"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -24,41 +24,96 @@ type Tunnel struct {
 	ID string `json:"id"`
 
 	sshConn                   ssh.Conn
-	connectionIDAutoIncrement int
+	connectionIDAutoIncrement int64
 	connCount                 int32
 	connCloseChan             chan bool
 	stopFn                    func()
-	wg                        sync.WaitGroup // TODO: verify whether wait group is needed here
-	acl                       *TunnelACL     // parsed Remote.ACL field
+	wg                        sync.WaitGroup  // TODO: verify whether wait group is needed here
+	acl                       *TunnelACL      // parsed Remote.ACL field
+	ctx                       context.Context // context of a started tunnel, it's done when the tunnel is stopped
+	httpTransport             *http.Transport // is used by the HTTP proxy
+	stats                     tunnelStats
+	limit                     *chshare.TokenBucket // limits the bandwidth of all connections, nil if not limited
+	expiryTimer               *time.Timer          // terminates the tunnel when it expires, nil if it never expires
 }
 
 func NewTunnel(logger *chshare.Logger, ssh ssh.Conn, id string, remote *chshare.Remote, acl *TunnelACL) *Tunnel {
-	return &Tunnel{
+	// tunnels saved before UDP was supported don't have a protocol
+	if remote.Protocol == "" {
+		remote.Protocol = chshare.ProtocolTCP
+	}
+	t := &Tunnel{
 		Logger:  logger.Fork("tunnel#%s:%s", id, remote),
 		Remote:  *remote,
 		ID:      id,
 		sshConn: ssh,
 		acl:     acl,
+		limit:   chshare.NewTokenBucket(remote.MaxBytesPerSec),
+	}
+	if remote.HTTPProxy {
+		t.httpTransport = t.newHTTPTransport()
 	}
+	return t
 }
 
 func (t *Tunnel) Start(ctx context.Context) (autoCloseChan chan bool, err error) {
-	// TODO(m-terel): consider to use ListenTCP
-	l, err := net.Listen("tcp4", t.LocalHost+":"+t.LocalPort)
+	if t.Reverse || t.HTTPProxy {
+		return t.startWithoutListener(ctx), nil
+	}
+
+	var l net.Listener
+	var pc net.PacketConn
+	if t.IsUDP() {
+		pc, err = net.ListenPacket(t.listenNetwork(), t.Local())
+	} else {
+		// TODO(m-terel): consider to use ListenTCP
+		l, err = net.Listen(t.listenNetwork(), t.Local())
+	}
 	if err != nil {
 		return nil, fmt.Errorf("%s: %s", t.Logger.Prefix(), err)
 	}
 
-	ctx, t.stopFn = context.WithCancel(ctx)
+	t.ctx, t.stopFn = context.WithCancel(ctx)
 	if t.IdleTimeoutMinutes > 0 {
 		t.connCloseChan = make(chan bool)
-		autoCloseChan = t.getAutoCloseChan(ctx)
+		autoCloseChan = t.getAutoCloseChan(t.ctx)
 	}
 	t.wg.Add(1)
-	go t.listen(ctx, l)
+	if pc != nil {
+		go t.listenUDP(t.ctx, pc)
+	} else {
+		go t.listen(t.ctx, l)
+	}
 	return
 }
 
+// Done returns a channel that is closed when the started tunnel is stopped, e.g. when it's terminated or its client
+// is disconnected.
+func (t *Tunnel) Done() <-chan struct{} {
+	return t.ctx.Done()
+}
+
+// listenNetwork returns a network to listen on. IPv6 addresses are listened without restricting the IP version,
+// so "::" accepts both IPv4 and IPv6 connections. Other hosts, e.g. 0.0.0.0, are listened on IPv4 only.
+func (t *Tunnel) listenNetwork() string {
+	if ip := net.ParseIP(t.LocalHost); ip != nil && ip.To4() == nil {
+		return t.Protocol
+	}
+	return t.Protocol + "4"
+}
+
+// startWithoutListener starts a tunnel which connections are not accepted by the server: they are either accepted
+// by the client for reverse tunnels or sent by the HTTP proxy.
+func (t *Tunnel) startWithoutListener(ctx context.Context) (autoCloseChan chan bool) {
+	t.ctx, t.stopFn = context.WithCancel(ctx)
+	if t.IdleTimeoutMinutes > 0 {
+		t.connCloseChan = make(chan bool)
+		autoCloseChan = t.getAutoCloseChan(t.ctx)
+	}
+	t.Infof("Started without listener")
+	return autoCloseChan
+}
+
 func (t *Tunnel) Terminate(force bool) error {
 	n := atomic.LoadInt32(&t.connCount)
 	if !force && n > 0 {
@@ -68,6 +123,15 @@ func (t *Tunnel) Terminate(force bool) error {
 		return nil
 	}
 
+	if t.Reverse {
+		t.stopReverseListener()
+	}
+	if t.httpTransport != nil {
+		t.httpTransport.CloseIdleConnections()
+	}
+	if t.expiryTimer != nil {
+		t.expiryTimer.Stop()
+	}
 	t.stopFn()
 	t.wg.Wait()
 	t.Infof("stopped")
@@ -114,7 +178,7 @@ func (t *Tunnel) listen(ctx context.Context, l net.Listener) {
 				continue
 			}
 
-			if !t.acl.CheckAccess(tcpAddr) {
+			if !t.acl.CheckAccess(tcpAddr.IP) {
 				t.Debugf("Access rejected. Remote addr: %s", tcpAddr)
 				conn.Close()
 				continue
@@ -162,14 +226,30 @@ func (t *Tunnel) getAutoCloseChan(ctx context.Context) chan bool {
 	return autoCloseChan
 }
 
-func (t *Tunnel) accept(ctx context.Context, src io.ReadWriteCloser) {
-	defer src.Close()
-	t.connectionIDAutoIncrement++
-	atomic.AddInt32(&t.connCount, 1)
-	defer atomic.AddInt32(&t.connCount, -1)
+// openConn counts a new connection of the tunnel. It returns false if the tunnel already has max connections,
+// otherwise the connection should be counted out by decrementing connCount when it's closed.
+func (t *Tunnel) openConn() bool {
+	n := atomic.AddInt32(&t.connCount, 1)
+	if t.MaxConnections > 0 && n > int32(t.MaxConnections) {
+		atomic.AddInt32(&t.connCount, -1)
+		return false
+	}
+	return true
+}
 
-	cid := t.connectionIDAutoIncrement
+func (t *Tunnel) accept(ctx context.Context, conn net.Conn) {
+	cid := t.nextConnID()
 	l := t.Fork("conn#%d", cid)
+	if !t.openConn() {
+		l.Debugf("Rejected: max connections (%d) reached", t.MaxConnections)
+		conn.Close()
+		return
+	}
+	defer atomic.AddInt32(&t.connCount, -1)
+	src := t.trackConn(cid, conn.RemoteAddr().String(), conn)
+	defer t.untrackConn(src)
+	defer src.Close()
+
 	l.Debugf("Open")
 
 	done := make(chan bool)
@@ -189,6 +269,11 @@ func (t *Tunnel) accept(ctx context.Context, src io.ReadWriteCloser) {
 		l.Debugf("No remote connection")
 		return
 	}
+	if t.Socks {
+		t.acceptSocks(l, src)
+		close(done)
+		return
+	}
 	//ssh request for tcp connection for this proxy's remote
 	dst, reqs, err := t.sshConn.OpenChannel("rport", []byte(t.Remote.Remote()))
 	if err != nil {
@@ -197,7 +282,7 @@ func (t *Tunnel) accept(ctx context.Context, src io.ReadWriteCloser) {
 	}
 	go ssh.DiscardRequests(reqs)
 	//then pipe
-	s, r := chshare.Pipe(src, dst)
+	s, r := chshare.Pipe(src, dst, t.limit)
 	l.Debugf("Close (sent %s received %s)", sizestr.ToString(s), sizestr.ToString(r))
 	close(done)
 }
@@ -8,15 +8,25 @@ import (
 
 type TunnelACL struct {
 	AllowedIPs []net.IPNet
+	// SessionIPs returns source IPs of API sessions that are allowed instead of AllowedIPs, nil if it's not used.
+	SessionIPs func() []net.IP
 }
 
-// CheckAccess returns true if connection from specified address is allowed
-func (a TunnelACL) CheckAccess(addr *net.TCPAddr) bool {
+// CheckAccess returns true if connection from specified IP address is allowed
+func (a TunnelACL) CheckAccess(ip net.IP) bool {
+	if a.SessionIPs != nil {
+		for _, allowed := range a.SessionIPs() {
+			if allowed.Equal(ip) {
+				return true
+			}
+		}
+		return false
+	}
 	if len(a.AllowedIPs) == 0 {
 		return true
 	}
 	for _, allowed := range a.AllowedIPs {
-		if allowed.Contains(addr.IP) {
+		if allowed.Contains(ip) {
 			return true
 		}
 	}
@@ -48,13 +58,13 @@ func ParseTunnelACL(str string) (*TunnelACL, error) {
 			}
 		}
 
-		if ip.To4() == nil {
-			return nil, fmt.Errorf("%s is not IPv4 address", strVal)
-		}
-
 		if ipNet == nil {
-			// if range is not specified, specify mask for one addr (/32)
-			ipMask := net.IPv4Mask(255, 255, 255, 255)
+			// if range is not specified, specify mask for one addr (/32 or /128)
+			ipMask := net.CIDRMask(8*net.IPv6len, 8*net.IPv6len)
+			if ip4 := ip.To4(); ip4 != nil {
+				ip = ip4
+				ipMask = net.CIDRMask(8*net.IPv4len, 8*net.IPv4len)
+			}
 			ipNet = &net.IPNet{IP: ip, Mask: ipMask}
 		}
 
@@ -0,0 +1,97 @@
+package clients
+
+import (
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTunnelACLCheckAccess(t *testing.T) {
+	acl, err := ParseTunnelACL("192.0.2.1,198.51.100.0/24,2001:db8::1,2001:db8:1::/48")
+	require.NoError(t, err)
+
+	testCases := []struct {
+		ip          string
+		wantAllowed bool
+	}{
+		{ip: "192.0.2.1", wantAllowed: true},
+		{ip: "::ffff:192.0.2.1", wantAllowed: true},
+		{ip: "192.0.2.2", wantAllowed: false},
+		{ip: "198.51.100.200", wantAllowed: true},
+		{ip: "2001:db8::1", wantAllowed: true},
+		{ip: "2001:db8::2", wantAllowed: false},
+		{ip: "2001:db8:1:ffff::1", wantAllowed: true},
+		{ip: "2001:db8:2::1", wantAllowed: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.ip, func(t *testing.T) {
+			assert.Equal(t, tc.wantAllowed, acl.CheckAccess(net.ParseIP(tc.ip)))
+		})
+	}
+}
+
+func TestTunnelACLCheckAccessSessionIPs(t *testing.T) {
+	sessionIPs := []net.IP{net.ParseIP("192.0.2.1"), net.ParseIP("2001:db8::1")}
+	acl := TunnelACL{
+		SessionIPs: func() []net.IP { return sessionIPs },
+	}
+
+	assert.True(t, acl.CheckAccess(net.ParseIP("192.0.2.1")))
+	assert.True(t, acl.CheckAccess(net.ParseIP("2001:db8::1")))
+	assert.False(t, acl.CheckAccess(net.ParseIP("192.0.2.2")))
+
+	// the user moved to another IP
+	sessionIPs = []net.IP{net.ParseIP("192.0.2.2")}
+	assert.False(t, acl.CheckAccess(net.ParseIP("192.0.2.1")))
+	assert.True(t, acl.CheckAccess(net.ParseIP("192.0.2.2")))
+
+	// sessions ended
+	sessionIPs = nil
+	assert.False(t, acl.CheckAccess(net.ParseIP("192.0.2.2")))
+}
+
+func TestParseTunnelACLInvalid(t *testing.T) {
+	testCases := []struct {
+		acl     string
+		wantErr string
+	}{
+		{acl: "192.0.2", wantErr: "invalid IP addr: 192.0.2"},
+		{acl: "2001:db8::1::", wantErr: "invalid IP addr: 2001:db8::1::"},
+		{acl: "2001:db8::/129", wantErr: "invalid CIDR address: 2001:db8::/129"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.acl, func(t *testing.T) {
+			_, err := ParseTunnelACL(tc.acl)
+
+			assert.EqualError(t, err, tc.wantErr)
+		})
+	}
+}
+
+func TestTunnelListenNetwork(t *testing.T) {
+	testCases := []struct {
+		localHost   string
+		protocol    string
+		wantNetwork string
+	}{
+		{localHost: "0.0.0.0", protocol: "tcp", wantNetwork: "tcp4"},
+		{localHost: "localhost", protocol: "tcp", wantNetwork: "tcp4"},
+		{localHost: "::", protocol: "tcp", wantNetwork: "tcp"},
+		{localHost: "2001:db8::1", protocol: "udp", wantNetwork: "udp"},
+		{localHost: "127.0.0.1", protocol: "udp", wantNetwork: "udp4"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.localHost+"/"+tc.protocol, func(t *testing.T) {
+			tunnel := &Tunnel{}
+			tunnel.LocalHost = tc.localHost
+			tunnel.Protocol = tc.protocol
+
+			assert.Equal(t, tc.wantNetwork, tunnel.listenNetwork())
+		})
+	}
+}
@@ -0,0 +1,24 @@
+package clients
+
+import (
+	"time"
+
+	chshare "github.com/cloudradar-monitoring/rport/share"
+)
+
+// TunnelConflict is a persistent tunnel that couldn't be re-established when its client reconnected, e.g. because
+// its local port was taken meanwhile. It's retried on the next connect of the client until it's deleted.
+type TunnelConflict struct {
+	chshare.Remote
+	Error      string    `json:"error"`
+	DetectedAt time.Time `json:"detected_at"`
+}
+
+// NewTunnelConflict returns a conflict of a given persistent tunnel that failed to start with a given error.
+func NewTunnelConflict(r *chshare.Remote, err error) *TunnelConflict {
+	return &TunnelConflict{
+		Remote:     *r,
+		Error:      err.Error(),
+		DetectedAt: now(),
+	}
+}
@@ -0,0 +1,108 @@
+package clients
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"sync/atomic"
+
+	"golang.org/x/crypto/ssh"
+
+	chshare "github.com/cloudradar-monitoring/rport/share"
+)
+
+// newHTTPTransport returns a transport that sends requests to the remote address over the client connection.
+func (t *Tunnel) newHTTPTransport() *http.Transport {
+	return &http.Transport{
+		DialContext:     t.dialRemote,
+		IdleConnTimeout: http.DefaultTransport.(*http.Transport).IdleConnTimeout,
+		// web interfaces of devices are usually served with self-signed certificates
+		TLSClientConfig: &tls.Config{InsecureSkipVerify: true}, // #nosec G402
+	}
+}
+
+// HTTPTransport returns a transport of a tunnel served by the HTTP proxy or nil otherwise.
+func (t *Tunnel) HTTPTransport() http.RoundTripper {
+	if t.httpTransport == nil {
+		return nil
+	}
+	return t.httpTransport
+}
+
+// IsAllowed returns true if a given IP address is allowed to use the tunnel by its ACL.
+func (t *Tunnel) IsAllowed(ip net.IP) bool {
+	return t.acl == nil || t.acl.CheckAccess(ip)
+}
+
+// dialRemote opens a connection to the remote address over the client connection. Given network and address
+// are ignored, the remote address of the tunnel is always used.
+func (t *Tunnel) dialRemote(ctx context.Context, network, addr string) (net.Conn, error) {
+	if t.ctx == nil || t.ctx.Err() != nil {
+		return nil, errors.New("tunnel is stopped")
+	}
+	if t.sshConn == nil {
+		return nil, errors.New("no remote connection")
+	}
+	if !t.openConn() {
+		return nil, fmt.Errorf("max connections (%d) reached", t.MaxConnections)
+	}
+	ch, reqs, err := t.sshConn.OpenChannel("rport", []byte(t.Remote.Remote()))
+	if err != nil {
+		atomic.AddInt32(&t.connCount, -1)
+		return nil, err
+	}
+	go ssh.DiscardRequests(reqs)
+
+	cid := t.nextConnID()
+	t.Debugf("Open HTTP proxy connection#%d", cid)
+	return &proxyConn{
+		Conn:   chshare.NewRWCConn(ch),
+		tunnel: t,
+		// the connection is shared by requests of API users, so there is no single peer
+		stats: t.stats.open(cid, ""),
+	}, nil
+}
+
+// proxyConn tracks connections of the HTTP proxy to let idle tunnels be closed automatically.
+// It's connected to the remote, so bytes read from it are sent out to API users.
+type proxyConn struct {
+	net.Conn
+	tunnel    *Tunnel
+	stats     *TunnelConnection
+	closeOnce sync.Once
+}
+
+func (c *proxyConn) Read(p []byte) (int, error) {
+	n, err := c.Conn.Read(p)
+	c.tunnel.stats.add(c.stats, 0, n)
+	c.tunnel.limit.Wait(n)
+	return n, err
+}
+
+func (c *proxyConn) Write(p []byte) (int, error) {
+	c.tunnel.limit.Wait(len(p))
+	n, err := c.Conn.Write(p)
+	c.tunnel.stats.add(c.stats, n, 0)
+	return n, err
+}
+
+func (c *proxyConn) Close() error {
+	err := c.Conn.Close()
+	c.closeOnce.Do(func() {
+		t := c.tunnel
+		atomic.AddInt32(&t.connCount, -1)
+		t.stats.close(c.stats)
+		t.Debugf("Close HTTP proxy connection#%d", c.stats.ID)
+		if t.connCloseChan != nil {
+			select {
+			case t.connCloseChan <- true:
+			case <-t.ctx.Done():
+			}
+		}
+	})
+	return err
+}
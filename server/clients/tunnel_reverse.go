@@ -0,0 +1,101 @@
+package clients
+
+import (
+	"fmt"
+	"sync/atomic"
+
+	"golang.org/x/crypto/ssh"
+
+	chshare "github.com/cloudradar-monitoring/rport/share"
+	"github.com/cloudradar-monitoring/rport/share/comm"
+)
+
+// StartReverseListener asks the client to listen on the local address of a reverse tunnel. Reverse tunnels requested
+// on connect don't need it, the client starts them when the connection is established.
+func (t *Tunnel) StartReverseListener() error {
+	if t.sshConn == nil {
+		return fmt.Errorf("no remote connection")
+	}
+	req := &comm.ReverseTunnelRequest{Local: t.Local(), Remote: t.Remote.Remote()}
+	return comm.SendRequestAndGetResponse(t.sshConn, comm.RequestTypeStartReverseTunnel, req, &struct{}{})
+}
+
+func (t *Tunnel) stopReverseListener() {
+	if t.sshConn == nil {
+		return
+	}
+	req := &comm.ReverseTunnelRequest{Local: t.Local(), Remote: t.Remote.Remote()}
+	if err := comm.SendRequestAndGetResponse(t.sshConn, comm.RequestTypeStopReverseTunnel, req, &struct{}{}); err != nil {
+		t.Errorf("Failed to stop reverse tunnel listener on client: %v", err)
+	}
+}
+
+// HandleReverseChannel connects a channel opened by the client for a connection of a reverse tunnel to the remote address.
+func (t *Tunnel) HandleReverseChannel(newCh ssh.NewChannel, connStats *chshare.ConnStats) {
+	ctx := t.ctx
+	if ctx == nil || ctx.Err() != nil {
+		if err := newCh.Reject(ssh.ConnectionFailed, "tunnel is stopped"); err != nil {
+			t.Errorf("Failed to reject channel: %v", err)
+		}
+		return
+	}
+	if !t.openConn() {
+		if err := newCh.Reject(ssh.ResourceShortage, fmt.Sprintf("max connections (%d) reached", t.MaxConnections)); err != nil {
+			t.Errorf("Failed to reject channel: %v", err)
+		}
+		return
+	}
+	defer atomic.AddInt32(&t.connCount, -1)
+	ch, reqs, err := newCh.Accept()
+	if err != nil {
+		t.Debugf("Failed to accept stream: %s", err)
+		return
+	}
+	go ssh.DiscardRequests(reqs)
+	cid := t.nextConnID()
+	// the peer connected to the client, its address is not known to the server
+	src := t.trackConn(cid, "", ch)
+	defer t.untrackConn(src)
+
+	t.wg.Add(1)
+	defer func() {
+		t.wg.Done()
+		if t.connCloseChan != nil {
+			select {
+			case t.connCloseChan <- true:
+			case <-ctx.Done():
+			}
+		}
+	}()
+	defer src.Close()
+
+	l := t.Fork("conn#%d", cid)
+
+	done := make(chan bool)
+	defer close(done)
+	// link ctx to conn
+	go func() {
+		select {
+		case <-ctx.Done():
+			if src.Close() == nil {
+				l.Debugf("closed")
+			}
+		case <-done:
+			// do nothing
+		}
+	}()
+
+	chshare.HandleTCPStream(l, connStats, src, t.Remote.Remote(), t.limit)
+}
+
+// FindReverseTunnel returns an active reverse tunnel to a given remote address or nil if it's not found.
+func (c *Client) FindReverseTunnel(remote string) *Tunnel {
+	c.Lock()
+	defer c.Unlock()
+	for _, t := range c.Tunnels {
+		if t.Reverse && t.Remote.Remote() == remote {
+			return t
+		}
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+package clients
+
+import (
+	"context"
+	"io"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh"
+
+	chshare "github.com/cloudradar-monitoring/rport/share"
+	"github.com/cloudradar-monitoring/rport/share/test"
+)
+
+func TestHandleReverseChannel(t *testing.T) {
+	// given
+	echo, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer echo.Close()
+	go func() {
+		conn, err := echo.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		_, _ = io.Copy(conn, conn)
+	}()
+	host, port, err := net.SplitHostPort(echo.Addr().String())
+	require.NoError(t, err)
+
+	remote := &chshare.Remote{LocalHost: "127.0.0.1", LocalPort: "5432", RemoteHost: host, RemotePort: port, Reverse: true}
+	tunnel := NewTunnel(testLog, nil, "1", remote, nil)
+	_, err = tunnel.Start(context.Background())
+	require.NoError(t, err)
+
+	tunnelCh, clientCh := test.NewChannelPair()
+	newCh := &test.NewChannelMock{Type: "reverse", Data: []byte(remote.Remote()), Channel: tunnelCh}
+	done := make(chan struct{})
+
+	// when
+	go func() {
+		tunnel.HandleReverseChannel(newCh, &chshare.ConnStats{})
+		close(done)
+	}()
+
+	// then
+	_, err = clientCh.Write([]byte("ping"))
+	require.NoError(t, err)
+	buf := make([]byte, 4)
+	_, err = io.ReadFull(clientCh, buf)
+	require.NoError(t, err)
+	assert.Equal(t, "ping", string(buf))
+
+	// when
+	require.NoError(t, tunnel.Terminate(true))
+
+	// then
+	<-done
+}
+
+func TestHandleReverseChannelStoppedTunnel(t *testing.T) {
+	remote := &chshare.Remote{LocalHost: "127.0.0.1", LocalPort: "5432", RemoteHost: "127.0.0.1", RemotePort: "5432", Reverse: true}
+	tunnel := NewTunnel(testLog, nil, "1", remote, nil)
+	newCh := &test.NewChannelMock{Type: "reverse", Data: []byte(remote.Remote())}
+
+	tunnel.HandleReverseChannel(newCh, &chshare.ConnStats{})
+
+	assert.True(t, newCh.Rejected)
+}
+
+func TestHandleReverseChannelMaxConnections(t *testing.T) {
+	remote := &chshare.Remote{LocalHost: "127.0.0.1", LocalPort: "5432", RemoteHost: "127.0.0.1", RemotePort: "5432", Reverse: true, MaxConnections: 1}
+	tunnel := NewTunnel(testLog, nil, "1", remote, nil)
+	_, err := tunnel.Start(context.Background())
+	require.NoError(t, err)
+	defer tunnel.Terminate(true)
+	require.True(t, tunnel.openConn())
+	newCh := &test.NewChannelMock{Type: "reverse", Data: []byte(remote.Remote())}
+
+	tunnel.HandleReverseChannel(newCh, &chshare.ConnStats{})
+
+	assert.True(t, newCh.Rejected)
+	assert.Equal(t, ssh.ResourceShortage, newCh.RejectReason)
+	assert.Equal(t, "max connections (1) reached", newCh.RejectMessage)
+	assert.EqualValues(t, 1, tunnel.connCount)
+}
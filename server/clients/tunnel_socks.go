@@ -0,0 +1,172 @@
+package clients
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+
+	"github.com/jpillora/sizestr"
+	"golang.org/x/crypto/ssh"
+
+	chshare "github.com/cloudradar-monitoring/rport/share"
+	"github.com/cloudradar-monitoring/rport/share/comm"
+)
+
+// SOCKS5 protocol constants, see RFC 1928.
+const (
+	socksVersion = 0x05
+
+	socksMethodNoAuth       = 0x00
+	socksMethodNoAcceptable = 0xff
+
+	socksCmdConnect = 0x01
+
+	socksAddrIPv4   = 0x01
+	socksAddrDomain = 0x03
+	socksAddrIPv6   = 0x04
+
+	socksReplySucceeded           = 0x00
+	socksReplyGeneralFailure      = 0x01
+	socksReplyNotAllowed          = 0x02
+	socksReplyHostUnreachable     = 0x04
+	socksReplyCommandNotSupported = 0x07
+	socksReplyAddressNotSupported = 0x08
+)
+
+// socksError is returned by socksHandshake if the request was rejected with a reply.
+type socksError struct {
+	reply byte
+	msg   string
+}
+
+func (e *socksError) Error() string {
+	return e.msg
+}
+
+// acceptSocks connects a connection of a SOCKS tunnel to the destination of its CONNECT request over the client
+// connection. The client rejects destinations that are not in its allow list.
+func (t *Tunnel) acceptSocks(l *chshare.Logger, src io.ReadWriteCloser) {
+	remote, err := socksHandshake(src)
+	if err != nil {
+		l.Debugf("SOCKS handshake failed: %v", err)
+		var socksErr *socksError
+		if errors.As(err, &socksErr) {
+			_ = socksReply(src, socksErr.reply)
+		}
+		return
+	}
+
+	dst, reqs, err := t.sshConn.OpenChannel(comm.ChannelTypeSocks, []byte(remote))
+	if err != nil {
+		l.Debugf("Failed to connect to %s: %v", remote, err)
+		_ = socksReply(src, socksReplyForErr(err))
+		return
+	}
+	go ssh.DiscardRequests(reqs)
+	if err = socksReply(src, socksReplySucceeded); err != nil {
+		l.Debugf("Failed to send SOCKS reply: %v", err)
+		dst.Close()
+		return
+	}
+
+	l.Debugf("Connected to %s", remote)
+	s, r := chshare.Pipe(src, dst, t.limit)
+	l.Debugf("Close (sent %s received %s)", sizestr.ToString(s), sizestr.ToString(r))
+}
+
+// socksHandshake negotiates a SOCKS5 connection without authentication and returns the destination
+// of its CONNECT request. A socksError is returned for requests that should be rejected with a reply,
+// otherwise the caller should send a reply with socksReply.
+func socksHandshake(rw io.ReadWriter) (string, error) {
+	buf := make([]byte, 256)
+
+	// greeting: VER NMETHODS METHODS
+	if _, err := io.ReadFull(rw, buf[:2]); err != nil {
+		return "", err
+	}
+	if buf[0] != socksVersion {
+		return "", fmt.Errorf("unsupported SOCKS version: %d", buf[0])
+	}
+	methods := buf[:buf[1]]
+	if _, err := io.ReadFull(rw, methods); err != nil {
+		return "", err
+	}
+	method := byte(socksMethodNoAcceptable)
+	for _, m := range methods {
+		if m == socksMethodNoAuth {
+			method = socksMethodNoAuth
+			break
+		}
+	}
+	if _, err := rw.Write([]byte{socksVersion, method}); err != nil {
+		return "", err
+	}
+	if method == socksMethodNoAcceptable {
+		return "", errors.New("SOCKS client doesn't support connections without authentication")
+	}
+
+	// request: VER CMD RSV ATYP DST.ADDR DST.PORT
+	if _, err := io.ReadFull(rw, buf[:4]); err != nil {
+		return "", err
+	}
+	if buf[0] != socksVersion {
+		return "", fmt.Errorf("unsupported SOCKS version: %d", buf[0])
+	}
+	cmd, addrType := buf[1], buf[3]
+
+	var host string
+	switch addrType {
+	case socksAddrIPv4, socksAddrIPv6:
+		ip := buf[:net.IPv4len]
+		if addrType == socksAddrIPv6 {
+			ip = buf[:net.IPv6len]
+		}
+		if _, err := io.ReadFull(rw, ip); err != nil {
+			return "", err
+		}
+		host = net.IP(ip).String()
+	case socksAddrDomain:
+		if _, err := io.ReadFull(rw, buf[:1]); err != nil {
+			return "", err
+		}
+		domain := buf[:buf[0]]
+		if _, err := io.ReadFull(rw, domain); err != nil {
+			return "", err
+		}
+		host = string(domain)
+	default:
+		return "", &socksError{reply: socksReplyAddressNotSupported, msg: fmt.Sprintf("unsupported SOCKS address type: %d", addrType)}
+	}
+	if _, err := io.ReadFull(rw, buf[:2]); err != nil {
+		return "", err
+	}
+	port := binary.BigEndian.Uint16(buf[:2])
+
+	if cmd != socksCmdConnect {
+		return "", &socksError{reply: socksReplyCommandNotSupported, msg: fmt.Sprintf("unsupported SOCKS command: %d", cmd)}
+	}
+	return net.JoinHostPort(host, strconv.Itoa(int(port))), nil
+}
+
+// socksReply sends a reply to a SOCKS5 request. The bound address is not known to the server, so it's always zero.
+func socksReply(w io.Writer, reply byte) error {
+	_, err := w.Write([]byte{socksVersion, reply, 0x00, socksAddrIPv4, 0, 0, 0, 0, 0, 0})
+	return err
+}
+
+// socksReplyForErr returns a reply to a SOCKS5 request which channel failed to open with a given error.
+func socksReplyForErr(err error) byte {
+	var openErr *ssh.OpenChannelError
+	if errors.As(err, &openErr) {
+		switch openErr.Reason {
+		case ssh.Prohibited:
+			return socksReplyNotAllowed
+		case ssh.ConnectionFailed:
+			return socksReplyHostUnreachable
+		}
+	}
+	return socksReplyGeneralFailure
+}
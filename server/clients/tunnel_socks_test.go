@@ -0,0 +1,183 @@
+package clients
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/net/proxy"
+
+	chshare "github.com/cloudradar-monitoring/rport/share"
+	"github.com/cloudradar-monitoring/rport/share/comm"
+	"github.com/cloudradar-monitoring/rport/share/test"
+)
+
+func freeTCPPort(t *testing.T) string {
+	l, err := net.Listen("tcp4", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer l.Close()
+	return strconv.Itoa(l.Addr().(*net.TCPAddr).Port)
+}
+
+func startSocksTunnel(t *testing.T, connMock *test.ConnMock) (*Tunnel, proxy.Dialer) {
+	remote := &chshare.Remote{LocalHost: "127.0.0.1", LocalPort: freeTCPPort(t), Socks: true}
+	tunnel := NewTunnel(testLog, connMock, "1", remote, nil)
+	_, err := tunnel.Start(context.Background())
+	require.NoError(t, err)
+
+	dialer, err := proxy.SOCKS5("tcp", tunnel.Local(), nil, proxy.Direct)
+	require.NoError(t, err)
+	return tunnel, dialer
+}
+
+func TestSocksTunnel(t *testing.T) {
+	// given
+	clientCh, tunnelCh := test.NewChannelPair()
+	connMock := test.NewConnMock()
+	connMock.ReturnChannel = tunnelCh
+	tunnel, dialer := startSocksTunnel(t, connMock)
+	defer tunnel.Terminate(true)
+
+	// when
+	conn, err := dialer.Dial("tcp", "nas.local:443")
+	require.NoError(t, err)
+	defer conn.Close()
+	_, err = conn.Write([]byte("ping"))
+	require.NoError(t, err)
+
+	// then
+	buf := make([]byte, 4)
+	_, err = io.ReadFull(clientCh, buf)
+	require.NoError(t, err)
+	assert.Equal(t, "ping", string(buf))
+	gotType, gotData := connMock.InputOpenChannel()
+	assert.Equal(t, comm.ChannelTypeSocks, gotType)
+	assert.Equal(t, "nas.local:443", string(gotData))
+
+	// when
+	_, err = clientCh.Write([]byte("pong"))
+	require.NoError(t, err)
+
+	// then
+	_, err = io.ReadFull(conn, buf)
+	require.NoError(t, err)
+	assert.Equal(t, "pong", string(buf))
+}
+
+func TestSocksTunnelRejected(t *testing.T) {
+	testCases := []struct {
+		name    string
+		openErr error
+		wantErr string
+	}{
+		{
+			name:    "destination not allowed",
+			openErr: &ssh.OpenChannelError{Reason: ssh.Prohibited, Message: "destination is not allowed: 192.168.1.1:22"},
+			wantErr: "connection not allowed by ruleset",
+		},
+		{
+			name:    "connection failed",
+			openErr: &ssh.OpenChannelError{Reason: ssh.ConnectionFailed, Message: "connection refused"},
+			wantErr: "host unreachable",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// given
+			connMock := test.NewConnMock()
+			connMock.ReturnOpenChannelErr = tc.openErr
+			tunnel, dialer := startSocksTunnel(t, connMock)
+			defer tunnel.Terminate(true)
+
+			// when
+			_, err := dialer.Dial("tcp", "192.168.1.1:22")
+
+			// then
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tc.wantErr)
+		})
+	}
+}
+
+func TestSocksHandshake(t *testing.T) {
+	testCases := []struct {
+		name      string
+		input     []byte
+		wantDest  string
+		wantErr   string
+		wantReply byte
+	}{
+		{
+			name:     "ipv4",
+			input:    []byte{5, 1, 0, 5, 1, 0, 1, 192, 168, 1, 1, 0, 22},
+			wantDest: "192.168.1.1:22",
+		},
+		{
+			name:     "ipv6",
+			input:    []byte{5, 1, 0, 5, 1, 0, 4, 0x20, 0x01, 0x0d, 0xb8, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 1, 1, 187},
+			wantDest: "[2001:db8::1]:443",
+		},
+		{
+			name:     "domain",
+			input:    []byte{5, 2, 2, 0, 5, 1, 0, 3, 9, 'n', 'a', 's', '.', 'l', 'o', 'c', 'a', 'l', 0, 80},
+			wantDest: "nas.local:80",
+		},
+		{
+			name:    "unsupported version",
+			input:   []byte{4, 1, 0},
+			wantErr: "unsupported SOCKS version: 4",
+		},
+		{
+			name:    "authentication required",
+			input:   []byte{5, 1, 2},
+			wantErr: "SOCKS client doesn't support connections without authentication",
+		},
+		{
+			name:      "bind command",
+			input:     []byte{5, 1, 0, 5, 2, 0, 1, 192, 168, 1, 1, 0, 22},
+			wantErr:   "unsupported SOCKS command: 2",
+			wantReply: socksReplyCommandNotSupported,
+		},
+		{
+			name:      "unknown address type",
+			input:     []byte{5, 1, 0, 5, 1, 0, 9},
+			wantErr:   "unsupported SOCKS address type: 9",
+			wantReply: socksReplyAddressNotSupported,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rw := struct {
+				io.Reader
+				io.Writer
+			}{
+				Reader: bytes.NewReader(tc.input),
+				Writer: &bytes.Buffer{},
+			}
+
+			gotDest, err := socksHandshake(rw)
+
+			if tc.wantErr != "" {
+				require.EqualError(t, err, tc.wantErr)
+				var socksErr *socksError
+				isSocksErr := errors.As(err, &socksErr)
+				require.Equal(t, tc.wantReply != 0, isSocksErr)
+				if isSocksErr {
+					assert.Equal(t, tc.wantReply, socksErr.reply)
+				}
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.wantDest, gotDest)
+		})
+	}
+}
@@ -0,0 +1,162 @@
+package clients
+
+import (
+	"encoding/json"
+	"io"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	chshare "github.com/cloudradar-monitoring/rport/share"
+)
+
+// TunnelConnection is an active connection of a tunnel. Bytes in are received from the peer, bytes out are sent to it.
+type TunnelConnection struct {
+	ID int64 `json:"id"`
+	// PeerAddr is the address of the peer that opened the connection, it's empty if the peer is not known to the server,
+	// e.g. for reverse tunnels and the HTTP proxy.
+	PeerAddr     string    `json:"peer_addr"`
+	OpenedAt     time.Time `json:"opened_at"`
+	LastActivity time.Time `json:"last_activity"`
+	BytesIn      int64     `json:"bytes_in"`
+	BytesOut     int64     `json:"bytes_out"`
+}
+
+// TunnelStats is a snapshot of the traffic of a tunnel. Bytes are counted for all connections since the tunnel was started.
+type TunnelStats struct {
+	BytesIn           int64              `json:"bytes_in"`
+	BytesOut          int64              `json:"bytes_out"`
+	ActiveConnections int32              `json:"active_connections"`
+	LastActivity      *time.Time         `json:"last_activity"`
+	Connections       []TunnelConnection `json:"connections"`
+}
+
+// tunnelStats tracks the traffic and active connections of a tunnel.
+type tunnelStats struct {
+	mu           sync.Mutex
+	bytesIn      int64
+	bytesOut     int64
+	lastActivity *time.Time
+	conns        map[int64]*TunnelConnection
+}
+
+func (s *tunnelStats) open(id int64, peerAddr string) *TunnelConnection {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.conns == nil {
+		s.conns = make(map[int64]*TunnelConnection)
+	}
+	now := time.Now()
+	conn := &TunnelConnection{
+		ID:           id,
+		PeerAddr:     peerAddr,
+		OpenedAt:     now,
+		LastActivity: now,
+	}
+	s.conns[id] = conn
+	s.lastActivity = &now
+	return conn
+}
+
+func (s *tunnelStats) close(conn *TunnelConnection) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.conns, conn.ID)
+}
+
+func (s *tunnelStats) add(conn *TunnelConnection, in, out int) {
+	if in == 0 && out == 0 {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	s.bytesIn += int64(in)
+	s.bytesOut += int64(out)
+	s.lastActivity = &now
+	conn.BytesIn += int64(in)
+	conn.BytesOut += int64(out)
+	conn.LastActivity = now
+}
+
+// connections returns active connections ordered by id, s.mu should be locked.
+func (s *tunnelStats) connections() []TunnelConnection {
+	res := make([]TunnelConnection, 0, len(s.conns))
+	for _, conn := range s.conns {
+		res = append(res, *conn)
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].ID < res[j].ID })
+	return res
+}
+
+// Stats returns the current traffic statistics of the tunnel.
+func (t *Tunnel) Stats() TunnelStats {
+	t.stats.mu.Lock()
+	defer t.stats.mu.Unlock()
+	return TunnelStats{
+		BytesIn:           t.stats.bytesIn,
+		BytesOut:          t.stats.bytesOut,
+		ActiveConnections: atomic.LoadInt32(&t.connCount),
+		LastActivity:      t.stats.lastActivity,
+		Connections:       t.stats.connections(),
+	}
+}
+
+// Connections returns active connections of the tunnel ordered by id.
+func (t *Tunnel) Connections() []TunnelConnection {
+	t.stats.mu.Lock()
+	defer t.stats.mu.Unlock()
+	return t.stats.connections()
+}
+
+// MarshalJSON adds the current traffic statistics to the tunnel.
+func (t *Tunnel) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		chshare.Remote
+		ID string `json:"id"`
+		TunnelStats
+	}{
+		Remote:      t.Remote,
+		ID:          t.ID,
+		TunnelStats: t.Stats(),
+	})
+}
+
+// nextConnID returns an id of a new connection of the tunnel.
+func (t *Tunnel) nextConnID() int64 {
+	return atomic.AddInt64(&t.connectionIDAutoIncrement, 1)
+}
+
+// trackConn registers a connection opened by a peer of the tunnel. The returned connection counts its traffic
+// and should be passed to untrackConn when it's closed.
+func (t *Tunnel) trackConn(id int64, peerAddr string, rwc io.ReadWriteCloser) *statsConn {
+	return &statsConn{
+		ReadWriteCloser: rwc,
+		stats:           &t.stats,
+		conn:            t.stats.open(id, peerAddr),
+	}
+}
+
+func (t *Tunnel) untrackConn(c *statsConn) {
+	t.stats.close(c.conn)
+}
+
+// statsConn counts the traffic of a connection opened by a peer of the tunnel.
+type statsConn struct {
+	io.ReadWriteCloser
+	stats *tunnelStats
+	conn  *TunnelConnection
+}
+
+func (c *statsConn) Read(p []byte) (int, error) {
+	n, err := c.ReadWriteCloser.Read(p)
+	c.stats.add(c.conn, n, 0)
+	return n, err
+}
+
+func (c *statsConn) Write(p []byte) (int, error) {
+	n, err := c.ReadWriteCloser.Write(p)
+	c.stats.add(c.conn, 0, n)
+	return n, err
+}
@@ -0,0 +1,110 @@
+package clients
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	chshare "github.com/cloudradar-monitoring/rport/share"
+	"github.com/cloudradar-monitoring/rport/share/test"
+)
+
+func TestTunnelStats(t *testing.T) {
+	// given
+	clientCh, tunnelCh := test.NewChannelPair()
+	connMock := test.NewConnMock()
+	connMock.ReturnChannel = tunnelCh
+	remote := &chshare.Remote{LocalHost: "127.0.0.1", LocalPort: freeTCPPort(t), RemoteHost: "192.168.1.1", RemotePort: "22", Protocol: chshare.ProtocolTCP}
+	tunnel := NewTunnel(testLog, connMock, "1", remote, nil)
+	_, err := tunnel.Start(context.Background())
+	require.NoError(t, err)
+	defer tunnel.Terminate(true)
+	start := time.Now()
+
+	// when
+	conn, err := net.Dial("tcp", tunnel.Local())
+	require.NoError(t, err)
+	_, err = conn.Write([]byte("ping"))
+	require.NoError(t, err)
+	buf := make([]byte, 4)
+	_, err = io.ReadFull(clientCh, buf)
+	require.NoError(t, err)
+	_, err = clientCh.Write([]byte("hello"))
+	require.NoError(t, err)
+	buf = make([]byte, 5)
+	_, err = io.ReadFull(conn, buf)
+	require.NoError(t, err)
+
+	// then
+	stats := tunnel.Stats()
+	assert.EqualValues(t, 4, stats.BytesIn)
+	assert.EqualValues(t, 5, stats.BytesOut)
+	assert.EqualValues(t, 1, stats.ActiveConnections)
+	require.NotNil(t, stats.LastActivity)
+	assert.False(t, stats.LastActivity.Before(start))
+	require.Len(t, stats.Connections, 1)
+	gotConn := stats.Connections[0]
+	assert.EqualValues(t, 1, gotConn.ID)
+	assert.Equal(t, conn.LocalAddr().String(), gotConn.PeerAddr)
+	assert.False(t, gotConn.OpenedAt.Before(start))
+	assert.False(t, gotConn.LastActivity.Before(gotConn.OpenedAt))
+	assert.EqualValues(t, 4, gotConn.BytesIn)
+	assert.EqualValues(t, 5, gotConn.BytesOut)
+	assert.Equal(t, stats.Connections, tunnel.Connections())
+
+	// when
+	conn.Close()
+
+	// then
+	assert.Eventually(t, func() bool { return len(tunnel.Connections()) == 0 }, time.Second, 10*time.Millisecond)
+	stats = tunnel.Stats()
+	assert.EqualValues(t, 4, stats.BytesIn)
+	assert.EqualValues(t, 5, stats.BytesOut)
+	assert.EqualValues(t, 0, stats.ActiveConnections)
+}
+
+func TestTunnelMarshalJSON(t *testing.T) {
+	tunnel := NewTunnel(testLog, nil, "1", &chshare.Remote{LocalHost: "0.0.0.0", LocalPort: "2222", RemoteHost: "0.0.0.0", RemotePort: "22"}, nil)
+
+	b, err := json.Marshal(tunnel)
+
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"lhost":"0.0.0.0",
+		"lport":"2222",
+		"rhost":"0.0.0.0",
+		"rport":"22",
+		"lport_random":false,
+		"scheme":null,
+		"acl":null,
+		"idle_timeout_minutes":0,
+		"acl_session_user":"",
+		"protocol":"tcp",
+		"reverse":false,
+		"http_proxy":false,
+		"socks":false,
+		"max_bytes_per_sec":0,
+		"max_connections":0,
+		"expires_at":null,
+		"persistent":false,
+		"created_by":"",
+		"id":"1",
+		"bytes_in":0,
+		"bytes_out":0,
+		"active_connections":0,
+		"last_activity":null,
+		"connections":[]
+	}`, string(b))
+
+	// stats are not restored with a saved tunnel
+	var got Tunnel
+	require.NoError(t, json.Unmarshal(b, &got))
+	assert.Equal(t, tunnel.Remote, got.Remote)
+	assert.Equal(t, tunnel.ID, got.ID)
+}
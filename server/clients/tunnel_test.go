@@ -0,0 +1,46 @@
+package clients
+
+import (
+	"context"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	chshare "github.com/cloudradar-monitoring/rport/share"
+	"github.com/cloudradar-monitoring/rport/share/test"
+)
+
+func TestTunnelMaxConnections(t *testing.T) {
+	// given
+	clientCh, tunnelCh := test.NewChannelPair()
+	connMock := test.NewConnMock()
+	connMock.ReturnChannel = tunnelCh
+	remote := &chshare.Remote{LocalHost: "127.0.0.1", LocalPort: freeTCPPort(t), RemoteHost: "192.168.1.1", RemotePort: "22", Protocol: chshare.ProtocolTCP, MaxConnections: 1}
+	tunnel := NewTunnel(testLog, connMock, "1", remote, nil)
+	_, err := tunnel.Start(context.Background())
+	require.NoError(t, err)
+	defer tunnel.Terminate(true)
+
+	first, err := net.Dial("tcp", tunnel.Local())
+	require.NoError(t, err)
+	defer first.Close()
+	_, err = first.Write([]byte("ping"))
+	require.NoError(t, err)
+	_, err = io.ReadFull(clientCh, make([]byte, 4))
+	require.NoError(t, err)
+
+	// when
+	second, err := net.Dial("tcp", tunnel.Local())
+	require.NoError(t, err)
+	defer second.Close()
+
+	// then
+	require.NoError(t, second.SetReadDeadline(time.Now().Add(time.Second)))
+	_, err = second.Read(make([]byte, 1))
+	assert.Equal(t, io.EOF, err)
+	assert.EqualValues(t, 1, tunnel.Stats().ActiveConnections)
+}
@@ -0,0 +1,203 @@
+package clients
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/jpillora/sizestr"
+	"golang.org/x/crypto/ssh"
+
+	chshare "github.com/cloudradar-monitoring/rport/share"
+	"github.com/cloudradar-monitoring/rport/share/comm"
+)
+
+// udpSessionIdleTimeout defines how long a session of a UDP tunnel is kept without datagrams in any direction.
+var udpSessionIdleTimeout = time.Minute
+
+// udpSession forwards datagrams of a single source address over a separate SSH channel.
+type udpSession struct {
+	*chshare.Logger
+	addr  net.Addr
+	ch    ssh.Channel
+	timer *time.Timer
+	conn  *TunnelConnection
+
+	sent, received int64
+}
+
+// udpSessions holds sessions of a UDP tunnel by source address.
+type udpSessions struct {
+	mu     sync.Mutex
+	m      map[string]*udpSession
+	closed bool
+}
+
+func (s *udpSessions) get(addr net.Addr) *udpSession {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.m[addr.String()]
+}
+
+// add returns false if sessions are already closed.
+func (s *udpSessions) add(session *udpSession) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return false
+	}
+	s.m[session.addr.String()] = session
+	return true
+}
+
+func (s *udpSessions) remove(session *udpSession) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.m[session.addr.String()] == session {
+		delete(s.m, session.addr.String())
+	}
+}
+
+func (s *udpSessions) closeAll() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+	for _, session := range s.m {
+		session.ch.Close()
+	}
+}
+
+func (t *Tunnel) listenUDP(ctx context.Context, pc net.PacketConn) {
+	defer t.wg.Done()
+
+	t.Infof("Listening UDP")
+
+	sessions := &udpSessions{m: make(map[string]*udpSession)}
+	// background goroutine to close the listener and all sessions when context is canceled
+	go func() {
+		<-ctx.Done()
+		if err := pc.Close(); err != nil {
+			t.Errorf("Failed to close listener: %v", err)
+		}
+		sessions.closeAll()
+		t.Debugf("Listener closed")
+	}()
+
+	buf := make([]byte, chshare.MaxDatagramSize)
+	for {
+		n, addr, err := pc.ReadFrom(buf)
+		if err != nil {
+			// If Done channel was closed then listener was closed by the background goroutine.
+			select {
+			case <-ctx.Done():
+				//listener closed
+			default:
+				t.Errorf("Failed to read datagram: %v", err)
+			}
+			return
+		}
+
+		if t.acl != nil {
+			udpAddr, ok := addr.(*net.UDPAddr)
+			if !ok {
+				t.Errorf("Unsupported remote address type. Expected net.UDPAddr. %v", addr)
+				continue
+			}
+			if !t.acl.CheckAccess(udpAddr.IP) {
+				t.Debugf("Access rejected. Remote addr: %s", udpAddr)
+				continue
+			}
+		}
+
+		session := sessions.get(addr)
+		if session == nil {
+			session, err = t.openUDPSession(addr)
+			if err != nil {
+				t.Errorf("Failed to open UDP session for %s: %v", addr, err)
+				continue
+			}
+			if !sessions.add(session) {
+				// the tunnel is being stopped, the session goroutine exits right away
+				session.ch.Close()
+			}
+			t.wg.Add(1)
+			go func() {
+				t.handleUDPSession(pc, session)
+				sessions.remove(session)
+				t.wg.Done()
+				if t.connCloseChan != nil {
+					select {
+					case t.connCloseChan <- true:
+					case <-ctx.Done():
+					}
+				}
+			}()
+		}
+
+		session.timer.Reset(udpSessionIdleTimeout)
+		t.limit.Wait(n)
+		if err := chshare.WriteDatagram(session.ch, buf[:n]); err != nil {
+			session.Debugf("Failed to send datagram: %v", err)
+			session.ch.Close()
+			continue
+		}
+		atomic.AddInt64(&session.sent, int64(n))
+		t.stats.add(session.conn, n, 0)
+	}
+}
+
+func (t *Tunnel) openUDPSession(addr net.Addr) (*udpSession, error) {
+	if t.sshConn == nil {
+		return nil, errors.New("no remote connection")
+	}
+	if !t.openConn() {
+		return nil, fmt.Errorf("max connections (%d) reached", t.MaxConnections)
+	}
+	cid := t.nextConnID()
+	l := t.Fork("udp#%d", cid)
+
+	ch, reqs, err := t.sshConn.OpenChannel(comm.ChannelTypeUDP, []byte(t.Remote.Remote()))
+	if err != nil {
+		atomic.AddInt32(&t.connCount, -1)
+		return nil, err
+	}
+	go ssh.DiscardRequests(reqs)
+
+	l.Debugf("Open for %s", addr)
+	return &udpSession{
+		Logger: l,
+		addr:   addr,
+		ch:     ch,
+		timer:  time.AfterFunc(udpSessionIdleTimeout, func() { ch.Close() }),
+		conn:   t.stats.open(cid, addr.String()),
+	}, nil
+}
+
+// handleUDPSession sends back datagrams received from the client until the session channel is closed.
+func (t *Tunnel) handleUDPSession(pc net.PacketConn, s *udpSession) {
+	defer atomic.AddInt32(&t.connCount, -1)
+	defer t.stats.close(s.conn)
+	defer s.timer.Stop()
+	defer s.ch.Close()
+
+	buf := make([]byte, chshare.MaxDatagramSize)
+	for {
+		b, err := chshare.ReadDatagram(s.ch, buf)
+		if err != nil {
+			break
+		}
+		s.timer.Reset(udpSessionIdleTimeout)
+		t.limit.Wait(len(b))
+		if _, err := pc.WriteTo(b, s.addr); err != nil {
+			s.Debugf("Failed to write datagram: %v", err)
+			break
+		}
+		s.received += int64(len(b))
+		t.stats.add(s.conn, 0, len(b))
+	}
+	s.Debugf("Close (sent %s received %s)", sizestr.ToString(atomic.LoadInt64(&s.sent)), sizestr.ToString(s.received))
+}
@@ -0,0 +1,110 @@
+package clients
+
+import (
+	"context"
+	"net"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	chshare "github.com/cloudradar-monitoring/rport/share"
+	"github.com/cloudradar-monitoring/rport/share/comm"
+	"github.com/cloudradar-monitoring/rport/share/test"
+)
+
+func freeUDPPort(t *testing.T) string {
+	pc, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer pc.Close()
+	return strconv.Itoa(pc.LocalAddr().(*net.UDPAddr).Port)
+}
+
+func TestUDPTunnel(t *testing.T) {
+	// given
+	defaultTimeout := udpSessionIdleTimeout
+	udpSessionIdleTimeout = 200 * time.Millisecond
+	defer func() { udpSessionIdleTimeout = defaultTimeout }()
+
+	tunnelCh, clientCh := test.NewChannelPair()
+	connMock := test.NewConnMock()
+	connMock.ReturnChannel = tunnelCh
+	remote := &chshare.Remote{
+		LocalHost:  "127.0.0.1",
+		LocalPort:  freeUDPPort(t),
+		RemoteHost: "8.8.8.8",
+		RemotePort: "53",
+		Protocol:   chshare.ProtocolUDP,
+	}
+	tunnel := NewTunnel(testLog, connMock, "1", remote, nil)
+	_, err := tunnel.Start(context.Background())
+	require.NoError(t, err)
+	defer tunnel.Terminate(true)
+
+	conn, err := net.Dial("udp4", "127.0.0.1:"+remote.LocalPort)
+	require.NoError(t, err)
+	defer conn.Close()
+	buf := make([]byte, chshare.MaxDatagramSize)
+
+	// when
+	_, err = conn.Write([]byte("query"))
+	require.NoError(t, err)
+
+	// then
+	got, err := chshare.ReadDatagram(clientCh, buf)
+	require.NoError(t, err)
+	assert.Equal(t, "query", string(got))
+	gotType, gotData := connMock.InputOpenChannel()
+	assert.Equal(t, comm.ChannelTypeUDP, gotType)
+	assert.Equal(t, "8.8.8.8:53", string(gotData))
+
+	// when
+	require.NoError(t, chshare.WriteDatagram(clientCh, []byte("answer")))
+
+	// then
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(time.Second)))
+	n, err := conn.Read(buf)
+	require.NoError(t, err)
+	assert.Equal(t, "answer", string(buf[:n]))
+
+	// when idle timeout expires the session channel is closed
+	_, err = chshare.ReadDatagram(clientCh, buf)
+
+	// then
+	assert.Error(t, err)
+	assert.Eventually(t, func() bool { return atomic.LoadInt32(&tunnel.connCount) == 0 }, time.Second, 10*time.Millisecond)
+}
+
+func TestUDPTunnelACL(t *testing.T) {
+	// given
+	connMock := test.NewConnMock()
+	remote := &chshare.Remote{
+		LocalHost:  "127.0.0.1",
+		LocalPort:  freeUDPPort(t),
+		RemoteHost: "8.8.8.8",
+		RemotePort: "53",
+		Protocol:   chshare.ProtocolUDP,
+	}
+	acl, err := ParseTunnelACL("192.168.0.1")
+	require.NoError(t, err)
+	tunnel := NewTunnel(testLog, connMock, "1", remote, acl)
+	_, err = tunnel.Start(context.Background())
+	require.NoError(t, err)
+	defer tunnel.Terminate(true)
+
+	conn, err := net.Dial("udp4", "127.0.0.1:"+remote.LocalPort)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	// when
+	_, err = conn.Write([]byte("query"))
+	require.NoError(t, err)
+
+	// then
+	time.Sleep(100 * time.Millisecond)
+	gotType, _ := connMock.InputOpenChannel()
+	assert.Empty(t, gotType)
+}
@@ -6,6 +6,8 @@ import "sort"
 type ClientAuth struct {
 	ID       string `json:"id" db:"id"`
 	Password string `json:"password" db:"password"`
+	// Tenant is set only when multi-tenancy is enabled.
+	Tenant string `json:"-" db:"tenant"`
 }
 
 func SortByID(a []*ClientAuth, desc bool) {
@@ -11,33 +11,48 @@ import (
 const mysqlDuplicateEntryErrorCode = 1062
 
 type DatabaseProvider struct {
-	db        *sqlx.DB
-	tableName string
+	db           *sqlx.DB
+	tableName    string
+	multiTenancy bool
 }
 
 var _ Provider = &DatabaseProvider{}
 
-func NewDatabaseProvider(DB *sqlx.DB, tableName string) *DatabaseProvider {
+// NewDatabaseProvider returns a provider backed by a given database table.
+// If multiTenancy is true, the table is expected to have a 'tenant' column.
+func NewDatabaseProvider(DB *sqlx.DB, tableName string, multiTenancy bool) *DatabaseProvider {
 	return &DatabaseProvider{
-		db:        DB,
-		tableName: tableName,
+		db:           DB,
+		tableName:    tableName,
+		multiTenancy: multiTenancy,
 	}
 }
 
+func (c *DatabaseProvider) columns() string {
+	if c.multiTenancy {
+		return "id, password, tenant"
+	}
+	return "id, password"
+}
+
 func (c *DatabaseProvider) GetAll() ([]*ClientAuth, error) {
 	var result []*ClientAuth
-	err := c.db.Select(&result, fmt.Sprintf("SELECT id, password FROM %s", c.tableName))
+	err := c.db.Select(&result, fmt.Sprintf("SELECT %s FROM %s", c.columns(), c.tableName))
 	return result, err
 }
 
 func (c *DatabaseProvider) Get(id string) (*ClientAuth, error) {
 	result := &ClientAuth{}
-	err := c.db.Get(result, fmt.Sprintf("SELECT id, password FROM %s WHERE id = ?", c.tableName), id)
+	err := c.db.Get(result, fmt.Sprintf("SELECT %s FROM %s WHERE id = ?", c.columns(), c.tableName), id)
 	return result, err
 }
 
 func (c *DatabaseProvider) Add(client *ClientAuth) (bool, error) {
-	_, err := c.db.NamedExec(fmt.Sprintf("INSERT INTO %s (id, password) VALUES (:id, :password)", c.tableName), client)
+	query := fmt.Sprintf("INSERT INTO %s (id, password) VALUES (:id, :password)", c.tableName)
+	if c.multiTenancy {
+		query = fmt.Sprintf("INSERT INTO %s (id, password, tenant) VALUES (:id, :password, :tenant)", c.tableName)
+	}
+	_, err := c.db.NamedExec(query, client)
 	if err != nil {
 		// Check for client already exists error
 		switch typeErr := err.(type) {
@@ -16,7 +16,7 @@ func TestDatabaseProvider(t *testing.T) {
 	require.NoError(t, err)
 	c := &ClientAuth{ID: "test-client", Password: "test-password"}
 
-	p := NewDatabaseProvider(db, "clients")
+	p := NewDatabaseProvider(db, "clients", false)
 	assert.Equal(t, ProviderSourceDB, p.Source())
 
 	// initial empty
@@ -52,3 +52,29 @@ func TestDatabaseProvider(t *testing.T) {
 	require.NoError(t, err)
 	assert.ElementsMatch(t, []*ClientAuth{}, clients)
 }
+
+func TestDatabaseProviderWithTenant(t *testing.T) {
+	db, err := sqlx.Connect("sqlite3", ":memory:")
+	require.NoError(t, err)
+	defer db.Close()
+	_, err = db.Exec("CREATE TABLE clients (id TEXT PRIMARY KEY, password TEXT, tenant TEXT)")
+	require.NoError(t, err)
+	c1 := &ClientAuth{ID: "test-client-1", Password: "test-password", Tenant: "tenant1"}
+	c2 := &ClientAuth{ID: "test-client-2", Password: "test-password", Tenant: "tenant2"}
+
+	p := NewDatabaseProvider(db, "clients", true)
+
+	for _, c := range []*ClientAuth{c1, c2} {
+		added, err := p.Add(c)
+		require.NoError(t, err)
+		assert.True(t, added)
+	}
+
+	clients, err := p.GetAll()
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []*ClientAuth{c1, c2}, clients)
+
+	client, err := p.Get(c2.ID)
+	require.NoError(t, err)
+	assert.Equal(t, c2, client)
+}
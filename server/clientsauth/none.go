@@ -0,0 +1,38 @@
+package clientsauth
+
+import (
+	"errors"
+)
+
+// NoneProvider has no client auth credentials. It's used when clients authenticate only with certificates.
+type NoneProvider struct{}
+
+var _ Provider = &NoneProvider{}
+
+func NewNoneProvider() *NoneProvider {
+	return &NoneProvider{}
+}
+
+func (c *NoneProvider) GetAll() ([]*ClientAuth, error) {
+	return []*ClientAuth{}, nil
+}
+
+func (c *NoneProvider) Get(string) (*ClientAuth, error) {
+	return nil, nil
+}
+
+func (c *NoneProvider) Add(*ClientAuth) (bool, error) {
+	return false, errors.New("password authentication of clients is disabled")
+}
+
+func (c *NoneProvider) Delete(string) error {
+	return errors.New("password authentication of clients is disabled")
+}
+
+func (c *NoneProvider) IsWriteable() bool {
+	return false
+}
+
+func (c *NoneProvider) Source() ProviderSource {
+	return ProviderSourceNone
+}
@@ -7,6 +7,7 @@ const (
 	ProviderSourceFile   ProviderSource = "File"
 	ProviderSourceDB     ProviderSource = "DB"
 	ProviderSourceMock   ProviderSource = "Mock"
+	ProviderSourceNone   ProviderSource = "None"
 )
 
 type Provider interface {
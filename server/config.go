@@ -32,6 +32,29 @@ type APIConfig struct {
 	UserLoginWait  float32 `mapstructure:"user_login_wait"`
 	MaxFailedLogin int     `mapstructure:"max_failed_login"`
 	BanTime        int     `mapstructure:"ban_time"`
+	TunnelProxy    bool    `mapstructure:"tunnel_proxy"`
+	// TunnelProxyDomain is a domain which subdomains serve tunnels of the HTTP proxy.
+	TunnelProxyDomain string `mapstructure:"tunnel_proxy_domain"`
+
+	// Permissions maps a permission to user groups which are granted it.
+	Permissions map[string][]string `mapstructure:"permissions"`
+	// ClientAccess restricts members of user groups to clients of given client groups.
+	ClientAccess []ClientAccessRule `mapstructure:"client_access"`
+}
+
+// ClientAccessRule grants members of a user group access to clients of given client groups only.
+type ClientAccessRule struct {
+	UserGroup    string   `mapstructure:"user_group"`
+	ClientGroups []string `mapstructure:"client_groups"`
+}
+
+// PortPoolConfig reserves server ports for random ports of tunnels of clients that belong to given client groups or
+// tunnels created by members of given user groups.
+type PortPoolConfig struct {
+	Name         string   `mapstructure:"name"`
+	Ports        []string `mapstructure:"ports"`
+	ClientGroups []string `mapstructure:"client_groups"`
+	UserGroups   []string `mapstructure:"user_groups"`
 }
 
 const (
@@ -42,17 +65,22 @@ const (
 )
 
 type LogConfig struct {
-	LogOutput chshare.LogOutput `mapstructure:"log_file"`
-	LogLevel  chshare.LogLevel  `mapstructure:"log_level"`
+	LogOutput     chshare.LogOutput `mapstructure:"log_file"`
+	LogLevel      chshare.LogLevel  `mapstructure:"log_level"`
+	AuditLogFile  string            `mapstructure:"audit_log_file"`
+	AuditLogTable string            `mapstructure:"audit_log_table"`
 }
 
 type ServerConfig struct {
 	ListenAddress              string        `mapstructure:"address"`
 	URL                        string        `mapstructure:"url"`
 	KeySeed                    string        `mapstructure:"key_seed"`
+	KeyFiles                   []string      `mapstructure:"key_files"`
 	Auth                       string        `mapstructure:"auth"`
 	AuthFile                   string        `mapstructure:"auth_file"`
 	AuthTable                  string        `mapstructure:"auth_table"`
+	ClientCAKeyFile            string        `mapstructure:"client_ca_key_file"`
+	ClientCertValidity         time.Duration `mapstructure:"client_cert_validity"`
 	Proxy                      string        `mapstructure:"proxy"`
 	ExcludedPortsRaw           []string      `mapstructure:"excluded_ports"`
 	DataDir                    string        `mapstructure:"data_dir"`
@@ -69,10 +97,16 @@ type ServerConfig struct {
 	ClientLoginWait            float32       `mapstructure:"client_login_wait"`
 	MaxFailedLogin             int           `mapstructure:"max_failed_login"`
 	BanTime                    int           `mapstructure:"ban_time"`
-
-	excludedPorts mapset.Set
-	authID        string
-	authPassword  string
+	MultiTenancy               bool          `mapstructure:"multi_tenancy"`
+	ReverseTunnelDestinations  []string      `mapstructure:"reverse_tunnel_destinations"`
+	// PortPools are checked in the given order, the first pool that matches a tunnel is used.
+	PortPools []PortPoolConfig `mapstructure:"port_pools"`
+
+	excludedPorts             mapset.Set
+	portPools                 []*ports.PortPool
+	reverseTunnelDestinations *chshare.TunnelDestinations
+	authID                    string
+	authPassword              string
 }
 
 type DatabaseConfig struct {
@@ -106,6 +140,14 @@ func (c *Config) ExcludedPorts() mapset.Set {
 	return c.Server.excludedPorts
 }
 
+func (c *Config) ReverseTunnelDestinations() *chshare.TunnelDestinations {
+	return c.Server.reverseTunnelDestinations
+}
+
+func (c *Config) PortPools() []*ports.PortPool {
+	return c.Server.portPools
+}
+
 func (c *Config) ParseAndValidate() error {
 	if c.Server.URL == "" {
 		c.Server.URL = "http://" + c.Server.ListenAddress
@@ -124,6 +166,15 @@ func (c *Config) ParseAndValidate() error {
 	}
 	c.Server.excludedPorts = excludedPorts
 
+	c.Server.reverseTunnelDestinations, err = chshare.ParseTunnelDestinations(c.Server.ReverseTunnelDestinations)
+	if err != nil {
+		return fmt.Errorf("can't parse reverse tunnel destinations: %s", err)
+	}
+
+	if err := c.parseAndValidatePortPools(); err != nil {
+		return err
+	}
+
 	if c.Server.DataDir == "" {
 		return errors.New("'data directory path' cannot be empty")
 	}
@@ -133,6 +184,10 @@ func (c *Config) ParseAndValidate() error {
 		return fmt.Errorf("expected 'Keep Lost Clients' can be in range [%v, %v], actual: %v", MinKeepLostClients, MaxKeepLostClients, c.Server.KeepLostClients)
 	}
 
+	if c.Server.KeySeed != "" && len(c.Server.KeyFiles) > 0 {
+		return errors.New("'key_seed' and 'key_files' are both set: expected only one of them")
+	}
+
 	if err := c.parseAndValidateClientAuth(); err != nil {
 		return err
 	}
@@ -141,6 +196,14 @@ func (c *Config) ParseAndValidate() error {
 		return fmt.Errorf("API: %v", err)
 	}
 
+	if c.Server.MultiTenancy && (c.Server.AuthTable == "" || c.API.AuthUserTable == "" || c.API.AuthGroupTable == "") {
+		return errors.New("'multi_tenancy' requires 'auth_table', 'auth_user_table' and 'auth_group_table' to be set")
+	}
+
+	if err := c.parseAndValidateAuditLog(); err != nil {
+		return err
+	}
+
 	if err := c.Database.ParseAndValidate(); err != nil {
 		return err
 	}
@@ -149,8 +212,11 @@ func (c *Config) ParseAndValidate() error {
 }
 
 func (c *Config) parseAndValidateClientAuth() error {
-	if c.Server.Auth == "" && c.Server.AuthFile == "" && c.Server.AuthTable == "" {
-		return errors.New("client authentication must be enabled: set either 'auth', 'auth_file' or 'auth_table'")
+	if c.Server.Auth == "" && c.Server.AuthFile == "" && c.Server.AuthTable == "" && c.Server.ClientCAKeyFile == "" {
+		return errors.New("client authentication must be enabled: set either 'auth', 'auth_file', 'auth_table' or 'client_ca_key_file'")
+	}
+	if c.Server.ClientCAKeyFile != "" && c.Server.ClientCertValidity <= 0 {
+		return errors.New("'client_cert_validity' must be positive when 'client_ca_key_file' is set")
 	}
 
 	if c.Server.AuthFile != "" && c.Server.Auth != "" {
@@ -177,6 +243,50 @@ func (c *Config) parseAndValidateClientAuth() error {
 	return nil
 }
 
+func (c *Config) parseAndValidatePortPools() error {
+	c.Server.portPools = nil
+	reserved := mapset.NewThreadUnsafeSet()
+	for _, pool := range c.Server.PortPools {
+		if pool.Name == "" {
+			return errors.New("'port_pools': 'name' cannot be empty")
+		}
+		for _, cur := range c.Server.portPools {
+			if cur.Name == pool.Name {
+				return fmt.Errorf("'port_pools': duplicate pool name %q", pool.Name)
+			}
+		}
+		if len(pool.Ports) == 0 {
+			return fmt.Errorf("'port_pools': 'ports' of pool %q cannot be empty", pool.Name)
+		}
+		if len(pool.ClientGroups) == 0 && len(pool.UserGroups) == 0 {
+			return fmt.Errorf("'port_pools': pool %q should have either 'client_groups' or 'user_groups'", pool.Name)
+		}
+		if len(pool.UserGroups) > 0 && c.API.Auth != "" {
+			return errors.New("'port_pools': 'user_groups' can't be used with API 'auth': users have no groups, use 'auth_file' or 'auth_user_table' instead")
+		}
+		poolPorts, err := ports.TryParsePortRanges(pool.Ports)
+		if err != nil {
+			return fmt.Errorf("'port_pools': can't parse ports of pool %q: %s", pool.Name, err)
+		}
+		if overlap := reserved.Intersect(poolPorts); overlap.Cardinality() > 0 {
+			return fmt.Errorf("'port_pools': ports of pool %q overlap with other pools", pool.Name)
+		}
+		reserved = reserved.Union(poolPorts)
+		c.Server.portPools = append(c.Server.portPools, &ports.PortPool{Name: pool.Name, Ports: poolPorts})
+	}
+	return nil
+}
+
+func (c *Config) parseAndValidateAuditLog() error {
+	if c.Logging.AuditLogFile != "" && c.Logging.AuditLogTable != "" {
+		return errors.New("'audit_log_file' and 'audit_log_table' are both set: expected only one of them")
+	}
+	if c.Logging.AuditLogTable != "" && c.Database.Type == "" {
+		return errors.New("'db_type' must be set when 'audit_log_table' is set")
+	}
+	return nil
+}
+
 func (c *Config) parseAndValidateAPI() error {
 	if c.API.Address != "" {
 		// API enabled
@@ -188,6 +298,14 @@ func (c *Config) parseAndValidateAPI() error {
 		if err != nil {
 			return err
 		}
+		err = c.parseAndValidateAPIPermissions()
+		if err != nil {
+			return err
+		}
+		err = c.parseAndValidateAPIClientAccess()
+		if err != nil {
+			return err
+		}
 		if c.API.JWTSecret == "" {
 			c.API.JWTSecret, err = generateJWTSecret()
 			if err != nil {
@@ -232,8 +350,59 @@ func (c *Config) parseAndValidateAPIAuth() error {
 	return nil
 }
 
+func (c *Config) parseAndValidateAPIPermissions() error {
+	if len(c.API.Permissions) == 0 {
+		return nil
+	}
+
+	if c.API.Auth != "" {
+		return errors.New("'permissions' can't be used with 'auth': users have no groups, use 'auth_file' or 'auth_user_table' instead")
+	}
+
+	for permission := range c.API.Permissions {
+		if !isKnownPermission(permission) {
+			return fmt.Errorf("unknown permission %q, expected one of %s", permission, strings.Join(AllPermissions, ", "))
+		}
+	}
+
+	return nil
+}
+
+func (c *Config) parseAndValidateAPIClientAccess() error {
+	if len(c.API.ClientAccess) == 0 {
+		return nil
+	}
+
+	if c.API.Auth != "" {
+		return errors.New("'client_access' can't be used with 'auth': users have no groups, use 'auth_file' or 'auth_user_table' instead")
+	}
+
+	for _, rule := range c.API.ClientAccess {
+		if rule.UserGroup == "" {
+			return errors.New("'client_access': 'user_group' cannot be empty")
+		}
+		if len(rule.ClientGroups) == 0 {
+			return fmt.Errorf("'client_access': 'client_groups' of user group %q cannot be empty", rule.UserGroup)
+		}
+	}
+
+	return nil
+}
+
+func isKnownPermission(permission string) bool {
+	for _, cur := range AllPermissions {
+		if cur == permission {
+			return true
+		}
+	}
+	return false
+}
+
 func (c *Config) parseAndValidateAPIHTTPSOptions() error {
 	if c.API.CertFile == "" && c.API.KeyFile == "" {
+		if c.API.TunnelProxy {
+			return errors.New("'tunnel_proxy' requires 'cert_file' and 'key_file' to be set")
+		}
 		return nil
 	}
 	if c.API.CertFile != "" && c.API.KeyFile == "" {
@@ -246,6 +415,12 @@ func (c *Config) parseAndValidateAPIHTTPSOptions() error {
 	if err != nil {
 		return fmt.Errorf("invalid 'cert_file', 'key_file': %v", err)
 	}
+	if c.API.TunnelProxy {
+		c.API.TunnelProxyDomain = strings.TrimPrefix(strings.ToLower(c.API.TunnelProxyDomain), ".")
+		if c.API.TunnelProxyDomain == "" {
+			return errors.New("'tunnel_proxy' requires 'tunnel_proxy_domain' to be set")
+		}
+	}
 	return nil
 }
 
@@ -3,6 +3,7 @@ package chserver
 import (
 	"errors"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -100,7 +101,23 @@ func TestParseAndValidateClientAuth(t *testing.T) {
 		{
 			Name:          "no auth",
 			Config:        Config{},
-			ExpectedError: errors.New("client authentication must be enabled: set either 'auth', 'auth_file' or 'auth_table'"),
+			ExpectedError: errors.New("client authentication must be enabled: set either 'auth', 'auth_file', 'auth_table' or 'client_ca_key_file'"),
+		}, {
+			Name: "client CA only",
+			Config: Config{
+				Server: ServerConfig{
+					ClientCAKeyFile:    "client-ca.key",
+					ClientCertValidity: time.Hour,
+				},
+			},
+		}, {
+			Name: "client CA without validity",
+			Config: Config{
+				Server: ServerConfig{
+					ClientCAKeyFile: "client-ca.key",
+				},
+			},
+			ExpectedError: errors.New("'client_cert_validity' must be positive when 'client_ca_key_file' is set"),
 		}, {
 			Name: "auth and auth_file",
 			Config: Config{
@@ -325,6 +342,107 @@ func TestParseAndValidateAPI(t *testing.T) {
 			},
 			ExpectedError: errors.New("API: when 'key_file' is set, 'cert_file' must be set as well"),
 		},
+		{
+			Name: "api enabled, tunnel proxy without https",
+			Config: Config{
+				API: APIConfig{
+					Address:     "0.0.0.0:3000",
+					Auth:        "abc:def",
+					TunnelProxy: true,
+				},
+			},
+			ExpectedError: errors.New("API: 'tunnel_proxy' requires 'cert_file' and 'key_file' to be set"),
+		},
+		{
+			Name: "api enabled, valid permissions",
+			Config: Config{
+				API: APIConfig{
+					Address:  "0.0.0.0:3000",
+					AuthFile: "test.json",
+					Permissions: map[string][]string{
+						PermissionTunnels:  {"Admins", "Operators"},
+						PermissionCommands: {"Admins"},
+					},
+				},
+			},
+		},
+		{
+			Name: "api enabled, unknown permission",
+			Config: Config{
+				API: APIConfig{
+					Address:  "0.0.0.0:3000",
+					AuthFile: "test.json",
+					Permissions: map[string][]string{
+						"unknown": {"Admins"},
+					},
+				},
+			},
+			ExpectedError: errors.New(`API: unknown permission "unknown", expected one of tunnels, commands, clients_auth, client_groups, audit_log, files`),
+		},
+		{
+			Name: "api enabled, permissions with auth",
+			Config: Config{
+				API: APIConfig{
+					Address: "0.0.0.0:3000",
+					Auth:    "abc:def",
+					Permissions: map[string][]string{
+						PermissionTunnels: {"Admins"},
+					},
+				},
+			},
+			ExpectedError: errors.New("API: 'permissions' can't be used with 'auth': users have no groups, use 'auth_file' or 'auth_user_table' instead"),
+		},
+		{
+			Name: "api enabled, valid client access",
+			Config: Config{
+				API: APIConfig{
+					Address:  "0.0.0.0:3000",
+					AuthFile: "test.json",
+					ClientAccess: []ClientAccessRule{
+						{UserGroup: "Support", ClientGroups: []string{"group-1", "group-2"}},
+					},
+				},
+			},
+		},
+		{
+			Name: "api enabled, client access without user group",
+			Config: Config{
+				API: APIConfig{
+					Address:  "0.0.0.0:3000",
+					AuthFile: "test.json",
+					ClientAccess: []ClientAccessRule{
+						{ClientGroups: []string{"group-1"}},
+					},
+				},
+			},
+			ExpectedError: errors.New("API: 'client_access': 'user_group' cannot be empty"),
+		},
+		{
+			Name: "api enabled, client access without client groups",
+			Config: Config{
+				API: APIConfig{
+					Address:  "0.0.0.0:3000",
+					AuthFile: "test.json",
+					ClientAccess: []ClientAccessRule{
+						{UserGroup: "Support"},
+					},
+				},
+			},
+			ExpectedError: errors.New(`API: 'client_access': 'client_groups' of user group "Support" cannot be empty`),
+		},
+		{
+			Name: "api enabled, client access with auth",
+			Config: Config{
+				API: APIConfig{
+					Address: "0.0.0.0:3000",
+					Auth:    "abc:def",
+					ClientAccess: []ClientAccessRule{
+						{UserGroup: "Support", ClientGroups: []string{"group-1"}},
+					},
+				},
+			},
+			ExpectedError: errors.New("API: 'client_access' can't be used with 'auth': users have no groups, use 'auth_file' or 'auth_user_table' instead"),
+		},
 	}
 
 	for _, tc := range testCases {
@@ -338,3 +456,228 @@ func TestParseAndValidateAPI(t *testing.T) {
 		})
 	}
 }
+
+func TestParseAndValidateMultiTenancy(t *testing.T) {
+	testCases := []struct {
+		Name          string
+		Config        Config
+		ExpectedError error
+	}{
+		{
+			Name: "multi tenancy with database auth",
+			Config: Config{
+				Server: ServerConfig{
+					URL:          "http://localhost/",
+					DataDir:      "./",
+					AuthTable:    "clients_auth",
+					MultiTenancy: true,
+				},
+				API: APIConfig{
+					Address:        "0.0.0.0:3000",
+					AuthUserTable:  "users",
+					AuthGroupTable: "groups",
+				},
+				Database: DatabaseConfig{
+					Type: "sqlite",
+					Name: "/var/lib/rport/rport.db",
+				},
+			},
+		},
+		{
+			Name: "multi tenancy without auth_table",
+			Config: Config{
+				Server: ServerConfig{
+					URL:          "http://localhost/",
+					DataDir:      "./",
+					Auth:         "abc:def",
+					MultiTenancy: true,
+				},
+				API: APIConfig{
+					Address:        "0.0.0.0:3000",
+					AuthUserTable:  "users",
+					AuthGroupTable: "groups",
+				},
+				Database: DatabaseConfig{
+					Type: "sqlite",
+					Name: "/var/lib/rport/rport.db",
+				},
+			},
+			ExpectedError: errors.New("'multi_tenancy' requires 'auth_table', 'auth_user_table' and 'auth_group_table' to be set"),
+		},
+		{
+			Name: "multi tenancy without auth_user_table",
+			Config: Config{
+				Server: ServerConfig{
+					URL:          "http://localhost/",
+					DataDir:      "./",
+					AuthTable:    "clients_auth",
+					MultiTenancy: true,
+				},
+				API: APIConfig{
+					Address:  "0.0.0.0:3000",
+					AuthFile: "test.json",
+				},
+				Database: DatabaseConfig{
+					Type: "sqlite",
+					Name: "/var/lib/rport/rport.db",
+				},
+			},
+			ExpectedError: errors.New("'multi_tenancy' requires 'auth_table', 'auth_user_table' and 'auth_group_table' to be set"),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			err := tc.Config.ParseAndValidate()
+			assert.Equal(t, tc.ExpectedError, err)
+		})
+	}
+}
+
+func TestParseAndValidateAuditLog(t *testing.T) {
+	testCases := []struct {
+		Name          string
+		Logging       LogConfig
+		Database      DatabaseConfig
+		ExpectedError error
+	}{
+		{
+			Name: "audit log disabled",
+		},
+		{
+			Name:    "audit log file",
+			Logging: LogConfig{AuditLogFile: "/var/log/rport/rportd-audit.log"},
+		},
+		{
+			Name:     "audit log table",
+			Logging:  LogConfig{AuditLogTable: "audit_log"},
+			Database: DatabaseConfig{Type: "sqlite", Name: "/var/lib/rport/rport.db"},
+		},
+		{
+			Name:          "audit log table without database",
+			Logging:       LogConfig{AuditLogTable: "audit_log"},
+			ExpectedError: errors.New("'db_type' must be set when 'audit_log_table' is set"),
+		},
+		{
+			Name:          "both audit log file and table",
+			Logging:       LogConfig{AuditLogFile: "/var/log/rport/rportd-audit.log", AuditLogTable: "audit_log"},
+			Database:      DatabaseConfig{Type: "sqlite", Name: "/var/lib/rport/rport.db"},
+			ExpectedError: errors.New("'audit_log_file' and 'audit_log_table' are both set: expected only one of them"),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			config := Config{
+				Server: ServerConfig{
+					URL:     "http://localhost/",
+					DataDir: "./",
+					Auth:    "abc:def",
+				},
+				Logging:  tc.Logging,
+				Database: tc.Database,
+			}
+			err := config.ParseAndValidate()
+			assert.Equal(t, tc.ExpectedError, err)
+		})
+	}
+}
+
+func TestParseAndValidatePortPools(t *testing.T) {
+	testCases := []struct {
+		Name          string
+		PortPools     []PortPoolConfig
+		APIAuth       string
+		ExpectedError error
+	}{
+		{
+			Name: "no port pools",
+		},
+		{
+			Name: "valid port pools",
+			PortPools: []PortPoolConfig{
+				{Name: "support", Ports: []string{"20000-20999"}, UserGroups: []string{"Support"}},
+				{Name: "monitoring", Ports: []string{"30000-30999", "31000"}, ClientGroups: []string{"monitoring"}},
+			},
+		},
+		{
+			Name:          "empty name",
+			PortPools:     []PortPoolConfig{{Ports: []string{"20000-20999"}, UserGroups: []string{"Support"}}},
+			ExpectedError: errors.New("'port_pools': 'name' cannot be empty"),
+		},
+		{
+			Name: "duplicate name",
+			PortPools: []PortPoolConfig{
+				{Name: "support", Ports: []string{"20000-20999"}, UserGroups: []string{"Support"}},
+				{Name: "support", Ports: []string{"30000-30999"}, UserGroups: []string{"Support"}},
+			},
+			ExpectedError: errors.New(`'port_pools': duplicate pool name "support"`),
+		},
+		{
+			Name:          "no ports",
+			PortPools:     []PortPoolConfig{{Name: "support", UserGroups: []string{"Support"}}},
+			ExpectedError: errors.New(`'port_pools': 'ports' of pool "support" cannot be empty`),
+		},
+		{
+			Name:          "no groups",
+			PortPools:     []PortPoolConfig{{Name: "support", Ports: []string{"20000-20999"}}},
+			ExpectedError: errors.New(`'port_pools': pool "support" should have either 'client_groups' or 'user_groups'`),
+		},
+		{
+			Name:          "invalid ports",
+			PortPools:     []PortPoolConfig{{Name: "support", Ports: []string{"20999-20000"}, UserGroups: []string{"Support"}}},
+			ExpectedError: errors.New(`'port_pools': can't parse ports of pool "support": invalid port range 20999-20000`),
+		},
+		{
+			Name: "overlapping pools",
+			PortPools: []PortPoolConfig{
+				{Name: "support", Ports: []string{"20000-20999"}, UserGroups: []string{"Support"}},
+				{Name: "monitoring", Ports: []string{"20500-21499"}, ClientGroups: []string{"monitoring"}},
+			},
+			ExpectedError: errors.New(`'port_pools': ports of pool "monitoring" overlap with other pools`),
+		},
+		{
+			Name:          "user groups with api auth",
+			PortPools:     []PortPoolConfig{{Name: "support", Ports: []string{"20000-20999"}, UserGroups: []string{"Support"}}},
+			APIAuth:       "admin:foobaz",
+			ExpectedError: errors.New("'port_pools': 'user_groups' can't be used with API 'auth': users have no groups, use 'auth_file' or 'auth_user_table' instead"),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			config := Config{
+				Server: ServerConfig{
+					URL:       "http://localhost/",
+					DataDir:   "./",
+					Auth:      "abc:def",
+					PortPools: tc.PortPools,
+				},
+				API: APIConfig{
+					Auth: tc.APIAuth,
+				},
+			}
+			err := config.ParseAndValidate()
+			assert.Equal(t, tc.ExpectedError, err)
+			if err == nil {
+				assert.Len(t, config.PortPools(), len(tc.PortPools))
+			}
+		})
+	}
+}
+
+func TestParseAndValidateHostKeys(t *testing.T) {
+	config := Config{
+		Server: ServerConfig{
+			URL:      "http://localhost/",
+			DataDir:  "./",
+			Auth:     "abc:def",
+			KeySeed:  "seed",
+			KeyFiles: []string{"/var/lib/rport/host-key.pem"},
+		},
+	}
+
+	err := config.ParseAndValidate()
+
+	assert.EqualError(t, err, "'key_seed' and 'key_files' are both set: expected only one of them")
+}
@@ -3,60 +3,211 @@ package ports
 import (
 	"fmt"
 	"math"
+	"math/rand"
+	"net"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
 
 	mapset "github.com/deckarep/golang-set"
-	"github.com/shirou/gopsutil/net"
 )
 
+// PortPool is a named set of server ports that are reserved for random ports of some tunnels.
+type PortPool struct {
+	Name  string
+	Ports mapset.Set
+}
+
+// PortPoolUsage shows how many ports of a port pool are in use.
+type PortPoolUsage struct {
+	Name      string
+	Total     int
+	Available int
+}
+
+// PortDistributor gives out random ports to tunnels. Ports that are given out are tracked in memory until they are
+// released. Other candidates are verified by binding them, so ports used by other processes are skipped without
+// listing all sockets of the system. It's safe for concurrent use.
 type PortDistributor struct {
-	allowedPorts mapset.Set
-	portsPool    mapset.Set
+	mu sync.Mutex
+	// allowedPorts are sorted ports that are not excluded and not reserved by any pool
+	allowedPorts []int
+	pools        []*portPool
+	// poolOf contains names of the pools by reserved port
+	poolOf map[int]string
+	// inUse contains ports that are given out and not released yet
+	inUse map[int]bool
+	rand  *rand.Rand
+	// isFree verifies that a candidate port is not used by other processes
+	isFree func(port int) bool
+}
+
+type portPool struct {
+	name  string
+	ports []int
 }
 
-func NewPortDistributor(excludedPorts mapset.Set) *PortDistributor {
-	return &PortDistributor{
-		allowedPorts: setFromRange(1, math.MaxUint16).Difference(excludedPorts),
+// NewPortDistributor returns a distributor of random ports that are not excluded. Ports of given pools are given out
+// only to tunnels that request a port of the pool.
+func NewPortDistributor(excludedPorts mapset.Set, pools ...*PortPool) *PortDistributor {
+	d := &PortDistributor{
+		poolOf: make(map[int]string),
+		inUse:  make(map[int]bool),
+		rand:   rand.New(rand.NewSource(time.Now().UnixNano())),
+		isFree: IsPortFree,
 	}
+	for _, pool := range pools {
+		ports := pool.Ports.Difference(excludedPorts)
+		for port := range ports.Iter() {
+			d.poolOf[port.(int)] = pool.Name
+		}
+		d.pools = append(d.pools, &portPool{name: pool.Name, ports: sortedPorts(ports)})
+	}
+	for port := 1; port <= math.MaxUint16; port++ {
+		if _, reserved := d.poolOf[port]; !reserved && !excludedPorts.Contains(port) {
+			d.allowedPorts = append(d.allowedPorts, port)
+		}
+	}
+	return d
 }
 
+// GetRandomPort returns a random available port that is not reserved by any pool.
 func (d *PortDistributor) GetRandomPort() (int, error) {
-	if d.portsPool == nil {
-		err := d.Refresh()
-		if err != nil {
-			return 0, err
+	return d.GetRandomPortFromPool("")
+}
+
+// GetRandomPortFromPool returns a random available port of a given pool. An empty name means ports that are not
+// reserved by any pool. The port is not given out again until it's released.
+func (d *PortDistributor) GetRandomPortFromPool(name string) (int, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	ports, ok := d.poolPorts(name)
+	if !ok {
+		return 0, fmt.Errorf("unknown port pool %q", name)
+	}
+	if len(ports) > 0 {
+		start := d.rand.Intn(len(ports))
+		for i := range ports {
+			port := ports[(start+i)%len(ports)]
+			if !d.inUse[port] && d.isFree(port) {
+				d.inUse[port] = true
+				return port, nil
+			}
 		}
 	}
-
-	port := d.portsPool.Pop()
-	if port == nil {
+	if name == "" {
 		return 0, fmt.Errorf("no ports available")
 	}
-	return port.(int), nil
+	return 0, fmt.Errorf("no ports available in port pool %q", name)
 }
 
-func (d *PortDistributor) Refresh() error {
-	busyPorts, err := ListBusyPorts()
-	if err != nil {
-		return err
+// TakePortFromPool takes a given port like it was returned as a random port of a given pool. It returns false if
+// the port is not in the pool or it is not available.
+func (d *PortDistributor) TakePortFromPool(name string, port int) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.poolOf[port] != name || !d.isAvailable(port) {
+		return false
 	}
+	d.inUse[port] = true
+	return true
+}
 
-	d.portsPool = d.allowedPorts.Difference(busyPorts)
-	return nil
+// Release makes a given port that was given out available again.
+func (d *PortDistributor) Release(port int) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	delete(d.inUse, port)
 }
 
-func ListBusyPorts() (mapset.Set, error) {
-	result := mapset.NewThreadUnsafeSet()
-	connections, err := net.Connections("all")
-	if err != nil {
-		return nil, err
+// IsPortAvailable returns true if a given port is allowed, it's not given out and it's not used by other processes.
+func (d *PortDistributor) IsPortAvailable(port int) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.isAvailable(port)
+}
+
+func (d *PortDistributor) isAvailable(port int) bool {
+	if !d.isAllowed(port) || d.inUse[port] {
+		return false
 	}
+	return d.isFree(port)
+}
 
-	for _, c := range connections {
-		isActive := c.Status == "LISTEN" || c.Status == "NONE" || c.Status == ""
-		if isActive && c.Laddr.Port != 0 {
-			result.Add(int(c.Laddr.Port))
+func (d *PortDistributor) isAllowed(port int) bool {
+	if _, reserved := d.poolOf[port]; reserved {
+		return true
+	}
+	i := sort.SearchInts(d.allowedPorts, port)
+	return i < len(d.allowedPorts) && d.allowedPorts[i] == port
+}
+
+// PoolOf returns a name of the pool that reserves a given port or an empty string if the port is not reserved.
+func (d *PortDistributor) PoolOf(port int) string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.poolOf[port]
+}
+
+// poolPorts returns sorted ports of a given pool, an empty name means ports that are not reserved by any pool.
+func (d *PortDistributor) poolPorts(name string) ([]int, bool) {
+	if name == "" {
+		return d.allowedPorts, true
+	}
+	for _, pool := range d.pools {
+		if pool.name == name {
+			return pool.ports, true
+		}
+	}
+	return nil, false
+}
+
+// PoolsUsage returns the usage of all pools in the order they were given. Only ports that are given out are counted
+// as used, ports of the pools used by other processes are not checked.
+func (d *PortDistributor) PoolsUsage() []PortPoolUsage {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	used := make(map[string]int, len(d.pools))
+	for port := range d.inUse {
+		if name, reserved := d.poolOf[port]; reserved {
+			used[name]++
 		}
 	}
+	res := make([]PortPoolUsage, 0, len(d.pools))
+	for _, pool := range d.pools {
+		res = append(res, PortPoolUsage{
+			Name:      pool.name,
+			Total:     len(pool.ports),
+			Available: len(pool.ports) - used[pool.name],
+		})
+	}
+	return res
+}
+
+// IsPortFree returns true if a given port can be listened on all interfaces with both TCP and UDP.
+func IsPortFree(port int) bool {
+	addr := ":" + strconv.Itoa(port)
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return false
+	}
+	l.Close()
+	pc, err := net.ListenPacket("udp", addr)
+	if err != nil {
+		return false
+	}
+	pc.Close()
+	return true
+}
 
-	return result, nil
+func sortedPorts(set mapset.Set) []int {
+	res := make([]int, 0, set.Cardinality())
+	for port := range set.Iter() {
+		res = append(res, port.(int))
+	}
+	sort.Ints(res)
+	return res
 }
@@ -0,0 +1,157 @@
+package ports
+
+import (
+	"net"
+	"strconv"
+	"sync"
+	"testing"
+
+	mapset "github.com/deckarep/golang-set"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPortDistributorPools(t *testing.T) {
+	// given
+	excluded := mapset.NewThreadUnsafeSetFromSlice([]interface{}{20001})
+	d := NewPortDistributor(excluded,
+		&PortPool{Name: "support", Ports: setFromRange(20000, 20004)},
+		&PortPool{Name: "monitoring", Ports: setFromRange(20010, 20011)},
+	)
+	d.isFree = func(port int) bool { return true }
+
+	// then
+	assert.Equal(t, "support", d.PoolOf(20000))
+	assert.Equal(t, "monitoring", d.PoolOf(20011))
+	assert.Equal(t, "", d.PoolOf(20005))
+	assert.Equal(t, "", d.PoolOf(20001), "excluded ports should not be reserved by pools")
+	assert.NotContains(t, d.allowedPorts, 20000, "ports of pools should not be given out to other tunnels")
+	assert.Contains(t, d.allowedPorts, 20005)
+
+	// when
+	port, err := d.GetRandomPortFromPool("monitoring")
+
+	// then
+	require.NoError(t, err)
+	assert.Contains(t, []int{20010, 20011}, port)
+	assert.False(t, d.IsPortAvailable(port))
+	assert.Equal(t, []PortPoolUsage{
+		{Name: "support", Total: 4, Available: 4},
+		{Name: "monitoring", Total: 2, Available: 1},
+	}, d.PoolsUsage())
+
+	_, err = d.GetRandomPortFromPool("monitoring")
+	require.NoError(t, err)
+	_, err = d.GetRandomPortFromPool("monitoring")
+	assert.EqualError(t, err, `no ports available in port pool "monitoring"`)
+	_, err = d.GetRandomPortFromPool("unknown")
+	assert.EqualError(t, err, `unknown port pool "unknown"`)
+
+	// when
+	d.Release(port)
+
+	// then
+	assert.True(t, d.IsPortAvailable(port))
+	got, err := d.GetRandomPortFromPool("monitoring")
+	require.NoError(t, err)
+	assert.Equal(t, port, got)
+}
+
+func TestGetRandomPortSkipsBusyPorts(t *testing.T) {
+	excluded := setFromRange(1, 65535).Difference(setFromRange(20000, 20002))
+	d := NewPortDistributor(excluded)
+	d.isFree = func(port int) bool { return port != 20001 }
+
+	got := make(map[int]bool)
+	for i := 0; i < 2; i++ {
+		port, err := d.GetRandomPort()
+		require.NoError(t, err)
+		got[port] = true
+	}
+	assert.Equal(t, map[int]bool{20000: true, 20002: true}, got)
+
+	_, err := d.GetRandomPort()
+	assert.EqualError(t, err, "no ports available")
+}
+
+func TestTakePortFromPool(t *testing.T) {
+	d := NewPortDistributor(mapset.NewThreadUnsafeSetFromSlice([]interface{}{20006}), &PortPool{Name: "support", Ports: setFromRange(20000, 20001)})
+	d.isFree = func(port int) bool { return port != 20007 }
+
+	assert.True(t, d.TakePortFromPool("", 20005))
+	assert.False(t, d.TakePortFromPool("", 20005), "port is already taken")
+	assert.False(t, d.IsPortAvailable(20005))
+	assert.False(t, d.TakePortFromPool("", 20006), "port is excluded")
+	assert.False(t, d.TakePortFromPool("", 20007), "port is used by another process")
+	assert.False(t, d.TakePortFromPool("", 20000), "port is reserved by a pool")
+	assert.False(t, d.TakePortFromPool("unknown", 20000))
+	assert.True(t, d.TakePortFromPool("support", 20000))
+	assert.False(t, d.TakePortFromPool("support", 20005))
+}
+
+func TestIsPortFree(t *testing.T) {
+	l, err := net.Listen("tcp", ":0")
+	require.NoError(t, err)
+	port := l.Addr().(*net.TCPAddr).Port
+
+	assert.False(t, IsPortFree(port))
+
+	l.Close()
+	assert.True(t, IsPortFree(port))
+}
+
+func TestGetRandomPortConcurrently(t *testing.T) {
+	d := NewPortDistributor(setFromRange(1, 65535).Difference(setFromRange(20000, 20099)))
+	d.isFree = func(port int) bool { return true }
+
+	var mu sync.Mutex
+	got := make(map[int]bool)
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			port, err := d.GetRandomPort()
+			assert.NoError(t, err)
+			mu.Lock()
+			defer mu.Unlock()
+			assert.False(t, got[port], "port %d is given out twice", port)
+			got[port] = true
+		}()
+	}
+	wg.Wait()
+	assert.Len(t, got, 100)
+}
+
+func BenchmarkGetRandomPort(b *testing.B) {
+	d := NewPortDistributor(setFromRange(1, 1000))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		port, err := d.GetRandomPort()
+		if err != nil {
+			b.Fatal(err)
+		}
+		d.Release(port)
+	}
+}
+
+// BenchmarkGetRandomPortWithListen simulates concurrent tunnel creation: each port is listened right after it's given
+// out, like Tunnel.Start does, so a port that is given out twice fails the benchmark.
+func BenchmarkGetRandomPortWithListen(b *testing.B) {
+	d := NewPortDistributor(setFromRange(1, 1000))
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			port, err := d.GetRandomPort()
+			if err != nil {
+				b.Fatal(err)
+			}
+			l, err := net.Listen("tcp", ":"+strconv.Itoa(port))
+			if err != nil {
+				b.Fatal(err)
+			}
+			l.Close()
+			d.Release(port)
+		}
+	})
+}
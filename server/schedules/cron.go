@@ -0,0 +1,149 @@
+package schedules
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// cronMacros are shortcuts for commonly used cron expressions.
+var cronMacros = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+type cronField struct {
+	name     string
+	min, max int
+}
+
+var cronFields = []cronField{
+	{name: "minute", min: 0, max: 59},
+	{name: "hour", min: 0, max: 23},
+	{name: "day of month", min: 1, max: 31},
+	{name: "month", min: 1, max: 12},
+	{name: "day of week", min: 0, max: 7},
+}
+
+// Cron is a parsed cron expression.
+type Cron struct {
+	minutes  map[int]bool
+	hours    map[int]bool
+	days     map[int]bool
+	months   map[int]bool
+	weekdays map[int]bool
+	// anyDay and anyWeekday are true when the corresponding field starts with '*'.
+	anyDay     bool
+	anyWeekday bool
+}
+
+// ParseCron parses a standard cron expression with 5 fields: minute, hour, day of month, month and day of week.
+// Each field can be '*', a value, a range 'a-b', a step '*/n' or 'a-b/n' and a comma separated list of them.
+// Sunday is either 0 or 7. Macros like '@daily' and '@hourly' are supported as well.
+func ParseCron(spec string) (*Cron, error) {
+	spec = strings.TrimSpace(spec)
+	if macro, ok := cronMacros[spec]; ok {
+		spec = macro
+	}
+	parts := strings.Fields(spec)
+	if len(parts) != len(cronFields) {
+		return nil, fmt.Errorf("expected %d fields, got %d", len(cronFields), len(parts))
+	}
+
+	values := make([]map[int]bool, len(parts))
+	for i, part := range parts {
+		v, err := parseCronField(part, cronFields[i])
+		if err != nil {
+			return nil, err
+		}
+		values[i] = v
+	}
+	// both 0 and 7 mean Sunday
+	if values[4][7] {
+		values[4][0] = true
+	}
+
+	return &Cron{
+		minutes:    values[0],
+		hours:      values[1],
+		days:       values[2],
+		months:     values[3],
+		weekdays:   values[4],
+		anyDay:     strings.HasPrefix(parts[2], "*"),
+		anyWeekday: strings.HasPrefix(parts[4], "*"),
+	}, nil
+}
+
+func parseCronField(value string, field cronField) (map[int]bool, error) {
+	res := make(map[int]bool)
+	for _, item := range strings.Split(value, ",") {
+		rangeStr, step := item, 1
+		if i := strings.Index(item, "/"); i >= 0 {
+			rangeStr = item[:i]
+			var err error
+			step, err = strconv.Atoi(item[i+1:])
+			if err != nil || step <= 0 {
+				return nil, fmt.Errorf("invalid step in %s field: %q", field.name, item)
+			}
+		}
+
+		from, to := field.min, field.max
+		if rangeStr != "*" {
+			var err error
+			bounds := strings.SplitN(rangeStr, "-", 2)
+			from, err = parseCronValue(bounds[0], field)
+			if err != nil {
+				return nil, err
+			}
+			to = from
+			if len(bounds) == 2 {
+				to, err = parseCronValue(bounds[1], field)
+				if err != nil {
+					return nil, err
+				}
+			} else if step > 1 {
+				// 'a/n' means from a to the max value with a step n
+				to = field.max
+			}
+			if from > to {
+				return nil, fmt.Errorf("invalid range in %s field: %q", field.name, item)
+			}
+		}
+
+		for v := from; v <= to; v += step {
+			res[v] = true
+		}
+	}
+	return res, nil
+}
+
+func parseCronValue(value string, field cronField) (int, error) {
+	v, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value in %s field: %q", field.name, value)
+	}
+	if v < field.min || v > field.max {
+		return 0, fmt.Errorf("%s should be between %d and %d, got %d", field.name, field.min, field.max, v)
+	}
+	return v, nil
+}
+
+// Matches returns true if a given time matches the cron expression with a minute precision.
+func (c *Cron) Matches(t time.Time) bool {
+	if !c.minutes[t.Minute()] || !c.hours[t.Hour()] || !c.months[int(t.Month())] {
+		return false
+	}
+	dayMatches := c.days[t.Day()]
+	weekdayMatches := c.weekdays[int(t.Weekday())]
+	// as in standard cron, if both day fields are restricted, a time should match one of them
+	if !c.anyDay && !c.anyWeekday {
+		return dayMatches || weekdayMatches
+	}
+	return dayMatches && weekdayMatches
+}
@@ -0,0 +1,77 @@
+package schedules
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCronMatches(t *testing.T) {
+	// Monday
+	base := time.Date(2021, 3, 15, 10, 30, 0, 0, time.UTC)
+
+	testCases := []struct {
+		spec      string
+		time      time.Time
+		wantMatch bool
+	}{
+		{spec: "* * * * *", time: base, wantMatch: true},
+		{spec: "30 10 * * *", time: base, wantMatch: true},
+		{spec: "31 10 * * *", time: base, wantMatch: false},
+		{spec: "*/15 * * * *", time: base, wantMatch: true},
+		{spec: "*/20 * * * *", time: base, wantMatch: false},
+		{spec: "0-30/10 * * * *", time: base, wantMatch: true},
+		{spec: "5/25 * * * *", time: base, wantMatch: true},
+		{spec: "0,15,45 * * * *", time: base, wantMatch: false},
+		{spec: "30 9-17 * * 1-5", time: base, wantMatch: true},
+		{spec: "30 10 * * 0,6", time: base, wantMatch: false},
+		{spec: "30 10 * * 7", time: base.AddDate(0, 0, 6), wantMatch: true},
+		{spec: "30 10 15 3 *", time: base, wantMatch: true},
+		{spec: "30 10 15 4 *", time: base, wantMatch: false},
+		// restricted day of month and day of week match if any of them matches
+		{spec: "30 10 1 * 1", time: base, wantMatch: true},
+		{spec: "30 10 1 * 2", time: base, wantMatch: false},
+		{spec: "30 10 */2 * 2", time: base, wantMatch: false},
+		{spec: "@hourly", time: base, wantMatch: false},
+		{spec: "@hourly", time: base.Add(30 * time.Minute), wantMatch: true},
+		{spec: "@daily", time: time.Date(2021, 3, 15, 0, 0, 0, 0, time.UTC), wantMatch: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.spec+" "+tc.time.String(), func(t *testing.T) {
+			cron, err := ParseCron(tc.spec)
+			require.NoError(t, err)
+
+			assert.Equal(t, tc.wantMatch, cron.Matches(tc.time))
+		})
+	}
+}
+
+func TestParseCronInvalid(t *testing.T) {
+	testCases := []struct {
+		spec    string
+		wantErr string
+	}{
+		{spec: "", wantErr: "expected 5 fields, got 0"},
+		{spec: "* * * *", wantErr: "expected 5 fields, got 4"},
+		{spec: "60 * * * *", wantErr: "minute should be between 0 and 59, got 60"},
+		{spec: "* 24 * * *", wantErr: "hour should be between 0 and 23, got 24"},
+		{spec: "* * 0 * *", wantErr: "day of month should be between 1 and 31, got 0"},
+		{spec: "* * * 13 *", wantErr: "month should be between 1 and 12, got 13"},
+		{spec: "* * * * 8", wantErr: "day of week should be between 0 and 7, got 8"},
+		{spec: "* * * * mon", wantErr: `invalid value in day of week field: "mon"`},
+		{spec: "*/0 * * * *", wantErr: `invalid step in minute field: "*/0"`},
+		{spec: "10-5 * * * *", wantErr: `invalid range in minute field: "10-5"`},
+		{spec: "@every", wantErr: "expected 5 fields, got 1"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.spec, func(t *testing.T) {
+			_, err := ParseCron(tc.spec)
+
+			assert.EqualError(t, err, tc.wantErr)
+		})
+	}
+}
@@ -0,0 +1,99 @@
+package schedules
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Schedule is a command or a script that runs periodically on given clients and client groups.
+type Schedule struct {
+	ID string `json:"id" db:"id"`
+	// Name is an optional human readable name.
+	Name string `json:"name" db:"name"`
+	// Schedule is a cron expression, see ParseCron.
+	Schedule  string    `json:"schedule" db:"schedule"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	CreatedBy string    `json:"created_by" db:"created_by"`
+	Details
+	// Tenant is set only when multi-tenancy is enabled.
+	Tenant string `json:"-" db:"tenant"`
+}
+
+// Details contains what and where a schedule executes.
+type Details struct {
+	ClientIDs   []string `json:"client_ids"`
+	GroupIDs    []string `json:"group_ids"`
+	Command     string   `json:"command"`
+	Shell       string   `json:"shell"`
+	Interpreter string   `json:"interpreter"`
+	Script      string   `json:"script"`
+	TimeoutSec  int      `json:"timeout_sec"`
+	Concurrent  bool     `json:"execute_concurrently"`
+	AbortOnErr  bool     `json:"abort_on_error"`
+}
+
+type scheduleSqlite struct {
+	ID        string         `db:"id"`
+	Name      string         `db:"name"`
+	Schedule  string         `db:"schedule"`
+	CreatedAt time.Time      `db:"created_at"`
+	CreatedBy string         `db:"created_by"`
+	Tenant    string         `db:"tenant"`
+	Details   *detailsSqlite `db:"details"`
+}
+
+type detailsSqlite Details
+
+func (d *detailsSqlite) Scan(value interface{}) error {
+	if d == nil {
+		return errors.New("'details' cannot be nil")
+	}
+	valueStr, ok := value.(string)
+	if !ok {
+		return fmt.Errorf("expected to have string, got %T", value)
+	}
+	err := json.Unmarshal([]byte(valueStr), d)
+	if err != nil {
+		return fmt.Errorf("failed to decode 'details' field: %v", err)
+	}
+	return nil
+}
+
+func (d *detailsSqlite) Value() (driver.Value, error) {
+	if d == nil {
+		return nil, errors.New("'details' cannot be nil")
+	}
+	b, err := json.Marshal(d)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode 'details' field: %v", err)
+	}
+	return string(b), nil
+}
+
+func (s *scheduleSqlite) convert() *Schedule {
+	return &Schedule{
+		ID:        s.ID,
+		Name:      s.Name,
+		Schedule:  s.Schedule,
+		CreatedAt: s.CreatedAt,
+		CreatedBy: s.CreatedBy,
+		Details:   Details(*s.Details),
+		Tenant:    s.Tenant,
+	}
+}
+
+func convertToSqlite(s *Schedule) *scheduleSqlite {
+	details := detailsSqlite(s.Details)
+	return &scheduleSqlite{
+		ID:        s.ID,
+		Name:      s.Name,
+		Schedule:  s.Schedule,
+		CreatedAt: s.CreatedAt,
+		CreatedBy: s.CreatedBy,
+		Tenant:    s.Tenant,
+		Details:   &details,
+	}
+}
@@ -0,0 +1,78 @@
+package schedules
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/cloudradar-monitoring/rport/db/migration/schedules"
+	"github.com/cloudradar-monitoring/rport/db/sqlite"
+)
+
+type Provider interface {
+	Get(ctx context.Context, id string) (*Schedule, error)
+	GetAll(ctx context.Context) ([]*Schedule, error)
+	Save(ctx context.Context, s *Schedule) error
+	Delete(ctx context.Context, id string) error
+	Close() error
+}
+
+type SqliteProvider struct {
+	db *sqlx.DB
+}
+
+func NewSqliteProvider(dbPath string) (*SqliteProvider, error) {
+	db, err := sqlite.New(dbPath, schedules.AssetNames(), schedules.Asset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create schedules DB instance: %v", err)
+	}
+	return &SqliteProvider{db: db}, nil
+}
+
+// GetAll returns all schedules sorted by created_at, id order.
+func (p *SqliteProvider) GetAll(ctx context.Context) ([]*Schedule, error) {
+	var res []*scheduleSqlite
+	err := p.db.SelectContext(ctx, &res, "SELECT * FROM schedules ORDER BY DATETIME(created_at), id")
+	if err != nil {
+		return nil, err
+	}
+	list := make([]*Schedule, 0, len(res))
+	for _, cur := range res {
+		list = append(list, cur.convert())
+	}
+	return list, nil
+}
+
+func (p *SqliteProvider) Get(ctx context.Context, id string) (*Schedule, error) {
+	res := &scheduleSqlite{}
+	err := p.db.GetContext(ctx, res, "SELECT * FROM schedules WHERE id = ?", id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return res.convert(), nil
+}
+
+// Save creates a new or updates an existing schedule.
+func (p *SqliteProvider) Save(ctx context.Context, s *Schedule) error {
+	_, err := p.db.NamedExecContext(
+		ctx,
+		`INSERT OR REPLACE INTO schedules (id, name, schedule, created_at, created_by, tenant, details)
+		VALUES (:id, :name, :schedule, :created_at, :created_by, :tenant, :details)`,
+		convertToSqlite(s),
+	)
+	return err
+}
+
+func (p *SqliteProvider) Delete(ctx context.Context, id string) error {
+	_, err := p.db.ExecContext(ctx, "DELETE FROM schedules WHERE id = ?", id)
+	return err
+}
+
+func (p *SqliteProvider) Close() error {
+	return p.db.Close()
+}
@@ -0,0 +1,93 @@
+package schedules
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSqliteProvider(t *testing.T) {
+	ctx := context.Background()
+	p, err := NewSqliteProvider(":memory:")
+	require.NoError(t, err)
+	defer p.Close()
+
+	// verify schedules not found
+	all, err := p.GetAll(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, all)
+
+	// add schedules
+	t1 := time.Date(2021, 3, 15, 10, 30, 0, 0, time.UTC)
+	s1 := &Schedule{
+		ID:        "2222",
+		Name:      "disk usage",
+		Schedule:  "*/5 * * * *",
+		CreatedAt: t1,
+		CreatedBy: "admin",
+		Details: Details{
+			ClientIDs:  []string{"client-1", "client-2"},
+			Command:    "df -h",
+			TimeoutSec: 60,
+			AbortOnErr: true,
+		},
+	}
+	s2 := &Schedule{
+		ID:        "1111",
+		Schedule:  "@daily",
+		CreatedAt: t1,
+		CreatedBy: "admin",
+		Details: Details{
+			GroupIDs:    []string{"group-1"},
+			Interpreter: "bash",
+			Script:      "echo hello",
+			TimeoutSec:  30,
+			Concurrent:  true,
+		},
+		Tenant: "tenant-1",
+	}
+	s3 := &Schedule{
+		ID:        "3333",
+		Schedule:  "0 * * * *",
+		CreatedAt: t1.Add(-time.Hour),
+		CreatedBy: "admin",
+		Details: Details{
+			ClientIDs: []string{"client-1"},
+			Command:   "uptime",
+		},
+	}
+	require.NoError(t, p.Save(ctx, s1))
+	require.NoError(t, p.Save(ctx, s2))
+	require.NoError(t, p.Save(ctx, s3))
+
+	// verify added schedules
+	got, err := p.Get(ctx, s2.ID)
+	require.NoError(t, err)
+	assert.Equal(t, s2, got)
+
+	all, err = p.GetAll(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []*Schedule{s3, s2, s1}, all)
+
+	// verify not found
+	got, err = p.Get(ctx, "unknown")
+	require.NoError(t, err)
+	assert.Nil(t, got)
+
+	// verify update
+	s1.Schedule = "0 0 * * *"
+	s1.Command = "df -i"
+	require.NoError(t, p.Save(ctx, s1))
+	got, err = p.Get(ctx, s1.ID)
+	require.NoError(t, err)
+	assert.Equal(t, s1, got)
+
+	// verify delete
+	require.NoError(t, p.Delete(ctx, s2.ID))
+	all, err = p.GetAll(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []*Schedule{s3, s1}, all)
+}
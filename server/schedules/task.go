@@ -0,0 +1,78 @@
+package schedules
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	chshare "github.com/cloudradar-monitoring/rport/share"
+)
+
+// RunFunc executes a given schedule.
+type RunFunc func(ctx context.Context, s *Schedule) error
+
+// RunTask executes schedules which cron expressions match the current time.
+type RunTask struct {
+	log      *chshare.Logger
+	provider Provider
+	run      RunFunc
+	now      func() time.Time
+	// lastChecked is the last minute schedules were checked for.
+	lastChecked time.Time
+}
+
+// NewRunTask returns a task to execute schedules. It should run more often than once a minute to execute schedules in
+// time. Minutes that were missed since the previous run are checked as well, but a schedule is executed only once.
+func NewRunTask(log *chshare.Logger, provider Provider, run RunFunc) *RunTask {
+	return newRunTask(log, provider, run, time.Now)
+}
+
+func newRunTask(log *chshare.Logger, provider Provider, run RunFunc, now func() time.Time) *RunTask {
+	return &RunTask{
+		log:         log,
+		provider:    provider,
+		run:         run,
+		now:         now,
+		lastChecked: now().Truncate(time.Minute),
+	}
+}
+
+func (t *RunTask) Run(ctx context.Context) error {
+	now := t.now().Truncate(time.Minute)
+	from := t.lastChecked.Add(time.Minute)
+	if now.Before(from) {
+		return nil
+	}
+
+	all, err := t.provider.GetAll(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get schedules: %v", err)
+	}
+	t.lastChecked = now
+
+	for _, s := range all {
+		cron, err := ParseCron(s.Schedule)
+		if err != nil {
+			t.log.Errorf("Schedule[id=%q] has invalid cron expression %q: %v", s.ID, s.Schedule, err)
+			continue
+		}
+		if !matchesAny(cron, from, now) {
+			continue
+		}
+		t.log.Debugf("Schedule[id=%q] triggered.", s.ID)
+		if err := t.run(ctx, s); err != nil {
+			t.log.Errorf("Failed to run schedule[id=%q]: %v", s.ID, err)
+		}
+	}
+	return nil
+}
+
+// matchesAny returns true if any minute between from and to inclusively matches a given cron expression.
+func matchesAny(cron *Cron, from, to time.Time) bool {
+	for cur := from; !cur.After(to); cur = cur.Add(time.Minute) {
+		if cron.Matches(cur) {
+			return true
+		}
+	}
+	return false
+}
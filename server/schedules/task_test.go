@@ -0,0 +1,83 @@
+package schedules
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	chshare "github.com/cloudradar-monitoring/rport/share"
+)
+
+var testLog = chshare.NewLogger("schedules", chshare.LogOutput{File: os.Stdout}, chshare.LogLevelDebug)
+
+type mockProvider struct {
+	Provider
+	schedules []*Schedule
+}
+
+func (p *mockProvider) GetAll(ctx context.Context) ([]*Schedule, error) {
+	return p.schedules, nil
+}
+
+func TestRunTask(t *testing.T) {
+	provider := &mockProvider{
+		schedules: []*Schedule{
+			{ID: "every-minute", Schedule: "* * * * *"},
+			{ID: "every-hour", Schedule: "0 * * * *"},
+			{ID: "invalid", Schedule: "* * *"},
+			{ID: "failing", Schedule: "* * * * *"},
+		},
+	}
+	now := time.Date(2021, 3, 15, 10, 58, 30, 0, time.UTC)
+	var gotIDs []string
+	run := func(ctx context.Context, s *Schedule) error {
+		gotIDs = append(gotIDs, s.ID)
+		if s.ID == "failing" {
+			return errors.New("failed")
+		}
+		return nil
+	}
+	task := newRunTask(testLog, provider, run, func() time.Time { return now })
+
+	testCases := []struct {
+		name    string
+		now     time.Time
+		wantIDs []string
+	}{
+		{
+			name: "same minute as on start",
+			now:  now.Add(20 * time.Second),
+		},
+		{
+			name:    "next minute",
+			now:     now.Add(40 * time.Second),
+			wantIDs: []string{"every-minute", "failing"},
+		},
+		{
+			name: "same minute as on previous run",
+			now:  now.Add(50 * time.Second),
+		},
+		{
+			name:    "missed minutes",
+			now:     now.Add(3 * time.Minute),
+			wantIDs: []string{"every-minute", "every-hour", "failing"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			gotIDs = nil
+			now = tc.now
+
+			err := task.Run(context.Background())
+
+			require.NoError(t, err)
+			assert.Equal(t, tc.wantIDs, gotIDs)
+		})
+	}
+}
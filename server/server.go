@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io/ioutil"
 	"path"
 	"sync"
 	"time"
@@ -17,11 +18,14 @@ import (
 	_ "github.com/mattn/go-sqlite3"
 
 	"github.com/cloudradar-monitoring/rport/server/api/jobs"
+	"github.com/cloudradar-monitoring/rport/server/auditlog"
 	"github.com/cloudradar-monitoring/rport/server/cgroups"
+	"github.com/cloudradar-monitoring/rport/server/clientcerts"
 	"github.com/cloudradar-monitoring/rport/server/clients"
 	"github.com/cloudradar-monitoring/rport/server/clientsauth"
 	"github.com/cloudradar-monitoring/rport/server/ports"
 	"github.com/cloudradar-monitoring/rport/server/scheduler"
+	"github.com/cloudradar-monitoring/rport/server/schedules"
 	chshare "github.com/cloudradar-monitoring/rport/share"
 	"github.com/cloudradar-monitoring/rport/share/files"
 	"github.com/cloudradar-monitoring/rport/share/models"
@@ -35,13 +39,19 @@ type Server struct {
 	apiListener         *APIListener
 	config              *Config
 	clientService       *ClientService
+	apiSessionRepo      *APISessionRepository
 	clientProvider      clients.ClientProvider
 	clientAuthProvider  clientsauth.Provider
+	clientCertProvider  clientcerts.Provider   // nil if certificate authentication of clients is disabled
+	clientCertAuthority *clientcerts.Authority // nil if certificate authentication of clients is disabled
 	jobProvider         JobProvider
 	clientGroupProvider cgroups.ClientGroupProvider
+	scheduleProvider    schedules.Provider
+	auditLogProvider    auditlog.Provider // nil if audit logging is disabled
 	db                  *sqlx.DB
 	uiJobWebSockets     ws.WebSocketCache // used to push job result to UI
 	jobsDoneChannel     jobResultChanMap  // used for sequential command execution to know when command is finished
+	nextFingerprints    []string          // fingerprints of next host keys that are announced to clients
 }
 
 // NewServer creates and returns a new rport server
@@ -56,12 +66,18 @@ func NewServer(config *Config, filesAPI files.FileAPI) (*Server, error) {
 		},
 	}
 
-	privateKey, err := initPrivateKey(config.Server.KeySeed)
+	privateKey, nextKeys, err := initHostKeys(&config.Server)
 	if err != nil {
 		return nil, err
 	}
 	fingerprint := chshare.FingerprintKey(privateKey.PublicKey())
-	s.Infof("Fingerprint %s", fingerprint)
+	s.Infof("Fingerprint %s (md5 %s)", fingerprint, chshare.FingerprintKeyMD5(privateKey.PublicKey()))
+	s.nextFingerprints = make([]string, 0, len(nextKeys))
+	for _, key := range nextKeys {
+		nextFingerprint := chshare.FingerprintKey(key.PublicKey())
+		s.Infof("Next fingerprint %s is announced to clients", nextFingerprint)
+		s.nextFingerprints = append(s.nextFingerprints, nextFingerprint)
+	}
 
 	s.Infof("data directory path: %q", config.Server.DataDir)
 	if config.Server.DataDir == "" {
@@ -90,13 +106,19 @@ func NewServer(config *Config, filesAPI files.FileAPI) (*Server, error) {
 		return nil, err
 	}
 
-	s.clientProvider, err = clients.NewSqliteProvider(
+	s.scheduleProvider, err = schedules.NewSqliteProvider(path.Join(config.Server.DataDir, "schedules.db"))
+	if err != nil {
+		return nil, err
+	}
+
+	clientProvider, err := clients.NewSqliteProvider(
 		path.Join(config.Server.DataDir, "clients.db"),
 		config.Server.KeepLostClients,
 	)
 	if err != nil {
 		return nil, err
 	}
+	s.clientProvider = clientProvider
 
 	initClients, err := clients.GetInitState(ctx, s.clientProvider)
 	if err != nil {
@@ -108,10 +130,16 @@ func NewServer(config *Config, filesAPI files.FileAPI) (*Server, error) {
 		keepLostClients = &config.Server.KeepLostClients
 	}
 	repo := clients.NewClientRepository(initClients, keepLostClients)
+	s.apiSessionRepo = NewAPISessionRepository()
 	s.clientService = NewClientService(
-		ports.NewPortDistributor(config.ExcludedPorts()),
+		ports.NewPortDistributor(config.ExcludedPorts(), config.PortPools()...),
 		repo,
 	)
+	s.clientService.reverseTunnelDestinations = config.ReverseTunnelDestinations()
+	s.clientService.apiSessions = s.apiSessionRepo
+	s.clientService.portPools = config.Server.PortPools
+	s.clientService.clientGroupProvider = s.clientGroupProvider
+	s.clientService.portReservations = clientProvider
 
 	if config.Database.driver != "" {
 		s.db, err = sqlx.Connect(config.Database.driver, config.Database.dsn)
@@ -125,6 +153,25 @@ func NewServer(config *Config, filesAPI files.FileAPI) (*Server, error) {
 	if err != nil {
 		return nil, err
 	}
+
+	if config.Server.ClientCAKeyFile != "" {
+		caKey, err := clientcerts.LoadOrGenerateCAKey(config.Server.ClientCAKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		s.Infof("Client CA fingerprint %s", ssh.FingerprintSHA256(caKey.PublicKey()))
+		s.clientCertProvider, err = clientcerts.NewSqliteProvider(path.Join(config.Server.DataDir, "client_certs.db"))
+		if err != nil {
+			return nil, err
+		}
+		s.clientCertAuthority = clientcerts.NewAuthority(caKey, s.clientCertProvider, config.Server.ClientCertValidity)
+	}
+
+	if config.Logging.AuditLogFile != "" {
+		s.auditLogProvider = auditlog.NewFileProvider(config.Logging.AuditLogFile)
+	} else if config.Logging.AuditLogTable != "" {
+		s.auditLogProvider = auditlog.NewDatabaseProvider(s.db, config.Logging.AuditLogTable)
+	}
 	s.clientListener, err = NewClientListener(s, privateKey)
 	if err != nil {
 		return nil, err
@@ -140,7 +187,7 @@ func NewServer(config *Config, filesAPI files.FileAPI) (*Server, error) {
 
 func getClientProvider(config *Config, db *sqlx.DB) (clientsauth.Provider, error) {
 	if config.Server.AuthTable != "" {
-		dbProvider := clientsauth.NewDatabaseProvider(db, config.Server.AuthTable)
+		dbProvider := clientsauth.NewDatabaseProvider(db, config.Server.AuthTable, config.Server.MultiTenancy)
 		cachedProvider, err := clientsauth.NewCachedProvider(dbProvider)
 		if err != nil {
 			return nil, err
@@ -161,7 +208,34 @@ func getClientProvider(config *Config, db *sqlx.DB) (clientsauth.Provider, error
 		return clientsauth.NewSingleProvider(config.Server.authID, config.Server.authPassword), nil
 	}
 
-	return nil, errors.New("client authentication must to be enabled: set either 'auth' or 'auth_file'")
+	if config.Server.ClientCAKeyFile != "" {
+		return clientsauth.NewNoneProvider(), nil
+	}
+
+	return nil, errors.New("client authentication must to be enabled: set either 'auth', 'auth_file' or 'client_ca_key_file'")
+}
+
+// initHostKeys returns the host key of the server and next host keys that are announced to clients, so clients accept
+// them after the server switches to one of them.
+func initHostKeys(config *ServerConfig) (ssh.Signer, []ssh.Signer, error) {
+	if len(config.KeyFiles) == 0 {
+		key, err := initPrivateKey(config.KeySeed)
+		return key, nil, err
+	}
+
+	keys := make([]ssh.Signer, 0, len(config.KeyFiles))
+	for _, file := range config.KeyFiles {
+		b, err := ioutil.ReadFile(file)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to read host key: %v", err)
+		}
+		key, err := ssh.ParsePrivateKey(b)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to parse host key %q: %v", file, err)
+		}
+		keys = append(keys, key)
+	}
+	return keys[0], keys[1:], nil
 }
 
 func initPrivateKey(seed string) (ssh.Signer, error) {
@@ -195,6 +269,11 @@ func (s *Server) Run() error {
 	go scheduler.Run(ctx, s.Logger, clients.NewSaveTask(s.Logger, s.clientListener.clientService.repo, s.clientProvider), s.config.Server.SaveClients)
 	s.Infof("Task to save clients to disk will run with interval %v", s.config.Server.SaveClients)
 
+	if s.config.API.Address != "" {
+		go scheduler.Run(ctx, s.Logger, schedules.NewRunTask(s.Logger, s.scheduleProvider, s.apiListener.runSchedule), scheduleCheckInterval)
+		s.Infof("Task to run schedules will run with interval %v", scheduleCheckInterval)
+	}
+
 	return s.Wait()
 }
 
@@ -228,6 +307,10 @@ func (s *Server) Close() error {
 	wg.Go(s.clientProvider.Close)
 	wg.Go(s.jobProvider.Close)
 	wg.Go(s.clientGroupProvider.Close)
+	wg.Go(s.scheduleProvider.Close)
+	if s.clientCertProvider != nil {
+		wg.Go(s.clientCertProvider.Close)
+	}
 	wg.Go(s.uiJobWebSockets.CloseConnections)
 	return wg.Wait()
 }
@@ -0,0 +1,48 @@
+package chserver
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	chshare "github.com/cloudradar-monitoring/rport/share"
+)
+
+func TestInitHostKeys(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rportd-host-keys")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	var files []string
+	for _, name := range []string{"current.pem", "next.pem"} {
+		key, err := chshare.GenerateKey("")
+		require.NoError(t, err)
+		file := filepath.Join(dir, name)
+		require.NoError(t, ioutil.WriteFile(file, key, 0600))
+		files = append(files, file)
+	}
+
+	current, next, err := initHostKeys(&ServerConfig{KeyFiles: files})
+
+	require.NoError(t, err)
+	require.Len(t, next, 1)
+	assert.NotEqual(t, chshare.FingerprintKey(current.PublicKey()), chshare.FingerprintKey(next[0].PublicKey()))
+
+	again, next, err := initHostKeys(&ServerConfig{KeyFiles: files[:1]})
+	require.NoError(t, err)
+	assert.Empty(t, next)
+	assert.Equal(t, chshare.FingerprintKey(current.PublicKey()), chshare.FingerprintKey(again.PublicKey()))
+
+	seeded, next, err := initHostKeys(&ServerConfig{KeySeed: "seed"})
+	require.NoError(t, err)
+	assert.Empty(t, next)
+	seededAgain, _, err := initHostKeys(&ServerConfig{KeySeed: "seed"})
+	require.NoError(t, err)
+	assert.Equal(t, chshare.FingerprintKey(seeded.PublicKey()), chshare.FingerprintKey(seededAgain.PublicKey()))
+
+	_, _, err = initHostKeys(&ServerConfig{KeyFiles: []string{filepath.Join(dir, "missing.pem")}})
+	assert.Error(t, err)
+}
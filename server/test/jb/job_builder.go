@@ -28,6 +28,9 @@ type JobBuilder struct {
 	startedAt  time.Time
 	finishedAt *time.Time
 	result     *models.JobResult
+
+	interpreter string
+	script      string
 }
 
 // New returns a builder to generate a job that can be used in tests.
@@ -85,11 +88,22 @@ func (b JobBuilder) Result(result *models.JobResult) JobBuilder {
 	return b
 }
 
+// Script makes the job execute a given script instead of the default command.
+func (b JobBuilder) Script(interpreter, script string) JobBuilder {
+	b.interpreter = interpreter
+	b.script = script
+	return b
+}
+
 func (b JobBuilder) Build() *models.Job {
 	if b.jid == "" {
 		b.jid = generateRandomJID()
 	}
 	pid := 1245
+	cmd := "/bin/date;foo;whoami"
+	if b.script != "" {
+		cmd = ""
+	}
 	// hardcoded values are used because currently was no need of other data, extend with more available options if needed
 	return &models.Job{
 		JobSummary: models.JobSummary{
@@ -97,15 +111,17 @@ func (b JobBuilder) Build() *models.Job {
 			Status:     b.status,
 			FinishedAt: b.finishedAt,
 		},
-		ClientID:   b.clientID,
-		ClientName: b.clientName,
-		Command:    "/bin/date;foo;whoami",
-		PID:        &pid,
-		StartedAt:  b.startedAt,
-		CreatedBy:  "test-user",
-		TimeoutSec: 60,
-		Result:     b.result,
-		MultiJobID: &b.multiJobID,
+		ClientID:    b.clientID,
+		ClientName:  b.clientName,
+		Command:     cmd,
+		Interpreter: b.interpreter,
+		Script:      b.script,
+		PID:         &pid,
+		StartedAt:   b.startedAt,
+		CreatedBy:   "test-user",
+		TimeoutSec:  60,
+		Result:      b.result,
+		MultiJobID:  &b.multiJobID,
 	}
 }
 
@@ -16,6 +16,7 @@ type MultiJobBuilder struct {
 	concurrent bool
 	abortOnErr bool
 	withJobs   bool
+	scheduleID *string
 }
 
 // NewMulti returns a builder to generate a multi-client job that can be used in tests.
@@ -51,6 +52,11 @@ func (b MultiJobBuilder) AbortOnErr(abortOnErr bool) MultiJobBuilder {
 	return b
 }
 
+func (b MultiJobBuilder) ScheduleID(scheduleID string) MultiJobBuilder {
+	b.scheduleID = &scheduleID
+	return b
+}
+
 func (b MultiJobBuilder) StartedAt(startedAt time.Time) MultiJobBuilder {
 	b.startedAt = startedAt
 	return b
@@ -83,6 +89,7 @@ func (b MultiJobBuilder) Build() *models.MultiJob {
 		TimeoutSec: 60,
 		Concurrent: b.concurrent,
 		AbortOnErr: b.abortOnErr,
+		ScheduleID: b.scheduleID,
 		Jobs:       jobs,
 	}
 }
@@ -8,14 +8,34 @@ import (
 
 const (
 	// request types sent by server to clients
-	RequestTypeCheckPort = "check_port"
-	RequestTypeRunCmd    = "run_cmd"
+	RequestTypeCheckPort          = "check_port"
+	RequestTypeRunCmd             = "run_cmd"
+	RequestTypeCancelCmd          = "cancel_cmd"
+	RequestTypeStartReverseTunnel = "start_reverse_tunnel"
+	RequestTypeStopReverseTunnel  = "stop_reverse_tunnel"
+	// RequestTypeHostKeys announces next host keys of the server, it's sent without waiting for a reply
+	RequestTypeHostKeys = "host_keys"
 
 	// request types sent by clients to server
 	RequestTypePing      = "ping"
+	RequestTypeCmdOutput = "cmd_output"
 	RequestTypeCmdResult = "cmd_result"
 )
 
+const (
+	// channel types opened by server on clients, the "rport" channel type is used for TCP tunnels
+	ChannelTypeFile = "file"
+	ChannelTypeUDP  = "udp"
+	// extra data is the destination requested by a connection of a SOCKS tunnel, it's checked against the client allow list
+	ChannelTypeSocks = "socks"
+
+	// channel type opened by clients on server for connections of reverse tunnels, extra data is the remote address
+	ChannelTypeReverse = "reverse"
+
+	FileOpUpload   = "upload"
+	FileOpDownload = "download"
+)
+
 type CheckPortRequest struct {
 	HostPort string
 	Timeout  time.Duration
@@ -34,7 +54,43 @@ type CheckPortResponse struct {
 	ErrMsg string
 }
 
+// HostKeysRequest announces SHA256 fingerprints of host keys the server is going to switch to, so connected clients
+// accept them on reconnect.
+type HostKeysRequest struct {
+	NextFingerprints []string
+}
+
+// ReverseTunnelRequest asks a client to start or stop listening on a local address of a reverse tunnel.
+type ReverseTunnelRequest struct {
+	Local  string
+	Remote string
+}
+
 type RunCmdResponse struct {
 	Pid       int
 	StartedAt time.Time
+	// Queued is true if the command is not started yet because the client already runs the maximum number of commands.
+	// When the command is started, the client sends the job with the running status.
+	Queued bool
+}
+
+type CancelCmdRequest struct {
+	JID string
+}
+
+type CancelCmdResponse struct {
+	Pid int
+}
+
+// FileRequest is sent as extra data of a file channel. The file content is transferred over the channel using
+// the chunked transfer encoding, so an incomplete transfer is detected on the receiver side.
+type FileRequest struct {
+	Op   string
+	Path string
+}
+
+// FileUploadResult is sent back by a client over a file channel when an uploaded file is stored.
+type FileUploadResult struct {
+	Size   int64
+	ErrMsg string
 }
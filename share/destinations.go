@@ -0,0 +1,79 @@
+package chshare
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// TunnelDestinations is an allow list of addresses tunnels are allowed to connect to.
+type TunnelDestinations struct {
+	destinations []tunnelDestination
+}
+
+type tunnelDestination struct {
+	host  string
+	ipNet *net.IPNet // set instead of host if a range is given
+	port  string     // "*" allows any port
+}
+
+// ParseTunnelDestinations parses destinations in a form of <host>:<port>, where host is a hostname, an IP address or
+// a range in CIDR notation and port is a port number or "*" to allow any port. IPv6 addresses should be enclosed in
+// square brackets. Hostnames are not resolved, they only allow remotes with the same hostname.
+func ParseTunnelDestinations(values []string) (*TunnelDestinations, error) {
+	d := &TunnelDestinations{}
+	for _, v := range values {
+		host, port, err := net.SplitHostPort(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid destination %q: %v", v, err)
+		}
+		if host == "" {
+			return nil, fmt.Errorf("invalid destination %q: missing host", v)
+		}
+		if port != "*" {
+			if _, err := strconv.ParseUint(port, 10, 16); err != nil {
+				return nil, fmt.Errorf("invalid destination %q: invalid port %q", v, port)
+			}
+		}
+
+		dest := tunnelDestination{host: host, port: port}
+		if strings.ContainsRune(host, '/') {
+			_, dest.ipNet, err = net.ParseCIDR(host)
+			if err != nil {
+				return nil, fmt.Errorf("invalid destination %q: %v", v, err)
+			}
+		}
+		d.destinations = append(d.destinations, dest)
+	}
+	return d, nil
+}
+
+// Allows returns true if the server is allowed to connect to a given address. Nothing is allowed if destinations are nil.
+func (d *TunnelDestinations) Allows(host, port string) bool {
+	if d == nil {
+		return false
+	}
+	ip := net.ParseIP(host)
+	for _, dest := range d.destinations {
+		if dest.port != "*" && dest.port != port {
+			continue
+		}
+		if dest.ipNet != nil {
+			if ip != nil && dest.ipNet.Contains(ip) {
+				return true
+			}
+			continue
+		}
+		if destIP := net.ParseIP(dest.host); destIP != nil && ip != nil {
+			if destIP.Equal(ip) {
+				return true
+			}
+			continue
+		}
+		if strings.EqualFold(dest.host, host) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,60 @@
+package chshare
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTunnelDestinationsAllows(t *testing.T) {
+	destinations, err := ParseTunnelDestinations([]string{"db.internal:5432", "10.0.0.0/16:*", "192.0.2.1:80", "[2001:db8::1]:443"})
+	require.NoError(t, err)
+
+	testCases := []struct {
+		host        string
+		port        string
+		wantAllowed bool
+	}{
+		{host: "db.internal", port: "5432", wantAllowed: true},
+		{host: "DB.internal", port: "5432", wantAllowed: true},
+		{host: "db.internal", port: "5433", wantAllowed: false},
+		{host: "10.0.1.1", port: "22", wantAllowed: true},
+		{host: "10.1.0.1", port: "22", wantAllowed: false},
+		{host: "192.0.2.1", port: "80", wantAllowed: true},
+		{host: "192.0.2.1", port: "81", wantAllowed: false},
+		{host: "2001:db8::1", port: "443", wantAllowed: true},
+		{host: "2001:db8:0::1", port: "443", wantAllowed: true},
+		{host: "2001:db8::2", port: "443", wantAllowed: false},
+		{host: "other.internal", port: "5432", wantAllowed: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.host+":"+tc.port, func(t *testing.T) {
+			assert.Equal(t, tc.wantAllowed, destinations.Allows(tc.host, tc.port))
+		})
+	}
+
+	var nilDestinations *TunnelDestinations
+	assert.False(t, nilDestinations.Allows("db.internal", "5432"))
+}
+
+func TestParseTunnelDestinationsInvalid(t *testing.T) {
+	testCases := []struct {
+		destination string
+		wantErr     string
+	}{
+		{destination: "db.internal", wantErr: `invalid destination "db.internal": address db.internal: missing port in address`},
+		{destination: ":5432", wantErr: `invalid destination ":5432": missing host`},
+		{destination: "db.internal:http", wantErr: `invalid destination "db.internal:http": invalid port "http"`},
+		{destination: "10.0.0.0/33:22", wantErr: `invalid destination "10.0.0.0/33:22": invalid CIDR address: 10.0.0.0/33`},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.destination, func(t *testing.T) {
+			_, err := ParseTunnelDestinations([]string{tc.destination})
+
+			assert.EqualError(t, err, tc.wantErr)
+		})
+	}
+}
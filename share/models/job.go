@@ -7,24 +7,28 @@ import (
 
 const (
 	JobStatusSuccessful = "successful"
+	JobStatusQueued     = "queued"
 	JobStatusRunning    = "running"
 	JobStatusFailed     = "failed"
 	JobStatusUnknown    = "unknown"
+	JobStatusCancelled  = "cancelled"
 )
 
 type Job struct {
 	JobSummary
-	ClientID   string     `json:"client_id"`
-	ClientName string     `json:"client_name"`
-	Command    string     `json:"command"`
-	Shell      string     `json:"shell"`
-	PID        *int       `json:"pid"`
-	StartedAt  time.Time  `json:"started_at"`
-	CreatedBy  string     `json:"created_by"`
-	TimeoutSec int        `json:"timeout_sec"`
-	MultiJobID *string    `json:"multi_job_id"`
-	Error      string     `json:"error"`
-	Result     *JobResult `json:"result"`
+	ClientID    string     `json:"client_id"`
+	ClientName  string     `json:"client_name"`
+	Command     string     `json:"command"`
+	Shell       string     `json:"shell"`
+	Interpreter string     `json:"interpreter"`
+	Script      string     `json:"script"`
+	PID         *int       `json:"pid"`
+	StartedAt   time.Time  `json:"started_at"`
+	CreatedBy   string     `json:"created_by"`
+	TimeoutSec  int        `json:"timeout_sec"`
+	MultiJobID  *string    `json:"multi_job_id"`
+	Error       string     `json:"error"`
+	Result      *JobResult `json:"result"`
 }
 
 // JobSummary short info about a job.
@@ -39,22 +43,39 @@ type JobResult struct {
 	StdErr string `json:"stderr"`
 }
 
+// JobOutput is a chunk of output that a running job produced since the previous chunk.
+type JobOutput struct {
+	JID        string     `json:"jid"`
+	MultiJobID *string    `json:"multi_job_id"`
+	ClientID   string     `json:"client_id"`
+	Output     *JobResult `json:"output"`
+}
+
+func (o JobOutput) LogPrefix() string {
+	return Job{JobSummary: JobSummary{JID: o.JID}, ClientID: o.ClientID, MultiJobID: o.MultiJobID}.LogPrefix()
+}
+
 type MultiJob struct {
 	MultiJobSummary
-	ClientIDs  []string `json:"client_ids"`
-	GroupIDs   []string `json:"group_ids"`
-	Command    string   `json:"command"`
-	Shell      string   `json:"shell"`
-	TimeoutSec int      `json:"timeout_sec"`
-	Concurrent bool     `json:"concurrent"`
-	AbortOnErr bool     `json:"abort_on_err"`
-	Jobs       []*Job   `json:"jobs"`
+	ClientIDs   []string `json:"client_ids"`
+	GroupIDs    []string `json:"group_ids"`
+	Command     string   `json:"command"`
+	Shell       string   `json:"shell"`
+	Interpreter string   `json:"interpreter"`
+	Script      string   `json:"script"`
+	TimeoutSec  int      `json:"timeout_sec"`
+	Concurrent  bool     `json:"concurrent"`
+	AbortOnErr  bool     `json:"abort_on_err"`
+	ScheduleID  *string  `json:"schedule_id"`
+	Jobs        []*Job   `json:"jobs"`
 }
 
 type MultiJobSummary struct {
 	JID       string    `json:"jid"`
 	StartedAt time.Time `json:"started_at"`
 	CreatedBy string    `json:"created_by"`
+	// Tenant is set only when multi-tenancy is enabled.
+	Tenant string `json:"-"`
 }
 
 type MultiJobResult struct {
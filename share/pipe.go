@@ -5,7 +5,9 @@ import (
 	"sync"
 )
 
-func Pipe(src io.ReadWriteCloser, dst io.ReadWriteCloser) (int64, int64) {
+// Pipe copies data between given connections in both directions until one of them is closed. Both directions
+// share given token buckets, so they limit the total bandwidth of the connections that are piped with them.
+func Pipe(src io.ReadWriteCloser, dst io.ReadWriteCloser, limits ...*TokenBucket) (int64, int64) {
 	var sent, received int64
 	var wg sync.WaitGroup
 	var o sync.Once
@@ -15,12 +17,12 @@ func Pipe(src io.ReadWriteCloser, dst io.ReadWriteCloser) (int64, int64) {
 	}
 	wg.Add(2)
 	go func() {
-		received, _ = io.Copy(src, dst)
+		received, _ = io.Copy(src, LimitReader(dst, limits...))
 		o.Do(close)
 		wg.Done()
 	}()
 	go func() {
-		sent, _ = io.Copy(dst, src)
+		sent, _ = io.Copy(dst, LimitReader(src, limits...))
 		o.Do(close)
 		wg.Done()
 	}()
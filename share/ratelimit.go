@@ -0,0 +1,99 @@
+package chshare
+
+import (
+	"io"
+	"math"
+	"sync"
+	"time"
+)
+
+// TokenBucket limits the rate of transferred bytes. Tokens are refilled at a given rate up to a burst
+// of one second of traffic. A nil *TokenBucket doesn't limit anything, so it can be used for disabled limits.
+type TokenBucket struct {
+	mu     sync.Mutex
+	rate   float64
+	tokens float64
+	last   time.Time
+}
+
+// NewTokenBucket returns a token bucket that allows a given number of bytes per second or nil if it's not positive.
+func NewTokenBucket(bytesPerSec int64) *TokenBucket {
+	if bytesPerSec <= 0 {
+		return nil
+	}
+	return &TokenBucket{
+		rate:   float64(bytesPerSec),
+		tokens: float64(bytesPerSec),
+		last:   time.Now(),
+	}
+}
+
+// Wait blocks until n bytes are allowed to be transferred. Tokens are taken in advance, so concurrent callers
+// wait in turn and the bucket can go into debt for one call that is bigger than the burst.
+func (b *TokenBucket) Wait(n int) {
+	if b == nil || n <= 0 {
+		return
+	}
+	b.mu.Lock()
+	now := time.Now()
+	b.tokens = math.Min(b.rate, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	b.last = now
+	b.tokens -= float64(n)
+	var wait time.Duration
+	if b.tokens < 0 {
+		wait = time.Duration(-b.tokens / b.rate * float64(time.Second))
+	}
+	b.mu.Unlock()
+
+	time.Sleep(wait)
+}
+
+// burst returns the max number of bytes that are allowed at once.
+func (b *TokenBucket) burst() int {
+	if b.rate < 1 {
+		return 1
+	}
+	if b.rate > math.MaxInt32 {
+		return math.MaxInt32
+	}
+	return int(b.rate)
+}
+
+// LimitReader returns a reader that waits for given token buckets after each read. Reads are split into chunks
+// not bigger than the burst of the buckets, so the reader never sleeps much longer than a second at once.
+// Nil buckets are ignored, a given reader is returned as is if there are no other buckets.
+func LimitReader(r io.Reader, limits ...*TokenBucket) io.Reader {
+	lr := &limitedReader{r: r}
+	for _, b := range limits {
+		if b != nil {
+			lr.limits = append(lr.limits, b)
+		}
+	}
+	if len(lr.limits) == 0 {
+		return r
+	}
+	return lr
+}
+
+type limitedReader struct {
+	r      io.Reader
+	limits []*TokenBucket
+}
+
+func (r *limitedReader) Read(p []byte) (int, error) {
+	for _, b := range r.limits {
+		if burst := b.burst(); len(p) > burst {
+			p = p[:burst]
+		}
+	}
+	n, err := r.r.Read(p)
+	waitAll(r.limits, n)
+	return n, err
+}
+
+// waitAll blocks until n bytes are allowed by all given token buckets.
+func waitAll(limits []*TokenBucket, n int) {
+	for _, b := range limits {
+		b.Wait(n)
+	}
+}
@@ -0,0 +1,78 @@
+package chshare
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTokenBucket(t *testing.T) {
+	assert.Nil(t, NewTokenBucket(0))
+	assert.Nil(t, NewTokenBucket(-1))
+
+	// nil bucket doesn't limit
+	var disabled *TokenBucket
+	start := time.Now()
+	disabled.Wait(1 << 30)
+	assert.Less(t, int64(time.Since(start)), int64(100*time.Millisecond))
+
+	b := NewTokenBucket(1000)
+
+	// burst of one second is allowed right away
+	start = time.Now()
+	b.Wait(1000)
+	assert.Less(t, int64(time.Since(start)), int64(100*time.Millisecond))
+
+	// then bytes are allowed at the rate
+	start = time.Now()
+	b.Wait(300)
+	elapsed := time.Since(start)
+	assert.GreaterOrEqual(t, int64(elapsed), int64(250*time.Millisecond))
+	assert.Less(t, int64(elapsed), int64(600*time.Millisecond))
+}
+
+func TestLimitReader(t *testing.T) {
+	src := bytes.NewReader(make([]byte, 100))
+	assert.Equal(t, io.Reader(src), LimitReader(src))
+	assert.Equal(t, io.Reader(src), LimitReader(src, nil, nil))
+
+	r := LimitReader(src, NewTokenBucket(1000), nil, NewTokenBucket(10))
+
+	// reads are split by the smallest burst
+	buf := make([]byte, 64)
+	n, err := r.Read(buf)
+	require.NoError(t, err)
+	assert.Equal(t, 10, n)
+}
+
+func TestPipeWithLimits(t *testing.T) {
+	// given
+	src, srcPeer := net.Pipe()
+	dst, dstPeer := net.Pipe()
+	limit := NewTokenBucket(1000)
+	done := make(chan struct{})
+	go func() {
+		Pipe(src, dst, limit)
+		close(done)
+	}()
+	go func() {
+		_, _ = srcPeer.Write(make([]byte, 1500))
+	}()
+	start := time.Now()
+
+	// when
+	_, err := io.ReadFull(dstPeer, make([]byte, 1500))
+	require.NoError(t, err)
+
+	// then
+	elapsed := time.Since(start)
+	assert.GreaterOrEqual(t, int64(elapsed), int64(400*time.Millisecond))
+	assert.Less(t, int64(elapsed), int64(900*time.Millisecond))
+	srcPeer.Close()
+	<-done
+}
@@ -2,9 +2,11 @@ package chshare
 
 import (
 	"errors"
+	"net"
 	"net/url"
 	"regexp"
 	"strings"
+	"time"
 )
 
 // short-hand conversions
@@ -20,9 +22,32 @@ import (
 //   192.168.0.1:3000:google.com:80 ->
 //     local  192.168.0.1:3000
 //     remote google.com:80
+//   3000:8.8.8.8:53/udp ->
+//     local  127.0.0.1:3000
+//     remote 8.8.8.8:53 over UDP
+//   [::]:3000:[2001:db8::1]:80 ->
+//     local  [::]:3000
+//     remote [2001:db8::1]:80
+//   R:5432:db.internal:5432 ->
+//     local  127.0.0.1:5432 on the client
+//     remote db.internal:5432 connected from the server
+//   1080:socks ->
+//     local  0.0.0.0:1080 speaking SOCKS5
+//     remote requested by each connection
 
 const ZeroHost = "0.0.0.0"
 
+// ReversePrefix marks a remote of a reverse tunnel.
+const ReversePrefix = "R:"
+
+// SocksRemote is used instead of a remote address for SOCKS tunnels.
+const SocksRemote = "socks"
+
+const (
+	ProtocolTCP = "tcp"
+	ProtocolUDP = "udp"
+)
+
 // TODO(m-terel): Remote should be only used for parsing command args and URL query params. Current Remote is kind of a Tunnel model. Refactor to use separate models for representation and business logic.
 type Remote struct {
 	LocalHost          string  `json:"lhost"`
@@ -33,15 +58,56 @@ type Remote struct {
 	Scheme             *string `json:"scheme"`
 	ACL                *string `json:"acl"` // string representation of Tunnel.TunnelACL field
 	IdleTimeoutMinutes int     `json:"idle_timeout_minutes"`
+	// ACLSessionUser is a username of the API user which sessions' IPs are allowed to use the tunnel instead of ACL.
+	ACLSessionUser string `json:"acl_session_user"`
+	// Protocol is either tcp or udp. Empty value means tcp.
+	Protocol string `json:"protocol"`
+	// Reverse is true if the client listens on the local address and the server connects to the remote address.
+	Reverse bool `json:"reverse"`
+	// HTTPProxy is true if the tunnel is served by the HTTP proxy of the server instead of a server port.
+	HTTPProxy bool `json:"http_proxy"`
+	// Socks is true if the server speaks SOCKS5 on the local address and each connection requests its own remote.
+	Socks bool `json:"socks"`
+	// MaxBytesPerSec limits the total bandwidth of all connections of the tunnel in both directions. Zero means no limit.
+	MaxBytesPerSec int64 `json:"max_bytes_per_sec"`
+	// MaxConnections limits the number of active connections of the tunnel. Zero means no limit.
+	MaxConnections int `json:"max_connections"`
+	// ExpiresAt is a time when the tunnel is terminated even if it has active connections. Nil means it never expires.
+	ExpiresAt *time.Time `json:"expires_at"`
+	// Persistent is true if the tunnel is re-established with its original ports and ACL when the client reconnects.
+	Persistent bool `json:"persistent"`
+	// CreatedBy is a username of the API user who created the tunnel. Empty if the tunnel was requested by the client.
+	CreatedBy string `json:"created_by"`
 }
 
 func DecodeRemote(s string) (*Remote, error) {
-	parts := strings.Split(s, ":")
+	r := &Remote{Protocol: ProtocolTCP}
+	if strings.HasPrefix(s, ReversePrefix) {
+		r.Reverse = true
+		s = s[len(ReversePrefix):]
+	}
+	if i := strings.LastIndex(s, "/"); i >= 0 {
+		r.Protocol = s[i+1:]
+		if !IsValidProtocol(r.Protocol) {
+			return nil, errors.New("Invalid protocol")
+		}
+		s = s[:i]
+	}
+
+	parts, err := splitRemote(s)
+	if err != nil {
+		return nil, err
+	}
 	if len(parts) <= 0 || len(parts) >= 5 {
 		return nil, errors.New("Invalid remote")
 	}
+	if parts[len(parts)-1] == SocksRemote {
+		if err := decodeSocksRemote(r, parts[:len(parts)-1]); err != nil {
+			return nil, err
+		}
+		return r, nil
+	}
 
-	r := &Remote{}
 	for i := len(parts) - 1; i >= 0; i-- {
 		p := parts[i]
 		if isPort(p) {
@@ -64,6 +130,12 @@ func DecodeRemote(s string) (*Remote, error) {
 			r.LocalHost = p
 		}
 	}
+	if r.Reverse {
+		if err := decodeReverseRemote(r); err != nil {
+			return nil, err
+		}
+		return r, nil
+	}
 	if r.LocalHost == "" && r.LocalPort != "" {
 		r.LocalHost = ZeroHost
 	}
@@ -73,6 +145,88 @@ func DecodeRemote(s string) (*Remote, error) {
 	return r, nil
 }
 
+// decodeReverseRemote sets defaults of a reverse remote. The client listens on localhost by default
+// and on the same port as the remote one if the local port is omitted.
+func decodeReverseRemote(r *Remote) error {
+	if r.IsUDP() {
+		return errors.New("Reverse UDP tunnels are not supported")
+	}
+	if r.LocalHost == "" {
+		r.LocalHost = "127.0.0.1"
+	}
+	if r.LocalPort == "" {
+		r.LocalPort = r.RemotePort
+	}
+	if r.RemoteHost == "" {
+		r.RemoteHost = "127.0.0.1"
+	}
+	return nil
+}
+
+// decodeSocksRemote decodes the local part of a SOCKS remote. The local port is random if it's omitted.
+func decodeSocksRemote(r *Remote, local []string) error {
+	if r.Reverse {
+		return errors.New("Reverse SOCKS tunnels are not supported")
+	}
+	if r.IsUDP() {
+		return errors.New("SOCKS tunnels over UDP are not supported")
+	}
+	r.Socks = true
+	switch len(local) {
+	case 0:
+		return nil
+	case 1:
+		r.LocalHost, r.LocalPort = ZeroHost, local[0]
+	case 2:
+		r.LocalHost, r.LocalPort = local[0], local[1]
+	default:
+		return errors.New("Invalid remote")
+	}
+	if !isPort(r.LocalPort) {
+		return errors.New("Missing ports")
+	}
+	if !isHost(r.LocalHost) {
+		return errors.New("Invalid host")
+	}
+	return nil
+}
+
+// splitRemote splits a given remote by colons except the ones of IPv6 addresses enclosed in square brackets.
+// The brackets are removed from the returned parts.
+func splitRemote(s string) ([]string, error) {
+	var parts []string
+	for {
+		var part string
+		if strings.HasPrefix(s, "[") {
+			end := strings.Index(s, "]")
+			if end < 0 {
+				return nil, errors.New("Invalid remote")
+			}
+			part = s[1:end]
+			if ip := net.ParseIP(part); ip == nil || ip.To4() != nil {
+				return nil, errors.New("Invalid host")
+			}
+			s = s[end+1:]
+			if s != "" && s[0] != ':' {
+				return nil, errors.New("Invalid remote")
+			}
+		} else {
+			end := strings.Index(s, ":")
+			if end < 0 {
+				end = len(s)
+			}
+			part = s[:end]
+			s = s[end:]
+		}
+		parts = append(parts, part)
+		if s == "" {
+			return parts, nil
+		}
+		// skip the colon
+		s = s[1:]
+	}
+}
+
 var isPortRegExp = regexp.MustCompile(`^\d+$`)
 
 func isPort(s string) bool {
@@ -80,25 +234,54 @@ func isPort(s string) bool {
 }
 
 func isHost(s string) bool {
+	if net.ParseIP(s) != nil {
+		return true
+	}
 	_, err := url.Parse(s)
 	return err == nil
 }
 
+// IsValidProtocol returns true if a given tunnel protocol is supported.
+func IsValidProtocol(protocol string) bool {
+	return protocol == ProtocolTCP || protocol == ProtocolUDP
+}
+
 //implement Stringer
 func (r *Remote) String() string {
-	s := r.LocalHost + ":" + r.LocalPort + ":" + r.Remote()
+	s := r.Local() + ":" + r.Remote()
+	if r.Reverse {
+		s = ReversePrefix + s
+	}
+	if r.IsUDP() {
+		s += "/" + ProtocolUDP
+	}
 	if r.ACL == nil {
 		return s
 	}
 	return s + "(acl:" + *r.ACL + ")"
 }
 
+// Local returns the address the server listens on, IPv6 hosts are enclosed in square brackets.
+func (r *Remote) Local() string {
+	return net.JoinHostPort(r.LocalHost, r.LocalPort)
+}
+
+// Remote returns the address the client connects to, IPv6 hosts are enclosed in square brackets.
+// SocksRemote is returned for SOCKS tunnels.
 func (r *Remote) Remote() string {
-	return r.RemoteHost + ":" + r.RemotePort
+	if r.Socks {
+		return SocksRemote
+	}
+	return net.JoinHostPort(r.RemoteHost, r.RemotePort)
 }
 
 func (r *Remote) Equals(other *Remote) bool {
-	return r.String() == other.String()
+	return r.String() == other.String() && r.HTTPProxy == other.HTTPProxy
+}
+
+// Expired returns true if the tunnel has reached its expiry time.
+func (r *Remote) Expired() bool {
+	return r.ExpiresAt != nil && !r.ExpiresAt.After(time.Now())
 }
 
 func (r *Remote) EqualACL(acl *string) bool {
@@ -111,6 +294,11 @@ func (r *Remote) EqualACL(acl *string) bool {
 	return false
 }
 
+// IsUDP returns true if datagrams are forwarded instead of a stream.
+func (r *Remote) IsUDP() bool {
+	return r.Protocol == ProtocolUDP
+}
+
 func (r *Remote) IsLocalSpecified() bool {
 	return r.LocalHost != "" && r.LocalPort != ""
 }
@@ -0,0 +1,120 @@
+package chshare
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecodeRemote(t *testing.T) {
+	testCases := []struct {
+		remote     string
+		wantRemote *Remote
+		wantString string
+	}{
+		{
+			remote:     "3000",
+			wantRemote: &Remote{RemoteHost: ZeroHost, RemotePort: "3000", Protocol: ProtocolTCP},
+			wantString: "::0.0.0.0:3000",
+		},
+		{
+			remote:     "192.168.0.1:3000:google.com:80",
+			wantRemote: &Remote{LocalHost: "192.168.0.1", LocalPort: "3000", RemoteHost: "google.com", RemotePort: "80", Protocol: ProtocolTCP},
+			wantString: "192.168.0.1:3000:google.com:80",
+		},
+		{
+			remote:     "[2001:db8::1]:80",
+			wantRemote: &Remote{RemoteHost: "2001:db8::1", RemotePort: "80", Protocol: ProtocolTCP},
+			wantString: "::[2001:db8::1]:80",
+		},
+		{
+			remote:     "3000:[::1]:22",
+			wantRemote: &Remote{LocalHost: ZeroHost, LocalPort: "3000", RemoteHost: "::1", RemotePort: "22", Protocol: ProtocolTCP},
+			wantString: "0.0.0.0:3000:[::1]:22",
+		},
+		{
+			remote:     "[::]:3000:[2001:db8::1]:53/udp",
+			wantRemote: &Remote{LocalHost: "::", LocalPort: "3000", RemoteHost: "2001:db8::1", RemotePort: "53", Protocol: ProtocolUDP},
+			wantString: "[::]:3000:[2001:db8::1]:53/udp",
+		},
+		{
+			remote:     "R:5432:db.internal:5432",
+			wantRemote: &Remote{LocalHost: "127.0.0.1", LocalPort: "5432", RemoteHost: "db.internal", RemotePort: "5432", Protocol: ProtocolTCP, Reverse: true},
+			wantString: "R:127.0.0.1:5432:db.internal:5432",
+		},
+		{
+			remote:     "R:8080",
+			wantRemote: &Remote{LocalHost: "127.0.0.1", LocalPort: "8080", RemoteHost: "127.0.0.1", RemotePort: "8080", Protocol: ProtocolTCP, Reverse: true},
+			wantString: "R:127.0.0.1:8080:127.0.0.1:8080",
+		},
+		{
+			remote:     "R:[::]:15432:10.0.0.5:5432",
+			wantRemote: &Remote{LocalHost: "::", LocalPort: "15432", RemoteHost: "10.0.0.5", RemotePort: "5432", Protocol: ProtocolTCP, Reverse: true},
+			wantString: "R:[::]:15432:10.0.0.5:5432",
+		},
+		{
+			remote:     "1080:socks",
+			wantRemote: &Remote{LocalHost: ZeroHost, LocalPort: "1080", Protocol: ProtocolTCP, Socks: true},
+			wantString: "0.0.0.0:1080:socks",
+		},
+		{
+			remote:     "[::1]:1080:socks",
+			wantRemote: &Remote{LocalHost: "::1", LocalPort: "1080", Protocol: ProtocolTCP, Socks: true},
+			wantString: "[::1]:1080:socks",
+		},
+		{
+			remote:     "socks",
+			wantRemote: &Remote{Protocol: ProtocolTCP, Socks: true},
+			wantString: "::socks",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.remote, func(t *testing.T) {
+			got, err := DecodeRemote(tc.remote)
+
+			require.NoError(t, err)
+			assert.Equal(t, tc.wantRemote, got)
+			assert.Equal(t, tc.wantString, got.String())
+		})
+	}
+}
+
+func TestDecodeRemoteInvalid(t *testing.T) {
+	testCases := []struct {
+		remote  string
+		wantErr string
+	}{
+		{remote: "1:2:3:4:5", wantErr: "Invalid remote"},
+		{remote: "[::1:22", wantErr: "Invalid remote"},
+		{remote: "[::1]22", wantErr: "Invalid remote"},
+		{remote: "[127.0.0.1]:22", wantErr: "Invalid host"},
+		{remote: "[foo]:22", wantErr: "Invalid host"},
+		{remote: "localhost", wantErr: "Missing ports"},
+		{remote: "22/sctp", wantErr: "Invalid protocol"},
+		{remote: "R:5353:8.8.8.8:53/udp", wantErr: "Reverse UDP tunnels are not supported"},
+		{remote: "R:1080:socks", wantErr: "Reverse SOCKS tunnels are not supported"},
+		{remote: "1080:socks/udp", wantErr: "SOCKS tunnels over UDP are not supported"},
+		{remote: "localhost:socks", wantErr: "Missing ports"},
+		{remote: "1:2:1080:socks", wantErr: "Invalid remote"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.remote, func(t *testing.T) {
+			_, err := DecodeRemote(tc.remote)
+
+			assert.EqualError(t, err, tc.wantErr)
+		})
+	}
+}
+
+func TestRemoteExpired(t *testing.T) {
+	past := time.Now().Add(-time.Second)
+	future := time.Now().Add(time.Hour)
+
+	assert.False(t, (&Remote{}).Expired())
+	assert.True(t, (&Remote{ExpiresAt: &past}).Expired())
+	assert.False(t, (&Remote{ExpiresAt: &future}).Expired())
+}
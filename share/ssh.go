@@ -9,21 +9,25 @@ import (
 	"encoding/pem"
 	"fmt"
 	"io"
+	"math/big"
 	"net"
+	"regexp"
 	"strings"
 
 	"github.com/jpillora/sizestr"
 	"golang.org/x/crypto/ssh"
 )
 
+// GenerateKey returns a new PEM encoded ECDSA P-256 private key. If a seed is given, the same key is returned for
+// the same seed.
 func GenerateKey(seed string) ([]byte, error) {
-	var r io.Reader
+	var priv *ecdsa.PrivateKey
+	var err error
 	if seed == "" {
-		r = rand.Reader
+		priv, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
 	} else {
-		r = NewDetermRand([]byte(seed))
+		priv, err = generateKeyFromSeed(seed)
 	}
-	priv, err := ecdsa.GenerateKey(elliptic.P256(), r)
 	if err != nil {
 		return nil, err
 	}
@@ -34,7 +38,36 @@ func GenerateKey(seed string) ([]byte, error) {
 	return pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: b}), nil
 }
 
+// generateKeyFromSeed derives the private scalar directly from a given seed. ecdsa.GenerateKey can't be used with
+// a deterministic reader, because newer Go versions don't read the key from it, so the key would change on each call.
+func generateKeyFromSeed(seed string) (*ecdsa.PrivateKey, error) {
+	curve := elliptic.P256()
+	params := curve.Params()
+
+	// 8 extra bytes make the bias of the reduction negligible, see FIPS 186-4 B.4.1
+	b := make([]byte, params.BitSize/8+8)
+	if _, err := io.ReadFull(NewDetermRand([]byte(seed)), b); err != nil {
+		return nil, err
+	}
+	// d = b mod (N-1) + 1 is in [1, N-1]
+	one := big.NewInt(1)
+	d := new(big.Int).SetBytes(b)
+	d.Mod(d, new(big.Int).Sub(params.N, one))
+	d.Add(d, one)
+
+	priv := &ecdsa.PrivateKey{D: d}
+	priv.PublicKey.Curve = curve
+	priv.PublicKey.X, priv.PublicKey.Y = curve.ScalarBaseMult(d.Bytes())
+	return priv, nil
+}
+
+// FingerprintKey returns a SHA256 fingerprint of a given key in the OpenSSH format, e.g. "SHA256:mVPwvezndPv/ARo...".
 func FingerprintKey(k ssh.PublicKey) string {
+	return ssh.FingerprintSHA256(k)
+}
+
+// FingerprintKeyMD5 returns a legacy md5 fingerprint of a given key, e.g. "36:98:56:12:...".
+func FingerprintKeyMD5(k ssh.PublicKey) string {
 	bytes := md5.Sum(k.Marshal())
 	strbytes := make([]string, len(bytes))
 	for i, b := range bytes {
@@ -43,7 +76,39 @@ func FingerprintKey(k ssh.PublicKey) string {
 	return strings.Join(strbytes, ":")
 }
 
-func HandleTCPStream(l *Logger, connStats *ConnStats, src io.ReadWriteCloser, remote string) {
+const fingerprintPrefixMD5 = "MD5:"
+
+var md5FingerprintRegexp = regexp.MustCompile(`^([0-9a-f]{2}:)*[0-9a-f]{0,2}$`)
+
+// IsMD5Fingerprint returns true if a given fingerprint is in the legacy md5 format, a prefix of the fingerprint is
+// allowed.
+func IsMD5Fingerprint(fingerprint string) bool {
+	return md5FingerprintRegexp.MatchString(strings.TrimPrefix(strings.ToLower(fingerprint), strings.ToLower(fingerprintPrefixMD5)))
+}
+
+// ValidateFingerprint returns an error if a given fingerprint is neither a SHA256 nor a legacy md5 fingerprint.
+func ValidateFingerprint(fingerprint string) error {
+	if strings.HasPrefix(fingerprint, "SHA256:") && len(fingerprint) > len("SHA256:") {
+		return nil
+	}
+	if fingerprint != "" && IsMD5Fingerprint(fingerprint) {
+		return nil
+	}
+	return fmt.Errorf("invalid fingerprint %q: expected 'SHA256:<base64>' or md5 'xx:xx:...'", fingerprint)
+}
+
+// MatchFingerprint returns true if a given fingerprint identifies a given key. SHA256 fingerprints must match exactly,
+// for compatibility legacy md5 fingerprints may be a prefix of the key fingerprint.
+func MatchFingerprint(fingerprint string, k ssh.PublicKey) bool {
+	if strings.HasPrefix(fingerprint, "SHA256:") {
+		return fingerprint == FingerprintKey(k)
+	}
+	fingerprint = strings.TrimPrefix(strings.ToLower(fingerprint), strings.ToLower(fingerprintPrefixMD5))
+	return fingerprint != "" && strings.HasPrefix(FingerprintKeyMD5(k), fingerprint)
+}
+
+// HandleTCPStream connects a given stream to a given remote address, the traffic is limited by given token buckets.
+func HandleTCPStream(l *Logger, connStats *ConnStats, src io.ReadWriteCloser, remote string, limits ...*TokenBucket) {
 	dst, err := net.Dial("tcp", remote)
 	if err != nil {
 		l.Debugf("Remote failed (%s)", err)
@@ -52,7 +117,7 @@ func HandleTCPStream(l *Logger, connStats *ConnStats, src io.ReadWriteCloser, re
 	}
 	connStats.Open()
 	l.Debugf("%s: Open", connStats)
-	s, r := Pipe(src, dst)
+	s, r := Pipe(src, dst, limits...)
 	connStats.Close()
 	l.Debugf("%s: Close (sent %s received %s)", connStats, sizestr.ToString(s), sizestr.ToString(r))
 }
@@ -0,0 +1,72 @@
+package chshare
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh"
+)
+
+func TestGenerateKey(t *testing.T) {
+	seeded1, err := GenerateKey("seed")
+	require.NoError(t, err)
+	seeded2, err := GenerateKey("seed")
+	require.NoError(t, err)
+	otherSeed, err := GenerateKey("other seed")
+	require.NoError(t, err)
+	random1, err := GenerateKey("")
+	require.NoError(t, err)
+	random2, err := GenerateKey("")
+	require.NoError(t, err)
+
+	assert.Equal(t, seeded1, seeded2)
+	assert.NotEqual(t, seeded1, otherSeed)
+	assert.NotEqual(t, random1, random2)
+
+	signer, err := ssh.ParsePrivateKey(seeded1)
+	require.NoError(t, err)
+	assert.Equal(t, "ecdsa-sha2-nistp256", signer.PublicKey().Type())
+}
+
+func TestMatchFingerprint(t *testing.T) {
+	key, err := GenerateKey("seed")
+	require.NoError(t, err)
+	signer, err := ssh.ParsePrivateKey(key)
+	require.NoError(t, err)
+	pub := signer.PublicKey()
+	sha256 := FingerprintKey(pub)
+	md5 := FingerprintKeyMD5(pub)
+	require.True(t, strings.HasPrefix(sha256, "SHA256:"))
+
+	testCases := []struct {
+		Fingerprint string
+		Expected    bool
+	}{
+		{Fingerprint: sha256, Expected: true},
+		{Fingerprint: sha256[:20], Expected: false},
+		{Fingerprint: "SHA256:" + strings.Repeat("A", 43), Expected: false},
+		{Fingerprint: md5, Expected: true},
+		{Fingerprint: md5[:8], Expected: true},
+		{Fingerprint: "MD5:" + md5, Expected: true},
+		{Fingerprint: strings.ToUpper(md5), Expected: true},
+		{Fingerprint: "00:00", Expected: false},
+		{Fingerprint: "", Expected: false},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.Fingerprint, func(t *testing.T) {
+			assert.Equal(t, tc.Expected, MatchFingerprint(tc.Fingerprint, pub))
+		})
+	}
+}
+
+func TestValidateFingerprint(t *testing.T) {
+	assert.NoError(t, ValidateFingerprint("SHA256:mVPwvezndPv/ARoIadVY98vAC0g+P/5633yTC4d/wXE"))
+	assert.NoError(t, ValidateFingerprint("36:98:56:12:f3:dc:e5:8d:ac:96:48:23:b6:f0:42:15"))
+	assert.NoError(t, ValidateFingerprint("36:98:56"))
+	assert.NoError(t, ValidateFingerprint("MD5:36:98:56"))
+	assert.EqualError(t, ValidateFingerprint("SHA256:"), `invalid fingerprint "SHA256:": expected 'SHA256:<base64>' or md5 'xx:xx:...'`)
+	assert.Error(t, ValidateFingerprint("mVPwvezndPv/ARoIadVY98vAC0g"))
+	assert.Error(t, ValidateFingerprint(""))
+}
@@ -1,6 +1,7 @@
 package test
 
 import (
+	"io"
 	"net"
 	"sync"
 
@@ -16,10 +17,15 @@ type ConnMock struct {
 	ReturnResponsePayload []byte
 	ReturnErr             error
 	ReturnRemoteAddr      net.Addr
+	ReturnChannel         ssh.Channel
+	ReturnOpenChannelErr  error
 
 	inputRequestName string
 	inputWantReply   bool
 	inputPayload     []byte
+
+	inputChannelType string
+	inputExtraData   []byte
 }
 
 func NewConnMock() *ConnMock {
@@ -47,3 +53,77 @@ func (c *ConnMock) InputSendRequest() (name string, wantReply bool, payload []by
 func (c *ConnMock) RemoteAddr() net.Addr {
 	return c.ReturnRemoteAddr
 }
+
+func (c *ConnMock) OpenChannel(name string, data []byte) (ssh.Channel, <-chan *ssh.Request, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.inputChannelType = name
+	c.inputExtraData = data
+	if c.ReturnOpenChannelErr != nil {
+		return nil, nil, c.ReturnOpenChannelErr
+	}
+	reqs := make(chan *ssh.Request)
+	close(reqs)
+	return c.ReturnChannel, reqs, nil
+}
+
+func (c *ConnMock) InputOpenChannel() (name string, data []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.inputChannelType, c.inputExtraData
+}
+
+// ChannelMock is an ssh channel connected to another ChannelMock returned by NewChannelPair.
+type ChannelMock struct {
+	net.Conn
+}
+
+// NewChannelPair returns two connected channels. Data written to one of them can be read from the other.
+func NewChannelPair() (*ChannelMock, *ChannelMock) {
+	c1, c2 := net.Pipe()
+	return &ChannelMock{Conn: c1}, &ChannelMock{Conn: c2}
+}
+
+func (c *ChannelMock) CloseWrite() error {
+	return nil
+}
+
+func (c *ChannelMock) SendRequest(name string, wantReply bool, payload []byte) (bool, error) {
+	return false, nil
+}
+
+func (c *ChannelMock) Stderr() io.ReadWriter {
+	return nil
+}
+
+// NewChannelMock is an ssh new channel request that returns a given channel when accepted.
+type NewChannelMock struct {
+	Type    string
+	Data    []byte
+	Channel ssh.Channel
+
+	Rejected      bool
+	RejectReason  ssh.RejectionReason
+	RejectMessage string
+}
+
+func (c *NewChannelMock) Accept() (ssh.Channel, <-chan *ssh.Request, error) {
+	reqs := make(chan *ssh.Request)
+	close(reqs)
+	return c.Channel, reqs, nil
+}
+
+func (c *NewChannelMock) Reject(reason ssh.RejectionReason, message string) error {
+	c.Rejected = true
+	c.RejectReason = reason
+	c.RejectMessage = message
+	return nil
+}
+
+func (c *NewChannelMock) ChannelType() string {
+	return c.Type
+}
+
+func (c *NewChannelMock) ExtraData() []byte {
+	return c.Data
+}
@@ -0,0 +1,90 @@
+package chshare
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"net"
+
+	"github.com/jpillora/sizestr"
+)
+
+// MaxDatagramSize is the max size of a UDP datagram that can be forwarded.
+const MaxDatagramSize = math.MaxUint16
+
+// WriteDatagram writes a given datagram to a stream prefixed with its length as 2 bytes in big endian order.
+func WriteDatagram(w io.Writer, b []byte) error {
+	if len(b) > MaxDatagramSize {
+		return fmt.Errorf("datagram is too big: %d bytes", len(b))
+	}
+	frame := make([]byte, 2+len(b))
+	binary.BigEndian.PutUint16(frame, uint16(len(b)))
+	copy(frame[2:], b)
+	_, err := w.Write(frame)
+	return err
+}
+
+// ReadDatagram reads a datagram that was written by WriteDatagram. A given buffer should be at least MaxDatagramSize
+// bytes long, the returned datagram is a slice of it.
+func ReadDatagram(r io.Reader, buf []byte) ([]byte, error) {
+	if _, err := io.ReadFull(r, buf[:2]); err != nil {
+		return nil, err
+	}
+	n := binary.BigEndian.Uint16(buf)
+	if _, err := io.ReadFull(r, buf[:n]); err != nil {
+		return nil, err
+	}
+	return buf[:n], nil
+}
+
+// HandleUDPStream forwards datagrams read from a given stream to a given remote address
+// and sends back datagrams the remote replies with. The traffic is limited by given token buckets.
+func HandleUDPStream(l *Logger, connStats *ConnStats, src io.ReadWriteCloser, remote string, limits ...*TokenBucket) {
+	dst, err := net.Dial("udp", remote)
+	if err != nil {
+		l.Debugf("Remote failed (%s)", err)
+		src.Close()
+		return
+	}
+	connStats.Open()
+	l.Debugf("%s: Open UDP", connStats)
+
+	var received int64
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		defer src.Close()
+		buf := make([]byte, MaxDatagramSize)
+		for {
+			n, err := dst.Read(buf)
+			if err != nil {
+				return
+			}
+			waitAll(limits, n)
+			if err := WriteDatagram(src, buf[:n]); err != nil {
+				return
+			}
+			received += int64(n)
+		}
+	}()
+
+	var sent int64
+	buf := make([]byte, MaxDatagramSize)
+	for {
+		b, err := ReadDatagram(src, buf)
+		if err != nil {
+			break
+		}
+		waitAll(limits, len(b))
+		if _, err := dst.Write(b); err != nil {
+			break
+		}
+		sent += int64(len(b))
+	}
+	dst.Close()
+	<-done
+
+	connStats.Close()
+	l.Debugf("%s: Close UDP (sent %s received %s)", connStats, sizestr.ToString(sent), sizestr.ToString(received))
+}
@@ -0,0 +1,65 @@
+package chshare
+
+import (
+	"bytes"
+	"net"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteAndReadDatagram(t *testing.T) {
+	var stream bytes.Buffer
+	datagrams := [][]byte{[]byte("first"), {}, bytes.Repeat([]byte{1}, MaxDatagramSize)}
+	for _, d := range datagrams {
+		require.NoError(t, WriteDatagram(&stream, d))
+	}
+
+	buf := make([]byte, MaxDatagramSize)
+	for _, d := range datagrams {
+		got, err := ReadDatagram(&stream, buf)
+		require.NoError(t, err)
+		assert.Equal(t, d, got)
+	}
+
+	assert.EqualError(t, WriteDatagram(&stream, make([]byte, MaxDatagramSize+1)), "datagram is too big: 65536 bytes")
+}
+
+func TestHandleUDPStream(t *testing.T) {
+	// given
+	echo, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer echo.Close()
+	go func() {
+		buf := make([]byte, MaxDatagramSize)
+		for {
+			n, addr, err := echo.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			_, _ = echo.WriteTo(append([]byte("echo "), buf[:n]...), addr)
+		}
+	}()
+	src, stream := net.Pipe()
+	done := make(chan struct{})
+	l := NewLogger("udp", LogOutput{File: os.Stdout}, LogLevelDebug)
+
+	// when
+	go func() {
+		HandleUDPStream(l, &ConnStats{}, stream, echo.LocalAddr().String())
+		close(done)
+	}()
+
+	// then
+	buf := make([]byte, MaxDatagramSize)
+	for _, msg := range []string{"ping", "pong"} {
+		require.NoError(t, WriteDatagram(src, []byte(msg)))
+		got, err := ReadDatagram(src, buf)
+		require.NoError(t, err)
+		assert.Equal(t, "echo "+msg, string(got))
+	}
+	src.Close()
+	<-done
+}